@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/pkg/errors"
+)
+
+// keys of the authenticated user and of its token in the gin context, set by the auth middleware
+const (
+	CTX_USER  = "user"
+	CTX_TOKEN = "token"
+)
+
+type AuthController struct {
+	AuthSrv auth.AuthService
+}
+
+func (actl *AuthController) Register(gc *gin.Context) {
+	var cred auth.CredentialsDto
+	if err := gc.ShouldBindJSON(&cred); err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "username and password are required"})
+		return
+	}
+
+	user, err := actl.AuthSrv.Register(gc, &cred)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidRegistration):
+			gc.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+		case errors.Is(err, auth.ErrUserExisted):
+			gc.JSON(http.StatusConflict, ErrorResponse{Message: err.Error()})
+		case errors.Is(err, auth.ErrRegistrationClosed):
+			gc.JSON(http.StatusForbidden, ErrorResponse{Message: err.Error()})
+		default:
+			logger.Log.Error().Err(err).Msg("request process failed")
+			gc.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		}
+		return
+	}
+
+	gc.JSON(http.StatusOK, user)
+}
+
+func (actl *AuthController) Login(gc *gin.Context) {
+	var cred auth.CredentialsDto
+	if err := gc.ShouldBindJSON(&cred); err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "username and password are required"})
+		return
+	}
+
+	token, err := actl.AuthSrv.Login(gc, &cred)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			gc.JSON(http.StatusUnauthorized, ErrorResponse{Message: err.Error()})
+			return
+		}
+		logger.Log.Error().Err(err).Msg("request process failed")
+		gc.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, token)
+}
+
+func (actl *AuthController) Logout(gc *gin.Context) {
+	err := actl.AuthSrv.Logout(gc, gc.GetString(CTX_TOKEN))
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("request process failed")
+		gc.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, "Success")
+}
+
+func (actl *AuthController) Me(gc *gin.Context) {
+	user, ok := auth.UserFromContext(gc)
+	if !ok {
+		gc.JSON(http.StatusUnauthorized, ErrorResponse{Message: auth.ErrUnauthorized.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, user)
+}
+
+func (actl *AuthController) CreateApiToken(gc *gin.Context) {
+	token, err := actl.AuthSrv.CreateApiToken(gc, gc.Query("name"))
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("request process failed")
+		gc.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, token)
+}
+
+func (actl *AuthController) ListTokens(gc *gin.Context) {
+	tokens, err := actl.AuthSrv.ListTokens(gc)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("request process failed")
+		gc.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, *tokens)
+}
+
+func (actl *AuthController) RevokeToken(gc *gin.Context) {
+	tokenID, err := strconv.ParseUint(gc.Param("token-id"), 10, 64)
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "token id must be a number"})
+		return
+	}
+
+	err = actl.AuthSrv.RevokeToken(gc, tokenID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("request process failed")
+		gc.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, "Success")
+}
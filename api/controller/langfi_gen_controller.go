@@ -1,11 +1,13 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model/jp"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
 )
@@ -47,7 +49,12 @@ func (jctl *JpxController) GetWordList(gc *gin.Context) {
 }
 
 func (jctl *JpxController) GetAvailableLang(gc *gin.Context) {
-	langs := []string{"jp"}
+	langs, err := jctl.JpxService.GetAvailableLangs(gc)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("request process failed")
+		gc.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		return
+	}
 
 	gc.JSON(http.StatusOK, langs)
 }
@@ -55,7 +62,7 @@ func (jctl *JpxController) GetAvailableLang(gc *gin.Context) {
 func (jctl *JpxController) FetchProposal(gc *gin.Context) {
 	group := gc.Query("group")
 	if group == "" {
-		group = "NA"
+		group = gc.Param("lang-id")
 	}
 	proposal, err := jctl.JpxService.FetchProposal(gc, group)
 	if err != nil {
@@ -107,13 +114,64 @@ func (jctl *JpxController) EditProposal(gc *gin.Context) {
 	updated, err := jctl.JpxService.EditCardText(gc, &newCard)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("request process failed")
-		gc.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		gc.JSON(revisionErrorStatus(err), ErrorResponse{Message: err.Error()})
 		return
 	}
 
 	gc.JSON(http.StatusOK, updated)
 }
 
+func revisionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, langfi.ErrInvalidRevision):
+		return http.StatusBadRequest
+	case errors.Is(err, langfi.ErrStaleRevision):
+		return http.StatusConflict
+	case errors.Is(err, model.ErrNoMoreDataAvailable):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (jctl *JpxController) GetCardRevisions(gc *gin.Context) {
+	cardID, err := strconv.ParseUint(gc.Param("card-id"), 10, 64)
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "card-id must be a number"})
+		return
+	}
+
+	revisions, err := jctl.JpxService.GetCardRevisions(gc, cardID)
+	if err != nil {
+		gc.JSON(revisionErrorStatus(err), ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, *revisions)
+}
+
+func (jctl *JpxController) RevertCard(gc *gin.Context) {
+	cardID, err := strconv.ParseUint(gc.Param("card-id"), 10, 64)
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "card-id must be a number"})
+		return
+	}
+	revisionID, err := strconv.ParseUint(gc.Param("revision-id"), 10, 64)
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "revision-id must be a number"})
+		return
+	}
+
+	card, err := jctl.JpxService.RevertCard(gc, cardID, revisionID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("request process failed")
+		gc.JSON(revisionErrorStatus(err), ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, card)
+}
+
 func (jctl *JpxController) DeleteAllNewCard(gc *gin.Context) {
 	err := jctl.JpxService.DeleteNewCards(gc)
 	if err != nil {
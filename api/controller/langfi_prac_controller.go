@@ -1,11 +1,19 @@
 package controller
 
 import (
+	"archive/zip"
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/nhuongmh/cfvs.jpx/pkg/supporter/anki"
 )
 
 type PracticeController struct {
@@ -13,16 +21,20 @@ type PracticeController struct {
 }
 
 func (pctl *PracticeController) GetPracticeGroups(gc *gin.Context) {
-	// langID := gc.Param("lang-id")
-	groups := pctl.PracticeSrv.GetGroups(gc)
+	langID := gc.Param("lang-id")
+	groups, err := pctl.PracticeSrv.GetGroups(gc, langID)
+	if err != nil {
+		gc.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		return
+	}
 	gc.JSON(http.StatusOK, groups)
 }
 
 func (pctl *PracticeController) FetchPracticeCard(gc *gin.Context) {
-	// langID := gc.Param("lang-id")
+	// without group, practice the whole language deck
 	group := gc.Query("group")
 	if group == "" {
-		group = "NA"
+		group = gc.Param("lang-id")
 	}
 	card, err := pctl.PracticeSrv.FetchCard(gc, group)
 	if err != nil {
@@ -93,3 +105,322 @@ func (pctl *PracticeController) GetGroupStats(gc *gin.Context) {
 
 	gc.JSON(http.StatusOK, *stats)
 }
+
+func (pctl *PracticeController) GetReviewStats(gc *gin.Context) {
+	forecastDays, err := strconv.Atoi(gc.DefaultQuery("days", "0"))
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "days must be a number"})
+		return
+	}
+
+	heatmapDays, err := strconv.Atoi(gc.DefaultQuery("heatmap_days", "0"))
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "heatmap_days must be a number"})
+		return
+	}
+
+	windowDays, err := strconv.Atoi(gc.DefaultQuery("window_days", "0"))
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "window_days must be a number"})
+		return
+	}
+
+	// minutes east of UTC, days are counted from the midnight of the client
+	tz, err := strconv.Atoi(gc.DefaultQuery("tz", "0"))
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "tz must be a number of minutes"})
+		return
+	}
+
+	stats, err := pctl.PracticeSrv.GetReviewStats(gc, &langfi.ReviewStatsQuery{
+		ForecastDays: forecastDays,
+		HeatmapDays:  heatmapDays,
+		WindowDays:   windowDays,
+		TzMinutes:    tz,
+	})
+	if errors.Is(err, langfi.ErrInvalidStatsQuery) {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		gc.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, *stats)
+}
+
+func (pctl *PracticeController) SubmitTypedAnswer(gc *gin.Context) {
+	var answer langfi.TypedAnswerDto
+	err := gc.ShouldBindJSON(&answer)
+	if err != nil || answer.CardID == 0 {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "card_id and answer are required"})
+		return
+	}
+
+	result, err := pctl.PracticeSrv.SubmitTypedAnswer(gc, &answer)
+	if err != nil {
+		gc.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, result)
+}
+
+func cardQueryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, langfi.ErrInvalidCardQuery):
+		return http.StatusBadRequest
+	case errors.Is(err, model.ErrNoMoreDataAvailable):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (pctl *PracticeController) SearchCards(gc *gin.Context) {
+	limit, err := strconv.Atoi(gc.DefaultQuery("limit", "0"))
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "limit must be a number"})
+		return
+	}
+
+	cards, err := pctl.PracticeSrv.SearchCards(gc, gc.Query("q"), limit)
+	if err != nil {
+		gc.JSON(cardQueryErrorStatus(err), ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, *cards)
+}
+
+func (pctl *PracticeController) FetchFilteredCard(gc *gin.Context) {
+	card, err := pctl.PracticeSrv.FetchFilteredCard(gc, gc.Query("q"))
+	if err != nil {
+		gc.JSON(cardQueryErrorStatus(err), ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, card)
+}
+
+func (pctl *PracticeController) BulkEditCards(gc *gin.Context) {
+	var edit langfi.BulkEditDto
+	err := gc.ShouldBindJSON(&edit)
+	if err != nil || edit.Query == "" {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "query is required"})
+		return
+	}
+
+	result, err := pctl.PracticeSrv.BulkEditCards(gc, &edit)
+	if err != nil {
+		gc.JSON(cardQueryErrorStatus(err), ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, result)
+}
+
+func deckErrorStatus(err error) int {
+	if errors.Is(err, langfi.ErrInvalidDeck) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (pctl *PracticeController) GetDeckTree(gc *gin.Context) {
+	tree, err := pctl.PracticeSrv.GetDeckTree(gc)
+	if err != nil {
+		gc.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, *tree)
+}
+
+func (pctl *PracticeController) CreateDeck(gc *gin.Context) {
+	var deckDto langfi.CreateDeckDto
+	err := gc.ShouldBindJSON(&deckDto)
+	if err != nil || deckDto.Name == "" {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "name is required"})
+		return
+	}
+
+	deck, err := pctl.PracticeSrv.CreateDeck(gc, deckDto.Name)
+	if err != nil {
+		gc.JSON(deckErrorStatus(err), ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, deck)
+}
+
+func (pctl *PracticeController) UpdateDeckSettings(gc *gin.Context) {
+	deckID, err := strconv.ParseUint(gc.Param("deck-id"), 10, 64)
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "deck-id must be a number"})
+		return
+	}
+
+	var settings langfi.DeckSettings
+	err = gc.ShouldBindJSON(&settings)
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid deck settings"})
+		return
+	}
+
+	deck, err := pctl.PracticeSrv.UpdateDeckSettings(gc, deckID, &settings)
+	if err != nil {
+		gc.JSON(deckErrorStatus(err), ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, deck)
+}
+
+// ExportAnki downloads an .apkg of the cards matching the query. GET takes the query string
+// parameters q, deck and scheduling, POST takes an AnkiExportDto to also choose the note model.
+// The cards are loaded and the collection is built before the response starts, only the zip
+// is written to the response as it is compressed, so it has no Content-Length.
+func (pctl *PracticeController) ExportAnki(gc *gin.Context) {
+	export := langfi.AnkiExportDto{Query: gc.Query("q"), Deck: gc.Query("deck"), IncludeScheduling: gc.Query("scheduling") == "true"}
+	if gc.Request.Method == http.MethodPost {
+		err := gc.ShouldBindJSON(&export)
+		if err != nil {
+			gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid export request"})
+			return
+		}
+	}
+
+	// only cards of the language of the url are exported
+	export.Query = fmt.Sprintf(`%v:"%v" %v`, langfi.QUERY_GROUP, gc.Param("lang-id"), export.Query)
+
+	pkg, err := pctl.PracticeSrv.ExportAnki(gc, &export)
+	if err != nil {
+		gc.JSON(cardQueryErrorStatus(err), ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.Header("Content-Type", "application/octet-stream")
+	gc.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%v.apkg"`, gc.Param("lang-id")))
+	err = pkg.Export(gc.Writer)
+	if err != nil {
+		// the download is already started, the client gets a truncated file
+		logger.Log.Error().Err(err).Msg("failed to export anki package")
+	}
+}
+
+// ImportAnki imports an uploaded .apkg or .colpkg from the multipart field file. The form
+// fields deck, front_field, back_field and dry_run are passed on as AnkiImportDto.
+func (pctl *PracticeController) ImportAnki(gc *gin.Context) {
+	gc.Request.Body = http.MaxBytesReader(gc.Writer, gc.Request.Body, langfi.MAX_ANKI_IMPORT_BYTES)
+	file, err := gc.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		gc.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Message: fmt.Sprintf("package is larger than %v bytes", tooLarge.Limit)})
+		return
+	}
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "file is required"})
+		return
+	}
+	opts := langfi.AnkiImportDto{
+		Deck:       gc.DefaultPostForm("deck", gc.Param("lang-id")),
+		FrontField: gc.PostForm("front_field"),
+		BackField:  gc.PostForm("back_field"),
+		DryRun:     gc.PostForm("dry_run") == "true",
+	}
+
+	tmpDir, err := os.MkdirTemp("", "anki-upload")
+	if err != nil {
+		gc.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "upload.apkg")
+	err = gc.SaveUploadedFile(file, path)
+	if err != nil {
+		gc.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	report, err := pctl.PracticeSrv.ImportAnki(gc, path, &opts)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, anki.ErrUnsupportedPackage) || errors.Is(err, langfi.ErrInvalidDeck) || errors.Is(err, zip.ErrFormat) {
+			status = http.StatusBadRequest
+		}
+		if errors.Is(err, anki.ErrPackageTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		gc.JSON(status, ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, report)
+}
+
+func syncErrorStatus(err error) int {
+	if errors.Is(err, langfi.ErrInvalidSync) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// PullChanges returns the cards changed since `cursor`, clients start with cursor 0
+// and pull again with the returned cursor while has_more is set
+func (pctl *PracticeController) PullChanges(gc *gin.Context) {
+	cursor, err := strconv.ParseUint(gc.DefaultQuery("cursor", "0"), 10, 64)
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "cursor must be a number"})
+		return
+	}
+	limit, err := strconv.Atoi(gc.DefaultQuery("limit", "0"))
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "limit must be a number"})
+		return
+	}
+
+	pull, err := pctl.PracticeSrv.PullChanges(gc, cursor, limit)
+	if err != nil {
+		gc.JSON(syncErrorStatus(err), ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, pull)
+}
+
+func (pctl *PracticeController) PushReviews(gc *gin.Context) {
+	var push langfi.SyncPushDto
+	err := gc.ShouldBindJSON(&push)
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "reviews are required"})
+		return
+	}
+
+	result, err := pctl.PracticeSrv.PushReviews(gc, &push)
+	if err != nil {
+		gc.JSON(syncErrorStatus(err), ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, result)
+}
+
+// GetReviewQueue queues the cards of every `deck` query parameter, e.g. ?deck=jp&deck=en::IE,
+// or of every root deck when there is none
+func (pctl *PracticeController) GetReviewQueue(gc *gin.Context) {
+	size, err := strconv.Atoi(gc.DefaultQuery("size", "0"))
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "size must be a number"})
+		return
+	}
+
+	queue, err := pctl.PracticeSrv.GetReviewQueue(gc, gc.QueryArray("deck"), size)
+	if err != nil {
+		gc.JSON(deckErrorStatus(err), ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, queue)
+}
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/search"
+)
+
+type SearchController struct {
+	SearchSrv search.SearchService
+}
+
+func searchErrorStatus(err error) int {
+	if errors.Is(err, search.ErrInvalidSearch) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// Search looks up `q` in cards, articles and vocab, `kinds` is a comma separated subset of them
+func (sctl *SearchController) Search(gc *gin.Context) {
+	limit, err := strconv.Atoi(gc.DefaultQuery("limit", "0"))
+	if err != nil {
+		gc.JSON(http.StatusBadRequest, ErrorResponse{Message: "limit must be a number"})
+		return
+	}
+	kinds := []string{}
+	for _, kind := range strings.Split(gc.Query("kinds"), ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			kinds = append(kinds, kind)
+		}
+	}
+
+	hits, err := sctl.SearchSrv.Search(gc, gc.Query("q"), kinds, limit)
+	if err != nil {
+		gc.JSON(searchErrorStatus(err), ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	gc.JSON(http.StatusOK, *hits)
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nhuongmh/cfvs.jpx/api/controller"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+)
+
+func bearerToken(gc *gin.Context) string {
+	header := gc.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+func setUser(gc *gin.Context, user *auth.User, token string) {
+	gc.Set(controller.CTX_USER, user)
+	gc.Set(controller.CTX_TOKEN, token)
+	// services read the user from the request context, the engine must enable ContextWithFallback
+	gc.Request = gc.Request.WithContext(auth.WithUser(gc.Request.Context(), user))
+}
+
+// RequireAuth rejects requests without a valid bearer token
+func RequireAuth(authSrv auth.AuthService) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		if gc.Request.Method == http.MethodOptions {
+			gc.Next()
+			return
+		}
+		token := bearerToken(gc)
+		user, err := authSrv.Authenticate(gc.Request.Context(), token)
+		if err != nil {
+			gc.AbortWithStatusJSON(http.StatusUnauthorized, controller.ErrorResponse{Message: auth.ErrUnauthorized.Error()})
+			return
+		}
+		setUser(gc, user, token)
+		gc.Next()
+	}
+}
+
+// OptionalAuth attaches the user when a valid token is sent, public routes serve
+// anonymous requests shared data only
+func OptionalAuth(authSrv auth.AuthService) gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		token := bearerToken(gc)
+		if token == "" {
+			gc.Next()
+			return
+		}
+		user, err := authSrv.Authenticate(gc.Request.Context(), token)
+		if err != nil {
+			gc.AbortWithStatusJSON(http.StatusUnauthorized, controller.ErrorResponse{Message: auth.ErrUnauthorized.Error()})
+			return
+		}
+		setUser(gc, user, token)
+		gc.Next()
+	}
+}
+
+// RequireAdmin rejects users other than the admin, it runs after RequireAuth
+func RequireAdmin() gin.HandlerFunc {
+	return func(gc *gin.Context) {
+		user, ok := auth.UserFromContext(gc.Request.Context())
+		if !ok || !user.IsAdmin {
+			gc.AbortWithStatusJSON(http.StatusForbidden, controller.ErrorResponse{Message: auth.ErrForbidden.Error()})
+			return
+		}
+		gc.Next()
+	}
+}
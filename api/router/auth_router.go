@@ -0,0 +1,19 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nhuongmh/cfvs.jpx/api/controller"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+)
+
+func NewAuthRouter(authSrv auth.AuthService, publicRouter, privateRouter *gin.RouterGroup) {
+	tc := &controller.AuthController{AuthSrv: authSrv}
+
+	publicRouter.POST(DEFAULT_API_PREFIX+"/auth/register", tc.Register)
+	publicRouter.POST(DEFAULT_API_PREFIX+"/auth/login", tc.Login)
+	privateRouter.POST(DEFAULT_API_PREFIX+"/auth/logout", tc.Logout)
+	privateRouter.GET(DEFAULT_API_PREFIX+"/auth/me", tc.Me)
+	privateRouter.GET(DEFAULT_API_PREFIX+"/auth/tokens", tc.ListTokens)
+	privateRouter.POST(DEFAULT_API_PREFIX+"/auth/tokens", tc.CreateApiToken)
+	privateRouter.DELETE(DEFAULT_API_PREFIX+"/auth/tokens/:token-id", tc.RevokeToken)
+}
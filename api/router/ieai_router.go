@@ -1,23 +1,28 @@
 package router
 
 import (
+	"context"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nhuongmh/cfvs.jpx/bootstrap"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
 	ieservice "github.com/nhuongmh/cfvs.jpx/pkg/service/ie"
 	"github.com/nhuongmh/cfvs.jpx/pkg/service/ie/controller"
 )
 
-func NewIeAiRouter(app *bootstrap.Application, timeout time.Duration, publicRouter, privateRouter *gin.RouterGroup) {
+func NewIeAiRouter(ctx context.Context, app *bootstrap.Application, repo langfi.PracticeRepo, timeout time.Duration, publicRouter, privateRouter *gin.RouterGroup) {
 
-	ts := ieservice.NewIEservice(timeout, app.Env, app.DB)
+	ts := ieservice.NewIEservice(timeout, app.Env, app.DB, repo)
 	tc := &controller.IeController{Service: ts}
 
 	privateRouter.GET(DEFAULT_API_PREFIX+"/ie/article", tc.GetAllArticle)
 	privateRouter.GET(DEFAULT_API_PREFIX+"/ie/article/:id", tc.GetArticle)
 	privateRouter.POST(DEFAULT_API_PREFIX+"/ie/article", tc.SaveArticle)
 	privateRouter.DELETE(DEFAULT_API_PREFIX+"/ie/article/:id", tc.DeleteArticle)
+	privateRouter.PUT(DEFAULT_API_PREFIX+"/ie/article/:id/tags", tc.SetArticleTags)
+	privateRouter.GET(DEFAULT_API_PREFIX+"/ie/article/:id/transitions", tc.GetArticleTransitions)
+	privateRouter.POST(DEFAULT_API_PREFIX+"/ie/article/:id/events", tc.ApplyArticleEvent)
 	privateRouter.GET(DEFAULT_API_PREFIX+"/ie/article/url", tc.ParseArticleFromUrl)
 	privateRouter.GET(DEFAULT_API_PREFIX+"/ie/article/:id/reading", tc.GetArticleReading)
 	privateRouter.PUT(DEFAULT_API_PREFIX+"/ie/article/:id/reading", tc.ReGenArticleReading)
@@ -26,10 +31,27 @@ func NewIeAiRouter(app *bootstrap.Application, timeout time.Duration, publicRout
 	privateRouter.GET(DEFAULT_API_PREFIX+"/ie/article/reading/:reading_id/submit", tc.GetTestSubmissionByReadingId)
 	privateRouter.GET(DEFAULT_API_PREFIX+"/ie/article/reading/submit/:submit_id", tc.GetTestSubmission)
 	privateRouter.DELETE(DEFAULT_API_PREFIX+"/ie/article/reading/submit/:submit_id", tc.DeleteTestSubmission)
+	privateRouter.GET(DEFAULT_API_PREFIX+"/ie/article/reading/submit/:submit_id/review", tc.GetTestReview)
+	privateRouter.POST(DEFAULT_API_PREFIX+"/ie/article/reading/:reading_id/test", tc.StartTestSession)
+	privateRouter.GET(DEFAULT_API_PREFIX+"/ie/test/history", tc.GetBandHistory)
+	privateRouter.GET(DEFAULT_API_PREFIX+"/ie/test/:session_id", tc.GetTestSession)
+	privateRouter.POST(DEFAULT_API_PREFIX+"/ie/test/:session_id/submit", tc.SubmitTestSession)
 
 	privateRouter.GET(DEFAULT_API_PREFIX+"/ie/article/:id/proposed_vocab", tc.ExtractProposedWordsForArticle)
 	privateRouter.POST(DEFAULT_API_PREFIX+"/ie/article/:id/proposed_vocab", tc.HandleVocabProposalSubmit)
 	privateRouter.GET(DEFAULT_API_PREFIX+"/ie/article/:id/vocab", tc.GetVocabListByArticleId)
 	privateRouter.PUT(DEFAULT_API_PREFIX+"/ie/vocab/:id/anki", tc.GenAnkiDeckForVocabList)
+	// the download link of the same package, for browsers
+	privateRouter.GET(DEFAULT_API_PREFIX+"/ie/vocab/:id/anki", tc.GenAnkiDeckForVocabList)
+	privateRouter.POST(DEFAULT_API_PREFIX+"/ie/vocab/:id/cloze", tc.GenClozeCardsForVocabList)
+
+	privateRouter.GET(DEFAULT_API_PREFIX+"/ie/feed", tc.GetFeeds)
+	privateRouter.POST(DEFAULT_API_PREFIX+"/ie/feed", tc.AddFeed)
+	privateRouter.PUT(DEFAULT_API_PREFIX+"/ie/feed/:id", tc.UpdateFeed)
+	privateRouter.DELETE(DEFAULT_API_PREFIX+"/ie/feed/:id", tc.DeleteFeed)
+	privateRouter.POST(DEFAULT_API_PREFIX+"/ie/feed/:id/poll", tc.PollFeed)
+
+	go ts.BackfillAnalysis(ctx)
+	ts.StartFeedPoller(ctx)
 
 }
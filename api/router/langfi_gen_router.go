@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/nhuongmh/cfvs.jpx/api/controller"
+	"github.com/nhuongmh/cfvs.jpx/api/middleware"
 	"github.com/nhuongmh/cfvs.jpx/bootstrap"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
 	"github.com/nhuongmh/cfvs.jpx/pkg/service/jpxgen"
@@ -15,13 +16,16 @@ func NewJpxServiceRouter(app *bootstrap.Application, repo langfi.PracticeRepo, t
 	ts := jpxgen.NewJpxService(repo, timeout, app.Env)
 	tc := &controller.JpxController{JpxService: ts}
 
-	privateRouter.PUT(DEFAULT_API_PREFIX+"/core/initdb", tc.InitData)
-	privateRouter.PUT(DEFAULT_API_PREFIX+"/core/buildcards", tc.GenerateProposalCards)
-	privateRouter.DELETE(DEFAULT_API_PREFIX+"/core/deletenew", tc.DeleteAllNewCard)
+	// these change the data of every user
+	privateRouter.PUT(DEFAULT_API_PREFIX+"/core/initdb", middleware.RequireAdmin(), tc.InitData)
+	privateRouter.PUT(DEFAULT_API_PREFIX+"/core/buildcards", middleware.RequireAdmin(), tc.GenerateProposalCards)
+	privateRouter.DELETE(DEFAULT_API_PREFIX+"/core/deletenew", middleware.RequireAdmin(), tc.DeleteAllNewCard)
 	publicRouter.GET(DEFAULT_API_PREFIX+"/core/langs", tc.GetAvailableLang)
-	publicRouter.GET(DEFAULT_API_PREFIX+"/process/:lang-id/fetch", tc.FetchProposal)
-	publicRouter.POST(DEFAULT_API_PREFIX+"/process/:lang-id/submit", tc.SubmitProposal)
-	publicRouter.POST(DEFAULT_API_PREFIX+"/process/:lang-id/edit", tc.EditProposal)
+	privateRouter.GET(DEFAULT_API_PREFIX+"/process/:lang-id/fetch", tc.FetchProposal)
+	privateRouter.POST(DEFAULT_API_PREFIX+"/process/:lang-id/submit", tc.SubmitProposal)
+	privateRouter.POST(DEFAULT_API_PREFIX+"/process/:lang-id/edit", tc.EditProposal)
+	privateRouter.GET(DEFAULT_API_PREFIX+"/process/:lang-id/cards/:card-id/revisions", tc.GetCardRevisions)
+	privateRouter.POST(DEFAULT_API_PREFIX+"/process/:lang-id/cards/:card-id/revert/:revision-id", tc.RevertCard)
 	// publicRouter.GET(DEFAULT_API_PREFIX+"/process/groups", tc.GetProcessGroups)
 	// publicRouter.GET(DEFAULT_API_PREFIX+"/process/:lang-id/:group-id", tc.GetProposalGroups)
 
@@ -16,10 +16,29 @@ func NewJpxPraServiceRouter(app *bootstrap.Application, repo langfi.PracticeRepo
 	tc := &controller.PracticeController{PracticeSrv: ts}
 
 	publicRouter.GET(DEFAULT_API_PREFIX+"/practice/:lang-id/groups", tc.GetPracticeGroups)
-	publicRouter.GET(DEFAULT_API_PREFIX+"/practice/:lang-id/:group-id/fetch", tc.FetchPracticeCard)
-	publicRouter.POST(DEFAULT_API_PREFIX+"/practice/:lang-id/:group-id/submit", tc.SubmitPracticeCard)
-	publicRouter.GET(DEFAULT_API_PREFIX+"/card/:card-id", tc.GetCard)
-	publicRouter.GET(DEFAULT_API_PREFIX+"/practice/:lang-id/stats", tc.GetGroupStats)
+	// the schedule of every card is kept per user, only the deck names are public
+	privateRouter.GET(DEFAULT_API_PREFIX+"/practice/:lang-id/:group-id/fetch", tc.FetchPracticeCard)
+	privateRouter.POST(DEFAULT_API_PREFIX+"/practice/:lang-id/:group-id/submit", tc.SubmitPracticeCard)
+	privateRouter.POST(DEFAULT_API_PREFIX+"/practice/:lang-id/answer", tc.SubmitTypedAnswer)
+	privateRouter.GET(DEFAULT_API_PREFIX+"/card/:card-id", tc.GetCard)
+	privateRouter.GET(DEFAULT_API_PREFIX+"/practice/:lang-id/stats", tc.GetGroupStats)
+	privateRouter.GET(DEFAULT_API_PREFIX+"/practice/:lang-id/stats/review", tc.GetReviewStats)
+
+	privateRouter.GET(DEFAULT_API_PREFIX+"/practice/:lang-id/search", tc.SearchCards)
+	privateRouter.GET(DEFAULT_API_PREFIX+"/practice/:lang-id/filtered/fetch", tc.FetchFilteredCard)
+	privateRouter.POST(DEFAULT_API_PREFIX+"/practice/:lang-id/bulk-edit", tc.BulkEditCards)
+	privateRouter.GET(DEFAULT_API_PREFIX+"/practice/:lang-id/export/anki", tc.ExportAnki)
+	privateRouter.POST(DEFAULT_API_PREFIX+"/practice/:lang-id/export/anki", tc.ExportAnki)
+	privateRouter.POST(DEFAULT_API_PREFIX+"/practice/:lang-id/import/anki", tc.ImportAnki)
+
+	privateRouter.GET(DEFAULT_API_PREFIX+"/sync", tc.PullChanges)
+	privateRouter.POST(DEFAULT_API_PREFIX+"/sync/reviews", tc.PushReviews)
+
+	privateRouter.GET(DEFAULT_API_PREFIX+"/queue", tc.GetReviewQueue)
+
+	privateRouter.GET(DEFAULT_API_PREFIX+"/decks", tc.GetDeckTree)
+	privateRouter.POST(DEFAULT_API_PREFIX+"/decks", tc.CreateDeck)
+	privateRouter.PUT(DEFAULT_API_PREFIX+"/decks/:deck-id/settings", tc.UpdateDeckSettings)
 	// publicRouter.POST(DEFAULT_API_PREFIX+"/practice/:card-id", tc.GetCard)
 
 }
@@ -1,11 +1,16 @@
 package router
 
 import (
+	"context"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/nhuongmh/cfvs.jpx/api/middleware"
 	"github.com/nhuongmh/cfvs.jpx/bootstrap"
+	authservice "github.com/nhuongmh/cfvs.jpx/pkg/service/auth"
+	"github.com/nhuongmh/cfvs.jpx/pkg/service/auth/authrepo"
+	"github.com/nhuongmh/cfvs.jpx/pkg/service/jpxpractice/repo"
 )
 
 const (
@@ -13,24 +18,46 @@ const (
 	DEFAULT_API_PREFIX = "/api/" + API_V1
 )
 
-func Setup(app *bootstrap.Application, timeout time.Duration, gine *gin.Engine) {
+// same as cors.Default but lets clients send their bearer token
+func corsConfig() cors.Config {
+	config := cors.DefaultConfig()
+	config.AllowAllOrigins = true
+	config.AddAllowHeaders("Authorization")
+	return config
+}
+
+// Setup registers the routes, background work of the services runs until ctx is done
+func Setup(ctx context.Context, app *bootstrap.Application, timeout time.Duration, gine *gin.Engine) {
 	publicRouter := gine.Group("public")
-	// privateRouter := gine.Group("private")
+	privateRouter := gine.Group("private")
 
-	publicRouter.Use(cors.Default())
+	authSrv := authservice.NewAuthService(authrepo.NewAuthRepo(app.DB), app.Env)
+	publicRouter.Use(cors.New(corsConfig()), middleware.OptionalAuth(authSrv))
+	privateRouter.Use(cors.New(corsConfig()), middleware.RequireAuth(authSrv))
 	publicRouter.Static("/data", "./data")
+	NewAuthRouter(authSrv, publicRouter, privateRouter)
 
-	// tr := repo.NewJpxPraticeRepo(app.DB)
-	// NewJpxServiceRouter(app, tr, timeout, publicRouter, privateRouter)
-	// NewJpxPraServiceRouter(app, tr, timeout, publicRouter, privateRouter)
+	tr := repo.NewJpxPostgresPracticeRepo(app.DB)
+	NewJpxServiceRouter(app, tr, timeout, publicRouter, privateRouter)
+	NewJpxPraServiceRouter(app, tr, timeout, publicRouter, privateRouter)
+	NewSearchRouter(app, tr, timeout, publicRouter, privateRouter)
 }
 
-func SetupPostgres(app *bootstrap.Application, timeout time.Duration, gine *gin.Engine) {
+// SetupPostgres registers the routes, background work of the services runs until ctx is done
+func SetupPostgres(ctx context.Context, app *bootstrap.Application, timeout time.Duration, gine *gin.Engine) {
 	publicRouter := gine.Group("public")
 	privateRouter := gine.Group("private")
 
-	publicRouter.Use(cors.Default())
+	authSrv := authservice.NewAuthService(authrepo.NewAuthRepo(app.DB), app.Env)
+	publicRouter.Use(cors.New(corsConfig()), middleware.OptionalAuth(authSrv))
+	privateRouter.Use(cors.New(corsConfig()), middleware.RequireAuth(authSrv))
 	publicRouter.Static("/data", "./data")
-	NewIeAiRouter(app, timeout, publicRouter, privateRouter)
+	NewAuthRouter(authSrv, publicRouter, privateRouter)
+
+	tr := repo.NewJpxPostgresPracticeRepo(app.DB)
+	NewIeAiRouter(ctx, app, tr, timeout, publicRouter, privateRouter)
+	NewJpxServiceRouter(app, tr, timeout, publicRouter, privateRouter)
+	NewJpxPraServiceRouter(app, tr, timeout, publicRouter, privateRouter)
+	NewSearchRouter(app, tr, timeout, publicRouter, privateRouter)
 
 }
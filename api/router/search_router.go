@@ -0,0 +1,26 @@
+package router
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nhuongmh/cfvs.jpx/api/controller"
+	"github.com/nhuongmh/cfvs.jpx/bootstrap"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/search"
+	"github.com/nhuongmh/cfvs.jpx/pkg/service/ie/ierepo"
+	searchservice "github.com/nhuongmh/cfvs.jpx/pkg/service/search"
+)
+
+func NewSearchRouter(app *bootstrap.Application, repo langfi.PracticeRepo, timeout time.Duration, publicRouter, privateRouter *gin.RouterGroup) {
+
+	ieRepo := ierepo.NewIeRepo(app.DB)
+	ss := searchservice.NewSearchService(timeout, map[string]search.Source{
+		search.KIND_CARD:    repo.SearchCardText,
+		search.KIND_ARTICLE: ieRepo.SearchArticles,
+		search.KIND_VOCAB:   ieRepo.SearchVocabs,
+	})
+	sc := &controller.SearchController{SearchSrv: ss}
+
+	privateRouter.GET(DEFAULT_API_PREFIX+"/search", sc.Search)
+}
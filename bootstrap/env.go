@@ -22,6 +22,14 @@ type Env struct {
 	GoogleSpreadSheetId    string `mapstructure:"GOOGLE_SPREADSHEET_ID"`
 	GoogleWordSheetName    string `mapstructure:"GOOGLE_WORD_SHEET_NAME"`
 	GoogleFormulaSheetName string `mapstructure:"GOOGLE_FORMULA_SHEET_NAME"`
+	AuthAllowRegistration  bool   `mapstructure:"AUTH_ALLOW_REGISTRATION"`
+	AuthSessionTTLHours    int    `mapstructure:"AUTH_SESSION_TTL_HOURS"`
+	ArticleFetcher         string `mapstructure:"ARTICLE_FETCHER"`
+	FeedPollMinutes        int    `mapstructure:"FEED_POLL_MINUTES"`
+	LLMGrading             bool   `mapstructure:"LLM_GRADING"`
+	ReadingTestMinutes     int    `mapstructure:"READING_TEST_MINUTES"`
+	BandTableAcademic      string `mapstructure:"BAND_TABLE_ACADEMIC"`
+	BandTableGeneral       string `mapstructure:"BAND_TABLE_GENERAL"`
 }
 
 func NewEnv() *Env {
@@ -1,6 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -10,17 +16,39 @@ import (
 	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
 )
 
+// requests still running get this long to finish on shutdown
+const SHUTDOWN_TIMEOUT = 10 * time.Second
+
 func main() {
 	logger.InitLog()
 	app := bootstrap.Init()
+	defer app.CloseDB()
+
+	// stops the background work of the services and the server
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	timeout := time.Duration(app.Env.ContextTimeout) * time.Second
 	gine := gin.Default()
+	// let services see values, like the logged in user, set on the request context
+	gine.ContextWithFallback = true
 
 	logger.Log.Info().Msg("Setting up router...")
-	router.Setup(&app, timeout, gine)
+	router.Setup(ctx, &app, timeout, gine)
 	gine.Use(cors.Default())
 
 	logger.Log.Info().Msg("Starting server...")
-	gine.Run(app.Env.ServerAddress)
+	srv := &http.Server{Addr: app.Env.ServerAddress, Handler: gine}
+	go func() {
+		<-ctx.Done()
+		logger.Log.Info().Msg("Shutting down server...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Log.Error().Err(err).Msg("failed to shut down server")
+		}
+	}()
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Log.Error().Err(err).Msg("server stopped")
+	}
 }
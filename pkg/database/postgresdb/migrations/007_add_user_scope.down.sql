@@ -0,0 +1,3 @@
+ALTER TABLE ie_vocab_list DROP COLUMN IF EXISTS user_id;
+ALTER TABLE article_test_result DROP COLUMN IF EXISTS user_id;
+ALTER TABLE ie_articles DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,7 @@
+ALTER TABLE ie_articles ADD COLUMN IF NOT EXISTS user_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE article_test_result ADD COLUMN IF NOT EXISTS user_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE ie_vocab_list ADD COLUMN IF NOT EXISTS user_id INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS ie_articles_user_idx ON ie_articles(user_id);
+CREATE INDEX IF NOT EXISTS article_test_result_user_idx ON article_test_result(user_id);
+CREATE INDEX IF NOT EXISTS ie_vocab_list_user_idx ON ie_vocab_list(user_id);
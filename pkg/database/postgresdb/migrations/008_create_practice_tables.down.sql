@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS card_tags;
+DROP TABLE IF EXISTS review_logs;
+DROP TABLE IF EXISTS fsrs;
+DROP TABLE IF EXISTS cards;
+DROP TABLE IF EXISTS decks;
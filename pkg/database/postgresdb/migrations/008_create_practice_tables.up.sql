@@ -0,0 +1,68 @@
+-- same tables as the sqlite practice database, scheduling times keep their time zone
+CREATE TABLE IF NOT EXISTS decks (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR NOT NULL UNIQUE,
+    parent_id INTEGER REFERENCES decks(id),
+    settings TEXT NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS cards (
+    id SERIAL PRIMARY KEY,
+    front TEXT,
+    back TEXT,
+    properties TEXT,
+    deck_id INTEGER REFERENCES decks(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS cards_deck_idx ON cards(deck_id);
+CREATE INDEX IF NOT EXISTS cards_front_idx ON cards(front);
+
+CREATE TABLE IF NOT EXISTS fsrs (
+    id SERIAL PRIMARY KEY,
+    card_id INTEGER NOT NULL REFERENCES cards(id),
+    user_id INTEGER NOT NULL DEFAULT 0,
+    status VARCHAR,
+    due TIMESTAMPTZ,
+    stability DOUBLE PRECISION,
+    difficulty DOUBLE PRECISION,
+    elapsed_days INTEGER,
+    scheduled_days INTEGER,
+    reps INTEGER,
+    lapses INTEGER,
+    state INTEGER,
+    last_review TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS fsrs_card_user_idx ON fsrs(card_id, user_id);
+
+CREATE TABLE IF NOT EXISTS review_logs (
+    id SERIAL PRIMARY KEY,
+    card_id INTEGER NOT NULL REFERENCES cards(id),
+    user_id INTEGER NOT NULL DEFAULT 0,
+    rating INTEGER,
+    state INTEGER,
+    elapsed_days INTEGER,
+    scheduled_days INTEGER,
+    stability DOUBLE PRECISION,
+    difficulty DOUBLE PRECISION,
+    review TIMESTAMPTZ,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS review_logs_user_idx ON review_logs(user_id, review);
+
+CREATE TABLE IF NOT EXISTS card_tags (
+    card_id INTEGER NOT NULL REFERENCES cards(id),
+    tag VARCHAR NOT NULL,
+    PRIMARY KEY (card_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS card_tags_tag_idx ON card_tags(tag);
+
+INSERT INTO decks (name, parent_id) VALUES ('jp', NULL) ON CONFLICT (name) DO NOTHING;
+INSERT INTO decks (name, parent_id) SELECT 'jp::Minna', id FROM decks WHERE name = 'jp' ON CONFLICT (name) DO NOTHING;
+INSERT INTO decks (name, parent_id) SELECT 'jp::NA', id FROM decks WHERE name = 'jp' ON CONFLICT (name) DO NOTHING;
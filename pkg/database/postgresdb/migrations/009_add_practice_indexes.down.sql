@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS cards_created_at_idx;
+DROP INDEX IF EXISTS fsrs_user_status_due_idx;
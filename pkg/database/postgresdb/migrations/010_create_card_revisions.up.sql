@@ -0,0 +1,13 @@
+-- text of a card before each edit, changes is a json list of the changed fields
+CREATE TABLE IF NOT EXISTS card_revisions (
+    id SERIAL PRIMARY KEY,
+    card_id INTEGER NOT NULL REFERENCES cards(id),
+    user_id INTEGER NOT NULL DEFAULT 0,
+    front TEXT,
+    back TEXT,
+    properties TEXT,
+    changes TEXT NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS card_revisions_card_idx ON card_revisions(card_id, created_at);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS ie_vocab_search_idx;
+ALTER TABLE ie_vocab DROP COLUMN IF EXISTS search_vector;
+DROP INDEX IF EXISTS ie_articles_search_idx;
+ALTER TABLE ie_articles DROP COLUMN IF EXISTS search_vector;
+DROP INDEX IF EXISTS cards_search_idx;
+ALTER TABLE cards DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,23 @@
+-- full-text search vectors, the 'simple' configuration does not stem so that any language is matched word by word
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', COALESCE(front, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(back, '')), 'B') ||
+        setweight(to_tsvector('simple', COALESCE(properties, '')), 'C')
+    ) STORED;
+CREATE INDEX IF NOT EXISTS cards_search_idx ON cards USING GIN (search_vector);
+
+ALTER TABLE ie_articles ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', title), 'A') ||
+        setweight(to_tsvector('simple', content), 'B')
+    ) STORED;
+CREATE INDEX IF NOT EXISTS ie_articles_search_idx ON ie_articles USING GIN (search_vector);
+
+-- only the string values of the definitions are indexed, not their keys
+ALTER TABLE ie_vocab ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', vocab), 'A') ||
+        setweight(json_to_tsvector('simple', defs, '["string"]'), 'B')
+    ) STORED;
+CREATE INDEX IF NOT EXISTS ie_vocab_search_idx ON ie_vocab USING GIN (search_vector);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS review_logs_card_idx;
+DROP TABLE IF EXISTS sync_changes;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS ie_feed_entries;
+DROP TABLE IF EXISTS ie_feeds;
+DROP INDEX IF EXISTS ie_articles_user_origin_idx;
+ALTER TABLE ie_articles DROP COLUMN IF EXISTS status;
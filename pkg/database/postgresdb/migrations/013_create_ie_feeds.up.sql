@@ -0,0 +1,41 @@
+ALTER TABLE ie_articles ADD COLUMN IF NOT EXISTS status VARCHAR NOT NULL DEFAULT 'NEW';
+CREATE INDEX IF NOT EXISTS ie_articles_user_origin_idx ON ie_articles(user_id, origin);
+
+-- feed subscriptions of a user, filter holds the per-feed import rules as JSON
+CREATE TABLE IF NOT EXISTS ie_feeds (
+    id SERIAL PRIMARY KEY,
+    url VARCHAR NOT NULL,
+    title VARCHAR NOT NULL DEFAULT '',
+    filter JSON NOT NULL,
+    gen_reading BOOLEAN NOT NULL DEFAULT FALSE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_polled_at TIMESTAMP,
+    last_error VARCHAR NOT NULL DEFAULT '',
+    user_id INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, url)
+);
+
+CREATE TRIGGER set_updated_at
+BEFORE UPDATE ON ie_feeds
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+-- entries of a feed already handled, so that a poll only looks at the new ones.
+-- updated_at is when the entry was last handled, imports of the day are counted with it
+CREATE TABLE IF NOT EXISTS ie_feed_entries (
+    id SERIAL PRIMARY KEY,
+    feed_id INTEGER NOT NULL REFERENCES ie_feeds(id) ON DELETE CASCADE,
+    guid VARCHAR NOT NULL,
+    link VARCHAR NOT NULL,
+    article_id INTEGER NOT NULL DEFAULT 0,
+    status VARCHAR NOT NULL,
+    reason VARCHAR NOT NULL DEFAULT '',
+    attempts INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (feed_id, guid)
+);
+
+CREATE INDEX IF NOT EXISTS ie_feed_entries_status_idx ON ie_feed_entries(feed_id, status, updated_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS ie_articles_user_canonical_url_idx;
+CREATE INDEX IF NOT EXISTS ie_articles_user_origin_idx ON ie_articles(user_id, origin);
+
+ALTER TABLE ie_articles DROP COLUMN IF EXISTS fingerprint;
+ALTER TABLE ie_articles DROP COLUMN IF EXISTS canonical_url;
@@ -0,0 +1,6 @@
+-- fingerprint is the SimHash of the content, NULL until computed for the articles saved before
+ALTER TABLE ie_articles ADD COLUMN IF NOT EXISTS canonical_url VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE ie_articles ADD COLUMN IF NOT EXISTS fingerprint BIGINT;
+
+DROP INDEX IF EXISTS ie_articles_user_origin_idx;
+CREATE INDEX IF NOT EXISTS ie_articles_user_canonical_url_idx ON ie_articles(user_id, canonical_url);
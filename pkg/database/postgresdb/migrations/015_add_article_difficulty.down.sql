@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS ie_articles_user_cefr_level_idx;
+
+ALTER TABLE ie_articles DROP COLUMN IF EXISTS difficulty;
+ALTER TABLE ie_articles DROP COLUMN IF EXISTS ielts_band;
+ALTER TABLE ie_articles DROP COLUMN IF EXISTS cefr_level;
+ALTER TABLE ie_articles DROP COLUMN IF EXISTS word_count;
@@ -0,0 +1,8 @@
+-- difficulty holds the full readability analysis, the level columns are copied out of it for filtering.
+-- It is NULL until computed for the articles saved before
+ALTER TABLE ie_articles ADD COLUMN IF NOT EXISTS word_count INT NOT NULL DEFAULT 0;
+ALTER TABLE ie_articles ADD COLUMN IF NOT EXISTS cefr_level VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE ie_articles ADD COLUMN IF NOT EXISTS ielts_band REAL NOT NULL DEFAULT 0;
+ALTER TABLE ie_articles ADD COLUMN IF NOT EXISTS difficulty JSON;
+
+CREATE INDEX IF NOT EXISTS ie_articles_user_cefr_level_idx ON ie_articles(user_id, cefr_level);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS ie_articles_user_updated_at_idx;
+DROP INDEX IF EXISTS ie_articles_user_created_at_idx;
+
+DROP TABLE IF EXISTS ie_article_tags;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS ie_article_tags (
+    article_id INTEGER NOT NULL REFERENCES ie_articles(id) ON DELETE CASCADE,
+    tag VARCHAR NOT NULL,
+    PRIMARY KEY (article_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS ie_article_tags_tag_idx ON ie_article_tags(tag);
+
+-- keyset pagination of the article list walks these in order
+CREATE INDEX IF NOT EXISTS ie_articles_user_created_at_idx ON ie_articles(user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS ie_articles_user_updated_at_idx ON ie_articles(user_id, updated_at, id);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS ie_article_transitions;
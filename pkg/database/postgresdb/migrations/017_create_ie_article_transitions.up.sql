@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS ie_article_transitions (
+    id SERIAL PRIMARY KEY,
+    article_id INTEGER NOT NULL REFERENCES ie_articles(id) ON DELETE CASCADE,
+    event VARCHAR NOT NULL,
+    from_status VARCHAR NOT NULL,
+    to_status VARCHAR NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS ie_article_transitions_article_idx ON ie_article_transitions(article_id, id);
+
+-- statuses were never written before, derive them from what was done with the articles
+UPDATE ie_articles SET status = 'ANALYZED'
+WHERE status = 'NEW' AND EXISTS (SELECT 1 FROM article_reading WHERE article_reading.article_id = ie_articles.id);
+
+UPDATE ie_articles SET status = 'TESTED'
+WHERE status = 'ANALYZED' AND EXISTS (
+    SELECT 1 FROM article_reading
+    JOIN article_test_result ON article_test_result.article_reading_id = article_reading.id
+    WHERE article_reading.article_id = ie_articles.id
+);
+
+UPDATE ie_articles SET status = 'REVIEWED'
+WHERE status IN ('NEW', 'ANALYZED', 'TESTED') AND EXISTS (SELECT 1 FROM ie_vocab_list WHERE ie_vocab_list.article_id = ie_articles.id);
@@ -0,0 +1 @@
+ALTER TABLE article_reading DROP COLUMN IF EXISTS paragraphs;
@@ -0,0 +1,2 @@
+-- the labelled paragraphs the questions of the reading refer to, NULL for the readings generated before
+ALTER TABLE article_reading ADD COLUMN IF NOT EXISTS paragraphs JSON;
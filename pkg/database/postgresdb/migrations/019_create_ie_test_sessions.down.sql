@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS article_test_result_user_module_idx;
+DROP INDEX IF EXISTS article_test_result_session_idx;
+
+ALTER TABLE article_test_result DROP COLUMN IF EXISTS elapsed_seconds;
+ALTER TABLE article_test_result DROP COLUMN IF EXISTS band;
+ALTER TABLE article_test_result DROP COLUMN IF EXISTS raw_score;
+ALTER TABLE article_test_result DROP COLUMN IF EXISTS module;
+ALTER TABLE article_test_result DROP COLUMN IF EXISTS test_session_id;
+
+DROP TABLE IF EXISTS ie_test_sessions;
@@ -0,0 +1,51 @@
+-- timed tests of a reading, the deadline is fixed by the server when the test starts
+CREATE TABLE IF NOT EXISTS ie_test_sessions (
+    id SERIAL PRIMARY KEY,
+    article_reading_id INTEGER NOT NULL REFERENCES article_reading(id) ON DELETE CASCADE,
+    module VARCHAR NOT NULL,
+    duration_seconds INTEGER NOT NULL,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deadline TIMESTAMP NOT NULL,
+    submitted_at TIMESTAMP,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS ie_test_sessions_user_idx ON ie_test_sessions(user_id, article_reading_id);
+
+CREATE TRIGGER set_updated_at
+BEFORE UPDATE ON ie_test_sessions
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE article_test_result ADD COLUMN IF NOT EXISTS test_session_id INTEGER REFERENCES ie_test_sessions(id) ON DELETE SET NULL;
+ALTER TABLE article_test_result ADD COLUMN IF NOT EXISTS module VARCHAR NOT NULL DEFAULT 'academic';
+ALTER TABLE article_test_result ADD COLUMN IF NOT EXISTS raw_score INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE article_test_result ADD COLUMN IF NOT EXISTS band REAL NOT NULL DEFAULT 0;
+ALTER TABLE article_test_result ADD COLUMN IF NOT EXISTS elapsed_seconds INTEGER NOT NULL DEFAULT 0;
+
+CREATE UNIQUE INDEX IF NOT EXISTS article_test_result_session_idx ON article_test_result(test_session_id);
+CREATE INDEX IF NOT EXISTS article_test_result_user_module_idx ON article_test_result(user_id, module, created_at);
+
+-- results graded before are converted from their percentage with the default academic table
+UPDATE article_test_result SET raw_score = ROUND(score * 40 / 100);
+UPDATE article_test_result SET band = CASE
+    WHEN raw_score >= 39 THEN 9
+    WHEN raw_score >= 37 THEN 8.5
+    WHEN raw_score >= 35 THEN 8
+    WHEN raw_score >= 33 THEN 7.5
+    WHEN raw_score >= 30 THEN 7
+    WHEN raw_score >= 27 THEN 6.5
+    WHEN raw_score >= 23 THEN 6
+    WHEN raw_score >= 19 THEN 5.5
+    WHEN raw_score >= 15 THEN 5
+    WHEN raw_score >= 13 THEN 4.5
+    WHEN raw_score >= 10 THEN 4
+    WHEN raw_score >= 8 THEN 3.5
+    WHEN raw_score >= 6 THEN 3
+    WHEN raw_score >= 4 THEN 2.5
+    WHEN raw_score >= 2 THEN 2
+    WHEN raw_score >= 1 THEN 1
+    ELSE 0
+END;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS card_tags_user_tag_idx;
+
+DELETE FROM card_tags WHERE user_id != 0;
+ALTER TABLE card_tags DROP CONSTRAINT IF EXISTS card_tags_pkey;
+ALTER TABLE card_tags DROP COLUMN IF EXISTS user_id;
+ALTER TABLE card_tags ADD PRIMARY KEY (card_id, tag);
+
+CREATE INDEX IF NOT EXISTS card_tags_tag_idx ON card_tags(tag);
@@ -0,0 +1,13 @@
+-- tags are kept per user like the fsrs row, every user who has processed a card keeps the tags it had
+ALTER TABLE card_tags ADD COLUMN IF NOT EXISTS user_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE card_tags DROP CONSTRAINT IF EXISTS card_tags_pkey;
+ALTER TABLE card_tags ADD PRIMARY KEY (card_id, user_id, tag);
+
+INSERT INTO card_tags (card_id, user_id, tag)
+    SELECT card_tags.card_id, fsrs.user_id, card_tags.tag
+    FROM card_tags
+    JOIN fsrs ON fsrs.card_id = card_tags.card_id AND fsrs.user_id != 0
+ON CONFLICT DO NOTHING;
+
+DROP INDEX IF EXISTS card_tags_tag_idx;
+CREATE INDEX IF NOT EXISTS card_tags_user_tag_idx ON card_tags(user_id, tag);
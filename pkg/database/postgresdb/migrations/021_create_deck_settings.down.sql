@@ -0,0 +1,5 @@
+ALTER TABLE decks ADD COLUMN IF NOT EXISTS settings TEXT NOT NULL DEFAULT '{}';
+UPDATE decks SET settings = deck_settings.settings
+    FROM deck_settings WHERE deck_settings.deck_id = decks.id AND deck_settings.user_id = 0;
+
+DROP TABLE IF EXISTS deck_settings;
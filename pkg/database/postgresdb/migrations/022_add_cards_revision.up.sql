@@ -0,0 +1,2 @@
+-- counts the changes of the text and deck of a card, an edit only applies to the revision it was made on
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS revision INTEGER NOT NULL DEFAULT 0;
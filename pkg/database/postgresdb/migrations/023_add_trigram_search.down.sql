@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS ie_vocab_search_text_idx;
+ALTER TABLE ie_vocab DROP COLUMN IF EXISTS search_text;
+DROP FUNCTION IF EXISTS ie_vocab_search_text(VARCHAR, JSON);
+DROP INDEX IF EXISTS ie_articles_search_text_idx;
+ALTER TABLE ie_articles DROP COLUMN IF EXISTS search_text;
+DROP INDEX IF EXISTS cards_search_text_idx;
+ALTER TABLE cards DROP COLUMN IF EXISTS search_text;
@@ -0,0 +1,25 @@
+-- the 'simple' search vectors keep a run of Japanese or Chinese text as one word, so a term inside it never matches.
+-- Trigram indexes on the lower-cased text match a term anywhere, the search vectors still rank the matches.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS search_text TEXT
+    GENERATED ALWAYS AS (
+        lower(COALESCE(front, '') || ' ' || COALESCE(back, '') || ' ' || COALESCE(properties, ''))
+    ) STORED;
+CREATE INDEX IF NOT EXISTS cards_search_text_idx ON cards USING GIN (search_text gin_trgm_ops);
+
+ALTER TABLE ie_articles ADD COLUMN IF NOT EXISTS search_text TEXT
+    GENERATED ALWAYS AS (lower(title || ' ' || content)) STORED;
+CREATE INDEX IF NOT EXISTS ie_articles_search_text_idx ON ie_articles USING GIN (search_text gin_trgm_ops);
+
+-- the word and the string values of the definitions, like the search vector
+CREATE OR REPLACE FUNCTION ie_vocab_search_text(vocab VARCHAR, defs JSON)
+RETURNS TEXT AS $$
+    SELECT lower(vocab || ' ' || COALESCE(string_agg(value #>> '{}', ' '), ''))
+    FROM jsonb_path_query(defs::jsonb, 'strict $.**') AS value
+    WHERE jsonb_typeof(value) = 'string';
+$$ LANGUAGE sql IMMUTABLE;
+
+ALTER TABLE ie_vocab ADD COLUMN IF NOT EXISTS search_text TEXT
+    GENERATED ALWAYS AS (ie_vocab_search_text(vocab, defs)) STORED;
+CREATE INDEX IF NOT EXISTS ie_vocab_search_text_idx ON ie_vocab USING GIN (search_text gin_trgm_ops);
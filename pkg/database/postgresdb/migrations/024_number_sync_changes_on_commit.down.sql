@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS sync_changes_unnumbered_idx;
+DROP INDEX IF EXISTS sync_changes_user_seq_idx;
+ALTER TABLE sync_changes DROP COLUMN IF EXISTS seq;
+ALTER TABLE sync_changes DROP COLUMN IF EXISTS tx;
+DROP SEQUENCE IF EXISTS sync_changes_seq;
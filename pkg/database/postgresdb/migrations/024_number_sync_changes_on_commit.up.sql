@@ -0,0 +1,12 @@
+-- ids are taken when a change is written, a change committed after a greater id has been pulled was skipped.
+-- seq numbers the changes of finished transactions when they are pulled, so pulls see them in commit order.
+ALTER TABLE sync_changes ADD COLUMN IF NOT EXISTS tx xid8 NOT NULL DEFAULT pg_current_xact_id();
+ALTER TABLE sync_changes ADD COLUMN IF NOT EXISTS seq BIGINT;
+CREATE SEQUENCE IF NOT EXISTS sync_changes_seq;
+
+-- the cursors of the clients are the ids of the changes they have seen
+UPDATE sync_changes SET seq = id WHERE seq IS NULL;
+SELECT setval('sync_changes_seq', GREATEST((SELECT MAX(id) FROM sync_changes), 1));
+
+CREATE INDEX IF NOT EXISTS sync_changes_user_seq_idx ON sync_changes(user_id, seq);
+CREATE INDEX IF NOT EXISTS sync_changes_unnumbered_idx ON sync_changes(id) WHERE seq IS NULL;
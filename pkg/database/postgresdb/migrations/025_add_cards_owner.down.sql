@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS cards_owner_idx;
+ALTER TABLE cards DROP COLUMN IF EXISTS owner_id;
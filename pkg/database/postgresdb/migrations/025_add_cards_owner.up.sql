@@ -0,0 +1,13 @@
+-- cards made from the private data of a user, e.g. the cloze cards of a vocab list, are only seen by that user.
+-- owner_id is NULL for the shared cards.
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS owner_id INTEGER;
+CREATE INDEX IF NOT EXISTS cards_owner_idx ON cards(owner_id);
+
+-- cloze cards made before keep the id of their vocab, the vocab list tells their owner
+UPDATE cards SET owner_id = ie_vocab_list.user_id
+FROM ie_vocab
+JOIN ie_vocab_list ON ie_vocab_list.id = ie_vocab.vocab_list_id
+WHERE cards.owner_id IS NULL
+    AND ie_vocab_list.user_id != 0
+    AND ie_vocab.id = CASE WHEN cards.properties LIKE '%"ie_vocab_id":%'
+        THEN (cards.properties::json->>'ie_vocab_id')::numeric::integer END;
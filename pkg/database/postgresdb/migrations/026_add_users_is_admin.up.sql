@@ -0,0 +1,3 @@
+-- the admin resets and rebuilds the shared data, the first account is the admin
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE users SET is_admin = TRUE WHERE id = (SELECT MIN(id) FROM users);
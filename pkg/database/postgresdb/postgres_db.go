@@ -2,6 +2,7 @@ package postgresdb
 
 import (
 	"context"
+	"database/sql"
 	"embed"
 	"fmt"
 	"log"
@@ -12,7 +13,7 @@ import (
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/jackc/pgx/v5/pgxpool"
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
 //go:embed migrations/*.sql
@@ -20,6 +21,8 @@ var migrationsFS embed.FS
 
 type DB struct {
 	*pgxpool.Pool
+	// database/sql view of the pool, for repos shared with sqlite
+	SqlDB        *sql.DB
 	QueryBuilder *squirrel.StatementBuilderType
 	url          string
 }
@@ -45,6 +48,7 @@ func ConnectDB(ctx context.Context, postgresUrl string) (*DB, error) {
 
 	return &DB{
 		Pool:         db,
+		SqlDB:        stdlib.OpenDBFromPool(db),
 		QueryBuilder: &psql,
 		url:          postgresUrl,
 	}, nil
@@ -68,5 +72,6 @@ func (db *DB) Migrate() error {
 
 func (db *DB) Close() {
 	log.Printf("Disconnecting from database: %s", db.url)
+	db.SqlDB.Close()
 	db.Pool.Close()
 }
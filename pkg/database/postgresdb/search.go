@@ -0,0 +1,20 @@
+package postgresdb
+
+import "strings"
+
+// PrefixTsQuery is the to_tsquery text matching documents with every term, each term also matching
+// the words it prefixes. Terms are single words, as given by search.ParseTerms.
+func PrefixTsQuery(terms []string) string {
+	lexemes := make([]string, len(terms))
+	for i, term := range terms {
+		lexemes[i] = "'" + strings.ReplaceAll(term, "'", "''") + "':*"
+	}
+	return strings.Join(lexemes, " & ")
+}
+
+// ContainsPattern is the LIKE pattern matching text containing the term, for the trigram indexed search_text columns.
+// The search_text columns are lower-cased, so is the term.
+func ContainsPattern(term string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(strings.ToLower(term))
+	return "%" + escaped + "%"
+}
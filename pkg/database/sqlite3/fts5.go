@@ -0,0 +1,61 @@
+//go:build sqlite_fts5 || fts5
+
+package sqlite3
+
+import (
+	_ "embed"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FTS5 is true when the driver is built with the fts5 extension, the card text is then indexed in cards_fts
+const FTS5 = true
+
+//go:embed search/cards_fts5.sql
+var cardsFtsSchema string
+
+// migrateSearch creates the full-text index of the cards, it is rebuilt when its triggers were missing
+// so that cards written by a build without fts5 are indexed too. An index made by the word tokenizer
+// of older builds is replaced by the trigram one.
+func (db *DB) migrateSearch() error {
+	var triggers int
+	err := db.SqlDB.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'cards_fts_%'").Scan(&triggers)
+	if err != nil {
+		return errors.Wrap(err, "Failed read search index triggers")
+	}
+	var tableSql string
+	err = db.SqlDB.QueryRow("SELECT COALESCE(MAX(sql), '') FROM sqlite_master WHERE type = 'table' AND name = 'cards_fts'").Scan(&tableSql)
+	if err != nil {
+		return errors.Wrap(err, "Failed read search index")
+	}
+
+	tx, err := db.SqlDB.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Failed begin transaction")
+	}
+	defer tx.Rollback()
+
+	if tableSql != "" && !strings.Contains(tableSql, "trigram") {
+		for _, stmt := range []string{"DROP TRIGGER IF EXISTS cards_fts_insert", "DROP TRIGGER IF EXISTS cards_fts_delete",
+			"DROP TRIGGER IF EXISTS cards_fts_update", "DROP TABLE cards_fts"} {
+			if _, err = tx.Exec(stmt); err != nil {
+				return errors.Wrap(err, "Failed drop word search index")
+			}
+		}
+		triggers = 0
+	}
+
+	_, err = tx.Exec(cardsFtsSchema)
+	if err != nil {
+		return errors.Wrap(err, "Failed create search index")
+	}
+	if triggers < 3 {
+		_, err = tx.Exec("INSERT INTO cards_fts(cards_fts) VALUES ('rebuild')")
+		if err != nil {
+			return errors.Wrap(err, "Failed rebuild search index")
+		}
+	}
+
+	return tx.Commit()
+}
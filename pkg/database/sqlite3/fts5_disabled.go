@@ -0,0 +1,24 @@
+//go:build !(sqlite_fts5 || fts5)
+
+package sqlite3
+
+import (
+	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+// FTS5 is true when the driver is built with the fts5 extension, the card text is then indexed in cards_fts
+const FTS5 = false
+
+// migrateSearch drops the index triggers of a database used by a build with fts5,
+// they fail every card write without the extension. The index is rebuilt once fts5 is back.
+func (db *DB) migrateSearch() error {
+	logger.Log.Warn().Msg("sqlite is built without fts5, card search scans every card. Build with -tags sqlite_fts5, see the Makefile")
+	for _, trigger := range []string{"cards_fts_insert", "cards_fts_delete", "cards_fts_update"} {
+		_, err := db.SqlDB.Exec("DROP TRIGGER IF EXISTS " + trigger)
+		if err != nil {
+			return errors.Wrapf(err, "Failed drop search index trigger %v", trigger)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+-- scheduling state and card status belong to a user, card content stays shared
+ALTER TABLE fsrs ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE fsrs ADD COLUMN status VARCHAR(255);
+UPDATE fsrs SET status = (SELECT cards.status FROM cards WHERE cards.id = fsrs.card_id);
+DELETE FROM fsrs WHERE card_id NOT IN (SELECT id FROM cards);
+CREATE UNIQUE INDEX IF NOT EXISTS fsrs_card_user_idx ON fsrs(card_id, user_id);
+
+ALTER TABLE review_logs ADD COLUMN user_id INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS review_logs_user_idx ON review_logs(user_id, review);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS card_tags (
+    card_id INTEGER NOT NULL,
+    tag VARCHAR(255) NOT NULL,
+    PRIMARY KEY (card_id, tag),
+    FOREIGN KEY(card_id) REFERENCES cards(id)
+);
+
+CREATE INDEX IF NOT EXISTS card_tags_tag_idx ON card_tags(tag);
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS decks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    parent_id INTEGER,
+    settings TEXT NOT NULL DEFAULT '{}',
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    udpated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(parent_id) REFERENCES decks(id)
+);
+
+ALTER TABLE cards ADD COLUMN deck_id INTEGER REFERENCES decks(id);
+CREATE INDEX IF NOT EXISTS cards_deck_idx ON cards(deck_id);
+
+-- card groups were minna lessons of japanese cards, or NA for cards without lesson
+INSERT INTO decks (name, parent_id) VALUES ('jp', NULL);
+INSERT INTO decks (name, parent_id) SELECT 'jp::Minna', id FROM decks WHERE name = 'jp';
+INSERT INTO decks (name, parent_id) SELECT 'jp::NA', id FROM decks WHERE name = 'jp';
+INSERT INTO decks (name, parent_id)
+    SELECT DISTINCT 'jp::Minna::' || TRIM(cards.card_group), minna.id
+    FROM cards, decks AS minna
+    WHERE minna.name = 'jp::Minna' AND TRIM(COALESCE(cards.card_group, '')) NOT IN ('', 'NA');
+
+UPDATE cards SET deck_id = (
+    SELECT decks.id FROM decks
+    WHERE decks.name = CASE
+        WHEN TRIM(COALESCE(cards.card_group, '')) IN ('', 'NA') THEN 'jp::NA'
+        ELSE 'jp::Minna::' || TRIM(cards.card_group)
+    END
+);
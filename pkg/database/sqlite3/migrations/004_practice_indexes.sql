@@ -0,0 +1,4 @@
+-- review and unprocessed card lookups filter the fsrs row of a user by status, reviews are ordered by due
+CREATE INDEX IF NOT EXISTS fsrs_user_status_due_idx ON fsrs(user_id, status, due);
+CREATE INDEX IF NOT EXISTS cards_front_idx ON cards(front);
+CREATE INDEX IF NOT EXISTS cards_created_at_idx ON cards(created_at);
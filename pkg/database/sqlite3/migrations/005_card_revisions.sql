@@ -0,0 +1,14 @@
+-- text of a card before each edit, changes is a json list of the changed fields
+CREATE TABLE IF NOT EXISTS card_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    card_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    front TEXT,
+    back TEXT,
+    properties TEXT,
+    changes TEXT NOT NULL DEFAULT '[]',
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(card_id) REFERENCES cards(id)
+);
+
+CREATE INDEX IF NOT EXISTS card_revisions_card_idx ON card_revisions(card_id, created_at);
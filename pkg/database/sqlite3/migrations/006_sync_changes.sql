@@ -0,0 +1,16 @@
+-- every write of a card, clients pull the cards changed after the last change they have seen.
+-- user_id is NULL when the card content changed, which every user sees, and the user of the fsrs row otherwise
+CREATE TABLE IF NOT EXISTS sync_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    card_id INTEGER NOT NULL,
+    user_id INTEGER,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS sync_changes_user_idx ON sync_changes(user_id, id);
+CREATE INDEX IF NOT EXISTS review_logs_card_idx ON review_logs(card_id, user_id);
+
+-- the first pull of a client returns the existing cards
+INSERT INTO sync_changes (card_id, user_id) SELECT id, NULL FROM cards ORDER BY id;
+INSERT INTO sync_changes (card_id, user_id) SELECT card_id, user_id FROM fsrs ORDER BY id;
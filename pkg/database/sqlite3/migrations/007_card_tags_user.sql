@@ -0,0 +1,18 @@
+-- tags are kept per user like the fsrs row, every user who has processed a card keeps the tags it had
+CREATE TABLE card_tags_by_user (
+    card_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    tag VARCHAR(255) NOT NULL,
+    PRIMARY KEY (card_id, user_id, tag),
+    FOREIGN KEY(card_id) REFERENCES cards(id)
+);
+
+INSERT INTO card_tags_by_user (card_id, user_id, tag) SELECT card_id, 0, tag FROM card_tags;
+INSERT OR IGNORE INTO card_tags_by_user (card_id, user_id, tag)
+    SELECT card_tags.card_id, fsrs.user_id, card_tags.tag
+    FROM card_tags
+    JOIN fsrs ON fsrs.card_id = card_tags.card_id AND fsrs.user_id != 0;
+
+DROP TABLE card_tags;
+ALTER TABLE card_tags_by_user RENAME TO card_tags;
+CREATE INDEX IF NOT EXISTS card_tags_user_tag_idx ON card_tags(user_id, tag);
@@ -0,0 +1,8 @@
+-- times are text with the offset they were written with, which only compare in time order within one zone.
+-- They are written in UTC from now on, existing ones are converted to UTC in the format of the driver.
+UPDATE fsrs SET due = REPLACE(strftime('%Y-%m-%d %H:%M:%f', due), '.000', '') || '+00:00'
+WHERE strftime('%Y-%m-%d %H:%M:%f', due) IS NOT NULL;
+UPDATE fsrs SET last_review = REPLACE(strftime('%Y-%m-%d %H:%M:%f', last_review), '.000', '') || '+00:00'
+WHERE strftime('%Y-%m-%d %H:%M:%f', last_review) IS NOT NULL;
+UPDATE review_logs SET review = REPLACE(strftime('%Y-%m-%d %H:%M:%f', review), '.000', '') || '+00:00'
+WHERE strftime('%Y-%m-%d %H:%M:%f', review) IS NOT NULL;
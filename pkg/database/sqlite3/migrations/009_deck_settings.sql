@@ -0,0 +1,18 @@
+-- deck settings are kept per user, every user who has processed a card keeps the settings the decks had
+CREATE TABLE IF NOT EXISTS deck_settings (
+    deck_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    settings TEXT NOT NULL DEFAULT '{}',
+    updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (deck_id, user_id),
+    FOREIGN KEY(deck_id) REFERENCES decks(id)
+);
+
+INSERT OR IGNORE INTO deck_settings (deck_id, user_id, settings)
+    SELECT decks.id, 0, decks.settings FROM decks WHERE decks.settings != '{}';
+INSERT OR IGNORE INTO deck_settings (deck_id, user_id, settings)
+    SELECT decks.id, users.user_id, decks.settings
+    FROM decks, (SELECT DISTINCT user_id FROM fsrs WHERE user_id != 0) AS users
+    WHERE decks.settings != '{}';
+
+ALTER TABLE decks DROP COLUMN settings;
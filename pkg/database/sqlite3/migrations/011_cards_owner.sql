@@ -0,0 +1,4 @@
+-- cards made from the private data of a user, e.g. the cloze cards of a vocab list, are only seen by that user.
+-- owner_id is NULL for the shared cards. The vocab lists are kept in postgres, cloze cards made before stay shared.
+ALTER TABLE cards ADD COLUMN owner_id INTEGER;
+CREATE INDEX IF NOT EXISTS cards_owner_idx ON cards(owner_id);
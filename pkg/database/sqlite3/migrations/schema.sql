@@ -31,4 +31,19 @@ CREATE TABLE IF NOT EXISTS fsrs (
     state VARCHAR(255),
     last_review datetime,
     FOREIGN KEY(card_id) REFERENCES cards(id)
-);
\ No newline at end of file
+);
+CREATE TABLE IF NOT EXISTS review_logs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    card_id INTEGER,
+    rating INTEGER,
+    state INTEGER,
+    elapsed_days INTEGER,
+    scheduled_days INTEGER,
+    stability REAL,
+    difficulty REAL,
+    review datetime,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(card_id) REFERENCES cards(id)
+);
+
+CREATE INDEX IF NOT EXISTS review_logs_review_idx ON review_logs(review);
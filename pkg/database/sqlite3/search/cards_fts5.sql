@@ -0,0 +1,20 @@
+-- full-text index of the card text, kept in sync with cards by the triggers.
+-- The trigram tokenizer matches text inside words, Japanese text has no spaces between them.
+CREATE VIRTUAL TABLE IF NOT EXISTS cards_fts USING fts5(
+    front, back, properties,
+    content='cards', content_rowid='id',
+    tokenize='trigram'
+);
+
+CREATE TRIGGER IF NOT EXISTS cards_fts_insert AFTER INSERT ON cards BEGIN
+    INSERT INTO cards_fts(rowid, front, back, properties) VALUES (new.id, new.front, new.back, new.properties);
+END;
+
+CREATE TRIGGER IF NOT EXISTS cards_fts_delete AFTER DELETE ON cards BEGIN
+    INSERT INTO cards_fts(cards_fts, rowid, front, back, properties) VALUES ('delete', old.id, old.front, old.back, old.properties);
+END;
+
+CREATE TRIGGER IF NOT EXISTS cards_fts_update AFTER UPDATE OF front, back, properties ON cards BEGIN
+    INSERT INTO cards_fts(cards_fts, rowid, front, back, properties) VALUES ('delete', old.id, old.front, old.back, old.properties);
+    INSERT INTO cards_fts(rowid, front, back, properties) VALUES (new.id, new.front, new.back, new.properties);
+END;
@@ -3,24 +3,33 @@ package sqlite3
 import (
 	"context"
 	"database/sql"
+	"embed"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
 	"github.com/pkg/errors"
 
-	_ "embed"
-
 	_ "github.com/mattn/go-sqlite3"
 )
 
 //go:embed migrations/schema.sql
 var schema string
 
+// schema changes applied in order on top of schema.sql, the applied version is kept in user_version
+//
+//go:embed migrations/[0-9]*.sql
+var versionedMigrations embed.FS
+
+// MIN_TRIGRAM_TERM is the length in characters of the shortest term the trigram search index can match
+const MIN_TRIGRAM_TERM = 3
+
 type DB struct {
 	SqlDB        *sql.DB
 	QueryBuilder *squirrel.StatementBuilderType
@@ -38,7 +47,13 @@ func ConnectDB(ctx context.Context, dbFileUrl string) (*DB, error) {
 			return nil, errors.Wrapf(err, "failed to create %v directory", dir)
 		}
 	}
-	db, err := sql.Open("sqlite3", dbFileUrl)
+	// transactions take the write lock when they begin, so that concurrent writers wait
+	// for each other instead of failing to upgrade a read lock
+	dsn := dbFileUrl + "?_txlock=immediate&_busy_timeout=5000"
+	if strings.Contains(dbFileUrl, "?") {
+		dsn = dbFileUrl + "&_txlock=immediate&_busy_timeout=5000"
+	}
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed open database file")
 	}
@@ -124,5 +139,54 @@ func (db *DB) Migrate() error {
 		return errors.Wrap(err, "Failed migrate database")
 	}
 
-	return nil
+	var version int
+	err = db.SqlDB.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		return errors.Wrap(err, "Failed read database version")
+	}
+
+	entries, err := fs.ReadDir(versionedMigrations, "migrations")
+	if err != nil {
+		return errors.Wrap(err, "Failed read migrations")
+	}
+	for _, entry := range entries {
+		migrationVersion, err := strconv.Atoi(strings.SplitN(entry.Name(), "_", 2)[0])
+		if err != nil {
+			return errors.Wrapf(err, "invalid migration file name %v", entry.Name())
+		}
+		if migrationVersion <= version {
+			continue
+		}
+		err = db.applyMigration(entry.Name(), migrationVersion)
+		if err != nil {
+			return err
+		}
+		logger.Log.Info().Msgf("applied database migration %v", entry.Name())
+	}
+
+	return db.migrateSearch()
+}
+
+func (db *DB) applyMigration(name string, version int) error {
+	content, err := fs.ReadFile(versionedMigrations, "migrations/"+name)
+	if err != nil {
+		return errors.Wrapf(err, "Failed read migration %v", name)
+	}
+
+	tx, err := db.SqlDB.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Failed begin transaction")
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(string(content))
+	if err != nil {
+		return errors.Wrapf(err, "Failed apply migration %v", name)
+	}
+	_, err = tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version))
+	if err != nil {
+		return errors.Wrapf(err, "Failed update database version to %v", version)
+	}
+
+	return tx.Commit()
 }
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+)
+
+const (
+	TOKEN_SESSION = "session"
+	TOKEN_API     = "api"
+)
+
+// data created before accounts existed, or by anonymous requests, belongs to this user id
+const ANONYMOUS_USER_ID uint64 = 0
+
+var (
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrForbidden           = errors.New("forbidden")
+	ErrInvalidCredentials  = errors.New("invalid username or password")
+	ErrUserExisted         = errors.New("username is already taken")
+	ErrRegistrationClosed  = errors.New("registration is closed")
+	ErrInvalidRegistration = errors.New("username and password of at least 8 characters are required")
+)
+
+type User struct {
+	model.Base
+	Username     string `json:"username"`
+	PasswordHash string `json:"-"`
+	// the admin may reset and rebuild the data shared by all users
+	IsAdmin bool `json:"is_admin"`
+}
+
+type AccessToken struct {
+	model.Base
+	UserID     uint64     `json:"user_id"`
+	Name       string     `json:"name"`
+	Type       string     `json:"type"`
+	TokenHash  string     `json:"-"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+type CredentialsDto struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// TokenDto is the only place a plain token is ever returned
+type TokenDto struct {
+	Token     string      `json:"token"`
+	Info      AccessToken `json:"info"`
+	User      *User       `json:"user,omitempty"`
+	ExpiresAt *time.Time  `json:"expires_at,omitempty"`
+}
+
+type AuthService interface {
+	Register(ctx context.Context, cred *CredentialsDto) (*User, error)
+	Login(ctx context.Context, cred *CredentialsDto) (*TokenDto, error)
+	Logout(ctx context.Context, token string) error
+	Authenticate(ctx context.Context, token string) (*User, error)
+	CreateApiToken(ctx context.Context, name string) (*TokenDto, error)
+	ListTokens(ctx context.Context) (*[]AccessToken, error)
+	RevokeToken(ctx context.Context, tokenID uint64) error
+}
+
+type AuthRepo interface {
+	// AddUser adds the user and reports whether it is the first one, which is the admin and takes over
+	// the data created before accounts existed. Once a user exists more are only added when allowMore is set,
+	// otherwise ErrRegistrationClosed. A taken username is ErrUserExisted.
+	AddUser(ctx context.Context, user *User, allowMore bool) (bool, error)
+	GetUserByName(ctx context.Context, username string) (*User, error)
+	GetUser(ctx context.Context, userID uint64) (*User, error)
+	AddToken(ctx context.Context, token *AccessToken) error
+	GetTokenByHash(ctx context.Context, tokenHash string) (*AccessToken, error)
+	TouchToken(ctx context.Context, tokenID uint64, usedAt time.Time) error
+	ListTokens(ctx context.Context, userID uint64) (*[]AccessToken, error)
+	DeleteToken(ctx context.Context, userID, tokenID uint64) error
+	DeleteTokenByHash(ctx context.Context, tokenHash string) error
+}
+
+type userCtxKey struct{}
+
+func WithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userCtxKey{}, user)
+}
+
+func UserFromContext(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(userCtxKey{}).(*User)
+	return user, ok && user != nil
+}
+
+// UserIDFromContext returns the id of the authenticated user, or ANONYMOUS_USER_ID
+func UserIDFromContext(ctx context.Context) uint64 {
+	if user, ok := UserFromContext(ctx); ok {
+		return user.ID
+	}
+	return ANONYMOUS_USER_ID
+}
@@ -0,0 +1,232 @@
+package ie
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/search"
+	"github.com/nhuongmh/cfvs.jpx/pkg/supporter/readlevel"
+)
+
+var ErrInvalidArticleQuery = errors.New("invalid article query")
+
+var ALL_ARTICLE_STATUS = []string{ARTICLE_NEW, ARTICLE_TESTED, ARTICLE_ANALYZED, ARTICLE_REVIEWED, ARTICLE_DISCARDED, ARTICLE_LEARNING}
+
+// fields the article list is sorted by, ties are broken by id
+const (
+	ARTICLE_SORT_CREATED = "created_at"
+	ARTICLE_SORT_UPDATED = "updated_at"
+	ARTICLE_SORT_TITLE   = "title"
+	ARTICLE_SORT_BAND    = "ielts_band"
+	ARTICLE_SORT_WORDS   = "word_count"
+	// rank of the text query, only with a text query
+	ARTICLE_SORT_RELEVANCE = "relevance"
+)
+
+const (
+	SORT_ASC  = "asc"
+	SORT_DESC = "desc"
+)
+
+var ARTICLE_SORTS = []string{ARTICLE_SORT_CREATED, ARTICLE_SORT_UPDATED, ARTICLE_SORT_TITLE, ARTICLE_SORT_BAND,
+	ARTICLE_SORT_WORDS, ARTICLE_SORT_RELEVANCE}
+
+const (
+	DEFAULT_ARTICLE_PAGE_SIZE = 20
+	// larger pages are cut to this size
+	MAX_ARTICLE_PAGE_SIZE = 100
+)
+
+// ArticleFilter narrows the article list, zero values do not filter
+type ArticleFilter struct {
+	// any of the statuses
+	Statuses []string `json:"statuses"`
+	// site of the articles, e.g. bbc.com, its subdomains included
+	Origin string `json:"origin"`
+	// articles added from this time and before this time
+	From *time.Time `json:"from"`
+	To   *time.Time `json:"to"`
+	// CEFR levels, any of them
+	Levels   []string `json:"levels"`
+	MinBand  float64  `json:"min_band"`
+	MaxBand  float64  `json:"max_band"`
+	MinWords int      `json:"min_words"`
+	MaxWords int      `json:"max_words"`
+	// articles having all of the tags
+	Tags []string `json:"tags"`
+	// words that must all be in the title or content, a word also matches the words it prefixes
+	Query string `json:"query"`
+	// parsed from Query by Validate
+	Terms []string `json:"-"`
+}
+
+// ArticleQuery is a page of the filtered articles. Pages are either numbered, or follow the cursor
+// returned with the previous page, which stays consistent while articles are added.
+type ArticleQuery struct {
+	Filter ArticleFilter
+	Sort   string
+	Order  string
+	// from 1, ignored when the cursor is set
+	Page     uint64
+	PageSize uint64
+	Cursor   string
+	// decoded from Cursor by Validate
+	After *ArticleCursor
+}
+
+// ArticleCursor is the position of the last article of a page in the sort order
+type ArticleCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	// sort value of the article, times in RFC 3339
+	Value string `json:"v"`
+	ID    uint64 `json:"id"`
+}
+
+type ArticlePageMeta struct {
+	// 0 when paging by cursor
+	Page     uint64 `json:"page"`
+	PageSize uint64 `json:"page_size"`
+	// articles matching the filter
+	Total   int    `json:"total"`
+	Sort    string `json:"sort"`
+	Order   string `json:"order"`
+	HasMore bool   `json:"has_more"`
+	// cursor of the following page, empty on the last page
+	NextCursor string `json:"next_cursor"`
+}
+
+// Validate normalizes the filter and checks its values
+func (f *ArticleFilter) Validate() error {
+	for i, status := range f.Statuses {
+		f.Statuses[i] = strings.ToUpper(strings.TrimSpace(status))
+		if !slices.Contains(ALL_ARTICLE_STATUS, f.Statuses[i]) {
+			return fmt.Errorf("%w: unknown status %q", ErrInvalidArticleQuery, status)
+		}
+	}
+	for i, level := range f.Levels {
+		f.Levels[i] = strings.ToUpper(strings.TrimSpace(level))
+		if !slices.Contains(readlevel.CEFR_LEVELS, f.Levels[i]) {
+			return fmt.Errorf("%w: unknown CEFR level %q", ErrInvalidArticleQuery, level)
+		}
+	}
+	if f.MinBand < 0 || f.MaxBand < 0 || f.MinBand > 9 || f.MaxBand > 9 {
+		return fmt.Errorf("%w: bands must be between 0 and 9", ErrInvalidArticleQuery)
+	}
+	if f.MaxBand > 0 && f.MinBand > f.MaxBand {
+		return fmt.Errorf("%w: minimum band %v is above maximum band %v", ErrInvalidArticleQuery, f.MinBand, f.MaxBand)
+	}
+	if f.MinWords < 0 || f.MaxWords < 0 {
+		return fmt.Errorf("%w: word counts cannot be negative", ErrInvalidArticleQuery)
+	}
+	if f.MaxWords > 0 && f.MinWords > f.MaxWords {
+		return fmt.Errorf("%w: minimum words %d is above maximum words %d", ErrInvalidArticleQuery, f.MinWords, f.MaxWords)
+	}
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidArticleQuery)
+	}
+	f.Origin = OriginHost(f.Origin)
+	f.Tags = langfi.NormalizeTags(f.Tags)
+
+	f.Terms = nil
+	if strings.TrimSpace(f.Query) != "" {
+		terms, err := search.ParseTerms(f.Query)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArticleQuery, err)
+		}
+		f.Terms = terms
+	}
+	return nil
+}
+
+// Validate checks the filter, the sort and the page size, and decodes the cursor.
+// The sort defaults to relevance with a text query and to the newest articles otherwise.
+func (q *ArticleQuery) Validate() error {
+	if err := q.Filter.Validate(); err != nil {
+		return err
+	}
+
+	q.Sort, q.Order = strings.ToLower(q.Sort), strings.ToLower(q.Order)
+	if q.Sort == "" {
+		q.Sort = ARTICLE_SORT_CREATED
+		if len(q.Filter.Terms) > 0 {
+			q.Sort = ARTICLE_SORT_RELEVANCE
+		}
+	}
+	if !slices.Contains(ARTICLE_SORTS, q.Sort) {
+		return fmt.Errorf("%w: sort must be one of %v", ErrInvalidArticleQuery, ARTICLE_SORTS)
+	}
+	if q.Sort == ARTICLE_SORT_RELEVANCE && len(q.Filter.Terms) == 0 {
+		return fmt.Errorf("%w: sorting by relevance needs a text query", ErrInvalidArticleQuery)
+	}
+	if q.Order == "" {
+		q.Order = SORT_DESC
+		if q.Sort == ARTICLE_SORT_TITLE {
+			q.Order = SORT_ASC
+		}
+	}
+	if q.Order != SORT_ASC && q.Order != SORT_DESC {
+		return fmt.Errorf("%w: order must be asc or desc", ErrInvalidArticleQuery)
+	}
+
+	if q.PageSize == 0 {
+		q.PageSize = DEFAULT_ARTICLE_PAGE_SIZE
+	}
+	q.PageSize = min(q.PageSize, MAX_ARTICLE_PAGE_SIZE)
+	if q.Page == 0 {
+		q.Page = 1
+	}
+
+	q.After = nil
+	if q.Cursor != "" {
+		cursor, err := DecodeArticleCursor(q.Cursor)
+		if err != nil {
+			return err
+		}
+		if cursor.Sort != q.Sort || cursor.Order != q.Order {
+			return fmt.Errorf("%w: the cursor is for sorting by %v %v", ErrInvalidArticleQuery, cursor.Sort, cursor.Order)
+		}
+		q.After = cursor
+	}
+	return nil
+}
+
+func (c *ArticleCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeArticleCursor(cursor string) (*ArticleCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidArticleQuery)
+	}
+	var decoded ArticleCursor
+	if err = json.Unmarshal(data, &decoded); err != nil || decoded.ID == 0 {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidArticleQuery)
+	}
+	return &decoded, nil
+}
+
+// OriginHost reduces a site or url to its lower case host without www
+func OriginHost(origin string) string {
+	origin = strings.ToLower(strings.TrimSpace(origin))
+	if origin == "" {
+		return ""
+	}
+	if !strings.Contains(origin, "://") {
+		origin = "https://" + origin
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return strings.TrimPrefix(strings.Trim(origin, "/"), "https://")
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
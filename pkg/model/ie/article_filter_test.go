@@ -0,0 +1,106 @@
+package ie
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestArticleFilterValidate(t *testing.T) {
+	filter := ArticleFilter{Levels: []string{" b2", "C1"}, MinBand: 5.5, MaxBand: 7}
+	if err := filter.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if filter.Levels[0] != "B2" {
+		t.Errorf("Validate() levels = %q, want upper case", filter.Levels)
+	}
+
+	invalid := []ArticleFilter{
+		{Levels: []string{"D1"}},
+		{MinBand: 7, MaxBand: 6},
+		{MaxBand: 10},
+		{MinWords: -1},
+		{MinWords: 500, MaxWords: 300},
+	}
+	for _, filter := range invalid {
+		if err := filter.Validate(); err == nil {
+			t.Errorf("Validate() of %+v succeeded", filter)
+		}
+	}
+}
+
+func TestArticleFilterValidateNormalizes(t *testing.T) {
+	filter := ArticleFilter{Statuses: []string{"new"}, Origin: "https://www.BBC.co.uk/news", Tags: []string{"Lesson 5", "lesson 5", ""},
+		Query: "Coral, reefs!"}
+	if err := filter.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if filter.Statuses[0] != ARTICLE_NEW || filter.Origin != "bbc.co.uk" {
+		t.Errorf("Validate() statuses, origin = %q, %q", filter.Statuses, filter.Origin)
+	}
+	if len(filter.Tags) != 1 || filter.Tags[0] != "lesson_5" {
+		t.Errorf("Validate() tags = %q, want [lesson_5]", filter.Tags)
+	}
+	if len(filter.Terms) != 2 || filter.Terms[0] != "coral" {
+		t.Errorf("Validate() terms = %q, want [coral reefs]", filter.Terms)
+	}
+	if err := (&ArticleFilter{Statuses: []string{"READ"}}).Validate(); err == nil {
+		t.Error("Validate() of an unknown status succeeded")
+	}
+}
+
+func TestArticleQueryValidate(t *testing.T) {
+	q := ArticleQuery{PageSize: 500}
+	if err := q.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if q.Sort != ARTICLE_SORT_CREATED || q.Order != SORT_DESC || q.Page != 1 || q.PageSize != MAX_ARTICLE_PAGE_SIZE {
+		t.Errorf("Validate() defaults = %+v", q)
+	}
+
+	q = ArticleQuery{Filter: ArticleFilter{Query: "coral"}}
+	if err := q.Validate(); err != nil || q.Sort != ARTICLE_SORT_RELEVANCE {
+		t.Errorf("Validate() with a text query sort = %v, %v, want relevance", q.Sort, err)
+	}
+	q = ArticleQuery{Sort: ARTICLE_SORT_TITLE}
+	if err := q.Validate(); err != nil || q.Order != SORT_ASC {
+		t.Errorf("Validate() of title sort order = %v, %v, want asc", q.Order, err)
+	}
+
+	invalid := []ArticleQuery{
+		{Sort: "author"},
+		{Sort: ARTICLE_SORT_RELEVANCE},
+		{Order: "up"},
+		{Cursor: "not a cursor"},
+		{Sort: ARTICLE_SORT_TITLE, Cursor: (&ArticleCursor{Sort: ARTICLE_SORT_CREATED, Order: SORT_DESC, ID: 3}).Encode()},
+	}
+	for _, q := range invalid {
+		if err := q.Validate(); !errors.Is(err, ErrInvalidArticleQuery) {
+			t.Errorf("Validate() of %+v error = %v, want ErrInvalidArticleQuery", q, err)
+		}
+	}
+}
+
+func TestArticleCursor(t *testing.T) {
+	cursor := ArticleCursor{Sort: ARTICLE_SORT_CREATED, Order: SORT_DESC, Value: "2024-05-31T10:00:00.123456Z", ID: 42}
+	q := ArticleQuery{Cursor: cursor.Encode()}
+	if err := q.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if q.After == nil || *q.After != cursor {
+		t.Errorf("Validate() cursor = %+v, want %+v", q.After, cursor)
+	}
+}
+
+func TestOriginHost(t *testing.T) {
+	tests := map[string]string{
+		"bbc.com":                     "bbc.com",
+		"https://www.BBC.com/news/x":  "bbc.com",
+		" www.theguardian.com/world ": "theguardian.com",
+		"":                            "",
+	}
+	for origin, want := range tests {
+		if got := OriginHost(origin); got != want {
+			t.Errorf("OriginHost(%q) = %q, want %q", origin, got, want)
+		}
+	}
+}
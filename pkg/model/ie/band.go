@@ -0,0 +1,194 @@
+package ie
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidBandTable = errors.New("invalid band table")
+	ErrInvalidBandQuery = errors.New("invalid band history query")
+)
+
+// IELTS reading modules, each converts raw scores with its own table
+const (
+	IELTS_ACADEMIC = "academic"
+	IELTS_GENERAL  = "general"
+)
+
+var IELTS_MODULES = []string{IELTS_ACADEMIC, IELTS_GENERAL}
+
+// questions of a full IELTS reading test, raw scores are out of them
+const IELTS_READING_QUESTIONS = 40
+
+// tests the moving average band of the history is taken over
+const BAND_HISTORY_WINDOW = 5
+
+// BandStep is the band of the raw scores from MinRaw up to the previous step
+type BandStep struct {
+	MinRaw int     `json:"min_raw"`
+	Band   float64 `json:"band"`
+}
+
+// BandTable converts raw scores to bands, steps go from the highest raw score down.
+// Raw scores below the last step are band 0.
+type BandTable []BandStep
+
+// Migration 019 backfilled the band of older results with a frozen copy of the academic table in a CASE,
+// changing the table here leaves those bands as they were.
+var DEFAULT_BAND_TABLES = map[string]BandTable{
+	IELTS_ACADEMIC: {
+		{39, 9}, {37, 8.5}, {35, 8}, {33, 7.5}, {30, 7}, {27, 6.5}, {23, 6}, {19, 5.5}, {15, 5}, {13, 4.5},
+		{10, 4}, {8, 3.5}, {6, 3}, {4, 2.5}, {2, 2}, {1, 1},
+	},
+	IELTS_GENERAL: {
+		{40, 9}, {39, 8.5}, {37, 8}, {36, 7.5}, {34, 7}, {32, 6.5}, {30, 6}, {27, 5.5}, {23, 5}, {19, 4.5},
+		{15, 4}, {12, 3.5}, {9, 3}, {6, 2.5}, {3, 2}, {1, 1},
+	},
+}
+
+// ParseBandTable reads a table written as raw:band steps separated by commas, e.g. "39:9,37:8.5,35:8"
+func ParseBandTable(table string) (BandTable, error) {
+	steps := BandTable{}
+	for _, step := range strings.Split(table, ",") {
+		raw, band, ok := strings.Cut(strings.TrimSpace(step), ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: step %q is not raw:band", ErrInvalidBandTable, step)
+		}
+		minRaw, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%w: raw score %q is not a number", ErrInvalidBandTable, raw)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(band), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: band %q is not a number", ErrInvalidBandTable, band)
+		}
+		steps = append(steps, BandStep{MinRaw: minRaw, Band: value})
+	}
+	if err := steps.Validate(); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+// Validate checks that raw scores and bands both go down, within 0 to 40 and 0 to 9 in half bands
+func (t BandTable) Validate() error {
+	if len(t) == 0 {
+		return fmt.Errorf("%w: no steps", ErrInvalidBandTable)
+	}
+	for i, step := range t {
+		if step.MinRaw < 0 || step.MinRaw > IELTS_READING_QUESTIONS {
+			return fmt.Errorf("%w: raw score %d is not between 0 and %d", ErrInvalidBandTable, step.MinRaw, IELTS_READING_QUESTIONS)
+		}
+		if step.Band < 0 || step.Band > 9 || step.Band*2 != math.Trunc(step.Band*2) {
+			return fmt.Errorf("%w: band %v is not a half band between 0 and 9", ErrInvalidBandTable, step.Band)
+		}
+		if i > 0 && (step.MinRaw >= t[i-1].MinRaw || step.Band >= t[i-1].Band) {
+			return fmt.Errorf("%w: steps must go down, %d:%v follows %d:%v", ErrInvalidBandTable,
+				step.MinRaw, step.Band, t[i-1].MinRaw, t[i-1].Band)
+		}
+	}
+	return nil
+}
+
+// Band returns the band of the raw score out of 40
+func (t BandTable) Band(raw int) float64 {
+	i := slices.IndexFunc(t, func(step BandStep) bool { return raw >= step.MinRaw })
+	if i < 0 {
+		return 0
+	}
+	return t[i].Band
+}
+
+// RawScore scales the marks of a test to a raw score out of the 40 questions of a full test
+func RawScore(marks, maxMarks int) int {
+	if maxMarks <= 0 {
+		return 0
+	}
+	return int(math.Round(float64(marks) * IELTS_READING_QUESTIONS / float64(maxMarks)))
+}
+
+// BandHistoryQuery selects the tests of the band history
+type BandHistoryQuery struct {
+	// academic when empty
+	Module string
+	// only the timed tests
+	TimedOnly bool
+	// tests submitted from this time and before this time
+	From *time.Time
+	To   *time.Time
+}
+
+func (q *BandHistoryQuery) Validate() error {
+	q.Module = strings.ToLower(strings.TrimSpace(q.Module))
+	if q.Module == "" {
+		q.Module = IELTS_ACADEMIC
+	}
+	if !slices.Contains(IELTS_MODULES, q.Module) {
+		return fmt.Errorf("%w: module must be one of %v", ErrInvalidBandQuery, IELTS_MODULES)
+	}
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidBandQuery)
+	}
+	return nil
+}
+
+// BandHistoryEntry is the band of a graded test
+type BandHistoryEntry struct {
+	TestResultID   uint64    `json:"test_result_id"`
+	TestSessionID  uint64    `json:"test_session_id,omitempty"`
+	ArticleID      uint64    `json:"article_id"`
+	ArticleTitle   string    `json:"article_title"`
+	Score          float32   `json:"score"`
+	RawScore       int       `json:"raw_score"`
+	Band           float64   `json:"band"`
+	ElapsedSeconds int       `json:"elapsed_seconds,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	// average band of this test and the ones before it in the window, set by NewBandHistory
+	AverageBand float64 `json:"average_band"`
+}
+
+// BandHistory is the band progression of the tests of a module, oldest first
+type BandHistory struct {
+	Module  string             `json:"module"`
+	Entries []BandHistoryEntry `json:"entries"`
+	Latest  float64            `json:"latest"`
+	Best    float64            `json:"best"`
+	Average float64            `json:"average"`
+	// band of the latest test minus the band of the first
+	Change float64 `json:"change"`
+}
+
+// NewBandHistory summarizes the entries, which must be sorted oldest first
+func NewBandHistory(module string, entries []BandHistoryEntry) *BandHistory {
+	history := &BandHistory{Module: module, Entries: entries}
+	if len(entries) == 0 {
+		return history
+	}
+	total := 0.0
+	for i := range entries {
+		total += entries[i].Band
+		history.Best = max(history.Best, entries[i].Band)
+
+		window := entries[max(0, i-BAND_HISTORY_WINDOW+1) : i+1]
+		windowTotal := 0.0
+		for _, entry := range window {
+			windowTotal += entry.Band
+		}
+		entries[i].AverageBand = roundBand(windowTotal / float64(len(window)))
+	}
+	history.Latest = entries[len(entries)-1].Band
+	history.Average = roundBand(total / float64(len(entries)))
+	history.Change = history.Latest - entries[0].Band
+	return history
+}
+
+// roundBand keeps two decimals of an average band
+func roundBand(band float64) float64 {
+	return math.Round(band*100) / 100
+}
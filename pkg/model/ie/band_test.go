@@ -0,0 +1,83 @@
+package ie
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDefaultBandTables(t *testing.T) {
+	for module, table := range DEFAULT_BAND_TABLES {
+		if err := table.Validate(); err != nil {
+			t.Errorf("default %v table: %v", module, err)
+		}
+	}
+
+	tests := []struct {
+		module string
+		raw    int
+		want   float64
+	}{
+		{IELTS_ACADEMIC, 40, 9},
+		{IELTS_ACADEMIC, 30, 7},
+		{IELTS_ACADEMIC, 29, 6.5},
+		{IELTS_ACADEMIC, 23, 6},
+		{IELTS_ACADEMIC, 0, 0},
+		{IELTS_GENERAL, 39, 8.5},
+		{IELTS_GENERAL, 30, 6},
+		{IELTS_GENERAL, 26, 5},
+	}
+	for _, tt := range tests {
+		if got := DEFAULT_BAND_TABLES[tt.module].Band(tt.raw); got != tt.want {
+			t.Errorf("%v band of %d = %v, want %v", tt.module, tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParseBandTable(t *testing.T) {
+	table, err := ParseBandTable(" 38:9, 30:7.5,10 : 4")
+	if err != nil {
+		t.Fatalf("ParseBandTable() = %v", err)
+	}
+	if len(table) != 3 || table.Band(35) != 7.5 || table.Band(9) != 0 {
+		t.Errorf("ParseBandTable() = %v", table)
+	}
+
+	for _, invalid := range []string{"", "39", "39:x", "41:9", "39:9.2", "30:7,35:6", "39:7,30:8"} {
+		if _, err := ParseBandTable(invalid); !errors.Is(err, ErrInvalidBandTable) {
+			t.Errorf("ParseBandTable(%q) = %v, want ErrInvalidBandTable", invalid, err)
+		}
+	}
+}
+
+func TestRawScore(t *testing.T) {
+	tests := []struct{ marks, maxMarks, want int }{
+		{13, 13, 40},
+		{10, 13, 31},
+		{0, 13, 0},
+		{1, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := RawScore(tt.marks, tt.maxMarks); got != tt.want {
+			t.Errorf("RawScore(%d, %d) = %d, want %d", tt.marks, tt.maxMarks, got, tt.want)
+		}
+	}
+}
+
+func TestNewBandHistory(t *testing.T) {
+	bands := []float64{5, 6, 7, 6, 7, 8}
+	entries := make([]BandHistoryEntry, len(bands))
+	for i, band := range bands {
+		entries[i].Band = band
+	}
+
+	history := NewBandHistory(IELTS_ACADEMIC, entries)
+	if history.Latest != 8 || history.Best != 8 || history.Average != 6.5 || history.Change != 3 {
+		t.Errorf("NewBandHistory() = %+v", history)
+	}
+	if entries[1].AverageBand != 5.5 || entries[5].AverageBand != 6.8 {
+		t.Errorf("average bands = %v and %v, want 5.5 and 6.8", entries[1].AverageBand, entries[5].AverageBand)
+	}
+	if empty := NewBandHistory(IELTS_GENERAL, nil); empty.Latest != 0 || empty.Module != IELTS_GENERAL {
+		t.Errorf("NewBandHistory(nil) = %+v", empty)
+	}
+}
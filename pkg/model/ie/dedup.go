@@ -0,0 +1,169 @@
+package ie
+
+import (
+	"errors"
+	"hash/fnv"
+	"math/bits"
+	"net/url"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+)
+
+var ErrDuplicateArticle = errors.New("duplicate article")
+
+// what to do when a saved article duplicates an existing one
+const (
+	DUPLICATE_REJECT = "reject"
+	// the existing article takes the missing metadata and the longer content of the new one
+	DUPLICATE_MERGE = "merge"
+)
+
+const (
+	// fingerprints of articles differing in at most this many bits are near duplicates,
+	// those of unrelated texts differ in about half of their bits
+	MAX_FINGERPRINT_DISTANCE = 5
+	// shorter texts have no fingerprint, their shingles are too few to compare
+	MIN_FINGERPRINT_TOKENS = 30
+	FINGERPRINT_SHINGLE    = 3
+)
+
+// query parameters that only track where the reader came from
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "msclkid": true, "igshid": true, "mc_cid": true, "mc_eid": true,
+	"ref": true, "ref_src": true, "ref_url": true, "cmpid": true, "ocid": true, "_ga": true, "smid": true, "rss": true,
+}
+
+// CanonicalUrl normalizes the url of an article so that the links of the same page compare equal:
+// https scheme, lower case host without www, no default port, fragment, tracking parameters,
+// index page or trailing slash, and sorted query parameters
+func CanonicalUrl(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(link)
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	query := u.Query()
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") || trackingParams[strings.ToLower(key)] {
+			query.Del(key)
+		}
+	}
+
+	p := u.EscapedPath()
+	if p != "" {
+		p = path.Clean(p)
+	}
+	for _, index := range []string{"/index.html", "/index.htm", "/index.php", "/amp"} {
+		p = strings.TrimSuffix(p, index)
+	}
+	p = strings.TrimSuffix(p, "/")
+
+	canonical := "https://" + host + p
+	if len(query) > 0 {
+		// Encode sorts by key
+		canonical += "?" + query.Encode()
+	}
+	return canonical
+}
+
+// Fingerprint is the 64 bit SimHash of the word shingles of the paragraphs of the content,
+// near duplicate texts have fingerprints differing in a few bits.
+// It is 0 when the content is too short to compare.
+func Fingerprint(content string) uint64 {
+	var weights [64]int
+	tokens := 0
+	for _, paragraph := range strings.Split(content, "\n") {
+		words := fingerprintTokens(paragraph)
+		if len(words) == 0 {
+			continue
+		}
+		tokens += len(words)
+		// shingles do not cross paragraphs, so moved paragraphs keep their features
+		shingles := max(len(words)-FINGERPRINT_SHINGLE+1, 1)
+		for i := 0; i < shingles; i++ {
+			h := fnv.New64a()
+			h.Write([]byte(strings.Join(words[i:min(i+FINGERPRINT_SHINGLE, len(words))], " ")))
+			sum := h.Sum64()
+			for bit := 0; bit < 64; bit++ {
+				if sum&(1<<bit) != 0 {
+					weights[bit]++
+				} else {
+					weights[bit]--
+				}
+			}
+		}
+	}
+	if tokens < MIN_FINGERPRINT_TOKENS {
+		return 0
+	}
+
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
+
+// fingerprintTokens lower cases the words of the text, scripts written without spaces give a token per character
+func fingerprintTokens(text string) []string {
+	tokens := []string{}
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// SimilarFingerprints tells whether the fingerprints are of near duplicate texts
+func SimilarFingerprints(a, b uint64) bool {
+	if a == 0 || b == 0 {
+		return false
+	}
+	return bits.OnesCount64(a^b) <= MAX_FINGERPRINT_DISTANCE
+}
+
+// MergeArticle fills the missing metadata of the existing article from the duplicate, adds its tags,
+// and takes the content of the duplicate when it is longer, e.g. the full text of a truncated import
+func MergeArticle(existing, duplicate *Article) {
+	fill := func(field *string, value string) {
+		if strings.TrimSpace(*field) == "" {
+			*field = value
+		}
+	}
+	fill(&existing.Title, duplicate.Title)
+	fill(&existing.Author, duplicate.Author)
+	fill(&existing.Image, duplicate.Image)
+	fill(&existing.PublishDate, duplicate.PublishDate)
+	fill(&existing.Origin, duplicate.Origin)
+	fill(&existing.CanonicalUrl, duplicate.CanonicalUrl)
+	existing.Tags = langfi.NormalizeTags(append(existing.Tags, duplicate.Tags...))
+	if len(duplicate.Content) > len(existing.Content) {
+		existing.Content = duplicate.Content
+		existing.Fingerprint = duplicate.Fingerprint
+		existing.Difficulty = duplicate.Difficulty
+	}
+}
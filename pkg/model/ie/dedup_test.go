@@ -0,0 +1,90 @@
+package ie
+
+import (
+	"math/bits"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/supporter/readlevel"
+)
+
+func TestCanonicalUrl(t *testing.T) {
+	tests := map[string]string{
+		"https://www.Example.com/news/story/":                        "https://example.com/news/story",
+		"http://example.com:80/news/story#comments":                  "https://example.com/news/story",
+		"https://example.com/news/story?utm_source=rss&utm_medium=x": "https://example.com/news/story",
+		"https://example.com/news/story?page=2&fbclid=abc&id=7":      "https://example.com/news/story?id=7&page=2",
+		"https://example.com/news/story/amp":                         "https://example.com/news/story",
+		"https://example.com/news/index.html":                        "https://example.com/news",
+		"https://example.com:8080/a/../story":                        "https://example.com:8080/story",
+		"https://example.com/blog/camp":                              "https://example.com/blog/camp",
+		"https://example.com":                                        "https://example.com",
+		"not a url":                                                  "not a url",
+	}
+	for link, want := range tests {
+		if got := CanonicalUrl(link); got != want {
+			t.Errorf("CanonicalUrl(%q) = %q, want %q", link, got, want)
+		}
+	}
+}
+
+const fingerprintText = `Night trains are returning to Europe as cities look for ways to cut short-haul flights.
+Operators report that sleeper services between the largest capitals sold out for most of the summer season.
+
+Rail companies are ordering new carriages, although the cost of track access remains a concern for smaller operators.
+Travellers say the main reasons to choose the train are comfort, the city centre stations and the lower emissions.
+
+Critics point out that a sleeper ticket often costs more than a flight, and that delays on the long routes are common.
+Several governments have promised subsidies, while the operators argue that only a common booking system across
+borders will bring enough passengers to make the routes profitable over the next decade.`
+
+func TestFingerprint(t *testing.T) {
+	base := Fingerprint(fingerprintText)
+	if base == 0 {
+		t.Fatal("Fingerprint() = 0 for a long text")
+	}
+
+	edited := strings.Replace(fingerprintText, "most of the summer season", "most of the summer", 1)
+	if d := bits.OnesCount64(base ^ Fingerprint(edited)); !SimilarFingerprints(base, Fingerprint(edited)) {
+		t.Errorf("fingerprint of a small edit differs in %d bits", d)
+	}
+	if Fingerprint(strings.ToUpper(fingerprintText)) != base {
+		t.Error("fingerprint depends on the case of the text")
+	}
+
+	other := `The central bank kept interest rates unchanged on Thursday, saying inflation was slowing faster than forecast
+while wages continued to rise. Economists expect the first cut in the autumn, once the labour market cools and energy
+prices settle at the levels seen before the winter.`
+	if d := bits.OnesCount64(base ^ Fingerprint(other)); SimilarFingerprints(base, Fingerprint(other)) {
+		t.Errorf("fingerprints of unrelated texts differ in only %d bits", d)
+	}
+
+	if Fingerprint("A short note.") != 0 {
+		t.Error("Fingerprint() of a short text is not 0")
+	}
+	if SimilarFingerprints(0, 0) {
+		t.Error("missing fingerprints are similar")
+	}
+}
+
+func TestMergeArticle(t *testing.T) {
+	existing := &Article{Title: "Night trains", Content: "Short summary.", Origin: "https://example.com/a", Fingerprint: 1,
+		Tags: []string{"travel"}}
+	duplicate := &Article{Title: "Other title", Author: "Maria Keller", Content: "The full text of the article.", Fingerprint: 2,
+		Difficulty: &readlevel.Stats{Words: 6}, Tags: []string{"Travel", "europe"}}
+	MergeArticle(existing, duplicate)
+
+	if existing.Title != "Night trains" || existing.Origin != "https://example.com/a" {
+		t.Errorf("MergeArticle() replaced existing metadata: %+v", existing)
+	}
+	if existing.Author != "Maria Keller" {
+		t.Errorf("MergeArticle() author = %q, want the one of the duplicate", existing.Author)
+	}
+	if existing.Content != duplicate.Content || existing.Fingerprint != 2 || existing.Difficulty != duplicate.Difficulty {
+		t.Errorf("MergeArticle() kept the shorter content %q", existing.Content)
+	}
+	if !reflect.DeepEqual(existing.Tags, []string{"travel", "europe"}) {
+		t.Errorf("MergeArticle() tags = %q, want [travel europe]", existing.Tags)
+	}
+}
@@ -0,0 +1,172 @@
+package ie
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrEvidenceNotFound = errors.New("evidence not found")
+
+// EvidenceSpan is the passage of the article content supporting an answer.
+// Offsets count characters (runes) of the content, End is exclusive.
+type EvidenceSpan struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Text  string `json:"text"`
+}
+
+// typography the LLM does not copy faithfully
+var evidenceReplacer = strings.NewReplacer("‘", "'", "’", "'", "“", `"`, "”", `"`, "–", "-", "—", "-", "…", "...")
+
+// Locate sets the offsets of the span to where its text is in the content, and its text to the exact
+// passage of the content. Offsets already pointing at the text are kept, otherwise the text is searched
+// ignoring case, spacing and typographic quotes and dashes.
+func (s *EvidenceSpan) Locate(content string) error {
+	if s.Valid(content) {
+		return nil
+	}
+	quote := strings.Trim(strings.TrimSpace(s.Text), `"'“”‘’`)
+	quote = strings.TrimSuffix(strings.TrimSuffix(quote, "..."), "…")
+	normalizedQuote, _ := normalizeEvidence(quote)
+	if normalizedQuote == "" {
+		return fmt.Errorf("%w: empty evidence", ErrEvidenceNotFound)
+	}
+
+	normalizedContent, offsets := normalizeEvidence(content)
+	i := strings.Index(normalizedContent, normalizedQuote)
+	if i < 0 {
+		return fmt.Errorf("%w: %q is not in the article", ErrEvidenceNotFound, s.Text)
+	}
+	start := utf8.RuneCountInString(normalizedContent[:i])
+	end := start + utf8.RuneCountInString(normalizedQuote)
+	s.Start = offsets[start]
+	s.End = offsets[end-1] + 1
+	s.Text = string([]rune(content)[s.Start:s.End])
+	return nil
+}
+
+// Valid reports whether the offsets of the span point at its text in the content
+func (s *EvidenceSpan) Valid(content string) bool {
+	runes := []rune(content)
+	if s.Text == "" || s.Start < 0 || s.End <= s.Start || s.End > len(runes) {
+		return false
+	}
+	return string(runes[s.Start:s.End]) == s.Text
+}
+
+// normalizeEvidence lower cases the text and collapses its spaces, offsets maps each rune of the
+// normalized text to the rune of the text it comes from
+func normalizeEvidence(text string) (string, []int) {
+	normalized := strings.Builder{}
+	offsets := []int{}
+	space := true
+	for i, r := range []rune(text) {
+		if unicode.IsSpace(r) {
+			if !space {
+				normalized.WriteRune(' ')
+				offsets = append(offsets, i)
+			}
+			space = true
+			continue
+		}
+		space = false
+		for _, replaced := range evidenceReplacer.Replace(string(unicode.ToLower(r))) {
+			normalized.WriteRune(replaced)
+			offsets = append(offsets, i)
+		}
+	}
+	return strings.TrimSuffix(normalized.String(), " "), offsets
+}
+
+// LocateEvidence locates the evidence of the question and of its items in the content. A matching headings
+// item without evidence is supported by its whole paragraph. Evidence that is missing or not in the content
+// is ErrEvidenceNotFound.
+func (q *Question) LocateEvidence(content string, paragraphs []Paragraph) error {
+	if len(q.Items) == 0 {
+		if q.Evidence == nil {
+			return fmt.Errorf("%w: question %d has no evidence", ErrEvidenceNotFound, q.ID)
+		}
+		return q.Evidence.Locate(content)
+	}
+	for i := range q.Items {
+		item := &q.Items[i]
+		if item.Evidence == nil && q.Type == QUESTION_TYPE_MATCHING {
+			for _, paragraph := range paragraphs {
+				if paragraph.Label == item.Paragraph {
+					item.Evidence = &EvidenceSpan{Text: paragraph.Text}
+				}
+			}
+		}
+		if item.Evidence == nil {
+			return fmt.Errorf("%w: item %d of question %d has no evidence", ErrEvidenceNotFound, item.ID, q.ID)
+		}
+		if err := item.Evidence.Locate(content); err != nil {
+			return fmt.Errorf("item %d of question %d: %w", item.ID, q.ID, err)
+		}
+	}
+	return nil
+}
+
+// TestReview is a graded test with the passages of the article supporting each answer, to highlight in the content
+type TestReview struct {
+	TestResult *TestResult      `json:"test_result"`
+	ArticleID  uint64           `json:"article_id"`
+	Content    string           `json:"content"`
+	Questions  []QuestionReview `json:"questions"`
+}
+
+type QuestionReview struct {
+	QuestionID uint64 `json:"question_id"`
+	Type       string `json:"type"`
+	Question   string `json:"question"`
+	Correct    bool   `json:"correct"`
+	// the evidence of the question, or of each of its items
+	Evidence []ReviewEvidence `json:"evidence"`
+}
+
+type ReviewEvidence struct {
+	// 0 for the evidence of a question without items
+	ItemID uint64 `json:"item_id,omitempty"`
+	EvidenceSpan
+}
+
+// NewTestReview matches the results to the evidence of their questions. Evidence that no longer points
+// at its text, after the content was replaced by a longer version, is located again or left out.
+func NewTestReview(result *TestResult, questions []Question, content string) *TestReview {
+	review := &TestReview{TestResult: result, Content: content, Questions: []QuestionReview{}}
+	for _, questionResult := range result.QuestionResults {
+		i := slices.IndexFunc(questions, func(q Question) bool { return q.ID == questionResult.QuestionID })
+		if i < 0 {
+			continue
+		}
+		q := questions[i]
+		questionReview := QuestionReview{
+			QuestionID: q.ID,
+			Type:       q.Type,
+			Question:   q.QuestionText,
+			Correct:    questionResult.Correct,
+			Evidence:   []ReviewEvidence{},
+		}
+		if q.Evidence != nil {
+			questionReview.Evidence = appendEvidence(questionReview.Evidence, 0, *q.Evidence, content)
+		}
+		for _, item := range q.Items {
+			if item.Evidence != nil {
+				questionReview.Evidence = appendEvidence(questionReview.Evidence, item.ID, *item.Evidence, content)
+			}
+		}
+		review.Questions = append(review.Questions, questionReview)
+	}
+	return review
+}
+
+func appendEvidence(evidence []ReviewEvidence, itemID uint64, span EvidenceSpan, content string) []ReviewEvidence {
+	if err := span.Locate(content); err != nil {
+		return evidence
+	}
+	return append(evidence, ReviewEvidence{ItemID: itemID, EvidenceSpan: span})
+}
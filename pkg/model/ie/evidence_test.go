@@ -0,0 +1,83 @@
+package ie
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+)
+
+const evidenceContent = "Café culture grew fast.\n\nThe  city’s first café opened in 1920 — by the river.\nIt closed later."
+
+func TestEvidenceSpanLocate(t *testing.T) {
+	tests := []struct {
+		name  string
+		span  EvidenceSpan
+		start int
+		text  string
+	}{
+		{"exact", EvidenceSpan{Text: "Café culture grew fast."}, 0, "Café culture grew fast."},
+		{"spacing and typography", EvidenceSpan{Text: "\"the city's first café opened in 1920 - by the river...\""},
+			25, "The  city’s first café opened in 1920 — by the river"},
+		{"offsets kept", EvidenceSpan{Start: 5, End: 12, Text: "culture"}, 5, "culture"},
+		{"wrong offsets", EvidenceSpan{Start: 0, End: 7, Text: "It closed"}, 79, "It closed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := tt.span
+			if err := span.Locate(evidenceContent); err != nil {
+				t.Fatalf("Locate() = %v", err)
+			}
+			if span.Start != tt.start || span.Text != tt.text || !span.Valid(evidenceContent) {
+				t.Errorf("Locate() = %+v, want start %d and text %q", span, tt.start, tt.text)
+			}
+		})
+	}
+
+	for _, text := range []string{"", "the tea culture", "..."} {
+		span := EvidenceSpan{Text: text}
+		if err := span.Locate(evidenceContent); !errors.Is(err, ErrEvidenceNotFound) {
+			t.Errorf("Locate(%q) = %v, want ErrEvidenceNotFound", text, err)
+		}
+	}
+}
+
+func TestQuestionLocateEvidence(t *testing.T) {
+	paragraphs := []Paragraph{{Label: "A", Text: "Café culture grew fast.\nThe  city’s first café opened in 1920 — by the river."}}
+	headings := Question{Base: model.Base{ID: 1}, Type: QUESTION_TYPE_MATCHING, Items: []QuestionItem{{ID: 1, Paragraph: "A"}}}
+	if err := headings.LocateEvidence(evidenceContent, paragraphs); err != nil {
+		t.Fatalf("LocateEvidence() of matching headings = %v", err)
+	}
+	if span := headings.Items[0].Evidence; span.Start != 0 || !span.Valid(evidenceContent) {
+		t.Errorf("paragraph evidence = %+v", span)
+	}
+
+	missing := Question{Base: model.Base{ID: 2}, Type: QUESTION_TYPE_TRUE_FALSE}
+	if err := missing.LocateEvidence(evidenceContent, paragraphs); !errors.Is(err, ErrEvidenceNotFound) {
+		t.Errorf("LocateEvidence() without evidence = %v, want ErrEvidenceNotFound", err)
+	}
+	invented := Question{Base: model.Base{ID: 3}, Type: QUESTION_TYPE_SENTENCE_COMPLETION,
+		Items: []QuestionItem{{ID: 1, Evidence: &EvidenceSpan{Text: "It closed in 1950."}}}}
+	if err := invented.LocateEvidence(evidenceContent, paragraphs); !errors.Is(err, ErrEvidenceNotFound) {
+		t.Errorf("LocateEvidence() of invented evidence = %v, want ErrEvidenceNotFound", err)
+	}
+}
+
+func TestNewTestReview(t *testing.T) {
+	questions := []Question{
+		{Base: model.Base{ID: 1}, Type: QUESTION_TYPE_TRUE_FALSE, Evidence: &EvidenceSpan{Start: 3, End: 10, Text: "It closed"}},
+		{Base: model.Base{ID: 2}, Type: QUESTION_TYPE_SHORT_ANSWER, Evidence: &EvidenceSpan{Text: "gone from the article"}},
+	}
+	result := &TestResult{QuestionResults: []QuestionResult{{QuestionID: 1, Correct: true}, {QuestionID: 2}}}
+
+	review := NewTestReview(result, questions, evidenceContent)
+	if len(review.Questions) != 2 {
+		t.Fatalf("NewTestReview() = %d questions, want 2", len(review.Questions))
+	}
+	if evidence := review.Questions[0].Evidence; len(evidence) != 1 || evidence[0].Start != 79 || !review.Questions[0].Correct {
+		t.Errorf("relocated evidence = %+v", review.Questions[0])
+	}
+	if len(review.Questions[1].Evidence) != 0 {
+		t.Errorf("evidence not in the content = %+v, want none", review.Questions[1].Evidence)
+	}
+}
@@ -0,0 +1,104 @@
+package ie
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+)
+
+// what happened to an entry of a feed
+const (
+	FEED_ENTRY_IMPORTED = "IMPORTED"
+	// filtered out or a duplicate of an existing article
+	FEED_ENTRY_SKIPPED = "SKIPPED"
+	// the page could not be fetched or extracted, retried on the next polls
+	FEED_ENTRY_FAILED = "FAILED"
+)
+
+type FeedFilter struct {
+	// articles with fewer words are skipped, no minimum when 0
+	MinWords int `json:"min_words"`
+	// when set, the title or content must contain one of the keywords, case insensitively
+	Keywords []string `json:"keywords"`
+	// articles imported from the feed per day, unlimited when 0
+	MaxPerDay int `json:"max_per_day"`
+}
+
+// Feed is an RSS or Atom subscription whose new entries are imported as articles
+type Feed struct {
+	model.Base
+	Url    string     `json:"url"`
+	Title  string     `json:"title"`
+	Filter FeedFilter `json:"filter"`
+	// generate the reading test of the imported articles
+	GenReading   bool       `json:"gen_reading"`
+	Enabled      bool       `json:"enabled"`
+	LastPolledAt *time.Time `json:"last_polled_at"`
+	LastError    string     `json:"last_error"`
+	UserID       uint64     `json:"-"`
+}
+
+type FeedDto struct {
+	Url        string     `json:"url"`
+	Filter     FeedFilter `json:"filter"`
+	GenReading bool       `json:"gen_reading"`
+	// defaults to true on creation
+	Enabled *bool `json:"enabled"`
+}
+
+type FeedEntry struct {
+	model.Base
+	FeedID    uint64 `json:"feed_id"`
+	Guid      string `json:"guid"`
+	Link      string `json:"link"`
+	ArticleID uint64 `json:"article_id"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason"`
+	Attempts  int    `json:"attempts"`
+}
+
+type FeedPollReport struct {
+	FeedID uint64 `json:"feed_id"`
+	// entries of the feed not handled before
+	NewEntries int      `json:"new_entries"`
+	Imported   []uint64 `json:"imported"`
+	Skipped    int      `json:"skipped"`
+	Failed     int      `json:"failed"`
+	// entries left for the next days once the daily maximum is reached
+	Deferred int `json:"deferred"`
+}
+
+// Validate checks the limits and drops the empty keywords
+func (f *FeedFilter) Validate() error {
+	if f.MinWords < 0 || f.MaxPerDay < 0 {
+		return errors.New("minimum words and maximum per day cannot be negative")
+	}
+	keywords := []string{}
+	for _, keyword := range f.Keywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+	f.Keywords = keywords
+	return nil
+}
+
+// Reject returns why the article does not pass the filter, empty when it does
+func (f *FeedFilter) Reject(article *Article) string {
+	if words := len(strings.Fields(article.Content)); words < f.MinWords {
+		return fmt.Sprintf("%d words, less than %d", words, f.MinWords)
+	}
+	if len(f.Keywords) == 0 {
+		return ""
+	}
+	text := strings.ToLower(article.Title + "\n" + article.Content)
+	for _, keyword := range f.Keywords {
+		if strings.Contains(text, strings.ToLower(keyword)) {
+			return ""
+		}
+	}
+	return "no keyword found"
+}
@@ -0,0 +1,43 @@
+package ie
+
+import "testing"
+
+func TestFeedFilterReject(t *testing.T) {
+	article := &Article{
+		Title:   "Coral reefs recover",
+		Content: "Researchers found that coral reefs bounce back quickly after bleaching events.",
+	}
+	tests := []struct {
+		name   string
+		filter FeedFilter
+		reject bool
+	}{
+		{"no filter", FeedFilter{}, false},
+		{"long enough", FeedFilter{MinWords: 11}, false},
+		{"too short", FeedFilter{MinWords: 12}, true},
+		{"keyword in title", FeedFilter{Keywords: []string{"CORAL"}}, false},
+		{"keyword in content", FeedFilter{Keywords: []string{"economy", "bleaching"}}, false},
+		{"no keyword", FeedFilter{Keywords: []string{"economy"}}, true},
+		{"short with keyword", FeedFilter{MinWords: 100, Keywords: []string{"coral"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if reason := tt.filter.Reject(article); (reason != "") != tt.reject {
+				t.Errorf("Reject() = %q, want rejected %v", reason, tt.reject)
+			}
+		})
+	}
+}
+
+func TestFeedFilterValidate(t *testing.T) {
+	filter := FeedFilter{Keywords: []string{" coral ", "", "  "}}
+	if err := filter.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if len(filter.Keywords) != 1 || filter.Keywords[0] != "coral" {
+		t.Errorf("Validate() keywords = %q, want [coral]", filter.Keywords)
+	}
+	if err := (&FeedFilter{MaxPerDay: -1}).Validate(); err == nil {
+		t.Error("Validate() of a negative maximum succeeded")
+	}
+}
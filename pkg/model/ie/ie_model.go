@@ -1,6 +1,11 @@
 package ie
 
-import "github.com/nhuongmh/cfvs.jpx/pkg/model"
+import (
+	"errors"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+	"github.com/nhuongmh/cfvs.jpx/pkg/supporter/readlevel"
+)
 
 type Article struct {
 	model.Base
@@ -10,6 +15,20 @@ type Article struct {
 	Author      string `json:"author"`
 	Image       string `json:"image"`
 	PublishDate string `json:"publish_date"`
+	// ARTICLE_NEW when the article is saved
+	Status string `json:"status"`
+	// normalized origin, or canonical url of the page, articles are deduplicated by it
+	CanonicalUrl string `json:"canonical_url"`
+	// SimHash of the content, see Fingerprint
+	Fingerprint uint64 `json:"-"`
+	// readability of the content, computed on save
+	Difficulty *readlevel.Stats `json:"difficulty"`
+	// normalized, see langfi.NormalizeTag
+	Tags []string `json:"tags"`
+}
+
+type ArticleTagsDto struct {
+	Tags []string `json:"tags"`
 }
 
 const (
@@ -22,10 +41,12 @@ const (
 )
 
 const (
-	QUESTION_TYPE_MULTIPLE_CHOICE = "multiple_choice"
-	QUESTION_TYPE_MATCHING        = "matching_headings"
-	QUESTION_TYPE_SHORT_ANSWER    = "short_answer"
-	QUESTION_TYPE_TRUE_FALSE      = "true_false_not_given"
+	QUESTION_TYPE_MULTIPLE_CHOICE      = "multiple_choice"
+	QUESTION_TYPE_MATCHING             = "matching_headings"
+	QUESTION_TYPE_MATCHING_INFORMATION = "matching_information"
+	QUESTION_TYPE_SENTENCE_COMPLETION  = "sentence_completion"
+	QUESTION_TYPE_SHORT_ANSWER         = "short_answer"
+	QUESTION_TYPE_TRUE_FALSE           = "true_false_not_given"
 )
 
 type ProposeWord struct {
@@ -41,16 +62,53 @@ type Question struct {
 	Type             string `json:"type"`
 	QuestionText     string `json:"question"`
 
-	Options []string `json:"options,omitempty"` // For multiple choice
-	Answer  string   `json:"answer"`
-	// Headings  []string `json:"headings,omitempty"`  // For matching headings
-	// Paragraph string   `json:"paragraph,omitempty"` // For matching headings
+	Options []string `json:"options,omitempty"` // For multiple choice, and the paragraph labels for matching information
+	Answer  string   `json:"answer"`            // Alternatives separated by / for short answer, empty for questions with items
+	// For matching headings, labelled i, ii, iii... in order
+	Headings []string `json:"headings,omitempty"`
+	// For matching and sentence completion, each item is a mark
+	Items []QuestionItem `json:"items,omitempty"`
+	// For short answer and sentence completion, the most words of an answer, no limit when 0
+	WordLimit int `json:"word_limit,omitempty"`
+	// the passage of the article supporting the answer, on the items for questions with items
+	Evidence *EvidenceSpan `json:"evidence,omitempty"`
+}
+
+type QuestionItem struct {
+	ID uint64 `json:"id"`
+	// the statement to match, or the sentence to complete with a ___ gap
+	Text string `json:"text,omitempty"`
+	// For matching headings, the label of the paragraph to find the heading of
+	Paragraph string `json:"paragraph,omitempty"`
+	// the heading label, the paragraph label, or the missing words, alternatives separated by /
+	Answer   string        `json:"answer"`
+	Evidence *EvidenceSpan `json:"evidence,omitempty"`
 }
 
 type QuestionResult struct {
 	QuestionID uint64 `json:"question_id"`
 	Answer     string `json:"answer"`
 	UserAnswer string `json:"user_answer"`
+	// all of the items are correct
+	Correct     bool         `json:"correct"`
+	ItemResults []ItemResult `json:"item_results,omitempty"`
+	// one per correct item, or one for a correct question without items
+	Marks    int `json:"marks"`
+	MaxMarks int `json:"max_marks"`
+	// a short answer the rules could not decide, the LLM gives the verdict when enabled
+	Ambiguous bool `json:"ambiguous,omitempty"`
+	// why the answer is wrong, or the explanation of the LLM verdict
+	Feedback string `json:"feedback,omitempty"`
+	// rules or llm
+	GradedBy string `json:"graded_by,omitempty"`
+	// time spent on the question in a timed test, when the client tracks it
+	Seconds int `json:"seconds,omitempty"`
+}
+
+type ItemResult struct {
+	ItemID     uint64 `json:"item_id"`
+	Answer     string `json:"answer"`
+	UserAnswer string `json:"user_answer"`
 	Correct    bool   `json:"correct"`
 }
 
@@ -59,13 +117,25 @@ type TestResult struct {
 	ArticleReadingId uint64           `json:"article_reading_id"`
 	QuestionResults  []QuestionResult `json:"question_results"`
 	Score            float32          `json:"score"`
+	// the timed test session of the submission, 0 for an untimed submission
+	TestSessionID uint64 `json:"test_session_id,omitempty"`
+	// IELTS module of the band, the marks scaled to a 40 questions test and their band
+	Module   string  `json:"module"`
+	RawScore int     `json:"raw_score"`
+	Band     float64 `json:"band"`
+	// from the start of the timed test to the submission
+	ElapsedSeconds int `json:"elapsed_seconds,omitempty"`
 }
 
+var ErrArticleReadingNotFound = errors.New("article reading not found")
+
 type ArticleReading struct {
 	model.Base
 	ArticleID uint64     `json:"article_id"`
 	Status    string     `json:"status"`
 	Questions []Question `json:"questions"`
+	// the content the questions were generated from, the questions refer to the paragraph labels
+	Paragraphs []Paragraph `json:"paragraphs"`
 }
 
 type LearningWord struct {
@@ -110,3 +180,19 @@ type IeVocabList struct {
 	Vocabs       []IeVocab `json:"vocabs"`
 	RefArticleID uint64    `json:"ref_article_id"`
 }
+
+type ClozeCardsDto struct {
+	// vocabs of the list turned into cards, every vocab of the list when empty
+	VocabIDs []uint64 `json:"vocab_ids"`
+	// deck of the cards, defaults to a deck named after the vocab list
+	Deck string `json:"deck"`
+}
+
+type ClozeCardsReport struct {
+	Deck  string `json:"deck"`
+	Added int    `json:"added"`
+	// vocabs whose card already exists
+	Duplicates int `json:"duplicates"`
+	// vocabs without a card, with the reason
+	Skipped []string `json:"skipped"`
+}
@@ -0,0 +1,77 @@
+package ie
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var ErrIllegalTransition = errors.New("illegal article transition")
+
+// events moving an article along its lifecycle
+const (
+	EVENT_READING_GENERATED  = "reading_generated"
+	EVENT_TEST_SUBMITTED     = "test_submitted"
+	EVENT_VOCAB_LIST_CREATED = "vocab_list_created"
+	EVENT_ANKI_EXPORTED      = "anki_exported"
+	EVENT_DISCARDED          = "discarded"
+	// back to the status before the article was discarded
+	EVENT_RESTORED = "restored"
+)
+
+// events the user triggers directly, the others follow the actions on the article
+var USER_ARTICLE_EVENTS = []string{EVENT_DISCARDED, EVENT_RESTORED}
+
+type articleTransition struct {
+	from []string
+	to   string
+}
+
+var articleTransitions = map[string]articleTransition{
+	EVENT_READING_GENERATED:  {from: []string{ARTICLE_NEW}, to: ARTICLE_ANALYZED},
+	EVENT_TEST_SUBMITTED:     {from: []string{ARTICLE_ANALYZED}, to: ARTICLE_TESTED},
+	EVENT_VOCAB_LIST_CREATED: {from: []string{ARTICLE_NEW, ARTICLE_ANALYZED, ARTICLE_TESTED}, to: ARTICLE_REVIEWED},
+	EVENT_ANKI_EXPORTED:      {from: []string{ARTICLE_REVIEWED}, to: ARTICLE_LEARNING},
+	EVENT_DISCARDED: {
+		from: []string{ARTICLE_NEW, ARTICLE_ANALYZED, ARTICLE_TESTED, ARTICLE_REVIEWED, ARTICLE_LEARNING},
+		to:   ARTICLE_DISCARDED,
+	},
+	EVENT_RESTORED: {from: []string{ARTICLE_DISCARDED}, to: ARTICLE_NEW},
+}
+
+// order of the statuses along the learning path
+var articleProgress = map[string]int{ARTICLE_NEW: 0, ARTICLE_ANALYZED: 1, ARTICLE_TESTED: 2, ARTICLE_REVIEWED: 3, ARTICLE_LEARNING: 4}
+
+// ArticleTransition is a status change of an article, the history of the article lists them
+type ArticleTransition struct {
+	ID         uint64    `json:"id"`
+	ArticleID  uint64    `json:"article_id"`
+	Event      string    `json:"event"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ArticleEventDto struct {
+	Event string `json:"event"`
+}
+
+// NextArticleStatus returns the status the event moves an article in the given status to.
+// An event repeating a step the article already passed, like a second test, leaves the status unchanged.
+// Unknown events, skipped steps and any step of a discarded article are ErrIllegalTransition.
+func NextArticleStatus(status, event string) (string, error) {
+	transition, ok := articleTransitions[event]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown event %q", ErrIllegalTransition, event)
+	}
+	if slices.Contains(transition.from, status) {
+		return transition.to, nil
+	}
+	current, inProgress := articleProgress[status]
+	target, isProgress := articleProgress[transition.to]
+	if inProgress && isProgress && current >= target && !slices.Contains(USER_ARTICLE_EVENTS, event) {
+		return status, nil
+	}
+	return "", fmt.Errorf("%w: %v article cannot be %v", ErrIllegalTransition, status, event)
+}
@@ -0,0 +1,49 @@
+package ie
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNextArticleStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		event  string
+		want   string
+	}{
+		{ARTICLE_NEW, EVENT_READING_GENERATED, ARTICLE_ANALYZED},
+		{ARTICLE_ANALYZED, EVENT_TEST_SUBMITTED, ARTICLE_TESTED},
+		{ARTICLE_TESTED, EVENT_VOCAB_LIST_CREATED, ARTICLE_REVIEWED},
+		{ARTICLE_NEW, EVENT_VOCAB_LIST_CREATED, ARTICLE_REVIEWED},
+		{ARTICLE_REVIEWED, EVENT_ANKI_EXPORTED, ARTICLE_LEARNING},
+		{ARTICLE_TESTED, EVENT_DISCARDED, ARTICLE_DISCARDED},
+		{ARTICLE_DISCARDED, EVENT_RESTORED, ARTICLE_NEW},
+		// steps already passed
+		{ARTICLE_TESTED, EVENT_TEST_SUBMITTED, ARTICLE_TESTED},
+		{ARTICLE_LEARNING, EVENT_READING_GENERATED, ARTICLE_LEARNING},
+		{ARTICLE_LEARNING, EVENT_ANKI_EXPORTED, ARTICLE_LEARNING},
+	}
+	for _, tt := range tests {
+		got, err := NextArticleStatus(tt.status, tt.event)
+		if err != nil || got != tt.want {
+			t.Errorf("NextArticleStatus(%v, %v) = %v, %v, want %v", tt.status, tt.event, got, err, tt.want)
+		}
+	}
+
+	illegal := []struct {
+		status string
+		event  string
+	}{
+		{ARTICLE_NEW, EVENT_TEST_SUBMITTED},
+		{ARTICLE_ANALYZED, EVENT_ANKI_EXPORTED},
+		{ARTICLE_DISCARDED, EVENT_READING_GENERATED},
+		{ARTICLE_DISCARDED, EVENT_DISCARDED},
+		{ARTICLE_NEW, EVENT_RESTORED},
+		{ARTICLE_NEW, "read"},
+	}
+	for _, tt := range illegal {
+		if got, err := NextArticleStatus(tt.status, tt.event); !errors.Is(err, ErrIllegalTransition) {
+			t.Errorf("NextArticleStatus(%v, %v) = %v, %v, want ErrIllegalTransition", tt.status, tt.event, got, err)
+		}
+	}
+}
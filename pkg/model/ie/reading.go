@@ -0,0 +1,286 @@
+package ie
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalidAnswers = errors.New("invalid test answers")
+
+const (
+	// shorter blocks of the content, like headings and captions, are joined to the following paragraph
+	MIN_PARAGRAPH_WORDS = 15
+	// the gap of a sentence completion item
+	SENTENCE_GAP = "___"
+)
+
+var paragraphBreak = regexp.MustCompile(`\n[ \t\r]*\n`)
+
+var headingLabels = []string{"i", "ii", "iii", "iv", "v", "vi", "vii", "viii", "ix", "x", "xi", "xii", "xiii", "xiv", "xv"}
+
+// Paragraph is a labelled paragraph of a reading, like paragraph B in IELTS
+type Paragraph struct {
+	Label string `json:"label"`
+	Text  string `json:"text"`
+}
+
+// LabelParagraphs splits the content into paragraphs labelled A, B, C... Paragraphs are separated by
+// empty lines, or by line breaks when the content has no empty line.
+func LabelParagraphs(content string) []Paragraph {
+	blocks := paragraphBreak.Split(strings.TrimSpace(content), -1)
+	if len(blocks) == 1 {
+		blocks = strings.Split(blocks[0], "\n")
+	}
+
+	texts := []string{}
+	pending := ""
+	for _, block := range blocks {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+		if pending != "" {
+			block = pending + "\n" + block
+			pending = ""
+		}
+		if len(strings.Fields(block)) < MIN_PARAGRAPH_WORDS {
+			pending = block
+			continue
+		}
+		texts = append(texts, block)
+	}
+	if pending != "" {
+		if len(texts) == 0 {
+			texts = append(texts, pending)
+		} else {
+			texts[len(texts)-1] += "\n" + pending
+		}
+	}
+
+	paragraphs := make([]Paragraph, len(texts))
+	for i, text := range texts {
+		paragraphs[i] = Paragraph{Label: paragraphLabel(i), Text: text}
+	}
+	return paragraphs
+}
+
+// paragraphLabel is A to Z, then AA, AB...
+func paragraphLabel(i int) string {
+	if i < 26 {
+		return string(rune('A' + i))
+	}
+	return paragraphLabel(i/26-1) + string(rune('A'+i%26))
+}
+
+// HeadingLabel is the roman numeral of the heading at the index
+func HeadingLabel(i int) string {
+	if i < len(headingLabels) {
+		return headingLabels[i]
+	}
+	return strconv.Itoa(i + 1)
+}
+
+func ParagraphLabels(paragraphs []Paragraph) []string {
+	labels := make([]string, len(paragraphs))
+	for i := range paragraphs {
+		labels[i] = paragraphs[i].Label
+	}
+	return labels
+}
+
+// AnswerKey is the key of the answer to an item of a question in a test submission,
+// questions without items are answered under their id
+func AnswerKey(questionID, itemID uint64) string {
+	return fmt.Sprintf("%d.%d", questionID, itemID)
+}
+
+// ValidateAnswers rejects answers to questions or items that are not in the test, unanswered ones are wrong
+func ValidateAnswers(questions []Question, answers map[string]string) error {
+	keys := map[string]bool{}
+	for _, q := range questions {
+		if len(q.Items) == 0 {
+			keys[strconv.FormatUint(q.ID, 10)] = true
+		}
+		for _, item := range q.Items {
+			keys[AnswerKey(q.ID, item.ID)] = true
+		}
+	}
+	for key := range answers {
+		if !keys[key] {
+			return fmt.Errorf("%w: no question or item %q", ErrInvalidAnswers, key)
+		}
+	}
+	return nil
+}
+
+// Validate checks that a generated question can be answered and graded,
+// matching questions must refer to the given paragraph labels
+func (q *Question) Validate(labels []string) error {
+	switch q.Type {
+	case QUESTION_TYPE_MULTIPLE_CHOICE:
+		if len(q.Options) < 2 {
+			return fmt.Errorf("multiple choice question %d has %d options", q.ID, len(q.Options))
+		}
+		if i := optionIndex(q.Answer); i < 0 || i >= len(q.Options) {
+			return fmt.Errorf("multiple choice question %d answer %q is not an option letter", q.ID, q.Answer)
+		}
+	case QUESTION_TYPE_TRUE_FALSE:
+		if strings.TrimSpace(q.Answer) == "" {
+			return fmt.Errorf("question %d has no answer", q.ID)
+		}
+	case QUESTION_TYPE_SHORT_ANSWER:
+		if err := validateWords(q.Answer, q.WordLimit); err != nil {
+			return fmt.Errorf("question %d: %w", q.ID, err)
+		}
+	case QUESTION_TYPE_MATCHING, QUESTION_TYPE_MATCHING_INFORMATION, QUESTION_TYPE_SENTENCE_COMPLETION:
+		if len(q.Items) == 0 {
+			return fmt.Errorf("%v question %d has no items", q.Type, q.ID)
+		}
+		for _, item := range q.Items {
+			if err := q.validateItem(&item, labels); err != nil {
+				return fmt.Errorf("%v question %d: %w", q.Type, q.ID, err)
+			}
+		}
+	default:
+		return fmt.Errorf("question %d has unknown type %q", q.ID, q.Type)
+	}
+	return nil
+}
+
+func (q *Question) validateItem(item *QuestionItem, labels []string) error {
+	switch q.Type {
+	case QUESTION_TYPE_MATCHING:
+		if !slices.Contains(labels, item.Paragraph) {
+			return fmt.Errorf("unknown paragraph %q", item.Paragraph)
+		}
+		if headingIndex(item.Answer) >= len(q.Headings) || headingIndex(item.Answer) < 0 {
+			return fmt.Errorf("answer %q is not a heading label", item.Answer)
+		}
+	case QUESTION_TYPE_MATCHING_INFORMATION:
+		if strings.TrimSpace(item.Text) == "" {
+			return fmt.Errorf("item %d has no statement", item.ID)
+		}
+		if !slices.Contains(labels, item.Answer) {
+			return fmt.Errorf("answer %q is not a paragraph label", item.Answer)
+		}
+	case QUESTION_TYPE_SENTENCE_COMPLETION:
+		if !strings.Contains(item.Text, SENTENCE_GAP) {
+			return fmt.Errorf("item %d has no gap", item.ID)
+		}
+		if err := validateWords(item.Answer, q.WordLimit); err != nil {
+			return fmt.Errorf("item %d: %w", item.ID, err)
+		}
+	}
+	return nil
+}
+
+// validateWords checks that the accepted answers separated by / are within the word limit
+func validateWords(answer string, wordLimit int) error {
+	alternatives := answerAlternatives(answer)
+	if len(alternatives) == 0 {
+		return errors.New("no answer")
+	}
+	for _, alternative := range alternatives {
+		if wordLimit > 0 && len(answerWords(alternative)) > wordLimit {
+			return fmt.Errorf("answer %q is longer than %d words", alternative, wordLimit)
+		}
+	}
+	return nil
+}
+
+// GradeQuestion grades the answers of a submission to the question, each item is a mark.
+// Short answers the rules cannot decide are wrong and marked ambiguous.
+func GradeQuestion(q *Question, answers map[string]string) QuestionResult {
+	result := QuestionResult{QuestionID: q.ID, Answer: q.Answer, GradedBy: GRADED_BY_RULES}
+	if len(q.Items) == 0 {
+		result.UserAnswer = answers[strconv.FormatUint(q.ID, 10)]
+		if q.Type == QUESTION_TYPE_SHORT_ANSWER {
+			verdict, explanation := ShortAnswerVerdict(q.Answer, result.UserAnswer, q.WordLimit)
+			result.Correct = verdict == VERDICT_CORRECT
+			result.Ambiguous = verdict == VERDICT_AMBIGUOUS
+			result.Feedback = explanation
+		} else {
+			result.Correct = q.matchAnswer(q.Answer, result.UserAnswer)
+		}
+		result.MaxMarks = 1
+		if result.Correct {
+			result.Marks = 1
+		}
+		return result
+	}
+
+	for _, item := range q.Items {
+		itemResult := ItemResult{ItemID: item.ID, Answer: item.Answer, UserAnswer: answers[AnswerKey(q.ID, item.ID)]}
+		itemResult.Correct = q.matchAnswer(item.Answer, itemResult.UserAnswer)
+		if itemResult.Correct {
+			result.Marks++
+		}
+		result.ItemResults = append(result.ItemResults, itemResult)
+	}
+	result.MaxMarks = len(q.Items)
+	result.Correct = result.Marks == result.MaxMarks
+	return result
+}
+
+func (q *Question) matchAnswer(answer, userAnswer string) bool {
+	userAnswer = strings.TrimSpace(userAnswer)
+	switch q.Type {
+	case QUESTION_TYPE_MATCHING:
+		// the label or the text of the heading
+		if i := headingIndex(answer); i >= 0 && i < len(q.Headings) && strings.EqualFold(q.Headings[i], userAnswer) {
+			return true
+		}
+		return userAnswer != "" && strings.EqualFold(answer, userAnswer)
+	case QUESTION_TYPE_MATCHING_INFORMATION:
+		userAnswer = strings.TrimSpace(strings.TrimPrefix(strings.ToLower(userAnswer), "paragraph"))
+		return userAnswer != "" && strings.EqualFold(answer, userAnswer)
+	case QUESTION_TYPE_SENTENCE_COMPLETION:
+		verdict, _ := ShortAnswerVerdict(answer, userAnswer, q.WordLimit)
+		return verdict == VERDICT_CORRECT
+	default:
+		return strings.EqualFold(answer, userAnswer)
+	}
+}
+
+// answerAlternatives splits the accepted answers separated by /
+func answerAlternatives(answer string) []string {
+	alternatives := []string{}
+	for _, alternative := range strings.Split(answer, "/") {
+		if strings.TrimSpace(alternative) != "" {
+			alternatives = append(alternatives, strings.TrimSpace(alternative))
+		}
+	}
+	return alternatives
+}
+
+// answerWords lower cases the words of an answer without their punctuation
+func answerWords(answer string) []string {
+	return strings.FieldsFunc(strings.ToLower(answer), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '-'
+	})
+}
+
+// optionIndex reads the option letter A, B, C... of a multiple choice answer
+func optionIndex(answer string) int {
+	answer = strings.ToUpper(strings.TrimSpace(answer))
+	if len(answer) != 1 || answer[0] < 'A' || answer[0] > 'Z' {
+		return -1
+	}
+	return int(answer[0] - 'A')
+}
+
+func headingIndex(label string) int {
+	label = strings.ToLower(strings.TrimSpace(label))
+	if i := slices.Index(headingLabels, label); i >= 0 {
+		return i
+	}
+	if n, err := strconv.Atoi(label); err == nil && n > len(headingLabels) {
+		return n - 1
+	}
+	return -1
+}
@@ -0,0 +1,130 @@
+package ie
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+)
+
+func TestLabelParagraphs(t *testing.T) {
+	long := strings.Repeat("word ", MIN_PARAGRAPH_WORDS)
+	content := "Title\n\n" + long + "\n\n  \n" + long + "\n\nCaption"
+
+	paragraphs := LabelParagraphs(content)
+	if len(paragraphs) != 2 {
+		t.Fatalf("LabelParagraphs() = %d paragraphs, want 2", len(paragraphs))
+	}
+	if paragraphs[0].Label != "A" || !strings.HasPrefix(paragraphs[0].Text, "Title\n") {
+		t.Errorf("first paragraph = %+v, want A with the title", paragraphs[0])
+	}
+	if paragraphs[1].Label != "B" || !strings.HasSuffix(paragraphs[1].Text, "\nCaption") {
+		t.Errorf("last paragraph = %+v, want B with the caption", paragraphs[1])
+	}
+
+	lines := LabelParagraphs(long + "\n" + long + "\n" + long)
+	if len(lines) != 3 || lines[2].Label != "C" {
+		t.Errorf("LabelParagraphs() of lines = %+v, want A to C", lines)
+	}
+	if got := LabelParagraphs("short"); len(got) != 1 || got[0].Text != "short" {
+		t.Errorf("LabelParagraphs(short) = %+v, want one paragraph", got)
+	}
+}
+
+func TestParagraphLabel(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 52: "BA"} {
+		if got := paragraphLabel(i); got != want {
+			t.Errorf("paragraphLabel(%d) = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestQuestionValidate(t *testing.T) {
+	labels := []string{"A", "B", "C"}
+	valid := []Question{
+		{Base: model.Base{ID: 1}, Type: QUESTION_TYPE_MULTIPLE_CHOICE, Options: []string{"x", "y"}, Answer: "b"},
+		{Base: model.Base{ID: 2}, Type: QUESTION_TYPE_MATCHING, Headings: []string{"h1", "h2"},
+			Items: []QuestionItem{{ID: 1, Paragraph: "A", Answer: "ii"}}},
+		{Base: model.Base{ID: 3}, Type: QUESTION_TYPE_MATCHING_INFORMATION, Items: []QuestionItem{{ID: 1, Text: "a date", Answer: "C"}}},
+		{Base: model.Base{ID: 4}, Type: QUESTION_TYPE_SENTENCE_COMPLETION, WordLimit: 2,
+			Items: []QuestionItem{{ID: 1, Text: "It was ___.", Answer: "very old/ancient"}}},
+	}
+	for _, q := range valid {
+		if err := q.Validate(labels); err != nil {
+			t.Errorf("Validate() of question %d = %v", q.ID, err)
+		}
+	}
+
+	invalid := []Question{
+		{Base: model.Base{ID: 1}, Type: QUESTION_TYPE_MULTIPLE_CHOICE, Options: []string{"x", "y"}, Answer: "C"},
+		{Base: model.Base{ID: 2}, Type: QUESTION_TYPE_MATCHING, Headings: []string{"h1"},
+			Items: []QuestionItem{{ID: 1, Paragraph: "A", Answer: "ii"}}},
+		{Base: model.Base{ID: 3}, Type: QUESTION_TYPE_MATCHING, Headings: []string{"h1"},
+			Items: []QuestionItem{{ID: 1, Paragraph: "D", Answer: "i"}}},
+		{Base: model.Base{ID: 4}, Type: QUESTION_TYPE_MATCHING_INFORMATION, Items: []QuestionItem{{ID: 1, Text: "a date", Answer: "E"}}},
+		{Base: model.Base{ID: 5}, Type: QUESTION_TYPE_SENTENCE_COMPLETION, Items: []QuestionItem{{ID: 1, Text: "It was old.", Answer: "very"}}},
+		{Base: model.Base{ID: 6}, Type: QUESTION_TYPE_SENTENCE_COMPLETION, WordLimit: 1,
+			Items: []QuestionItem{{ID: 1, Text: "It was ___.", Answer: "very old"}}},
+		{Base: model.Base{ID: 7}, Type: QUESTION_TYPE_SENTENCE_COMPLETION},
+		{Base: model.Base{ID: 8}, Type: "essay", Answer: "x"},
+	}
+	for _, q := range invalid {
+		if err := q.Validate(labels); err == nil {
+			t.Errorf("Validate() of question %d = nil, want an error", q.ID)
+		}
+	}
+}
+
+func TestGradeQuestion(t *testing.T) {
+	headings := Question{Base: model.Base{ID: 1}, Type: QUESTION_TYPE_MATCHING, Headings: []string{"The past", "The future"},
+		Items: []QuestionItem{{ID: 1, Paragraph: "A", Answer: "i"}, {ID: 2, Paragraph: "B", Answer: "ii"}}}
+	information := Question{Base: model.Base{ID: 2}, Type: QUESTION_TYPE_MATCHING_INFORMATION,
+		Items: []QuestionItem{{ID: 1, Text: "a date", Answer: "C"}}}
+	completion := Question{Base: model.Base{ID: 3}, Type: QUESTION_TYPE_SENTENCE_COMPLETION, WordLimit: 2,
+		Items: []QuestionItem{{ID: 1, Text: "It was ___.", Answer: "very old/ancient"}}}
+	choice := Question{Base: model.Base{ID: 4}, Type: QUESTION_TYPE_MULTIPLE_CHOICE, Options: []string{"x", "y"}, Answer: "B"}
+
+	tests := []struct {
+		name    string
+		q       *Question
+		answers map[string]string
+		marks   int
+	}{
+		{"heading labels", &headings, map[string]string{"1.1": "I", "1.2": "ii"}, 2},
+		{"heading text", &headings, map[string]string{"1.1": "the past", "1.2": "i"}, 1},
+		{"no answers", &headings, map[string]string{}, 0},
+		{"paragraph", &information, map[string]string{"2.1": "Paragraph c"}, 1},
+		{"wrong paragraph", &information, map[string]string{"2.1": "B"}, 0},
+		{"completion", &completion, map[string]string{"3.1": "Very old."}, 1},
+		{"alternative", &completion, map[string]string{"3.1": "ancient"}, 1},
+		{"over the word limit", &completion, map[string]string{"3.1": "very very old"}, 0},
+		{"choice", &choice, map[string]string{"4": "b"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := GradeQuestion(tt.q, tt.answers)
+			if result.Marks != tt.marks {
+				t.Errorf("GradeQuestion() marks = %d, want %d", result.Marks, tt.marks)
+			}
+			if result.Correct != (result.Marks == result.MaxMarks) {
+				t.Errorf("GradeQuestion() correct = %v with %d of %d marks", result.Correct, result.Marks, result.MaxMarks)
+			}
+		})
+	}
+}
+
+func TestValidateAnswers(t *testing.T) {
+	questions := []Question{
+		{Base: model.Base{ID: 1}, Type: QUESTION_TYPE_TRUE_FALSE, Answer: "TRUE"},
+		{Base: model.Base{ID: 2}, Type: QUESTION_TYPE_MATCHING_INFORMATION, Items: []QuestionItem{{ID: 1, Answer: "A"}}},
+	}
+	if err := ValidateAnswers(questions, map[string]string{"1": "true", "2.1": "A"}); err != nil {
+		t.Errorf("ValidateAnswers() = %v", err)
+	}
+	for _, key := range []string{"2", "1.1", "3"} {
+		if err := ValidateAnswers(questions, map[string]string{key: "A"}); !errors.Is(err, ErrInvalidAnswers) {
+			t.Errorf("ValidateAnswers(%v) = %v, want ErrInvalidAnswers", key, err)
+		}
+	}
+}
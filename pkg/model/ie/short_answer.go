@@ -0,0 +1,69 @@
+package ie
+
+import (
+	"fmt"
+	"slices"
+)
+
+// verdicts of the rules on a short answer
+const (
+	VERDICT_CORRECT = "correct"
+	VERDICT_WRONG   = "wrong"
+	// the answer shares words with an accepted answer without matching it, like a synonym or a longer phrase
+	VERDICT_AMBIGUOUS = "ambiguous"
+)
+
+// graders of a question result
+const (
+	GRADED_BY_RULES = "rules"
+	GRADED_BY_LLM   = "llm"
+)
+
+// words an answer can add or leave out, they still count towards the word limit
+var articleWords = []string{"a", "an", "the"}
+
+// ShortAnswerVerdict compares the user answer to the accepted answers separated by /, ignoring case,
+// punctuation and articles. As in IELTS an answer over the word limit is wrong. The explanation is empty
+// for a correct answer.
+func ShortAnswerVerdict(answer, userAnswer string, wordLimit int) (string, string) {
+	words := answerWords(userAnswer)
+	if len(words) == 0 {
+		return VERDICT_WRONG, "There is no answer."
+	}
+	if wordLimit > 0 && len(words) > wordLimit {
+		return VERDICT_WRONG, fmt.Sprintf("The answer has %d words, more than the limit of %d.", len(words), wordLimit)
+	}
+
+	contentWords := withoutArticles(words)
+	overlap := false
+	for _, alternative := range answerAlternatives(answer) {
+		accepted := withoutArticles(answerWords(alternative))
+		if slices.Equal(contentWords, accepted) {
+			return VERDICT_CORRECT, ""
+		}
+		for _, word := range contentWords {
+			overlap = overlap || slices.Contains(accepted, word)
+		}
+	}
+	if overlap {
+		return VERDICT_AMBIGUOUS, "The answer only partly matches the expected answer."
+	}
+	return VERDICT_WRONG, "The answer does not match the expected answer."
+}
+
+// SetVerdict overrides the rules with the verdict of the LLM on an ambiguous answer
+func (r *QuestionResult) SetVerdict(correct bool, explanation string) {
+	r.Correct = correct
+	r.Marks = 0
+	if correct {
+		r.Marks = r.MaxMarks
+	}
+	r.Feedback = explanation
+	r.GradedBy = GRADED_BY_LLM
+}
+
+func withoutArticles(words []string) []string {
+	return slices.DeleteFunc(slices.Clone(words), func(word string) bool {
+		return slices.Contains(articleWords, word)
+	})
+}
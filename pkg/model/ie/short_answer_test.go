@@ -0,0 +1,47 @@
+package ie
+
+import "testing"
+
+func TestShortAnswerVerdict(t *testing.T) {
+	tests := []struct {
+		name       string
+		answer     string
+		userAnswer string
+		wordLimit  int
+		want       string
+	}{
+		{"exact", "solar power", "solar power", 3, VERDICT_CORRECT},
+		{"case and punctuation", "solar power", "  Solar, POWER. ", 3, VERDICT_CORRECT},
+		{"article", "the harbour", "harbour", 3, VERDICT_CORRECT},
+		{"alternative", "1990/nineteen ninety", "Nineteen ninety", 3, VERDICT_CORRECT},
+		{"empty", "solar power", "", 3, VERDICT_WRONG},
+		{"single letter", "solar power", "a", 3, VERDICT_WRONG},
+		{"over the word limit", "solar power", "the solar power plant", 3, VERDICT_WRONG},
+		{"no limit", "solar power", "the power of the sun on solar panels", 0, VERDICT_AMBIGUOUS},
+		{"partial", "solar power", "power", 3, VERDICT_AMBIGUOUS},
+		{"unrelated", "solar power", "wind", 3, VERDICT_WRONG},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, explanation := ShortAnswerVerdict(tt.answer, tt.userAnswer, tt.wordLimit)
+			if got != tt.want {
+				t.Errorf("ShortAnswerVerdict(%q, %q, %d) = %v, want %v", tt.answer, tt.userAnswer, tt.wordLimit, got, tt.want)
+			}
+			if (got == VERDICT_CORRECT) != (explanation == "") {
+				t.Errorf("ShortAnswerVerdict(%q, %q, %d) explanation = %q", tt.answer, tt.userAnswer, tt.wordLimit, explanation)
+			}
+		})
+	}
+}
+
+func TestQuestionResultSetVerdict(t *testing.T) {
+	result := QuestionResult{MaxMarks: 1, Ambiguous: true, GradedBy: GRADED_BY_RULES}
+	result.SetVerdict(true, "A synonym of the answer.")
+	if !result.Correct || result.Marks != 1 || result.GradedBy != GRADED_BY_LLM || result.Feedback == "" {
+		t.Errorf("SetVerdict(true) = %+v", result)
+	}
+	result.SetVerdict(false, "A different place.")
+	if result.Correct || result.Marks != 0 {
+		t.Errorf("SetVerdict(false) = %+v", result)
+	}
+}
@@ -0,0 +1,115 @@
+package ie
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+)
+
+var (
+	ErrInvalidTestSession = errors.New("invalid test session")
+	// the session was submitted already, or its deadline passed
+	ErrTestSessionClosed   = errors.New("test session closed")
+	ErrTestSessionNotFound = errors.New("test session not found")
+)
+
+const (
+	TEST_SESSION_IN_PROGRESS = "in_progress"
+	TEST_SESSION_SUBMITTED   = "submitted"
+	// the deadline passed without a submission
+	TEST_SESSION_EXPIRED = "expired"
+)
+
+const (
+	// a reading of one article is one of the three passages of the 60 minutes test
+	DEFAULT_TEST_MINUTES = 20
+	MAX_TEST_MINUTES     = 180
+	// submissions arriving this late after the deadline are still accepted, for the network delay
+	TEST_SUBMIT_GRACE_SECONDS = 30
+)
+
+// TestSession is a timed test of a reading. The server records the start and the deadline,
+// the submission is only graded before the deadline.
+type TestSession struct {
+	model.Base
+	ArticleReadingID uint64     `json:"article_reading_id"`
+	Module           string     `json:"module"`
+	DurationSeconds  int        `json:"duration_seconds"`
+	StartedAt        time.Time  `json:"started_at"`
+	Deadline         time.Time  `json:"deadline"`
+	SubmittedAt      *time.Time `json:"submitted_at"`
+	// derived from the times when the session is read
+	Status           string `json:"status"`
+	RemainingSeconds int    `json:"remaining_seconds"`
+	// the result of the submission, 0 before
+	TestResultID uint64 `json:"test_result_id"`
+}
+
+type StartTestDto struct {
+	// academic when empty
+	Module string `json:"module"`
+	// the configured duration when 0
+	DurationMinutes int `json:"duration_minutes"`
+}
+
+type TestSubmissionDto struct {
+	// keyed like the answers of an untimed submission
+	Answers map[string]string `json:"answers"`
+	// seconds spent on each question keyed by question id, when the client tracks them
+	QuestionSeconds map[string]int `json:"question_seconds"`
+}
+
+// Validate normalizes the module and the duration, defaultMinutes is used without a duration
+func (d *StartTestDto) Validate(defaultMinutes int) error {
+	d.Module = strings.ToLower(strings.TrimSpace(d.Module))
+	if d.Module == "" {
+		d.Module = IELTS_ACADEMIC
+	}
+	if !slices.Contains(IELTS_MODULES, d.Module) {
+		return fmt.Errorf("%w: module must be one of %v", ErrInvalidTestSession, IELTS_MODULES)
+	}
+	if d.DurationMinutes == 0 {
+		d.DurationMinutes = defaultMinutes
+	}
+	if d.DurationMinutes < 1 || d.DurationMinutes > MAX_TEST_MINUTES {
+		return fmt.Errorf("%w: duration must be between 1 and %d minutes", ErrInvalidTestSession, MAX_TEST_MINUTES)
+	}
+	return nil
+}
+
+// ValidateQuestionSeconds rejects times of questions that are not in the test, and negative times
+func ValidateQuestionSeconds(questions []Question, seconds map[string]int) error {
+	for key, value := range seconds {
+		if !slices.ContainsFunc(questions, func(q Question) bool { return strconv.FormatUint(q.ID, 10) == key }) {
+			return fmt.Errorf("%w: no question %q", ErrInvalidAnswers, key)
+		}
+		if value < 0 {
+			return fmt.Errorf("%w: negative time on question %v", ErrInvalidAnswers, key)
+		}
+	}
+	return nil
+}
+
+// SetQuestionSeconds records the time spent on each question in its result
+func (r *TestResult) SetQuestionSeconds(seconds map[string]int) {
+	for i := range r.QuestionResults {
+		r.QuestionResults[i].Seconds = seconds[strconv.FormatUint(r.QuestionResults[i].QuestionID, 10)]
+	}
+}
+
+// SetBand converts the marks of the results to the raw score and the band of the module
+func (r *TestResult) SetBand(module string, table BandTable) {
+	marks, maxMarks := 0, 0
+	for _, result := range r.QuestionResults {
+		marks += result.Marks
+		maxMarks += result.MaxMarks
+	}
+	r.Module = module
+	r.RawScore = RawScore(marks, maxMarks)
+	r.Band = table.Band(r.RawScore)
+}
@@ -0,0 +1,50 @@
+package ie
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+)
+
+func TestStartTestDtoValidate(t *testing.T) {
+	dto := StartTestDto{Module: " General "}
+	if err := dto.Validate(20); err != nil || dto.Module != IELTS_GENERAL || dto.DurationMinutes != 20 {
+		t.Errorf("Validate() = %v, %+v", err, dto)
+	}
+	dto = StartTestDto{DurationMinutes: 60}
+	if err := dto.Validate(20); err != nil || dto.Module != IELTS_ACADEMIC || dto.DurationMinutes != 60 {
+		t.Errorf("Validate() = %v, %+v", err, dto)
+	}
+
+	for _, invalid := range []StartTestDto{{Module: "toefl"}, {DurationMinutes: -5}, {DurationMinutes: MAX_TEST_MINUTES + 1}} {
+		if err := invalid.Validate(20); !errors.Is(err, ErrInvalidTestSession) {
+			t.Errorf("Validate(%+v) = %v, want ErrInvalidTestSession", invalid, err)
+		}
+	}
+}
+
+func TestQuestionSeconds(t *testing.T) {
+	questions := []Question{{Base: model.Base{ID: 1}}, {Base: model.Base{ID: 2}}}
+	if err := ValidateQuestionSeconds(questions, map[string]int{"1": 40, "2": 0}); err != nil {
+		t.Errorf("ValidateQuestionSeconds() = %v", err)
+	}
+	for _, seconds := range []map[string]int{{"3": 10}, {"1.1": 10}, {"2": -1}} {
+		if err := ValidateQuestionSeconds(questions, seconds); !errors.Is(err, ErrInvalidAnswers) {
+			t.Errorf("ValidateQuestionSeconds(%v) = %v, want ErrInvalidAnswers", seconds, err)
+		}
+	}
+
+	result := TestResult{QuestionResults: []QuestionResult{
+		{QuestionID: 1, Marks: 2, MaxMarks: 3},
+		{QuestionID: 2, Marks: 1, MaxMarks: 1},
+	}}
+	result.SetQuestionSeconds(map[string]int{"2": 75})
+	if result.QuestionResults[0].Seconds != 0 || result.QuestionResults[1].Seconds != 75 {
+		t.Errorf("SetQuestionSeconds() = %+v", result.QuestionResults)
+	}
+	result.SetBand(IELTS_ACADEMIC, DEFAULT_BAND_TABLES[IELTS_ACADEMIC])
+	if result.Module != IELTS_ACADEMIC || result.RawScore != 30 || result.Band != 7 {
+		t.Errorf("SetBand() = %v raw %d band %v, want raw 30 band 7", result.Module, result.RawScore, result.Band)
+	}
+}
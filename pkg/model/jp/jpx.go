@@ -19,6 +19,13 @@ const (
 	FORM_VAR_REGEX  = `\[([a-zA-Z_]+[@]?[1-9]?)\]`
 )
 
+// generated cards go to DECK_ROOT::DECK_MINNA::<lesson>, or DECK_ROOT::DECK_NO_LESSON
+const (
+	DECK_ROOT      = "jp"
+	DECK_MINNA     = "Minna"
+	DECK_NO_LESSON = "NA"
+)
+
 // card state
 
 const (
@@ -103,7 +110,11 @@ type JpxGeneratorService interface {
 	FetchProposal(ctx context.Context, group string) (*langfi.ReviewCard, error)
 	SubmitProposal(ctx context.Context, cardID uint64, status string) error
 	// GetProcessGroups(ctx context.Context) []string
+	GetAvailableLangs(ctx context.Context) ([]string, error)
 	EditCardText(ctx context.Context, newCard *langfi.ReviewCard) (*langfi.ReviewCard, error)
+	// GetCardRevisions lists the earlier texts of a card, newest first
+	GetCardRevisions(ctx context.Context, cardID uint64) (*[]langfi.CardRevision, error)
+	RevertCard(ctx context.Context, cardID, revisionID uint64) (*langfi.ReviewCard, error)
 }
 
 // type JpxGeneratorRepository interface {
@@ -0,0 +1,61 @@
+package langfi
+
+import "github.com/nhuongmh/cfvs.jpx/pkg/supporter/anki"
+
+// media referenced by cards, e.g. [sound:x.mp3], is exported from and imported into this directory,
+// which is served under /data/media
+const MEDIA_DIR = "./data/media"
+
+// the largest anki package accepted for import
+const MAX_ANKI_IMPORT_BYTES = 512 << 20
+
+type AnkiExportDto struct {
+	// card query selecting the exported cards, see ParseCardQuery
+	Query string `json:"query"`
+	// puts every card in this deck instead of the deck of the card
+	Deck string `json:"deck"`
+	// nil uses anki.BasicModel, fields are filled by name from front, back or the card properties
+	Model *anki.NoteModel `json:"model"`
+	// exports the fsrs state and review history, otherwise cards are exported as new
+	IncludeScheduling bool `json:"include_scheduling"`
+}
+
+// properties of cards imported from anki
+const (
+	PROP_ANKI_GUID  = "anki_guid"
+	PROP_ANKI_MODEL = "anki_model"
+)
+
+type AnkiImportDto struct {
+	// parent deck of the imported anki decks, the anki Default deck maps to this deck itself
+	Deck string `json:"deck"`
+	// note fields used as card front and back, default to the first and second field.
+	// Every field is also stored in the card properties by its lower cased name.
+	FrontField string `json:"front_field"`
+	BackField  string `json:"back_field"`
+	// only reports what would be imported
+	DryRun bool `json:"dry_run"`
+}
+
+// AnkiImportReport counts what was imported, or would be with a dry run
+type AnkiImportReport struct {
+	DryRun   bool `json:"dry_run"`
+	Notes    int  `json:"notes"`
+	Imported int  `json:"imported"`
+	Reviews  int  `json:"reviews"`
+	Media    int  `json:"media"`
+	// media whose name is taken in MEDIA_DIR by a file of another content, which is kept
+	MediaConflicts []string `json:"media_conflicts"`
+	// imported cards by deck
+	Decks map[string]int `json:"decks"`
+	// fronts that already exist, or appear twice in the package, are not imported
+	Duplicates []string `json:"duplicates"`
+	// notes that could not be imported, with the reason
+	Skipped []string `json:"skipped"`
+}
+
+// ImportedCard is a new card with the review logs of its imported history
+type ImportedCard struct {
+	Card *ReviewCard
+	Logs []ReviewLog
+}
@@ -0,0 +1,170 @@
+package langfi
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// replaces the hidden word on the front of a cloze card
+	CLOZE_BLANK = "[...]"
+	// tag of every cloze card
+	CLOZE_TAG = "cloze"
+)
+
+// properties of cloze cards, the hidden word is also stored as PROP_ANSWER for typed practice
+const (
+	PROP_CLOZE_SENTENCE = "cloze_sentence"
+	PROP_CLOZE_WORD     = "cloze_word"
+	PROP_CLOZE_HINT     = "cloze_hint"
+)
+
+// the English inflection suffixes an inflected form of a word may end with, e.g. study -> studying
+var inflectionSuffixes = []string{"s", "es", "ed", "ing", "er", "est"}
+
+var ErrInvalidCloze = errors.New("invalid cloze")
+
+// NewClozeCard hides the word in the sentence, the card front is the sentence with the blank
+// and the back is the hidden form of the word
+func NewClozeCard(sentence, word, hint string) (ReviewCard, error) {
+	sentence = strings.Join(strings.Fields(sentence), " ")
+	if sentence == "" {
+		return ReviewCard{}, errors.Wrapf(ErrInvalidCloze, "no sentence to hide `%v` in", word)
+	}
+	cloze, answer, ok := ClozeDeletion(sentence, word)
+	if !ok {
+		return ReviewCard{}, errors.Wrapf(ErrInvalidCloze, "`%v` is not in the sentence `%v`", word, sentence)
+	}
+
+	card := NewReviewCard(cloze, answer)
+	card.SetProp(PROP_ANSWER, answer)
+	card.SetProp(PROP_CLOZE_SENTENCE, sentence)
+	card.SetProp(PROP_CLOZE_WORD, word)
+	if hint = strings.TrimSpace(hint); hint != "" {
+		card.SetProp(PROP_CLOZE_HINT, hint)
+	}
+	card.Tags = []string{CLOZE_TAG}
+	return card, nil
+}
+
+// ClozeDeletion replaces every occurrence of the word in the sentence with CLOZE_BLANK and returns
+// the first hidden occurrence as it is written. Words are compared case insensitively, the words
+// of the phrase may be inflected when the sentence does not contain it as is.
+func ClozeDeletion(sentence, word string) (cloze, answer string, ok bool) {
+	runes := []rune(sentence)
+	tokens := wordSpans(runes)
+	phrase := []string{}
+	for _, s := range wordSpans([]rune(word)) {
+		phrase = append(phrase, strings.ToLower(string([]rune(word)[s.start:s.end])))
+	}
+	if len(phrase) == 0 {
+		return sentence, "", false
+	}
+
+	matches := matchPhrase(runes, tokens, phrase, false)
+	if len(matches) == 0 {
+		matches = matchPhrase(runes, tokens, phrase, true)
+	}
+	if len(matches) == 0 {
+		return sentence, "", false
+	}
+
+	var b strings.Builder
+	pos := 0
+	for _, m := range matches {
+		b.WriteString(string(runes[pos:m.start]))
+		b.WriteString(CLOZE_BLANK)
+		pos = m.end
+	}
+	b.WriteString(string(runes[pos:]))
+	return b.String(), string(runes[matches[0].start:matches[0].end]), true
+}
+
+type runeSpan struct {
+	start, end int
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r)
+}
+
+func wordSpans(runes []rune) []runeSpan {
+	spans := []runeSpan{}
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(runes) && isWordRune(runes[i]) {
+			i++
+		}
+		spans = append(spans, runeSpan{start, i})
+	}
+	return spans
+}
+
+// matchPhrase finds the runs of tokens spelling the phrase, from the first token of the run to the last
+func matchPhrase(runes []rune, tokens []runeSpan, phrase []string, inflected bool) []runeSpan {
+	matches := []runeSpan{}
+	for i := 0; i+len(phrase) <= len(tokens); i++ {
+		matched := true
+		for j, word := range phrase {
+			token := strings.ToLower(string(runes[tokens[i+j].start:tokens[i+j].end]))
+			if token == word || (inflected && isInflectionOf(token, word)) {
+				continue
+			}
+			matched = false
+			break
+		}
+		if matched {
+			matches = append(matches, runeSpan{tokens[i].start, tokens[i+len(phrase)-1].end})
+			i += len(phrase) - 1
+		}
+	}
+	return matches
+}
+
+// isInflectionOf tells whether the token is the word with an inflection suffix. Before a suffix
+// a final e of the word may be dropped, a final consonant y may turn into i and a final consonant
+// after a single vowel may be doubled, e.g. analyze -> analyzing, study -> studies, stop -> stopped
+func isInflectionOf(token, word string) bool {
+	runes := []rune(word)
+	if len(runes) < 2 {
+		return false
+	}
+	last, stem := runes[len(runes)-1], string(runes[:len(runes)-1])
+	for _, suffix := range inflectionSuffixes {
+		forms := []string{word + suffix}
+		switch {
+		case last == 'e':
+			forms = append(forms, stem+suffix)
+		case last == 'y' && !isVowel(runes[len(runes)-2]) && suffix[0] == 'e':
+			forms = append(forms, stem+"i"+suffix)
+		case endsConsonantVowelConsonant(runes) && suffix != "s" && suffix != "es":
+			forms = append(forms, word+string(last)+suffix)
+		}
+		for _, form := range forms {
+			if token == form {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("aeiou", r)
+}
+
+// endsConsonantVowelConsonant tells whether the final consonant of the word is doubled before a suffix,
+// w, x and y are never doubled
+func endsConsonantVowelConsonant(runes []rune) bool {
+	if len(runes) < 3 {
+		return false
+	}
+	a, b, c := runes[len(runes)-3], runes[len(runes)-2], runes[len(runes)-1]
+	return !isVowel(a) && isVowel(b) && !isVowel(c) && unicode.IsLetter(c) && !strings.ContainsRune("wxy", c)
+}
@@ -0,0 +1,90 @@
+package langfi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClozeDeletion(t *testing.T) {
+	tests := []struct {
+		sentence   string
+		word       string
+		wantCloze  string
+		wantAnswer string
+		wantOk     bool
+	}{
+		{"The results were Robust.", "robust", "The results were [...].", "Robust", true},
+		{"Mind the gap, the gap is wide", "gap", "Mind the [...], the [...] is wide", "gap", true},
+		{"Prices are rising but nobody rises to the challenge", "rise", "Prices are [...] but nobody [...] to the challenge", "rising", true},
+		{"She studied the report.", "study", "She [...] the report.", "studied", true},
+		{"The plane took off, then it takes off again", "take off", "The plane took off, then it [...] again", "takes off", true},
+		// the exact form wins over inflected ones
+		{"run, running, runs", "run", "[...], running, runs", "run", true},
+		{"A self-evident truth", "self evident", "A [...] truth", "self-evident", true},
+		{"A category", "cat", "A category", "", false},
+		{"He stopped and kept running", "stop", "He [...] and kept running", "stopped", true},
+		{"The happiest day", "happy", "The [...] day", "happiest", true},
+		// only inflection suffixes make an inflected form
+		{"The artist wrote an article", "art", "The artist wrote an article", "", false},
+		{"Take care of the cart", "car", "Take care of the cart", "", false},
+		{"Two cars", "car", "Two [...]", "cars", true},
+		{"Nothing here", "", "Nothing here", "", false},
+	}
+	for _, tt := range tests {
+		cloze, answer, ok := ClozeDeletion(tt.sentence, tt.word)
+		if cloze != tt.wantCloze || answer != tt.wantAnswer || ok != tt.wantOk {
+			t.Errorf("ClozeDeletion(%q, %q) = %q, %q, %v, want %q, %q, %v",
+				tt.sentence, tt.word, cloze, answer, ok, tt.wantCloze, tt.wantAnswer, tt.wantOk)
+		}
+	}
+}
+
+func Test_isInflectionOf(t *testing.T) {
+	tests := []struct {
+		token string
+		word  string
+		want  bool
+	}{
+		{"analyzing", "analyze", true},
+		{"analyzed", "analyze", true},
+		{"studies", "study", true},
+		{"studying", "study", true},
+		{"plays", "play", true},
+		{"boxes", "box", true},
+		{"bigger", "big", true},
+		{"artist", "art", false},
+		{"article", "art", false},
+		{"cart", "car", false},
+		{"care", "car", false},
+		{"category", "cat", false},
+		{"boxxed", "box", false},
+		{"playied", "play", false},
+	}
+	for _, tt := range tests {
+		if got := isInflectionOf(tt.token, tt.word); got != tt.want {
+			t.Errorf("isInflectionOf(%q, %q) = %v, want %v", tt.token, tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestNewClozeCard(t *testing.T) {
+	card, err := NewClozeCard("  The data was\n analyzed twice. ", "analyze", "(verb) examine in detail")
+	if err != nil {
+		t.Fatalf("NewClozeCard() error = %v", err)
+	}
+	if card.Front != "The data was [...] twice." || card.Back != "analyzed" || card.GetProp(PROP_ANSWER) != "analyzed" {
+		t.Errorf("NewClozeCard() = %+v", card)
+	}
+	if card.GetProp(PROP_CLOZE_SENTENCE) != "The data was analyzed twice." || card.GetProp(PROP_CLOZE_HINT) != "(verb) examine in detail" {
+		t.Errorf("NewClozeCard() properties = %v", card.Properties)
+	}
+	if len(card.Tags) != 1 || card.Tags[0] != CLOZE_TAG {
+		t.Errorf("NewClozeCard() tags = %v", card.Tags)
+	}
+
+	for _, sentence := range []string{"", "Unrelated sentence."} {
+		if _, err := NewClozeCard(sentence, "analyze", ""); !errors.Is(err, ErrInvalidCloze) {
+			t.Errorf("NewClozeCard(%q) error = %v, want ErrInvalidCloze", sentence, err)
+		}
+	}
+}
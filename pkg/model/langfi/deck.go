@@ -0,0 +1,143 @@
+package langfi
+
+import (
+	"strings"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+	"github.com/open-spaced-repetition/go-fsrs/v3"
+	"github.com/pkg/errors"
+)
+
+// decks form a tree by name, e.g. "jp::Minna::L05" is a child of "jp::Minna".
+// The root deck is the language of its cards.
+const DECK_SEPARATOR = "::"
+
+var ErrInvalidDeck = errors.New("invalid deck")
+
+// DeckSettings are kept per user, and inherited from the parent deck for every zero value.
+// The daily limits of a deck count the reviews of its sub decks, and also limit them.
+type DeckSettings struct {
+	// 0 means no limit
+	NewPerDay     int `json:"new_per_day"`
+	ReviewsPerDay int `json:"reviews_per_day"`
+	// share of the deck in the review queue relative to the other queued decks, 0 counts as 1
+	Priority int `json:"priority"`
+
+	RequestRetention float64   `json:"request_retention"`
+	MaximumInterval  float64   `json:"maximum_interval"`
+	Weights          []float64 `json:"weights,omitempty"`
+}
+
+type Deck struct {
+	model.Base
+	Name     string       `json:"name"`
+	ParentID uint64       `json:"parent_id"`
+	Settings DeckSettings `json:"settings"`
+}
+
+type DeckTreeDto struct {
+	Deck
+	// settings after inheritance
+	Effective DeckSettings `json:"effective"`
+	// counts of cards directly in the deck, and including all sub decks
+	Own      GroupSummaryDto `json:"own"`
+	Total    GroupSummaryDto `json:"total"`
+	Children []DeckTreeDto   `json:"children"`
+}
+
+type CreateDeckDto struct {
+	Name string `json:"name"`
+}
+
+// DeckPath joins deck names into a full deck name
+func DeckPath(names ...string) string {
+	return strings.Join(names, DECK_SEPARATOR)
+}
+
+// NormalizeDeckName trims every level of the name, an empty level is invalid
+func NormalizeDeckName(name string) (string, error) {
+	levels := strings.Split(name, DECK_SEPARATOR)
+	for i := range levels {
+		levels[i] = strings.TrimSpace(levels[i])
+		if levels[i] == "" {
+			return "", errors.Wrapf(ErrInvalidDeck, "empty level in deck name `%v`", name)
+		}
+	}
+	return DeckPath(levels...), nil
+}
+
+// ParentDeckName returns "" for a root deck
+func ParentDeckName(name string) string {
+	idx := strings.LastIndex(name, DECK_SEPARATOR)
+	if idx < 0 {
+		return ""
+	}
+	return name[:idx]
+}
+
+// RootDeckName returns the language part of a deck name
+func RootDeckName(name string) string {
+	return strings.SplitN(name, DECK_SEPARATOR, 2)[0]
+}
+
+// IsInDeck reports whether a deck is the given deck or one of its sub decks
+func IsInDeck(name, deck string) bool {
+	return name == deck || strings.HasPrefix(name, deck+DECK_SEPARATOR)
+}
+
+// Inherit fills zero settings from the parent settings
+func (s DeckSettings) Inherit(parent DeckSettings) DeckSettings {
+	if s.NewPerDay == 0 {
+		s.NewPerDay = parent.NewPerDay
+	}
+	if s.ReviewsPerDay == 0 {
+		s.ReviewsPerDay = parent.ReviewsPerDay
+	}
+	if s.Priority == 0 {
+		s.Priority = parent.Priority
+	}
+	if s.RequestRetention == 0 {
+		s.RequestRetention = parent.RequestRetention
+	}
+	if s.MaximumInterval == 0 {
+		s.MaximumInterval = parent.MaximumInterval
+	}
+	if len(s.Weights) == 0 {
+		s.Weights = parent.Weights
+	}
+	return s
+}
+
+func (s *DeckSettings) Validate() error {
+	if s.NewPerDay < 0 || s.ReviewsPerDay < 0 {
+		return errors.Wrap(ErrInvalidDeck, "daily limits must not be negative")
+	}
+	if s.Priority < 0 {
+		return errors.Wrap(ErrInvalidDeck, "priority must not be negative")
+	}
+	if s.RequestRetention < 0 || s.RequestRetention >= 1 {
+		return errors.Wrap(ErrInvalidDeck, "request_retention must be between 0 and 1")
+	}
+	if s.MaximumInterval < 0 {
+		return errors.Wrap(ErrInvalidDeck, "maximum_interval must not be negative")
+	}
+	if len(s.Weights) != 0 && len(s.Weights) != len(fsrs.Weights{}) {
+		return errors.Wrapf(ErrInvalidDeck, "weights must have %v values", len(fsrs.Weights{}))
+	}
+	return nil
+}
+
+// FsrsParameters applies the deck overrides on top of the default parameters
+func (s *DeckSettings) FsrsParameters() fsrs.Parameters {
+	params := fsrs.DefaultParam()
+	if s.RequestRetention > 0 {
+		params.RequestRetention = s.RequestRetention
+	}
+	if s.MaximumInterval > 0 {
+		params.MaximumInterval = s.MaximumInterval
+	}
+	if len(s.Weights) == len(params.W) {
+		copy(params.W[:], s.Weights)
+	}
+	return params
+}
@@ -0,0 +1,51 @@
+package langfi
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_NormalizeDeckName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"jp", "jp", false},
+		{" jp :: Minna::L05 ", "jp::Minna::L05", false},
+		{"jp::", "", true},
+		{"::jp", "", true},
+		{"  ", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeDeckName(tt.name)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrInvalidDeck)) {
+				t.Fatalf("NormalizeDeckName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeDeckName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_DeckSettings_FsrsParameters(t *testing.T) {
+	weights := make([]float64, 19)
+	weights[0] = 1.5
+	settings := DeckSettings{RequestRetention: 0.85, Weights: weights}
+	if err := settings.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	params := settings.FsrsParameters()
+	if params.RequestRetention != 0.85 || params.MaximumInterval != 36500 || params.W[0] != 1.5 {
+		t.Errorf("FsrsParameters() = %+v", params)
+	}
+
+	for _, invalid := range []DeckSettings{{NewPerDay: -1}, {RequestRetention: 1}, {Weights: []float64{1, 2}}} {
+		if err := invalid.Validate(); !errors.Is(err, ErrInvalidDeck) {
+			t.Errorf("Validate(%+v) error = %v, want %v", invalid, err, ErrInvalidDeck)
+		}
+	}
+}
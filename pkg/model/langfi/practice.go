@@ -3,9 +3,12 @@ package langfi
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/search"
+	"github.com/nhuongmh/cfvs.jpx/pkg/supporter/anki"
 	"github.com/open-spaced-repetition/go-fsrs/v3"
 )
 
@@ -30,7 +33,12 @@ type ReviewCard struct {
 	Back       string                 `json:"back"`
 	Properties map[string]interface{} `json:"properties"`
 	Status     string                 `json:"status"`
-	Group      string                 `json:"group"`
+	Group      string                 `json:"group"` // full deck name, see DECK_SEPARATOR
+	Tags       []string               `json:"tags"`
+	// counts the changes of front, back, properties and deck, an edit of an older revision is rejected
+	Revision uint64 `json:"revision"`
+	// the only user seeing a card made from private data, 0 when every user sees the card
+	OwnerID uint64 `json:"owner_id,omitempty"`
 }
 
 func NewReviewCard(front string, back string) ReviewCard {
@@ -40,6 +48,7 @@ func NewReviewCard(front string, back string) ReviewCard {
 		Back:       back,
 		Properties: map[string]interface{}{},
 		Status:     CARD_NEW,
+		Tags:       []string{},
 	}
 }
 
@@ -80,21 +89,84 @@ type GroupSummaryDto struct {
 }
 
 type PracticeService interface {
-	GetGroups(ctx context.Context) []string
+	// GetGroups lists the decks of a language
+	GetGroups(ctx context.Context, lang string) ([]string, error)
 	FetchCard(ctx context.Context, group string) (*ReviewCard, error)
 	//newState should be Again=1, Hard=2, Good=3, Easy=4
 	SubmitCard(ctx context.Context, cardID, rating uint64) error
 	GetCard(ctx context.Context, cardId uint64) (*ReviewCard, error)
 	GetGroupStats(ctx context.Context) (*[]GroupSummaryDto, error)
+	GetReviewStats(ctx context.Context, query *ReviewStatsQuery) (*ReviewStatsDto, error)
+	// SubmitTypedAnswer grades the typed answer and suggests a rating, the card is not rated
+	SubmitTypedAnswer(ctx context.Context, answer *TypedAnswerDto) (*TypedAnswerResult, error)
+	SearchCards(ctx context.Context, query string, limit int) (*[]ReviewCard, error)
+	// FetchFilteredCard returns the next card to practice among the cards matching query
+	FetchFilteredCard(ctx context.Context, query string) (*ReviewCard, error)
+	BulkEditCards(ctx context.Context, edit *BulkEditDto) (*BulkEditResult, error)
+	GetDeckTree(ctx context.Context) (*[]DeckTreeDto, error)
+	CreateDeck(ctx context.Context, name string) (*Deck, error)
+	UpdateDeckSettings(ctx context.Context, deckID uint64, settings *DeckSettings) (*Deck, error)
+	// ExportAnki builds an anki package of the cards matching the export query
+	ExportAnki(ctx context.Context, export *AnkiExportDto) (*anki.Package, error)
+	// ImportAnki imports the notes of the .apkg or .colpkg at path
+	ImportAnki(ctx context.Context, path string, opts *AnkiImportDto) (*AnkiImportReport, error)
+	// PullChanges returns the cards changed for the user since the cursor, at most limit of them
+	PullChanges(ctx context.Context, cursor uint64, limit int) (*SyncPullDto, error)
+	// PushReviews records reviews given offline, each reviewed card is rescheduled by replaying its reviews in time order.
+	// Reviews older than the last review of a card whose earlier reviews are not all recorded are rejected.
+	PushReviews(ctx context.Context, push *SyncPushDto) (*SyncPushResult, error)
+	// GetReviewQueue interleaves the due cards of the decks by deck priority, within the daily limits of each deck.
+	// IE vocab is only queued through its cloze cards.
+	GetReviewQueue(ctx context.Context, decks []string, size int) (*ReviewQueueDto, error)
 }
 
+// CardReviewFunc schedules the card given its review logs of the current user, oldest first, and returns
+// the logs to store: logs without id are added, stored logs left out are deleted
+type CardReviewFunc func(card *ReviewCard, logs []ReviewLog) ([]ReviewLog, error)
+
 type PracticeRepo interface {
 	AddCard(ctx context.Context, card *ReviewCard) error
+	// AddNewCards adds the cards whose front is not stored yet and returns how many were added, all or none are added
+	AddNewCards(ctx context.Context, cards []ReviewCard) (int, error)
+	// ImportCards adds the cards with their review logs for the current user, all or none are added
+	ImportCards(ctx context.Context, cards []ImportedCard) error
 	GetCard(ctx context.Context, cardID uint64) (*ReviewCard, error)
 	UpdateCard(ctx context.Context, card *ReviewCard) error
+	// UpdateCardText stores front, back and properties of the card together with the revision of the edit,
+	// the fsrs data of the card is left as is
+	UpdateCardText(ctx context.Context, card *ReviewCard, revision *CardRevision) error
+	// GetCardRevisions returns the revisions of a card, newest first
+	GetCardRevisions(ctx context.Context, cardID uint64) (*[]CardRevision, error)
+	GetCardRevision(ctx context.Context, revisionID uint64) (*CardRevision, error)
 	FetchReviewCard(ctx context.Context, group string) (*ReviewCard, error)
 	GetCardByFront(ctx context.Context, front string) (*[]ReviewCard, error)
 	FetchUnProcessCard(ctx context.Context, group string) (*ReviewCard, error)
+	// DeleteNewCard removes the shared cards and the cards of the current user that no user has processed yet
 	DeleteNewCard(ctx context.Context) error
 	GetGroupStats(ctx context.Context) (*[]GroupSummaryDto, error)
+	GetCardsByStatus(ctx context.Context, status string) (*[]ReviewCard, error)
+	AddReviewLog(ctx context.Context, log *ReviewLog) error
+	GetReviewLogs(ctx context.Context, since time.Time) (*[]ReviewLog, error)
+	// GetFirstReviewLogs returns the earliest review log of each card for the current user
+	// among its logs with a stability of at least minStability
+	GetFirstReviewLogs(ctx context.Context, minStability float64) (*[]ReviewLog, error)
+	// GetCardReviewLogs returns the review logs of the card for the current user, oldest first
+	GetCardReviewLogs(ctx context.Context, cardID uint64) (*[]ReviewLog, error)
+	// ReviewCard reviews the card for the current user while no other review of it runs, and stores its fsrs data
+	// with the logs returned by review in one transaction. The card is ErrNoMoreDataAvailable when it does not exist.
+	ReviewCard(ctx context.Context, cardID uint64, review CardReviewFunc) (*ReviewCard, error)
+	// GetCardChanges returns the latest change of each card changed for the current user after the cursor,
+	// ordered by Seq
+	GetCardChanges(ctx context.Context, cursor uint64, limit int) (*[]CardChange, error)
+	GetCardsByID(ctx context.Context, cardIDs []uint64) (*[]ReviewCard, error)
+	// SearchCards returns cards matching the query, soonest due first and unscheduled cards last
+	SearchCards(ctx context.Context, query *CardQuery, limit int) (*[]ReviewCard, error)
+	// SearchCardText returns the cards whose front, back or properties contain every term, best match first
+	SearchCardText(ctx context.Context, terms []string, limit int) ([]search.Hit, error)
+	// GetDecks returns the decks, shared by every user, with the settings of the user
+	GetDecks(ctx context.Context) (*[]Deck, error)
+	// EnsureDeck returns the deck with the given name, creating it and its missing parents
+	EnsureDeck(ctx context.Context, name string) (*Deck, error)
+	// UpdateDeckSettings replaces the settings of the deck for the user
+	UpdateDeckSettings(ctx context.Context, deckID uint64, settings *DeckSettings) error
 }
@@ -0,0 +1,263 @@
+package langfi
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// fields of the card query language, e.g. `tag:lesson5 state:review due<3d lapses>2 front:*本*`
+const (
+	QUERY_TAG        = "tag"
+	QUERY_GROUP      = "group"
+	QUERY_DECK       = "deck" // same as group
+	QUERY_STATUS     = "status"
+	QUERY_STATE      = "state"
+	QUERY_FRONT      = "front"
+	QUERY_BACK       = "back"
+	QUERY_DUE        = "due"
+	QUERY_REPS       = "reps"
+	QUERY_LAPSES     = "lapses"
+	QUERY_STABILITY  = "stability"
+	QUERY_DIFFICULTY = "difficulty"
+	// bare words match front or back
+	QUERY_TEXT = ""
+)
+
+const (
+	OP_EQ  = "="
+	OP_LT  = "<"
+	OP_LTE = "<="
+	OP_GT  = ">"
+	OP_GTE = ">="
+)
+
+// in text values `*` matches any sequence of characters
+const QUERY_WILDCARD = "*"
+
+var ErrInvalidCardQuery = errors.New("invalid card query")
+
+var textQueryFields = map[string]bool{QUERY_TAG: true, QUERY_GROUP: true, QUERY_DECK: true, QUERY_FRONT: true, QUERY_BACK: true}
+var numberQueryFields = map[string]bool{QUERY_REPS: true, QUERY_LAPSES: true, QUERY_STABILITY: true, QUERY_DIFFICULTY: true}
+
+// fsrs states by their query name
+var QueryStates = map[string]int{"new": 0, "learning": 1, "review": 2, "relearning": 3}
+
+// QueryTerm is one condition of a card query, Value is already validated for its field:
+// a status or state name, a number, a due offset in days, or a text that may contain wildcards
+type QueryTerm struct {
+	Field  string `json:"field"`
+	Op     string `json:"op"`
+	Value  string `json:"value"`
+	Negate bool   `json:"negate"`
+}
+
+// CardQuery matches the cards satisfying all of its terms, an empty query matches every card
+type CardQuery struct {
+	Terms []QueryTerm `json:"terms"`
+}
+
+func (q *CardQuery) HasField(field string) bool {
+	for i := range q.Terms {
+		if q.Terms[i].Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// DueOffset returns the due offset of a due term, relative to now
+func (t *QueryTerm) DueOffset() time.Duration {
+	days, _ := strconv.ParseFloat(t.Value, 64)
+	return time.Duration(days * float64(24*time.Hour))
+}
+
+// ParseCardQuery parses space separated terms `[-]field(:|=|<|<=|>|>=)value`.
+// Values may be double quoted to contain spaces, a leading `-` negates the term.
+func ParseCardQuery(query string) (*CardQuery, error) {
+	tokens, err := tokenizeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	cardQuery := &CardQuery{Terms: []QueryTerm{}}
+	for _, token := range tokens {
+		term, err := parseQueryTerm(token)
+		if err != nil {
+			return nil, err
+		}
+		cardQuery.Terms = append(cardQuery.Terms, *term)
+	}
+	return cardQuery, nil
+}
+
+func tokenizeQuery(query string) ([]string, error) {
+	tokens := []string{}
+	var sb strings.Builder
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			// keep quotes so that `"tag:x"` stays a bare word
+			sb.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if sb.Len() > 0 {
+				tokens = append(tokens, sb.String())
+				sb.Reset()
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, errors.Wrap(ErrInvalidCardQuery, "unterminated quote")
+	}
+	if sb.Len() > 0 {
+		tokens = append(tokens, sb.String())
+	}
+	return tokens, nil
+}
+
+func unquote(s string) string {
+	return strings.ReplaceAll(s, `"`, "")
+}
+
+func parseQueryTerm(token string) (*QueryTerm, error) {
+	term := &QueryTerm{Field: QUERY_TEXT, Op: OP_EQ}
+	if len(token) > 1 && token[0] == '-' {
+		term.Negate = true
+		token = token[1:]
+	}
+
+	opIdx := strings.IndexAny(token, ":=<>")
+	field := ""
+	if opIdx > 0 {
+		field = strings.ToLower(token[:opIdx])
+	}
+	if field == "" || (!textQueryFields[field] && !numberQueryFields[field] &&
+		field != QUERY_STATUS && field != QUERY_STATE && field != QUERY_DUE) {
+		term.Value = unquote(token)
+		if term.Value == "" {
+			return nil, errors.Wrapf(ErrInvalidCardQuery, "empty term `%v`", token)
+		}
+		return term, nil
+	}
+
+	term.Field = field
+	if field == QUERY_DECK {
+		term.Field = QUERY_GROUP
+	}
+	rest := token[opIdx:]
+	switch {
+	case strings.HasPrefix(rest, OP_LTE), strings.HasPrefix(rest, OP_GTE):
+		term.Op = rest[:2]
+	case rest[0] == ':' || rest[0] == '=':
+		term.Op = OP_EQ
+	default:
+		term.Op = rest[:1]
+	}
+	// `:` and `=` have the same length
+	term.Value = unquote(rest[len(term.Op):])
+	if term.Value == "" {
+		return nil, errors.Wrapf(ErrInvalidCardQuery, "missing value in `%v`", token)
+	}
+
+	err := validateQueryTerm(term)
+	if err != nil {
+		return nil, err
+	}
+	return term, nil
+}
+
+func validateQueryTerm(term *QueryTerm) error {
+	switch {
+	case textQueryFields[term.Field]:
+		if term.Op != OP_EQ {
+			return errors.Wrapf(ErrInvalidCardQuery, "%v only supports `:`", term.Field)
+		}
+		if term.Field == QUERY_TAG {
+			term.Value = NormalizeTag(term.Value)
+		}
+	case term.Field == QUERY_STATUS:
+		for _, status := range ALL_CARD_STATUS {
+			if strings.EqualFold(status, term.Value) {
+				term.Value = status
+			}
+		}
+		if term.Op != OP_EQ || !slices.Contains(ALL_CARD_STATUS, term.Value) {
+			return errors.Wrapf(ErrInvalidCardQuery, "status must be one of %v", ALL_CARD_STATUS)
+		}
+	case term.Field == QUERY_STATE:
+		term.Value = strings.ToLower(term.Value)
+		if _, ok := QueryStates[term.Value]; term.Op != OP_EQ || !ok {
+			return errors.Wrap(ErrInvalidCardQuery, "state must be one of new, learning, review, relearning")
+		}
+	case term.Field == QUERY_DUE:
+		days, err := parseDays(term.Value)
+		if err != nil {
+			return err
+		}
+		term.Value = strconv.FormatFloat(days, 'f', -1, 64)
+	case numberQueryFields[term.Field]:
+		if _, err := strconv.ParseFloat(term.Value, 64); err != nil {
+			return errors.Wrapf(ErrInvalidCardQuery, "%v must be a number", term.Field)
+		}
+	}
+	return nil
+}
+
+// parseDays reads offsets such as `3`, `3d`, `2w`, `-1d` or `12h` as a number of days
+func parseDays(value string) (float64, error) {
+	unit := 1.0
+	switch value[len(value)-1] {
+	case 'd':
+		value = value[:len(value)-1]
+	case 'w':
+		unit = 7
+		value = value[:len(value)-1]
+	case 'h':
+		unit = 1.0 / 24
+		value = value[:len(value)-1]
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, errors.Wrapf(ErrInvalidCardQuery, "due offset `%v` must be like 3d, 2w or 12h", value)
+	}
+	return n * unit, nil
+}
+
+// NormalizeTag lower cases a tag and replaces inner spaces, tags are compared in this form
+func NormalizeTag(tag string) string {
+	return strings.Join(strings.Fields(strings.ToLower(tag)), "_")
+}
+
+// NormalizeTags normalizes and deduplicates tags, dropping empty ones
+func NormalizeTags(tags []string) []string {
+	result := []string{}
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag != "" && !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+type BulkEditDto struct {
+	Query      string   `json:"query"`
+	AddTags    []string `json:"add_tags"`
+	RemoveTags []string `json:"remove_tags"`
+	// empty keeps the current value. The deck is shared by every user, a group is rejected.
+	Group  string `json:"group"`
+	Status string `json:"status"`
+}
+
+type BulkEditResult struct {
+	Matched int `json:"matched"`
+	Updated int `json:"updated"`
+}
@@ -0,0 +1,54 @@
+package langfi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_ParseCardQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []QueryTerm
+	}{
+		{"empty", "  ", []QueryTerm{}},
+		{"example", "tag:Lesson5 state:review due<3d lapses>2 front:*本*", []QueryTerm{
+			{Field: QUERY_TAG, Op: OP_EQ, Value: "lesson5"},
+			{Field: QUERY_STATE, Op: OP_EQ, Value: "review"},
+			{Field: QUERY_DUE, Op: OP_LT, Value: "3"},
+			{Field: QUERY_LAPSES, Op: OP_GT, Value: "2"},
+			{Field: QUERY_FRONT, Op: OP_EQ, Value: "*本*"},
+		}},
+		{"negation and operators", "-status:save due>=2w reps<=1", []QueryTerm{
+			{Field: QUERY_STATUS, Op: OP_EQ, Value: CARD_SAVE, Negate: true},
+			{Field: QUERY_DUE, Op: OP_GTE, Value: "14"},
+			{Field: QUERY_REPS, Op: OP_LTE, Value: "1"},
+		}},
+		{"quoted values and bare words", `back:"to eat" 食べる "a:b"`, []QueryTerm{
+			{Field: QUERY_BACK, Op: OP_EQ, Value: "to eat"},
+			{Field: QUERY_TEXT, Op: OP_EQ, Value: "食べる"},
+			{Field: QUERY_TEXT, Op: OP_EQ, Value: "a:b"},
+		}},
+		{"unknown field is text", "note:x", []QueryTerm{{Field: QUERY_TEXT, Op: OP_EQ, Value: "note:x"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCardQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseCardQuery() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Terms, tt.want) {
+				t.Errorf("ParseCardQuery() = %+v, want %+v", got.Terms, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ParseCardQuery_invalid(t *testing.T) {
+	for _, query := range []string{`front:"abc`, "state:old", "status:x", "due<soon", "lapses>many", "tag>3", "tag:"} {
+		if _, err := ParseCardQuery(query); !errors.Is(err, ErrInvalidCardQuery) {
+			t.Errorf("ParseCardQuery(%q) error = %v, want %v", query, err, ErrInvalidCardQuery)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package langfi
+
+// QueueCardDto is a card of the review queue with the queued deck it was picked for
+type QueueCardDto struct {
+	Deck string `json:"deck"`
+	// the card was never reviewed and counts against the new cards limit of the deck
+	New  bool       `json:"new"`
+	Card ReviewCard `json:"card"`
+}
+
+type QueueDeckDto struct {
+	Deck     string `json:"deck"`
+	Priority int    `json:"priority"`
+	// queued due reviews and new cards of the deck
+	Reviews int `json:"reviews"`
+	New     int `json:"new"`
+}
+
+// ReviewQueueDto interleaves due cards of several decks, e.g. Japanese sentences and
+// English vocab cloze cards, in the order they should be practiced
+type ReviewQueueDto struct {
+	Cards []QueueCardDto `json:"cards"`
+	Decks []QueueDeckDto `json:"decks"`
+}
@@ -0,0 +1,90 @@
+package langfi
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+	"github.com/pkg/errors"
+)
+
+// revisions record the text of a card, status and scheduling are not part of them
+const (
+	REVISION_FIELD_FRONT = "front"
+	REVISION_FIELD_BACK  = "back"
+	// changed properties are named `properties.<key>`
+	REVISION_FIELD_PROPERTIES = "properties."
+)
+
+var ErrInvalidRevision = errors.New("invalid card revision")
+
+// ErrStaleRevision is an edit of a card that was changed since the edited revision
+var ErrStaleRevision = errors.New("card was changed since the edited revision")
+
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// CardRevision is the text of a card before an edit, together with what the edit changed.
+// Reverting to a revision restores that text.
+type CardRevision struct {
+	model.Base
+	CardID uint64 `json:"card_id"`
+	// user who made the edit
+	UserID     uint64                 `json:"user_id"`
+	Front      string                 `json:"front"`
+	Back       string                 `json:"back"`
+	Properties map[string]interface{} `json:"properties"`
+	Changes    []FieldChange          `json:"changes"`
+}
+
+// NewCardRevision records the edit of card into edited, it returns nil when the text is unchanged
+func NewCardRevision(card, edited *ReviewCard, userID uint64) *CardRevision {
+	changes := CardTextChanges(card, edited)
+	if len(changes) == 0 {
+		return nil
+	}
+	properties := make(map[string]interface{}, len(card.Properties))
+	for k, v := range card.Properties {
+		properties[k] = v
+	}
+	return &CardRevision{
+		CardID:     card.ID,
+		UserID:     userID,
+		Front:      card.Front,
+		Back:       card.Back,
+		Properties: properties,
+		Changes:    changes,
+	}
+}
+
+// CardTextChanges lists the changed front, back and properties, properties ordered by key
+func CardTextChanges(old, new *ReviewCard) []FieldChange {
+	changes := []FieldChange{}
+	if old.Front != new.Front {
+		changes = append(changes, FieldChange{Field: REVISION_FIELD_FRONT, Old: old.Front, New: new.Front})
+	}
+	if old.Back != new.Back {
+		changes = append(changes, FieldChange{Field: REVISION_FIELD_BACK, Old: old.Back, New: new.Back})
+	}
+
+	keys := []string{}
+	for k := range old.Properties {
+		keys = append(keys, k)
+	}
+	for k := range new.Properties {
+		if _, ok := old.Properties[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		oldValue, newValue := old.Properties[k], new.Properties[k]
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, FieldChange{Field: REVISION_FIELD_PROPERTIES + k, Old: oldValue, New: newValue})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,33 @@
+package langfi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_CardTextChanges(t *testing.T) {
+	old := NewReviewCard("先生", "teacher")
+	old.SetProp(PROP_READING, "せんせい")
+	old.SetProp("level", 5.0)
+	edited := NewReviewCard("先生", "the teacher")
+	edited.SetProp("level", 5.0)
+	edited.SetProp("note", "polite")
+
+	want := []FieldChange{
+		{Field: REVISION_FIELD_BACK, Old: "teacher", New: "the teacher"},
+		{Field: REVISION_FIELD_PROPERTIES + "note", Old: nil, New: "polite"},
+		{Field: REVISION_FIELD_PROPERTIES + PROP_READING, Old: "せんせい", New: nil},
+	}
+	if got := CardTextChanges(&old, &edited); !reflect.DeepEqual(got, want) {
+		t.Errorf("CardTextChanges() = %+v, want %+v", got, want)
+	}
+
+	if revision := NewCardRevision(&old, &old, 1); revision != nil {
+		t.Errorf("NewCardRevision() of an unchanged card = %+v, want nil", revision)
+	}
+	revision := NewCardRevision(&old, &edited, 1)
+	old.SetProp(PROP_READING, "changed later")
+	if revision.Back != "teacher" || revision.Properties[PROP_READING] != "せんせい" || len(revision.Changes) != 3 {
+		t.Errorf("NewCardRevision() = %+v", revision)
+	}
+}
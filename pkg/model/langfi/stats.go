@@ -0,0 +1,74 @@
+package langfi
+
+import (
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+	"github.com/open-spaced-repetition/go-fsrs/v3"
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidStatsQuery = errors.New("invalid stats query")
+
+// card is considered mastered once its stability reaches 3 weeks
+const MASTERY_STABILITY_DAYS = 21.0
+
+// ReviewLog records one rating submitted for a card, together with the
+// memory state produced by the review.
+type ReviewLog struct {
+	model.Base
+	fsrs.ReviewLog
+	CardID     uint64  `json:"card_id"`
+	Group      string  `json:"group"`
+	Stability  float64 `json:"stability"`
+	Difficulty float64 `json:"difficulty"`
+}
+
+type DailyCount struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+type RetentionDto struct {
+	Label     string  `json:"label"`
+	Reviews   int     `json:"reviews"`
+	Passed    int     `json:"passed"`
+	Retention float64 `json:"retention"`
+}
+
+type DistributionBucket struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+type MemoryDistributionDto struct {
+	NumCards      int                  `json:"num_cards"`
+	AvgStability  float64              `json:"avg_stability"`
+	AvgDifficulty float64              `json:"avg_difficulty"`
+	Stability     []DistributionBucket `json:"stability"`
+	Difficulty    []DistributionBucket `json:"difficulty"`
+}
+
+type GroupMasteryDto struct {
+	Group            string  `json:"group"`
+	NumCards         int     `json:"num_cards"`
+	Mastered         int     `json:"mastered"`
+	AvgDaysToMastery float64 `json:"avg_days_to_mastery"`
+}
+
+// ReviewStatsQuery selects the days covered by the review stats
+type ReviewStatsQuery struct {
+	ForecastDays int
+	HeatmapDays  int
+	// retention counts the reviews of the last WindowDays
+	WindowDays int
+	// days start at midnight of this offset from UTC, in minutes
+	TzMinutes int
+}
+
+type ReviewStatsDto struct {
+	Forecast         []DailyCount          `json:"forecast"`
+	Heatmap          []DailyCount          `json:"heatmap"`
+	RetentionByAge   []RetentionDto        `json:"retention_by_age"`
+	RetentionByGroup []RetentionDto        `json:"retention_by_group"`
+	Memory           MemoryDistributionDto `json:"memory"`
+	Mastery          []GroupMasteryDto     `json:"mastery"`
+}
@@ -0,0 +1,54 @@
+package langfi
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidSync = errors.New("invalid sync request")
+
+// CardChange is the latest change of a card after a sync cursor. Changes are numbered in the
+// order they are written, a card content change is seen by every user and a fsrs change only by its user.
+type CardChange struct {
+	Seq     uint64 `json:"seq"`
+	CardID  uint64 `json:"card_id"`
+	Deleted bool   `json:"deleted"`
+}
+
+// SyncPullDto is what changed for the user since the cursor of the client
+type SyncPullDto struct {
+	// cursor to pull the following changes with
+	Cursor uint64 `json:"cursor"`
+	// current state of the changed cards
+	Cards []ReviewCard `json:"cards"`
+	// ids of the cards deleted since the cursor
+	Deleted []uint64 `json:"deleted"`
+	// more changes are left after Cursor
+	HasMore bool `json:"has_more"`
+}
+
+// OfflineReview is a rating given on a device, at the time it was given
+type OfflineReview struct {
+	CardID uint64    `json:"card_id"`
+	Rating uint64    `json:"rating"`
+	Review time.Time `json:"review"`
+}
+
+type SyncPushDto struct {
+	Reviews []OfflineReview `json:"reviews"`
+}
+
+type RejectedReview struct {
+	OfflineReview
+	Reason string `json:"reason"`
+}
+
+type SyncPushResult struct {
+	Accepted int `json:"accepted"`
+	// reviews that were pushed before, e.g. by a retried request
+	Duplicates int              `json:"duplicates"`
+	Rejected   []RejectedReview `json:"rejected"`
+	// state of the reviewed cards after replaying their reviews
+	Cards []ReviewCard `json:"cards"`
+}
@@ -0,0 +1,32 @@
+package langfi
+
+// card properties that hold the expected answer for typed practice: the answer of a card prompting
+// with its front, e.g. the hidden word of a cloze card, and the kana reading of an answer written with kanji
+const (
+	PROP_ANSWER  = "answer"
+	PROP_READING = "reading"
+)
+
+type TypedAnswerDto struct {
+	CardID uint64 `json:"card_id"`
+	Answer string `json:"answer"`
+	// time from showing the card to submitting, 0 when unknown
+	ResponseTimeMs int64 `json:"response_time_ms"`
+}
+
+type AnswerDiffDto struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type TypedAnswerResult struct {
+	CardID             uint64          `json:"card_id"`
+	Answer             string          `json:"answer"`
+	Expected           string          `json:"expected"`
+	NormalizedAnswer   string          `json:"normalized_answer"`
+	NormalizedExpected string          `json:"normalized_expected"`
+	Exact              bool            `json:"exact"`
+	Similarity         float64         `json:"similarity"`
+	SuggestedRating    uint64          `json:"suggested_rating"`
+	Diff               []AnswerDiffDto `json:"diff"`
+}
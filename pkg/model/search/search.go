@@ -0,0 +1,150 @@
+package search
+
+import (
+	"context"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// kinds of searched documents
+const (
+	KIND_CARD    = "card"
+	KIND_ARTICLE = "article"
+	KIND_VOCAB   = "vocab"
+)
+
+// matched terms are wrapped in these in snippets, the rest of a snippet is HTML escaped
+const (
+	HIGHLIGHT_START  = "<mark>"
+	HIGHLIGHT_END    = "</mark>"
+	SNIPPET_ELLIPSIS = "…"
+)
+
+const (
+	// longer queries keep their first terms
+	MAX_QUERY_TERMS = 8
+	// length of a snippet in runes
+	SNIPPET_LENGTH = 160
+)
+
+var ErrInvalidSearch = errors.New("invalid search")
+
+type Hit struct {
+	Kind    string `json:"kind"`
+	ID      uint64 `json:"id"`
+	Title   string `json:"title"`
+	Snippet string `json:"snippet"`
+	// higher is more relevant, scores are only comparable within a source until the service scales them
+	Score float64 `json:"score"`
+}
+
+// Source returns documents of one kind containing every term, a term also matches the words it prefixes.
+// Hits are ordered best first.
+type Source func(ctx context.Context, terms []string, limit int) ([]Hit, error)
+
+type SearchService interface {
+	// Search looks for text in the given kinds, all kinds when empty, and merges the hits by relevance
+	Search(ctx context.Context, text string, kinds []string, limit int) (*[]Hit, error)
+}
+
+// ParseTerms splits the text into distinct lower case words, punctuation is dropped
+func ParseTerms(text string) ([]string, error) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsMark(r)
+	})
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, word := range words {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+		if len(terms) == MAX_QUERY_TERMS {
+			break
+		}
+	}
+	if len(terms) == 0 {
+		return nil, errors.Wrapf(ErrInvalidSearch, "no words to search in %q", text)
+	}
+	return terms, nil
+}
+
+type span struct {
+	start, end int
+}
+
+// Snippet cuts the text around the first matched term and highlights the terms in it
+func Snippet(text string, terms []string) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	termRunes := make([][]rune, len(terms))
+	for i, term := range terms {
+		termRunes[i] = []rune(strings.ToLower(term))
+	}
+
+	matches := []span{}
+	for i := 0; i < len(lower); {
+		end := i
+		for _, term := range termRunes {
+			if len(term) > 0 && hasPrefixAt(lower, i, term) && i+len(term) > end {
+				end = i + len(term)
+			}
+		}
+		if end > i {
+			matches = append(matches, span{i, end})
+			i = end
+		} else {
+			i++
+		}
+	}
+
+	start := 0
+	if len(matches) > 0 {
+		// show some context before the first match
+		start = max(0, min(matches[0].start-SNIPPET_LENGTH/4, len(runes)-SNIPPET_LENGTH))
+	}
+	end := min(len(runes), start+SNIPPET_LENGTH)
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString(SNIPPET_ELLIPSIS)
+	}
+	pos := start
+	for _, m := range matches {
+		if m.start < pos {
+			continue
+		}
+		if m.start >= end {
+			break
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:m.start])))
+		b.WriteString(HIGHLIGHT_START)
+		b.WriteString(html.EscapeString(string(runes[m.start:min(m.end, end)])))
+		b.WriteString(HIGHLIGHT_END)
+		pos = min(m.end, end)
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		b.WriteString(SNIPPET_ELLIPSIS)
+	}
+	return b.String()
+}
+
+func hasPrefixAt(text []rune, i int, prefix []rune) bool {
+	if i+len(prefix) > len(text) {
+		return false
+	}
+	for j, r := range prefix {
+		if text[i+j] != r {
+			return false
+		}
+	}
+	return true
+}
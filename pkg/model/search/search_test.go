@@ -0,0 +1,98 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTerms(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    []string
+		wantErr bool
+	}{
+		{text: "Apple pie", want: []string{"apple", "pie"}},
+		{text: `"apple" OR pie* -crust`, want: []string{"apple", "or", "pie", "crust"}},
+		{text: "apple APPLE", want: []string{"apple"}},
+		{text: "先生 です", want: []string{"先生", "です"}},
+		{text: "a b c d e f g h i j", want: []string{"a", "b", "c", "d", "e", "f", "g", "h"}},
+		{text: " *:() ", wantErr: true},
+		{text: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTerms(tt.text)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidSearch) {
+				t.Errorf("ParseTerms(%q) error = %v, want ErrInvalidSearch", tt.text, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseTerms(%q) = %q, %v, want %q", tt.text, got, err, tt.want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+	}{
+		{
+			name:  "highlights every match",
+			text:  "Apple pie with apples",
+			terms: []string{"apple"},
+			want:  "<mark>Apple</mark> pie with <mark>apple</mark>s",
+		},
+		{
+			name:  "longest term wins",
+			text:  "pineapple",
+			terms: []string{"pine", "pineapple"},
+			want:  "<mark>pineapple</mark>",
+		},
+		{
+			name:  "escapes the text",
+			text:  "<b>tom</b> & jerry",
+			terms: []string{"jerry"},
+			want:  "&lt;b&gt;tom&lt;/b&gt; &amp; <mark>jerry</mark>",
+		},
+		{
+			name:  "japanese",
+			text:  "わたしは先生です",
+			terms: []string{"先生"},
+			want:  "わたしは<mark>先生</mark>です",
+		},
+		{
+			name:  "no match keeps the start",
+			text:  "plain\n\ttext",
+			terms: []string{"zzz"},
+			want:  "plain text",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Snippet(tt.text, tt.terms); got != tt.want {
+				t.Errorf("Snippet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnippetCutsAroundFirstMatch(t *testing.T) {
+	text := strings.Repeat("lorem ", 100) + "needle " + strings.Repeat("ipsum ", 100)
+	got := Snippet(text, []string{"needle"})
+	if !strings.HasPrefix(got, SNIPPET_ELLIPSIS) || !strings.HasSuffix(got, SNIPPET_ELLIPSIS) {
+		t.Errorf("Snippet() = %q, want ellipsis on both sides", got)
+	}
+	if !strings.Contains(got, HIGHLIGHT_START+"needle"+HIGHLIGHT_END) {
+		t.Errorf("Snippet() = %q, want the highlighted needle", got)
+	}
+	plain := strings.NewReplacer(HIGHLIGHT_START, "", HIGHLIGHT_END, "", SNIPPET_ELLIPSIS, "").Replace(got)
+	if n := len([]rune(plain)); n != SNIPPET_LENGTH {
+		t.Errorf("snippet has %v runes, want %v", n, SNIPPET_LENGTH)
+	}
+}
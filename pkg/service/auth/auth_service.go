@@ -0,0 +1,178 @@
+package authservice
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/bootstrap"
+	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	DEFAULT_SESSION_TTL = 30 * 24 * time.Hour
+	MIN_PASSWORD_LEN    = 8
+	tokenBytes          = 32
+)
+
+type authService struct {
+	repo       auth.AuthRepo
+	env        *bootstrap.Env
+	sessionTTL time.Duration
+}
+
+func NewAuthService(repo auth.AuthRepo, env *bootstrap.Env) auth.AuthService {
+	as := &authService{
+		repo:       repo,
+		env:        env,
+		sessionTTL: DEFAULT_SESSION_TTL,
+	}
+	if env.AuthSessionTTLHours > 0 {
+		as.sessionTTL = time.Duration(env.AuthSessionTTLHours) * time.Hour
+	}
+	return as
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, tokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Wrap(err, "failed to generate token")
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Register creates an account. The very first account is always allowed and takes
+// over the data created before accounts existed, later ones need AUTH_ALLOW_REGISTRATION.
+func (as *authService) Register(ctx context.Context, cred *auth.CredentialsDto) (*auth.User, error) {
+	username := strings.TrimSpace(cred.Username)
+	if username == "" || len(cred.Password) < MIN_PASSWORD_LEN {
+		return nil, auth.ErrInvalidRegistration
+	}
+
+	if existed, err := as.repo.GetUserByName(ctx, username); err == nil && existed != nil {
+		return nil, auth.ErrUserExisted
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(cred.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to hash password")
+	}
+	user := &auth.User{Username: username, PasswordHash: string(hash)}
+	first, err := as.repo.AddUser(ctx, user, as.env.AuthAllowRegistration)
+	if errors.Is(err, auth.ErrRegistrationClosed) || errors.Is(err, auth.ErrUserExisted) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to add user")
+	}
+
+	if first {
+		logger.Log.Info().Msgf("first account %v created, existing data is assigned to it", user.Username)
+	}
+	return user, nil
+}
+
+func (as *authService) Login(ctx context.Context, cred *auth.CredentialsDto) (*auth.TokenDto, error) {
+	user, err := as.repo.GetUserByName(ctx, strings.TrimSpace(cred.Username))
+	if err != nil {
+		return nil, auth.ErrInvalidCredentials
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(cred.Password)) != nil {
+		return nil, auth.ErrInvalidCredentials
+	}
+
+	expiresAt := time.Now().Add(as.sessionTTL)
+	tokenDto, err := as.issueToken(ctx, user.ID, auth.TOKEN_SESSION, "login", &expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	tokenDto.User = user
+	return tokenDto, nil
+}
+
+func (as *authService) issueToken(ctx context.Context, userID uint64, tokenType, name string, expiresAt *time.Time) (*auth.TokenDto, error) {
+	plain, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	token := auth.AccessToken{
+		UserID:    userID,
+		Name:      name,
+		Type:      tokenType,
+		TokenHash: hashToken(plain),
+		ExpiresAt: expiresAt,
+	}
+	err = as.repo.AddToken(ctx, &token)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to save token")
+	}
+	return &auth.TokenDto{Token: plain, Info: token, ExpiresAt: expiresAt}, nil
+}
+
+func (as *authService) Logout(ctx context.Context, token string) error {
+	return as.repo.DeleteTokenByHash(ctx, hashToken(token))
+}
+
+func (as *authService) Authenticate(ctx context.Context, token string) (*auth.User, error) {
+	if token == "" {
+		return nil, auth.ErrUnauthorized
+	}
+	accessToken, err := as.repo.GetTokenByHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, auth.ErrUnauthorized
+	}
+	now := time.Now()
+	if accessToken.ExpiresAt != nil && accessToken.ExpiresAt.Before(now) {
+		return nil, auth.ErrUnauthorized
+	}
+
+	user, err := as.repo.GetUser(ctx, accessToken.UserID)
+	if err != nil {
+		return nil, auth.ErrUnauthorized
+	}
+
+	err = as.repo.TouchToken(ctx, accessToken.ID, now)
+	if err != nil {
+		logger.Log.Warn().Err(err).Msgf("failed to update last use of token %v", accessToken.ID)
+	}
+	return user, nil
+}
+
+// CreateApiToken issues a non expiring token for scripts and other devices of the current user
+func (as *authService) CreateApiToken(ctx context.Context, name string) (*auth.TokenDto, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthorized
+	}
+	if name == "" {
+		name = "api"
+	}
+	return as.issueToken(ctx, user.ID, auth.TOKEN_API, name, nil)
+}
+
+func (as *authService) ListTokens(ctx context.Context) (*[]auth.AccessToken, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthorized
+	}
+	return as.repo.ListTokens(ctx, user.ID)
+}
+
+func (as *authService) RevokeToken(ctx context.Context, tokenID uint64) error {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return auth.ErrUnauthorized
+	}
+	return as.repo.DeleteToken(ctx, user.ID, tokenID)
+}
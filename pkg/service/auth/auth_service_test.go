@@ -0,0 +1,134 @@
+package authservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/bootstrap"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+)
+
+type memAuthRepo struct {
+	users   []auth.User
+	tokens  []auth.AccessToken
+	claimed uint64
+}
+
+func (r *memAuthRepo) AddUser(ctx context.Context, user *auth.User, allowMore bool) (bool, error) {
+	if len(r.users) > 0 && !allowMore {
+		return false, auth.ErrRegistrationClosed
+	}
+	user.ID = uint64(len(r.users) + 1)
+	r.users = append(r.users, *user)
+	if user.ID == 1 {
+		r.claimed = user.ID
+		user.IsAdmin = true
+	}
+	return user.ID == 1, nil
+}
+
+func (r *memAuthRepo) GetUserByName(ctx context.Context, username string) (*auth.User, error) {
+	for i := range r.users {
+		if r.users[i].Username == username {
+			return &r.users[i], nil
+		}
+	}
+	return nil, model.ErrNoMoreDataAvailable
+}
+
+func (r *memAuthRepo) GetUser(ctx context.Context, userID uint64) (*auth.User, error) {
+	for i := range r.users {
+		if r.users[i].ID == userID {
+			return &r.users[i], nil
+		}
+	}
+	return nil, model.ErrNoMoreDataAvailable
+}
+
+func (r *memAuthRepo) AddToken(ctx context.Context, token *auth.AccessToken) error {
+	token.ID = uint64(len(r.tokens) + 1)
+	r.tokens = append(r.tokens, *token)
+	return nil
+}
+
+func (r *memAuthRepo) GetTokenByHash(ctx context.Context, tokenHash string) (*auth.AccessToken, error) {
+	for i := range r.tokens {
+		if r.tokens[i].TokenHash == tokenHash {
+			return &r.tokens[i], nil
+		}
+	}
+	return nil, model.ErrNoMoreDataAvailable
+}
+
+func (r *memAuthRepo) TouchToken(ctx context.Context, tokenID uint64, usedAt time.Time) error {
+	return nil
+}
+
+func (r *memAuthRepo) ListTokens(ctx context.Context, userID uint64) (*[]auth.AccessToken, error) {
+	return &r.tokens, nil
+}
+
+func (r *memAuthRepo) DeleteToken(ctx context.Context, userID, tokenID uint64) error { return nil }
+
+func (r *memAuthRepo) DeleteTokenByHash(ctx context.Context, tokenHash string) error {
+	for i := range r.tokens {
+		if r.tokens[i].TokenHash == tokenHash {
+			r.tokens = append(r.tokens[:i], r.tokens[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func Test_authService_Register(t *testing.T) {
+	ctx := context.Background()
+	repo := &memAuthRepo{}
+	as := NewAuthService(repo, &bootstrap.Env{})
+
+	if _, err := as.Register(ctx, &auth.CredentialsDto{Username: "a", Password: "short"}); err != auth.ErrInvalidRegistration {
+		t.Errorf("Register() with short password error = %v, want %v", err, auth.ErrInvalidRegistration)
+	}
+
+	first, err := as.Register(ctx, &auth.CredentialsDto{Username: " alice ", Password: "password1"})
+	if err != nil {
+		t.Fatalf("Register() first user error = %v", err)
+	}
+	if first.Username != "alice" || repo.claimed != first.ID || !first.IsAdmin {
+		t.Errorf("Register() first user = %+v, claimed by %v", first, repo.claimed)
+	}
+
+	if _, err := as.Register(ctx, &auth.CredentialsDto{Username: "bob", Password: "password2"}); err != auth.ErrRegistrationClosed {
+		t.Errorf("Register() second user error = %v, want %v", err, auth.ErrRegistrationClosed)
+	}
+}
+
+func Test_authService_LoginAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	as := NewAuthService(&memAuthRepo{}, &bootstrap.Env{})
+	cred := &auth.CredentialsDto{Username: "alice", Password: "password1"}
+	if _, err := as.Register(ctx, cred); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	if _, err := as.Login(ctx, &auth.CredentialsDto{Username: "alice", Password: "wrong-password"}); err != auth.ErrInvalidCredentials {
+		t.Errorf("Login() with wrong password error = %v, want %v", err, auth.ErrInvalidCredentials)
+	}
+
+	token, err := as.Login(ctx, cred)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	user, err := as.Authenticate(ctx, token.Token)
+	if err != nil || user.Username != "alice" {
+		t.Errorf("Authenticate() = %+v, %v", user, err)
+	}
+
+	if err := as.Logout(ctx, token.Token); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	if _, err := as.Authenticate(ctx, token.Token); err != auth.ErrUnauthorized {
+		t.Errorf("Authenticate() after logout error = %v, want %v", err, auth.ErrUnauthorized)
+	}
+}
@@ -0,0 +1,269 @@
+package authrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/nhuongmh/cfvs.jpx/pkg/database/postgresdb"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/pkg/errors"
+)
+
+// postgres error code of a duplicate key, usernames are unique ignoring case
+const uniqueViolation = "23505"
+
+// tables holding per-user data, rows created before accounts existed have user_id 0
+var userScopedTables = []string{"ie_articles", "article_test_result", "ie_vocab_list", "fsrs", "review_logs", "card_revisions",
+	"sync_changes", "ie_feeds", "card_tags", "deck_settings", "ie_test_sessions"}
+
+type authRepo struct {
+	db *postgresdb.DB
+}
+
+func NewAuthRepo(db *postgresdb.DB) auth.AuthRepo {
+	return &authRepo{
+		db: db,
+	}
+}
+
+// AddUser adds the user while holding the lock of the users table, so two registrations cannot both be
+// the first. The first user takes over the anonymous data in the same transaction.
+func (ar *authRepo) AddUser(ctx context.Context, user *auth.User, allowMore bool) (bool, error) {
+	first := false
+	err := pgx.BeginFunc(ctx, ar.db, func(tx pgx.Tx) error {
+		// conflicts with itself, not with reads
+		_, err := tx.Exec(ctx, "LOCK TABLE users IN SHARE ROW EXCLUSIVE MODE")
+		if err != nil {
+			return errors.Wrap(err, "lock users")
+		}
+		var count int
+		err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&count)
+		if err != nil {
+			return errors.Wrap(err, "query row")
+		}
+		if count > 0 && !allowMore {
+			return auth.ErrRegistrationClosed
+		}
+		first = count == 0
+		user.IsAdmin = first
+
+		query := ar.db.QueryBuilder.Insert("users").
+			Columns("username", "password_hash", "is_admin").
+			Values(user.Username, user.PasswordHash, user.IsAdmin).
+			Suffix("RETURNING id, created_at, updated_at")
+
+		sql, args, err := query.ToSql()
+		if err != nil {
+			return errors.Wrap(err, "build query")
+		}
+
+		err = tx.QueryRow(ctx, sql, args...).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return auth.ErrUserExisted
+		}
+		if err != nil {
+			return errors.Wrap(err, "query row")
+		}
+
+		if first {
+			return ar.claimAnonymousData(ctx, tx, user.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return first, nil
+}
+
+func (ar *authRepo) GetUserByName(ctx context.Context, username string) (*auth.User, error) {
+	query := ar.db.QueryBuilder.Select("id", "username", "password_hash", "is_admin", "created_at", "updated_at").
+		From("users").
+		Where("LOWER(username) = LOWER(?)", username)
+
+	return ar.getUser(ctx, query.ToSql)
+}
+
+func (ar *authRepo) GetUser(ctx context.Context, userID uint64) (*auth.User, error) {
+	query := ar.db.QueryBuilder.Select("id", "username", "password_hash", "is_admin", "created_at", "updated_at").
+		From("users").
+		Where("id = ?", userID)
+
+	return ar.getUser(ctx, query.ToSql)
+}
+
+func (ar *authRepo) getUser(ctx context.Context, toSql func() (string, []interface{}, error)) (*auth.User, error) {
+	sql, args, err := toSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "build query")
+	}
+
+	var user auth.User
+	err = ar.db.QueryRow(ctx, sql, args...).Scan(
+		&user.ID,
+		&user.Username,
+		&user.PasswordHash,
+		&user.IsAdmin,
+		&user.CreatedAt,
+		&user.UpdatedAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "query row")
+	}
+	return &user, nil
+}
+
+func (ar *authRepo) AddToken(ctx context.Context, token *auth.AccessToken) error {
+	query := ar.db.QueryBuilder.Insert("user_tokens").
+		Columns("user_id", "name", "token_type", "token_hash", "expires_at").
+		Values(token.UserID, token.Name, token.Type, token.TokenHash, token.ExpiresAt).
+		Suffix("RETURNING id, created_at, updated_at")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "build query")
+	}
+
+	err = ar.db.QueryRow(ctx, sql, args...).Scan(&token.ID, &token.CreatedAt, &token.UpdatedAt)
+	if err != nil {
+		return errors.Wrap(err, "query row")
+	}
+	return nil
+}
+
+func (ar *authRepo) GetTokenByHash(ctx context.Context, tokenHash string) (*auth.AccessToken, error) {
+	query := ar.db.QueryBuilder.Select("id", "user_id", "name", "token_type", "token_hash", "expires_at", "last_used_at", "created_at", "updated_at").
+		From("user_tokens").
+		Where("token_hash = ?", tokenHash)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "build query")
+	}
+
+	var token auth.AccessToken
+	err = ar.db.QueryRow(ctx, sql, args...).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.Type,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.CreatedAt,
+		&token.UpdatedAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "query row")
+	}
+	return &token, nil
+}
+
+func (ar *authRepo) TouchToken(ctx context.Context, tokenID uint64, usedAt time.Time) error {
+	query := ar.db.QueryBuilder.Update("user_tokens").
+		Set("last_used_at", usedAt).
+		Where("id = ?", tokenID)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "build query")
+	}
+
+	_, err = ar.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return errors.Wrap(err, "exec")
+	}
+	return nil
+}
+
+func (ar *authRepo) ListTokens(ctx context.Context, userID uint64) (*[]auth.AccessToken, error) {
+	query := ar.db.QueryBuilder.Select("id", "user_id", "name", "token_type", "expires_at", "last_used_at", "created_at", "updated_at").
+		From("user_tokens").
+		Where("user_id = ?", userID).
+		OrderBy("id")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "build query")
+	}
+
+	rows, err := ar.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query")
+	}
+	defer rows.Close()
+
+	tokens := []auth.AccessToken{}
+	for rows.Next() {
+		var token auth.AccessToken
+		err = rows.Scan(
+			&token.ID,
+			&token.UserID,
+			&token.Name,
+			&token.Type,
+			&token.ExpiresAt,
+			&token.LastUsedAt,
+			&token.CreatedAt,
+			&token.UpdatedAt)
+		if err != nil {
+			return nil, errors.Wrap(err, "scan row")
+		}
+		tokens = append(tokens, token)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "rows iteration")
+	}
+	return &tokens, nil
+}
+
+func (ar *authRepo) DeleteToken(ctx context.Context, userID, tokenID uint64) error {
+	query := ar.db.QueryBuilder.Delete("user_tokens").
+		Where("id = ? AND user_id = ?", tokenID, userID)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "build query")
+	}
+
+	_, err = ar.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return errors.Wrap(err, "exec")
+	}
+	return nil
+}
+
+func (ar *authRepo) DeleteTokenByHash(ctx context.Context, tokenHash string) error {
+	query := ar.db.QueryBuilder.Delete("user_tokens").Where("token_hash = ?", tokenHash)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "build query")
+	}
+
+	_, err = ar.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return errors.Wrap(err, "exec")
+	}
+	return nil
+}
+
+// claimAnonymousData assigns the data created before accounts existed to the user
+func (ar *authRepo) claimAnonymousData(ctx context.Context, tx pgx.Tx, userID uint64) error {
+	for _, table := range userScopedTables {
+		query := ar.db.QueryBuilder.Update(table).
+			Set("user_id", userID).
+			Where("user_id = ?", auth.ANONYMOUS_USER_ID)
+
+		sql, args, err := query.ToSql()
+		if err != nil {
+			return errors.Wrap(err, "build query")
+		}
+
+		_, err = tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return errors.Wrapf(err, "claim %v", table)
+		}
+	}
+	return nil
+}
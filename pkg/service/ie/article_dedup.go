@@ -0,0 +1,103 @@
+package ieservice
+
+import (
+	"context"
+	"math/bits"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
+	"github.com/nhuongmh/cfvs.jpx/pkg/supporter/readlevel"
+	"github.com/pkg/errors"
+)
+
+// articles analyzed per query of the backfill
+const ANALYSIS_BACKFILL_BATCH = 100
+
+// how a duplicate article matched the existing one
+const (
+	DUPLICATE_MATCH_URL     = "url"
+	DUPLICATE_MATCH_CONTENT = "content"
+)
+
+// analyzeArticle sets the canonical url, from the one of the page or the origin, the content fingerprint
+// and the difficulty of the content
+func analyzeArticle(article *ie.Article) {
+	if article.CanonicalUrl == "" {
+		article.CanonicalUrl = article.Origin
+	}
+	article.CanonicalUrl = ie.CanonicalUrl(article.CanonicalUrl)
+	article.Fingerprint = ie.Fingerprint(article.Content)
+	article.Difficulty = readlevel.Analyze(article.Content)
+}
+
+// findDuplicate returns the article with the canonical url of the given one, or else the one with the closest
+// similar fingerprint, and how it matched. The existing article is nil when there is no duplicate.
+func (ies *IEservice) findDuplicate(ctx context.Context, article *ie.Article) (*ie.Article, string, error) {
+	if article.CanonicalUrl != "" {
+		existing, err := ies.repo.FindByCanonicalUrl(ctx, article.CanonicalUrl)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "failed to find article by url")
+		}
+		if existing != nil && existing.ID != article.ID {
+			existing, err = ies.repo.FindByID(ctx, existing.ID)
+			if err != nil {
+				return nil, "", errors.Wrap(err, "failed to get article")
+			}
+			return existing, DUPLICATE_MATCH_URL, nil
+		}
+	}
+
+	if article.Fingerprint == 0 {
+		return nil, "", nil
+	}
+	fingerprints, err := ies.repo.FindFingerprints(ctx)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to get fingerprints")
+	}
+	var closestID uint64
+	closest := 0
+	for id, fingerprint := range fingerprints {
+		if id == article.ID || !ie.SimilarFingerprints(fingerprint, article.Fingerprint) {
+			continue
+		}
+		// the map is not ordered, ties go to the oldest article
+		distance := bits.OnesCount64(fingerprint ^ article.Fingerprint)
+		if closestID == 0 || distance < closest || (distance == closest && id < closestID) {
+			closestID, closest = id, distance
+		}
+	}
+	if closestID == 0 {
+		return nil, "", nil
+	}
+	existing, err := ies.repo.FindByID(ctx, closestID)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to get article")
+	}
+	return existing, DUPLICATE_MATCH_CONTENT, nil
+}
+
+// BackfillAnalysis fingerprints and analyzes the difficulty of the articles saved before either existed
+func (ies *IEservice) BackfillAnalysis(ctx context.Context) {
+	total := 0
+	for ctx.Err() == nil {
+		articles, err := ies.repo.FindUnanalyzed(ctx, ANALYSIS_BACKFILL_BATCH)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("failed to get articles without analysis")
+			return
+		}
+		if len(articles) == 0 {
+			break
+		}
+		for _, article := range articles {
+			analyzeArticle(article)
+			if err := ies.repo.UpdateAnalysis(ctx, article); err != nil {
+				logger.Log.Error().Err(err).Msgf("failed to analyze article %d", article.ID)
+				return
+			}
+		}
+		total += len(articles)
+	}
+	if total > 0 {
+		logger.Log.Info().Msgf("analyzed %d articles", total)
+	}
+}
@@ -6,18 +6,126 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
+	"strings"
+	"time"
 
 	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
+	"github.com/nhuongmh/cfvs.jpx/pkg/supporter/publicnet"
+	"github.com/nhuongmh/cfvs.jpx/pkg/supporter/readability"
 	"github.com/nhuongmh/cfvs.jpx/pkg/utils"
 	"github.com/pkg/errors"
 )
 
+// article fetchers selected with ARTICLE_FETCHER, the python ones need cvfspy or scripts/article_fetcher.py
+const (
+	ARTICLE_FETCHER_NATIVE = "native"
+	ARTICLE_FETCHER_CVFSPY = "cvfspy"
+	ARTICLE_FETCHER_SCRIPT = "script"
+)
+
+const (
+	ARTICLE_FETCH_TIMEOUT = 30 * time.Second
+	// pages are cut at this size before extraction
+	MAX_ARTICLE_PAGE_BYTES = 5 << 20
+	ARTICLE_USER_AGENT     = "Mozilla/5.0 (compatible; cfvs-article-fetcher/1.0)"
+)
+
+var ErrInvalidArticleUrl = errors.New("invalid article url")
+
+// pageClient fetches the pages and feeds of urls given by users, it does not connect to the private
+// network of the server
+var pageClient = publicnet.NewClient(ARTICLE_FETCH_TIMEOUT)
+
+// FetchArticleUrl downloads the page and extracts its article with the fetcher of the env,
+// the Go extractor by default
 func (ies *IEservice) FetchArticleUrl(ctx context.Context, link string) (*ie.Article, error) {
-	cvfspyUrl := fmt.Sprintf("http://localhost:5000/api/article?url=%s", link)
-	resp, err := http.Get(cvfspyUrl)
+	if !isHttpUrl(link) {
+		return nil, errors.Wrapf(ErrInvalidArticleUrl, "`%v` is not an http url", link)
+	}
+
+	if ies.env.ArticleFetcher == ARTICLE_FETCHER_CVFSPY || ies.env.ArticleFetcher == ARTICLE_FETCHER_SCRIPT {
+		u, _ := url.Parse(link)
+		if err := publicnet.CheckHost(ctx, u.Hostname()); err != nil {
+			return nil, errors.Wrapf(ErrInvalidArticleUrl, "`%v` is not a public address: %v", link, err)
+		}
+	}
+
+	switch ies.env.ArticleFetcher {
+	case ARTICLE_FETCHER_CVFSPY:
+		return ies.FetchArticleUrlWithCvfspy(ctx, link)
+	case ARTICLE_FETCHER_SCRIPT:
+		return ies.FetchArticleUrlWithLocalScript(link)
+	case "", ARTICLE_FETCHER_NATIVE:
+		return ies.fetchArticle(ctx, link)
+	default:
+		return nil, errors.Errorf("unknown article fetcher %v", ies.env.ArticleFetcher)
+	}
+}
+
+func isHttpUrl(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func (ies *IEservice) fetchArticle(ctx context.Context, link string) (*ie.Article, error) {
+	ctx, cancel := context.WithTimeout(ctx, ARTICLE_FETCH_TIMEOUT)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("User-Agent", ARTICLE_USER_AGENT)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := pageClient.Do(req)
+	if errors.Is(err, publicnet.ErrNonPublicAddress) {
+		return nil, errors.Wrapf(ErrInvalidArticleUrl, "`%v` is not a public address: %v", link, err)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch article from link")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to fetch article from link, status code: %d", resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType != "" && !strings.Contains(contentType, "html") {
+		return nil, errors.Errorf("link is not a web page but %v", contentType)
+	}
+
+	// relative links of redirected pages resolve against the final url
+	extracted, err := readability.Extract(io.LimitReader(resp.Body, MAX_ARTICLE_PAGE_BYTES), contentType, resp.Request.URL.String())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to extract article of %v", link)
+	}
+	article := &ie.Article{
+		Title:       extracted.Title,
+		Content:     extracted.Content,
+		Origin:      link,
+		Author:      extracted.Author,
+		Image:       extracted.Image,
+		PublishDate: extracted.PublishDate,
+	}
+	// some sites declare their home page as canonical url of every page, those are ignored
+	if canonical, err := url.Parse(extracted.Canonical); err == nil && strings.Trim(canonical.Path, "/") != "" {
+		article.CanonicalUrl = extracted.Canonical
+	}
+	return article, nil
+}
+
+// FetchArticleUrlWithCvfspy asks the cvfspy server to extract the article
+func (ies *IEservice) FetchArticleUrlWithCvfspy(ctx context.Context, link string) (*ie.Article, error) {
+	cvfspyUrl := fmt.Sprintf("http://localhost:5000/api/article?url=%s", url.QueryEscape(link))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cvfspyUrl, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to fetch article from link")
 	}
@@ -43,7 +151,8 @@ func (ies *IEservice) FetchArticleUrl(ctx context.Context, link string) (*ie.Art
 
 // parse and propose article for user to approve
 func (ies *IEservice) FetchArticleUrlWithLocalScript(link string) (*ie.Article, error) {
-	output, err := utils.ExecuteHostCmd(fmt.Sprintf("python3 scripts/article_fetcher.py %v", link))
+	// the link is passed as its own argument, never through a shell
+	output, err := utils.ExecuteHostProgram("python3", "scripts/article_fetcher.py", "-u", link)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to fetch article from link")
 		// Find the file path in the output with the pattern "ARTICLE_JSON: <file_path>" using regex
@@ -0,0 +1,97 @@
+package ieservice
+
+import (
+	"context"
+	"slices"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
+	"github.com/pkg/errors"
+)
+
+// ApplyArticleEvent moves the article along its lifecycle and returns the recorded transition,
+// nil when the event leaves the status unchanged. Illegal events are ErrIllegalTransition.
+func (ies *IEservice) ApplyArticleEvent(ctx context.Context, articleID uint64, event string) (*ie.ArticleTransition, error) {
+	article, err := ies.repo.FindByID(ctx, articleID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get article")
+	}
+	to, err := ie.NextArticleStatus(article.Status, event)
+	if err != nil {
+		return nil, errors.Wrapf(err, "article %d", articleID)
+	}
+	if to == article.Status {
+		return nil, nil
+	}
+	if event == ie.EVENT_RESTORED {
+		to, err = ies.statusBeforeDiscard(ctx, articleID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	transition := &ie.ArticleTransition{ArticleID: articleID, Event: event, FromStatus: article.Status, ToStatus: to}
+	moved, err := ies.repo.TransitionArticle(ctx, transition)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to transition article")
+	}
+	if !moved {
+		return nil, errors.Wrapf(ie.ErrIllegalTransition, "article %d changed status concurrently", articleID)
+	}
+	logger.Log.Info().Msgf("article %d %v: %v -> %v", articleID, event, transition.FromStatus, transition.ToStatus)
+	return transition, nil
+}
+
+// ApplyUserArticleEvent applies an event the user triggers directly, like discarding the article
+func (ies *IEservice) ApplyUserArticleEvent(ctx context.Context, articleID uint64, event string) (*ie.ArticleTransition, error) {
+	if !slices.Contains(ie.USER_ARTICLE_EVENTS, event) {
+		return nil, errors.Wrapf(ie.ErrIllegalTransition, "event must be one of %v", ie.USER_ARTICLE_EVENTS)
+	}
+	return ies.ApplyArticleEvent(ctx, articleID, event)
+}
+
+func (ies *IEservice) GetArticleTransitions(ctx context.Context, articleID uint64) ([]*ie.ArticleTransition, error) {
+	if _, err := ies.repo.FindByID(ctx, articleID); err != nil {
+		return nil, errors.Wrap(err, "failed to get article")
+	}
+	transitions, err := ies.repo.FindArticleTransitions(ctx, articleID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get article transitions")
+	}
+	return transitions, nil
+}
+
+// checkArticleEvent rejects an action on the article whose event would be an illegal transition,
+// before the action is done
+func checkArticleEvent(article *ie.Article, event string) error {
+	_, err := ie.NextArticleStatus(article.Status, event)
+	return errors.Wrapf(err, "article %d", article.ID)
+}
+
+// recordArticleEvent applies the event of an action that is already done. The status only moves when it is
+// still the one the action was checked against, otherwise the conflict is ErrIllegalTransition.
+func (ies *IEservice) recordArticleEvent(ctx context.Context, articleID uint64, event string) error {
+	_, err := ies.ApplyArticleEvent(ctx, articleID, event)
+	return err
+}
+
+// logArticleEvent records the event of an action whose result is already saved, a failure is only logged
+func (ies *IEservice) logArticleEvent(ctx context.Context, articleID uint64, event string) {
+	if err := ies.recordArticleEvent(ctx, articleID, event); err != nil {
+		logger.Log.Error().Err(err).Msgf("failed to record %v of article %d", event, articleID)
+	}
+}
+
+// statusBeforeDiscard returns the status the article had when it was last discarded, new when unknown
+func (ies *IEservice) statusBeforeDiscard(ctx context.Context, articleID uint64) (string, error) {
+	transitions, err := ies.repo.FindArticleTransitions(ctx, articleID)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get article transitions")
+	}
+	for i := len(transitions) - 1; i >= 0; i-- {
+		if transitions[i].Event == ie.EVENT_DISCARDED {
+			return transitions[i].FromStatus, nil
+		}
+	}
+	return ie.ARTICLE_NEW, nil
+}
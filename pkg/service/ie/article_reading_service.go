@@ -13,6 +13,9 @@ import (
 )
 
 func (ies *IEservice) GenArticleReading(ctx context.Context, article *ie.Article, force bool) (*ie.ArticleReading, error) {
+	if err := checkArticleEvent(article, ie.EVENT_READING_GENERATED); err != nil {
+		return nil, err
+	}
 	articleRds, err := ies.repo.FindReadingByArticleId(ctx, article.ID)
 	if err == nil && articleRds != nil && !force {
 		logger.Log.Warn().Msg("article reading already exists")
@@ -26,19 +29,30 @@ func (ies *IEservice) GenArticleReading(ctx context.Context, article *ie.Article
 		}
 	}
 
-	questions, err := ies.GenerateQuestion(ctx, article.ID)
+	questions, paragraphs, err := ies.GenerateQuestion(ctx, article.ID)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to generate questions")
 	}
 	articleReading := &ie.ArticleReading{
-		ArticleID: article.ID,
-		Status:    ie.ARTICLE_NEW,
-		Questions: *questions,
+		ArticleID:  article.ID,
+		Status:     ie.ARTICLE_NEW,
+		Questions:  *questions,
+		Paragraphs: paragraphs,
+	}
+	articleReading, err = ies.repo.SaveArticleReading(ctx, articleReading)
+	if err != nil {
+		return nil, err
 	}
-	return ies.repo.SaveArticleReading(ctx, articleReading)
+	ies.logArticleEvent(ctx, article.ID, ie.EVENT_READING_GENERATED)
+	return articleReading, nil
 }
 
 func (ies *IEservice) GetArticleReading(ctx context.Context, articleId uint64) (*ie.ArticleReading, error) {
+	// readings are shared by the article, access goes through the owner of the article
+	_, err := ies.repo.FindByID(ctx, articleId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get article")
+	}
 	articleReading, err := ies.repo.FindReadingByArticleId(ctx, articleId)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get article reading")
@@ -46,64 +60,136 @@ func (ies *IEservice) GetArticleReading(ctx context.Context, articleId uint64) (
 	return articleReading, nil
 }
 
-func (ies *IEservice) GradeQuestionSubmit(ctx context.Context, articleReadingId uint64, answers map[int]string) (*ie.TestResult, error) {
+// GradeQuestionSubmit grades the answers keyed by question id, or by ie.AnswerKey for the items of a question.
+// Each item is a mark, the score is the percentage of the marks. With LLM grading enabled,
+// the LLM gives the verdict on the short answers the rules cannot decide. The band of an untimed submission is academic.
+func (ies *IEservice) GradeQuestionSubmit(ctx context.Context, articleReadingId uint64, answers map[string]string) (*ie.TestResult, error) {
 	articleReading, err := ies.repo.FindArticleReadingByID(ctx, articleReadingId)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get article reading")
 	}
-	if articleReading == nil {
-		return nil, errors.New("article reading not found")
+	article, err := ies.repo.FindByID(ctx, articleReading.ArticleID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get article of reading")
+	}
+	if err = checkArticleEvent(article, ie.EVENT_TEST_SUBMITTED); err != nil {
+		return nil, err
 	}
-	if len(articleReading.Questions) != len(answers) {
-		return nil, errors.New("number of answers does not match number of questions")
+	if err = ie.ValidateAnswers(articleReading.Questions, answers); err != nil {
+		return nil, err
 	}
 
-	numberCorrect := 0
-	testResult := ie.TestResult{
+	testResult := ies.gradeReading(ctx, articleReading, answers, ie.IELTS_ACADEMIC)
+	savedTestResult, err := ies.repo.SaveTestSubmission(ctx, testResult)
+	if err != nil {
+		return nil, err
+	}
+	ies.logArticleEvent(ctx, article.ID, ie.EVENT_TEST_SUBMITTED)
+	return savedTestResult, nil
+}
+
+// gradeReading grades the answers to the questions of the reading, with the band of the module
+func (ies *IEservice) gradeReading(ctx context.Context, articleReading *ie.ArticleReading, answers map[string]string, module string) *ie.TestResult {
+	testResult := &ie.TestResult{
 		ArticleReadingId: articleReading.ID,
 		Score:            0.0,
 		QuestionResults:  []ie.QuestionResult{},
 	}
 	for i := range articleReading.Questions {
-		q := articleReading.Questions[i]
-		userAnswer, ok := answers[int(q.ID)]
-		if !ok {
-			logger.Log.Warn().Msgf("answer for question %d not found", i)
-			userAnswer = ""
+		testResult.QuestionResults = append(testResult.QuestionResults, ie.GradeQuestion(&articleReading.Questions[i], answers))
+	}
+	if ies.env.LLMGrading && ies.gemi != nil {
+		ies.judgeAmbiguousAnswers(ctx, articleReading.Questions, testResult.QuestionResults)
+	}
+
+	marks, maxMarks := 0, 0
+	for _, questionResult := range testResult.QuestionResults {
+		marks += questionResult.Marks
+		maxMarks += questionResult.MaxMarks
+	}
+	if maxMarks > 0 {
+		testResult.Score = float32(marks) / float32(maxMarks) * 100.0
+	}
+	testResult.SetBand(module, ies.bandTables[module])
+	return testResult
+}
+
+// verdictSchema is the array of verdicts the model answers with
+var verdictSchema = &genai.Schema{
+	Type: genai.TypeArray,
+	Items: &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"question_id": {Type: genai.TypeInteger},
+			"correct":     {Type: genai.TypeBoolean},
+			"explanation": {Type: genai.TypeString},
+		},
+		Required: []string{"question_id", "correct", "explanation"},
+	},
+}
+
+type answerVerdict struct {
+	QuestionID  uint64 `json:"question_id"`
+	Correct     bool   `json:"correct"`
+	Explanation string `json:"explanation"`
+}
+
+// judgeAmbiguousAnswers asks the LLM for the verdicts on the ambiguous short answers in one request.
+// When it fails the answers keep the verdict of the rules.
+func (ies *IEservice) judgeAmbiguousAnswers(ctx context.Context, questions []ie.Question, results []ie.QuestionResult) {
+	ambiguous := map[uint64]*ie.QuestionResult{}
+	answers := strings.Builder{}
+	for i := range results {
+		if !results[i].Ambiguous {
+			continue
 		}
-		questionResult := ie.QuestionResult{
-			QuestionID: q.ID,
-			Answer:     q.Answer,
-			UserAnswer: userAnswer,
-			Correct:    false,
+		q := questions[i]
+		ambiguous[q.ID] = &results[i]
+		fmt.Fprintf(&answers, "- question_id: %d\n  question: %v\n  accepted answers: %v\n  word limit: %d\n  student answer: %v\n",
+			q.ID, q.QuestionText, strings.Join(strings.Split(q.Answer, "/"), " | "), q.WordLimit, results[i].UserAnswer)
+		if q.Evidence != nil {
+			fmt.Fprintf(&answers, "  passage: %v\n", q.Evidence.Text)
 		}
-		if q.Type == ie.QUESTION_TYPE_MULTIPLE_CHOICE {
-			if strings.EqualFold(q.Answer, userAnswer) {
-				questionResult.Correct = true
-			}
-		} else if q.Type == ie.QUESTION_TYPE_SHORT_ANSWER {
-			if strings.Contains(strings.ToLower(q.Answer), strings.ToLower(userAnswer)) {
-				questionResult.Correct = true
-			}
-		} else if q.Type == ie.QUESTION_TYPE_TRUE_FALSE {
-			if strings.EqualFold(q.Answer, userAnswer) {
-				questionResult.Correct = true
-			}
-		} else {
-			logger.Log.Warn().Msgf("unsupport question type %s", q.Type)
-			if strings.EqualFold(q.Answer, userAnswer) {
-				questionResult.Correct = true
+	}
+	if len(ambiguous) == 0 {
+		return
+	}
+
+	prompt := fmt.Sprintf(`
+	You are grading the short answers of an IELTS reading test. Each student answer partly matches the accepted answers.
+	Decide if it has the same meaning as an accepted answer, as an IELTS examiner would: synonyms or a different
+	word form of the answer are correct, an answer missing key information or adding wrong information is not.
+	Explain the verdict to the student in one sentence.
+	-----
+	%v
+	`, answers.String())
+
+	resp, err := ies.gemi.GenerateContent(ctx, verdictSchema, prompt)
+	if err != nil {
+		logger.Log.Warn().Err(err).Msg("failed to grade ambiguous answers with the LLM")
+		return
+	}
+	var verdicts []answerVerdict
+	for _, cand := range resp.Candidates {
+		if cand.Content == nil {
+			continue
+		}
+		for _, part := range cand.Content.Parts {
+			if txt, ok := part.(genai.Text); ok {
+				if err := json.Unmarshal([]byte(txt), &verdicts); err != nil {
+					logger.Log.Error().Err(err).Msg("Failed to unmarshal AI verdicts")
+				}
 			}
 		}
-
-		if questionResult.Correct {
-			numberCorrect++
+		if len(verdicts) > 0 {
+			break
+		}
+	}
+	for _, verdict := range verdicts {
+		if result, ok := ambiguous[verdict.QuestionID]; ok {
+			result.SetVerdict(verdict.Correct, verdict.Explanation)
 		}
-		testResult.QuestionResults = append(testResult.QuestionResults, questionResult)
 	}
-	testResult.Score = float32(numberCorrect) / float32(len(articleReading.Questions)) * 100.0
-	savedTestResult, err := ies.repo.SaveTestSubmission(ctx, &testResult)
-	return savedTestResult, err
 }
 
 func (ies *IEservice) GetTestSubmission(ctx context.Context, id uint64) (*ie.TestResult, error) {
@@ -114,6 +200,25 @@ func (ies *IEservice) GetTestSubmission(ctx context.Context, id uint64) (*ie.Tes
 	return testResult, nil
 }
 
+// GetTestReview returns the graded test with the passages of the article supporting the answers
+func (ies *IEservice) GetTestReview(ctx context.Context, id uint64) (*ie.TestReview, error) {
+	testResult, err := ies.repo.GetTestSubmissionById(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get test submission")
+	}
+	articleReading, err := ies.repo.FindArticleReadingByID(ctx, testResult.ArticleReadingId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get article reading")
+	}
+	article, err := ies.repo.FindByID(ctx, articleReading.ArticleID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get article of reading")
+	}
+	review := ie.NewTestReview(testResult, articleReading.Questions, article.Content)
+	review.ArticleID = article.ID
+	return review, nil
+}
+
 func (ies *IEservice) GetTestSubmissionByReadingId(ctx context.Context, readingId uint64) (*[]ie.TestResult, error) {
 	testResults, err := ies.repo.FindSubmissionByReadingId(ctx, readingId)
 	if err != nil {
@@ -130,41 +235,94 @@ func (ies *IEservice) DeleteTestSubmission(ctx context.Context, id uint64) error
 	return nil
 }
 
-func (ies *IEservice) GenerateQuestion(ctx context.Context, id uint64) (*[]ie.Question, error) {
+// evidenceSchema is the passage quoted from the article, the offsets are located by the service
+var evidenceSchema = &genai.Schema{
+	Type:       genai.TypeObject,
+	Properties: map[string]*genai.Schema{"text": {Type: genai.TypeString}},
+	Required:   []string{"text"},
+}
+
+// questionSchema is the array of questions the model answers with
+var questionSchema = &genai.Schema{
+	Type: genai.TypeArray,
+	Items: &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"type": {Type: genai.TypeString, Enum: []string{ie.QUESTION_TYPE_MULTIPLE_CHOICE, ie.QUESTION_TYPE_SHORT_ANSWER,
+				ie.QUESTION_TYPE_TRUE_FALSE, ie.QUESTION_TYPE_MATCHING, ie.QUESTION_TYPE_MATCHING_INFORMATION,
+				ie.QUESTION_TYPE_SENTENCE_COMPLETION}},
+			"question":   {Type: genai.TypeString},
+			"options":    {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}},
+			"answer":     {Type: genai.TypeString},
+			"headings":   {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}},
+			"word_limit": {Type: genai.TypeInteger},
+			"evidence":   evidenceSchema,
+			"items": {
+				Type: genai.TypeArray,
+				Items: &genai.Schema{
+					Type: genai.TypeObject,
+					Properties: map[string]*genai.Schema{
+						"text":      {Type: genai.TypeString},
+						"paragraph": {Type: genai.TypeString},
+						"answer":    {Type: genai.TypeString},
+						"evidence":  evidenceSchema,
+					},
+					Required: []string{"answer"},
+				},
+			},
+		},
+		Required: []string{"type", "question"},
+	},
+}
+
+// GenerateQuestion generates the questions of the article, and returns them with the labelled paragraphs they refer to.
+// Generated questions that cannot be graded, or whose evidence is not in the article content, are dropped.
+func (ies *IEservice) GenerateQuestion(ctx context.Context, id uint64) (*[]ie.Question, []ie.Paragraph, error) {
 	if ies.gemi == nil {
-		return nil, errors.New("gemini client is not initialized")
+		return nil, nil, errors.New("gemini client is not initialized")
 	}
 
 	article, err := ies.repo.FindByID(ctx, id)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get article")
+		return nil, nil, errors.Wrap(err, "failed to get article")
 	}
 
 	if article.Content == "" {
-		return nil, errors.New("article content is empty")
+		return nil, nil, errors.New("article content is empty")
+	}
+
+	paragraphs := ie.LabelParagraphs(article.Content)
+	labels := ie.ParagraphLabels(paragraphs)
+	labelledContent := strings.Builder{}
+	for _, paragraph := range paragraphs {
+		fmt.Fprintf(&labelledContent, "[%v] %v\n\n", paragraph.Label, paragraph.Text)
 	}
 
 	prompt := fmt.Sprintf(`
-	From below article, help me generate 13 questions with answer in IELTS style. Question type such as
-		- Multiple choice
-		- Short answer
-		- True/False/Not Given
-	Response in JSON format with schema as follows:
-		Question = {'type': string, 'question': string, 'options': []string, 'answer': string}
-		Return: Array<Question>
-		Where: 
-		- type: type of question, can be one of the following: 'multiple_choice', 'short_answer', 'true_false_not_given'
-		- question: the question text
-		- options: the options for multiple choice question, empty for other types
-		- answer: the answer for the question, if the question is multiple choice, the answer should be A, B, C, D,... otherwise it is the answer text
+	From below article, help me generate 13 questions with answer in IELTS style. The paragraphs of the article are labelled %v.
+	Mix the question types:
+		- multiple_choice: 'options' are the choices, 'answer' is the letter of the correct option A, B, C, D,...
+		- short_answer: 'word_limit' is the most words of an answer, at most 3.
+		  'answer' is the answer text from the article, other correct answers separated by /
+		- true_false_not_given: 'answer' is TRUE, FALSE or NOT GIVEN
+		- matching_headings: 'headings' lists the headings in order i, ii, iii,..., with a few more headings than items
+		  Each of 'items' is a paragraph to find the heading of, 'paragraph' is its label and 'answer' the numeral of its heading
+		- matching_information: each of 'items' is a statement in 'text' found in one paragraph, 'answer' is the paragraph label
+		- sentence_completion: 'word_limit' is the most words of an answer, e.g. 2 for NO MORE THAN TWO WORDS.
+		  Each of 'items' is a sentence in 'text' with the gap ___ to fill with words from the article,
+		  'answer' is the missing words, alternatives separated by /
+	'question' is the instruction of the question. Questions with items have no 'answer' of their own.
+	'evidence' of a question, or of each item of a question with items, is the sentence of the article supporting the answer,
+	copied word for word without the paragraph label. For NOT GIVEN it is the sentence about the topic of the statement.
 	-----
 	Title: %v
-	Article: %v
-	`, article.Title, article.Content)
+	Article:
+	%v
+	`, strings.Join(labels, ", "), article.Title, labelledContent.String())
 
-	resp, err := ies.gemi.GenerateContentSimp(context.Background(), prompt)
+	resp, err := ies.gemi.GenerateContent(ctx, questionSchema, prompt)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to generate content")
+		return nil, nil, errors.Wrap(err, "failed to generate content")
 	}
 	var questions []ie.Question
 	for _, cand := range resp.Candidates {
@@ -185,13 +343,30 @@ func (ies *IEservice) GenerateQuestion(ctx context.Context, id uint64) (*[]ie.Qu
 			break
 		}
 	}
-	if len(questions) == 0 {
-		return nil, errors.New("failed to generate questions")
+
+	valid := []ie.Question{}
+	for _, q := range questions {
+		q.ArticleReadingId = id
+		q.ID = uint64(len(valid) + 1)
+		for i := range q.Items {
+			q.Items[i].ID = uint64(i + 1)
+		}
+		if q.Type == ie.QUESTION_TYPE_MATCHING_INFORMATION {
+			q.Options = labels
+		}
+		if err := q.Validate(labels); err != nil {
+			logger.Log.Warn().Err(err).Msgf("dropped generated question of article %d", id)
+			continue
+		}
+		if err := q.LocateEvidence(article.Content, paragraphs); err != nil {
+			logger.Log.Warn().Err(err).Msgf("dropped generated question of article %d", id)
+			continue
+		}
+		valid = append(valid, q)
 	}
-	for i := range questions {
-		questions[i].ArticleReadingId = id
-		questions[i].ID = uint64(i + 1)
+	if len(valid) == 0 {
+		return nil, nil, errors.New("failed to generate questions")
 	}
 
-	return &questions, nil
+	return &valid, paragraphs, nil
 }
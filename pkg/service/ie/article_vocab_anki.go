@@ -0,0 +1,88 @@
+package ieservice
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
+	"github.com/nhuongmh/cfvs.jpx/pkg/supporter/anki"
+	"github.com/pkg/errors"
+)
+
+// vocab lists are exported as sub decks of this deck
+const ANKI_VOCAB_DECK = "IE"
+
+func vocabNoteModel() *anki.NoteModel {
+	return &anki.NoteModel{
+		Name:   "cfvs IE Vocab",
+		Fields: []string{"Word", "Pronunciation", "Definition", "Examples", "Context"},
+		Templates: []anki.Template{{
+			Name:  "Recognition",
+			Front: "<div class=word>{{Word}}</div><div class=pron>{{Pronunciation}}</div>",
+			Back: "{{FrontSide}}<hr id=answer>{{Definition}}<div class=examples>{{Examples}}</div>" +
+				"<div class=context>{{Context}}</div>",
+		}},
+		CSS: anki.DEFAULT_CSS + `
+.word { font-size: 32px; font-weight: bold; }
+.pron, .context { color: #666; }
+.examples, .context { text-align: left; font-size: 16px; }`,
+	}
+}
+
+func vocabListPackage(vocabList *ie.IeVocabList) (*anki.Package, error) {
+	if len(vocabList.Vocabs) == 0 {
+		return nil, errors.New("vocab list is empty")
+	}
+
+	noteModel := vocabNoteModel()
+	deck := ANKI_VOCAB_DECK + "::" + strings.ReplaceAll(vocabList.Name, "::", ":")
+	pkg := anki.NewPackage()
+	for i := range vocabList.Vocabs {
+		vocab := &vocabList.Vocabs[i]
+		err := pkg.AddNote(anki.Note{
+			GUID:   anki.GUIDFor("cfvs-ie-vocab-" + strconv.FormatUint(vocab.ID, 10)),
+			Deck:   deck,
+			Model:  noteModel,
+			Fields: vocabFields(vocab),
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to export vocab %v", vocab.Word)
+		}
+	}
+	return pkg, nil
+}
+
+// vocabFields renders the vocab in the field order of vocabNoteModel
+func vocabFields(vocab *ie.IeVocab) []string {
+	prons := []string{}
+	for _, pron := range vocab.Pronunciation {
+		prons = append(prons, html.EscapeString(strings.TrimSpace(pron.Lang+" "+pron.Pron)))
+	}
+
+	definitions := []string{}
+	examples := []string{}
+	for _, def := range vocab.Definitions {
+		text := html.EscapeString(def.Text)
+		if def.Position != "" {
+			text = fmt.Sprintf("<i>%v</i> %v", html.EscapeString(def.Position), text)
+		}
+		definitions = append(definitions, text)
+		for _, example := range def.Examples {
+			examples = append(examples, "<li>"+html.EscapeString(example.Text)+"</li>")
+		}
+	}
+	exampleList := ""
+	if len(examples) > 0 {
+		exampleList = "<ul>" + strings.Join(examples, "") + "</ul>"
+	}
+
+	return []string{
+		html.EscapeString(vocab.Word),
+		strings.Join(prons, " · "),
+		strings.Join(definitions, "<br>"),
+		exampleList,
+		html.EscapeString(vocab.Context),
+	}
+}
@@ -0,0 +1,88 @@
+package ieservice
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/pkg/errors"
+)
+
+// cloze cards of a vocab list go to a sub deck of this deck unless another deck is given
+const CLOZE_VOCAB_DECK = "en::IE"
+
+// property of cloze cards linking them to their vocab
+const PROP_IE_VOCAB_ID = "ie_vocab_id"
+
+// GenClozeCardsForVocabList adds a cloze card per selected vocab to the practice cards, hiding the word
+// in its context sentence with its first definition as hint. Cards are added as learning cards
+// so they are scheduled right away, they are owned by the user of the vocab list and seen by no one else.
+func (ies *IEservice) GenClozeCardsForVocabList(ctx context.Context, vocabListId uint64, opts *ie.ClozeCardsDto) (*ie.ClozeCardsReport, error) {
+	vocabList, err := ies.GetVocabList(ctx, vocabListId)
+	if err != nil {
+		return nil, err
+	}
+	deck := opts.Deck
+	if deck == "" {
+		deck = langfi.DeckPath(CLOZE_VOCAB_DECK, strings.ReplaceAll(vocabList.Name, langfi.DECK_SEPARATOR, ":"))
+	}
+	deck, err = langfi.NormalizeDeckName(deck)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ie.ClozeCardsReport{Deck: deck, Skipped: []string{}}
+	listed := map[uint64]bool{}
+	cards := []langfi.ReviewCard{}
+	for i := range vocabList.Vocabs {
+		vocab := &vocabList.Vocabs[i]
+		listed[vocab.ID] = true
+		if len(opts.VocabIDs) > 0 && !slices.Contains(opts.VocabIDs, vocab.ID) {
+			continue
+		}
+		card, err := langfi.NewClozeCard(vocab.Context, vocab.Word, vocabHint(vocab))
+		if err != nil {
+			report.Skipped = append(report.Skipped, err.Error())
+			continue
+		}
+		card.Group = deck
+		card.Status = langfi.CARD_LEARN
+		card.OwnerID = auth.UserIDFromContext(ctx)
+		card.SetProp(PROP_IE_VOCAB_ID, vocab.ID)
+		cards = append(cards, card)
+	}
+	for _, id := range opts.VocabIDs {
+		if !listed[id] {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("vocab id %v is not in the vocab list", id))
+		}
+	}
+	if len(cards) == 0 {
+		return report, nil
+	}
+
+	report.Added, err = ies.practiceRepo.AddNewCards(ctx, cards)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to add cloze cards")
+	}
+	report.Duplicates = len(cards) - report.Added
+	return report, nil
+}
+
+// vocabHint is the first definition of the vocab with its part of speech
+func vocabHint(vocab *ie.IeVocab) string {
+	for _, def := range vocab.Definitions {
+		text := strings.TrimSpace(def.Text)
+		if text == "" {
+			continue
+		}
+		if def.Position != "" {
+			return fmt.Sprintf("(%v) %v", def.Position, text)
+		}
+		return text
+	}
+	return ""
+}
@@ -14,16 +14,16 @@ import (
 )
 
 func (ies *IEservice) ExtractVocab(ctx context.Context, id uint64) (*[]ie.ProposeWord, error) {
-	cachedVocabs, ok := ies.vocabProposalCache[id]
-	if ok {
-		return cachedVocabs, nil
-	}
-
 	article, err := ies.repo.FindByID(ctx, id)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get article")
 	}
 
+	cachedVocabs, ok := ies.vocabProposalCache[id]
+	if ok && cachedVocabs != nil {
+		return cachedVocabs, nil
+	}
+
 	//serialize article to pass to http.Post command
 	articleJson, err := json.Marshal(article)
 	if err != nil {
@@ -61,7 +61,10 @@ func (ies *IEservice) GenVocabListFromProposal(ctx context.Context, articleId ui
 	//check if vocab list already exists
 	article, err := ies.repo.FindByID(ctx, articleId)
 	if err != nil {
-		logger.Log.Warn().Msgf("Article ID %v not found", articleId)
+		return nil, errors.Wrapf(err, "article ID %v not found", articleId)
+	}
+	if err = checkArticleEvent(article, ie.EVENT_VOCAB_LIST_CREATED); err != nil {
+		return nil, err
 	}
 	processed, err := ies.processVocabProposalList(proposals)
 	if err != nil {
@@ -73,7 +76,12 @@ func (ies *IEservice) GenVocabListFromProposal(ctx context.Context, articleId ui
 		logger.Log.Warn().Msgf("Vocab list for article ID %v already exist, appending", article.ID)
 		// vocabList.Vocabs = append(vocabList.Vocabs, *processed...)
 		vocabList.Vocabs = *processed
-		return ies.repo.UpdateVocabList(ctx, vocabList)
+		vocabList, err = ies.repo.UpdateVocabList(ctx, vocabList)
+		if err != nil {
+			return nil, err
+		}
+		ies.logArticleEvent(ctx, article.ID, ie.EVENT_VOCAB_LIST_CREATED)
+		return vocabList, nil
 	}
 
 	vocabList = &ie.IeVocabList{
@@ -81,21 +89,41 @@ func (ies *IEservice) GenVocabListFromProposal(ctx context.Context, articleId ui
 		RefArticleID: article.ID,
 		Vocabs:       *processed,
 	}
-	// err = ies.generateAnkiDeck(vocabList)
-	// if err != nil {
-	// 	logger.Log.Warn().Err(err).Msg("failed to generate Anki deck")
-	// }
 	ies.vocabProposalCache[articleId] = nil //clear the cache for this article
-	return ies.repo.SaveVocabList(ctx, vocabList)
+	vocabList, err = ies.repo.SaveVocabList(ctx, vocabList)
+	if err != nil {
+		return nil, err
+	}
+	ies.logArticleEvent(ctx, article.ID, ie.EVENT_VOCAB_LIST_CREATED)
+	return vocabList, nil
 }
 
-func (ies *IEservice) GenAnkiDeckForVocabList(ctx context.Context, vocabListId uint64) error {
-	vocabList, err := ies.repo.FindVocabListByID(ctx, vocabListId, true)
+// GenAnkiDeckForVocabList writes an anki package with one note per vocab. The export is recorded once the
+// package is written, and before it is returned, so that a conflicting status fails the request.
+func (ies *IEservice) GenAnkiDeckForVocabList(ctx context.Context, vocabListId uint64) ([]byte, error) {
+	vocabList, err := ies.GetVocabList(ctx, vocabListId)
 	if err != nil {
-		return errors.Wrap(err, "failed to get vocab list")
+		return nil, err
 	}
-
-	return ies.generateAnkiDeck(vocabList)
+	article, err := ies.repo.FindByID(ctx, vocabList.RefArticleID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get article of vocab list")
+	}
+	if err = checkArticleEvent(article, ie.EVENT_ANKI_EXPORTED); err != nil {
+		return nil, err
+	}
+	pkg, err := vocabListPackage(vocabList)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = pkg.Export(&buf); err != nil {
+		return nil, errors.Wrap(err, "failed to write anki package")
+	}
+	if err = ies.recordArticleEvent(ctx, article.ID, ie.EVENT_ANKI_EXPORTED); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
 func (ies *IEservice) GetVocabList(ctx context.Context, vocabListId uint64) (*ie.IeVocabList, error) {
@@ -193,27 +221,3 @@ func (ies *IEservice) processVocabProposalList(proposals *[]ie.ProposeWord) (*[]
 	}
 	return &processed, nil
 }
-
-func (ies *IEservice) generateAnkiDeck(vocabList *ie.IeVocabList) error {
-	vocabJson, err := json.Marshal(vocabList)
-	if err != nil {
-		return errors.Wrap(err, "failed to marshal proposal")
-	}
-
-	cvfspyUrl := "http://localhost:5000/api/vocab/ankigen/local"
-	resp, err := http.Post(cvfspyUrl, "application/json", bytes.NewBuffer(vocabJson))
-	if err != nil {
-		return errors.Wrap(err, "failed to send request to CVFSpy")
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return errors.Errorf("failed to send request to CVFSpy, status code: %d", resp.StatusCode)
-	}
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return errors.Wrap(err, "failed to read response body")
-	}
-	logger.Log.Info().Msgf("Anki deck generated: %s", string(body))
-	return nil
-}
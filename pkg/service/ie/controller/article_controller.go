@@ -1,12 +1,17 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
 	ieservice "github.com/nhuongmh/cfvs.jpx/pkg/service/ie"
 )
 
@@ -14,21 +19,88 @@ type IeController struct {
 	Service *ieservice.IEservice
 }
 
-type pageMeta struct {
-	Page     uint64 `json:"page"`
-	PageSize uint64 `json:"page_size"`
-	Total    int    `json:"total"`
-}
-
 func (tc *IeController) GetAllArticle(c *gin.Context) {
-	page, pageSize := tc.parsePagination(c, 1, 20)
-	articles, total, err := tc.Service.GetAllArticles(c, pageSize, page)
+	query, err := tc.parseArticleQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	articles, meta, err := tc.Service.GetAllArticles(c, query)
+	if errors.Is(err, ie.ErrInvalidArticleQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("failed to get all articles")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get all articles"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"articles": articles, "meta": pageMeta{Page: page, PageSize: pageSize, Total: total}})
+	c.JSON(http.StatusOK, gin.H{"articles": articles, "meta": meta})
+}
+
+func (tc *IeController) SetArticleTags(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to parse id")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse id"})
+		return
+	}
+	var dto ie.ArticleTagsDto
+	if err = c.ShouldBindJSON(&dto); err != nil {
+		logger.Log.Error().Err(err).Msg("failed to bind tags")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to bind tags"})
+		return
+	}
+	tags, err := tc.Service.SetArticleTags(c, id, dto.Tags)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to set article tags")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set article tags"})
+		return
+	}
+	c.JSON(http.StatusOK, ie.ArticleTagsDto{Tags: tags})
+}
+
+func (tc *IeController) GetArticleTransitions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to parse id")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse id"})
+		return
+	}
+	transitions, err := tc.Service.GetArticleTransitions(c, id)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to get article transitions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get article transitions"})
+		return
+	}
+	c.JSON(http.StatusOK, transitions)
+}
+
+// ApplyArticleEvent discards or restores the article
+func (tc *IeController) ApplyArticleEvent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to parse id")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse id"})
+		return
+	}
+	var dto ie.ArticleEventDto
+	if err = c.ShouldBindJSON(&dto); err != nil {
+		logger.Log.Error().Err(err).Msg("failed to bind article event")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to bind article event"})
+		return
+	}
+	transition, err := tc.Service.ApplyUserArticleEvent(c, id, dto.Event)
+	if errors.Is(err, ie.ErrIllegalTransition) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to apply article event")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to apply article event"})
+		return
+	}
+	c.JSON(http.StatusOK, transition)
 }
 
 func (tc *IeController) SaveArticle(c *gin.Context) {
@@ -39,7 +111,17 @@ func (tc *IeController) SaveArticle(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to bind article"})
 		return
 	}
-	savedArticle, err := tc.Service.SaveArticle(c, &article)
+	onDuplicate := c.DefaultQuery("on_duplicate", ie.DUPLICATE_REJECT)
+	if onDuplicate != ie.DUPLICATE_REJECT && onDuplicate != ie.DUPLICATE_MERGE {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "on_duplicate must be reject or merge"})
+		return
+	}
+	savedArticle, err := tc.Service.SaveArticle(c, &article, onDuplicate)
+	if errors.Is(err, ie.ErrDuplicateArticle) {
+		// the existing article is returned so that the client can open it
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "article": savedArticle})
+		return
+	}
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("failed to save article")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save article"})
@@ -104,6 +186,10 @@ func (tc *IeController) ParseArticleFromUrl(c *gin.Context) {
 		return
 	}
 	article, err := tc.Service.FetchArticleUrl(c, url)
+	if errors.Is(err, ieservice.ErrInvalidArticleUrl) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("failed to fetch article from url")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch article from url"})
@@ -137,6 +223,10 @@ func (tc *IeController) ReGenArticleReading(c *gin.Context) {
 		return
 	}
 	err = tc.Service.ReGenArticleReading(c, id)
+	if errors.Is(err, ie.ErrIllegalTransition) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("failed to regenerate question")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to regenerate question"})
@@ -152,7 +242,7 @@ func (tc *IeController) SubmitReadingTest(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse id"})
 		return
 	}
-	var answers map[int]string
+	var answers map[string]string
 	err = c.ShouldBindJSON(&answers)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("failed to bind answers")
@@ -161,6 +251,18 @@ func (tc *IeController) SubmitReadingTest(c *gin.Context) {
 	}
 
 	testResult, err := tc.Service.GradeQuestionSubmit(c, readingId, answers)
+	if errors.Is(err, ie.ErrArticleReadingNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ie.ErrInvalidAnswers) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ie.ErrIllegalTransition) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("failed to grade question submit")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to grade question submit"})
@@ -185,6 +287,23 @@ func (tc *IeController) GetTestSubmission(c *gin.Context) {
 	c.JSON(http.StatusOK, testResult)
 }
 
+// GetTestReview returns the graded test with the evidence of each answer to highlight in the article
+func (tc *IeController) GetTestReview(c *gin.Context) {
+	submitId, err := strconv.ParseUint(c.Param("submit_id"), 10, 64)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to parse id")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse id"})
+		return
+	}
+	review, err := tc.Service.GetTestReview(c, submitId)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to get test review")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get test review"})
+		return
+	}
+	c.JSON(http.StatusOK, review)
+}
+
 func (tc *IeController) GetTestSubmissionByReadingId(c *gin.Context) {
 	readingId, err := strconv.ParseUint(c.Param("reading_id"), 10, 64)
 	if err != nil {
@@ -250,6 +369,10 @@ func (tc *IeController) HandleVocabProposalSubmit(c *gin.Context) {
 	}
 
 	list, err := tc.Service.GenVocabListFromProposal(c, id, &proposals)
+	if errors.Is(err, ie.ErrIllegalTransition) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("failed to gen vocab list from proposal")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to gen vocab list from proposal"})
@@ -283,13 +406,50 @@ func (tc *IeController) GenAnkiDeckForVocabList(c *gin.Context) {
 		return
 	}
 
-	err = tc.Service.GenAnkiDeckForVocabList(c, id)
+	data, err := tc.Service.GenAnkiDeckForVocabList(c, id)
+	if errors.Is(err, ie.ErrIllegalTransition) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("failed to gen Anki deck for vocab list")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to gen Anki deck for vocab list"})
 		return
 	}
-	c.JSON(http.StatusOK, "Success")
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="vocab-%v.apkg"`, id))
+	c.Data(http.StatusOK, "application/octet-stream", data)
+}
+
+func (tc *IeController) GenClozeCardsForVocabList(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to parse id")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse id"})
+		return
+	}
+
+	var opts ie.ClozeCardsDto
+	if c.Request.ContentLength != 0 {
+		err = c.ShouldBindJSON(&opts)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("failed to bind cloze options")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to bind cloze options"})
+			return
+		}
+	}
+
+	report, err := tc.Service.GenClozeCardsForVocabList(c, id, &opts)
+	if errors.Is(err, langfi.ErrInvalidDeck) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to gen cloze cards for vocab list")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to gen cloze cards for vocab list"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
 }
 
 func (tc *IeController) parsePagination(c *gin.Context, defaultPage, defaultSize uint64) (uint64, uint64) {
@@ -307,3 +467,79 @@ func (tc *IeController) parsePagination(c *gin.Context, defaultPage, defaultSize
 	}
 	return pageNum, size
 }
+
+// parseArticleQuery reads the paging, sort and filter query parameters of the article list.
+// status, level and tag may be repeated or comma separated, from and to are dates or RFC 3339 times.
+func (tc *IeController) parseArticleQuery(c *gin.Context) (*ie.ArticleQuery, error) {
+	page, pageSize := tc.parsePagination(c, 1, 20)
+	query := &ie.ArticleQuery{
+		Page:     page,
+		PageSize: pageSize,
+		Cursor:   c.Query("cursor"),
+		Sort:     c.Query("sort"),
+		Order:    c.Query("order"),
+		Filter: ie.ArticleFilter{
+			Statuses: queryList(c, "status"),
+			Origin:   c.Query("origin"),
+			Levels:   queryList(c, "level"),
+			Tags:     queryList(c, "tag"),
+			Query:    c.Query("q"),
+		},
+	}
+	filter := &query.Filter
+
+	var err error
+	for param, value := range map[string]*float64{"min_band": &filter.MinBand, "max_band": &filter.MaxBand} {
+		if raw := c.Query(param); raw != "" {
+			if *value, err = strconv.ParseFloat(raw, 64); err != nil {
+				return nil, fmt.Errorf("invalid %s %q", param, raw)
+			}
+		}
+	}
+	for param, value := range map[string]*int{"min_words": &filter.MinWords, "max_words": &filter.MaxWords} {
+		if raw := c.Query(param); raw != "" {
+			if *value, err = strconv.Atoi(raw); err != nil {
+				return nil, fmt.Errorf("invalid %s %q", param, raw)
+			}
+		}
+	}
+	if filter.From, err = parseDateParam(c, "from", false); err != nil {
+		return nil, err
+	}
+	// a date includes its whole day
+	if filter.To, err = parseDateParam(c, "to", true); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+func queryList(c *gin.Context, param string) []string {
+	values := []string{}
+	for _, list := range c.QueryArray(param) {
+		for _, value := range strings.Split(list, ",") {
+			if strings.TrimSpace(value) != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// parseDateParam reads a date or a RFC 3339 time, nil when missing. With nextDay a date is read as the start of the following day.
+func parseDateParam(c *gin.Context, param string, nextDay bool) (*time.Time, error) {
+	raw := c.Query(param)
+	if raw == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q, want a date like 2024-05-31 or a RFC 3339 time", param, raw)
+	}
+	if nextDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
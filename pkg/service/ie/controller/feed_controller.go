@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
+	ieservice "github.com/nhuongmh/cfvs.jpx/pkg/service/ie"
+)
+
+func (tc *IeController) GetFeeds(c *gin.Context) {
+	feeds, err := tc.Service.GetFeeds(c)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to get feeds")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get feeds"})
+		return
+	}
+	c.JSON(http.StatusOK, feeds)
+}
+
+func (tc *IeController) AddFeed(c *gin.Context) {
+	var dto ie.FeedDto
+	err := c.ShouldBindJSON(&dto)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to bind feed")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to bind feed"})
+		return
+	}
+	feed, err := tc.Service.AddFeed(c, &dto)
+	if errors.Is(err, ieservice.ErrInvalidFeed) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to add feed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add feed"})
+		return
+	}
+	c.JSON(http.StatusOK, feed)
+}
+
+func (tc *IeController) UpdateFeed(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to parse id")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse id"})
+		return
+	}
+	var dto ie.FeedDto
+	err = c.ShouldBindJSON(&dto)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to bind feed")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to bind feed"})
+		return
+	}
+	feed, err := tc.Service.UpdateFeed(c, id, &dto)
+	if errors.Is(err, ieservice.ErrInvalidFeed) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to update feed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update feed"})
+		return
+	}
+	c.JSON(http.StatusOK, feed)
+}
+
+func (tc *IeController) DeleteFeed(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to parse id")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse id"})
+		return
+	}
+	err = tc.Service.DeleteFeed(c, id)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to delete feed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete feed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "feed deleted"})
+}
+
+func (tc *IeController) PollFeed(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to parse id")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse id"})
+		return
+	}
+	report, err := tc.Service.PollFeed(c, id)
+	if errors.Is(err, ieservice.ErrFeedPolling) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to poll feed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to poll feed"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
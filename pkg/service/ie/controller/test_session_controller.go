@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
+)
+
+// StartTestSession starts a timed test of the reading, the body with the module and the duration is optional
+func (tc *IeController) StartTestSession(c *gin.Context) {
+	readingId, err := strconv.ParseUint(c.Param("reading_id"), 10, 64)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to parse id")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse id"})
+		return
+	}
+	var dto ie.StartTestDto
+	if err = c.ShouldBindJSON(&dto); err != nil && !errors.Is(err, io.EOF) {
+		logger.Log.Error().Err(err).Msg("failed to bind test session")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to bind test session"})
+		return
+	}
+	session, err := tc.Service.StartTestSession(c, readingId, &dto)
+	if errors.Is(err, ie.ErrArticleReadingNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ie.ErrInvalidTestSession) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ie.ErrIllegalTransition) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to start test session")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start test session"})
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+func (tc *IeController) GetTestSession(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("session_id"), 10, 64)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to parse id")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse id"})
+		return
+	}
+	session, err := tc.Service.GetTestSession(c, id)
+	if errors.Is(err, ie.ErrTestSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to get test session")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get test session"})
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+func (tc *IeController) SubmitTestSession(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("session_id"), 10, 64)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to parse id")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse id"})
+		return
+	}
+	var dto ie.TestSubmissionDto
+	if err = c.ShouldBindJSON(&dto); err != nil {
+		logger.Log.Error().Err(err).Msg("failed to bind test submission")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to bind test submission"})
+		return
+	}
+	testResult, err := tc.Service.SubmitTestSession(c, id, &dto)
+	if errors.Is(err, ie.ErrTestSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ie.ErrInvalidAnswers) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ie.ErrTestSessionClosed) || errors.Is(err, ie.ErrIllegalTransition) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to submit test session")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to submit test session"})
+		return
+	}
+	c.JSON(http.StatusOK, testResult)
+}
+
+// GetBandHistory returns the bands of the tests over time, filtered by module, timed and from/to dates
+func (tc *IeController) GetBandHistory(c *gin.Context) {
+	query := ie.BandHistoryQuery{Module: c.Query("module")}
+	if raw := c.Query("timed"); raw != "" {
+		timed, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "timed must be true or false"})
+			return
+		}
+		query.TimedOnly = timed
+	}
+	var err error
+	if query.From, err = parseDateParam(c, "from", false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.To, err = parseDateParam(c, "to", true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	history, err := tc.Service.GetBandHistory(c, &query)
+	if errors.Is(err, ie.ErrInvalidBandQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to get band history")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get band history"})
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
@@ -0,0 +1,328 @@
+package ieservice
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
+	"github.com/nhuongmh/cfvs.jpx/pkg/supporter/feed"
+	"github.com/pkg/errors"
+)
+
+const (
+	// feeds are polled this often when FEED_POLL_MINUTES is not set, a negative value stops polling
+	DEFAULT_FEED_POLL_INTERVAL = time.Hour
+	// the poller looks for due feeds at most this long apart
+	FEED_POLL_CHECK_INTERVAL = 5 * time.Minute
+	MAX_FEED_BYTES           = 5 << 20
+	// entries whose page keeps failing are given up after these attempts
+	MAX_FEED_ENTRY_ATTEMPTS = 3
+)
+
+var (
+	ErrInvalidFeed = errors.New("invalid feed")
+	ErrFeedPolling = errors.New("feed is being polled")
+)
+
+// AddFeed subscribes to the feed after checking that it can be read
+func (ies *IEservice) AddFeed(ctx context.Context, dto *ie.FeedDto) (*ie.Feed, error) {
+	fd := &ie.Feed{
+		Url:        strings.TrimSpace(dto.Url),
+		Filter:     dto.Filter,
+		GenReading: dto.GenReading,
+		Enabled:    dto.Enabled == nil || *dto.Enabled,
+	}
+	if err := ies.validateFeed(ctx, fd); err != nil {
+		return nil, err
+	}
+	parsed, err := fetchFeed(ctx, fd.Url)
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalidFeed, "failed to read %v: %v", fd.Url, err)
+	}
+	fd.Title = parsed.Title
+
+	fd, err = ies.repo.SaveFeed(ctx, fd)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to save feed")
+	}
+	return fd, nil
+}
+
+func (ies *IEservice) GetFeeds(ctx context.Context) ([]*ie.Feed, error) {
+	feeds, err := ies.repo.FindAllFeeds(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get feeds")
+	}
+	return feeds, nil
+}
+
+// UpdateFeed changes the url, filter and options of the feed, the title is kept
+func (ies *IEservice) UpdateFeed(ctx context.Context, id uint64, dto *ie.FeedDto) (*ie.Feed, error) {
+	fd, err := ies.repo.FindFeedByID(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get feed")
+	}
+	fd.Url = strings.TrimSpace(dto.Url)
+	fd.Filter = dto.Filter
+	fd.GenReading = dto.GenReading
+	if dto.Enabled != nil {
+		fd.Enabled = *dto.Enabled
+	}
+	if err := ies.validateFeed(ctx, fd); err != nil {
+		return nil, err
+	}
+
+	fd, err = ies.repo.UpdateFeed(ctx, fd)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to update feed")
+	}
+	return fd, nil
+}
+
+func (ies *IEservice) DeleteFeed(ctx context.Context, id uint64) error {
+	err := ies.repo.DeleteFeed(ctx, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete feed")
+	}
+	return nil
+}
+
+func (ies *IEservice) validateFeed(ctx context.Context, fd *ie.Feed) error {
+	if !isHttpUrl(fd.Url) {
+		return errors.Wrapf(ErrInvalidFeed, "`%v` is not an http url", fd.Url)
+	}
+	if err := fd.Filter.Validate(); err != nil {
+		return errors.Wrap(ErrInvalidFeed, err.Error())
+	}
+	feeds, err := ies.repo.FindAllFeeds(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get feeds")
+	}
+	for _, other := range feeds {
+		if other.Url == fd.Url && other.ID != fd.ID {
+			return errors.Wrapf(ErrInvalidFeed, "already subscribed to %v", fd.Url)
+		}
+	}
+	return nil
+}
+
+// PollFeed imports the new entries of the feed now, without waiting for the poller
+func (ies *IEservice) PollFeed(ctx context.Context, id uint64) (*ie.FeedPollReport, error) {
+	fd, err := ies.repo.FindFeedByID(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get feed")
+	}
+	return ies.pollFeed(ctx, fd)
+}
+
+// StartFeedPoller polls the enabled feeds of every user in the background until the context is done
+func (ies *IEservice) StartFeedPoller(ctx context.Context) {
+	interval := DEFAULT_FEED_POLL_INTERVAL
+	if ies.env.FeedPollMinutes < 0 {
+		logger.Log.Info().Msg("feed polling is disabled")
+		return
+	}
+	if ies.env.FeedPollMinutes > 0 {
+		interval = time.Duration(ies.env.FeedPollMinutes) * time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(min(interval, FEED_POLL_CHECK_INTERVAL))
+		defer ticker.Stop()
+		for {
+			ies.pollDueFeeds(ctx, interval)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	logger.Log.Info().Msgf("polling feeds every %v", interval)
+}
+
+func (ies *IEservice) pollDueFeeds(ctx context.Context, interval time.Duration) {
+	feeds, err := ies.repo.FindFeedsToPoll(ctx, interval)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to get feeds to poll")
+		return
+	}
+	for _, fd := range feeds {
+		if ctx.Err() != nil {
+			return
+		}
+		// the articles belong to the owner of the feed
+		userCtx := auth.WithUser(ctx, &auth.User{Base: model.Base{ID: fd.UserID}})
+		report, err := ies.pollFeed(userCtx, fd)
+		if err != nil {
+			logger.Log.Warn().Err(err).Msgf("failed to poll feed %d", fd.ID)
+			continue
+		}
+		logger.Log.Info().Msgf("polled feed %d: %d new entries, %d imported, %d skipped, %d failed",
+			fd.ID, report.NewEntries, len(report.Imported), report.Skipped, report.Failed)
+	}
+}
+
+// pollFeed imports the entries of the feed not handled yet, within the daily maximum of the feed.
+// A feed is polled once at a time so that an entry is never imported twice, other feeds are not held up.
+func (ies *IEservice) pollFeed(ctx context.Context, fd *ie.Feed) (*ie.FeedPollReport, error) {
+	ies.feedPollMu.Lock()
+	if ies.feedsPolling[fd.ID] {
+		ies.feedPollMu.Unlock()
+		return nil, errors.Wrapf(ErrFeedPolling, "feed %d", fd.ID)
+	}
+	ies.feedsPolling[fd.ID] = true
+	ies.feedPollMu.Unlock()
+	defer func() {
+		ies.feedPollMu.Lock()
+		delete(ies.feedsPolling, fd.ID)
+		ies.feedPollMu.Unlock()
+	}()
+
+	parsed, err := fetchFeed(ctx, fd.Url)
+	if err != nil {
+		if err := ies.repo.UpdateFeedPolled(ctx, fd.ID, err.Error()); err != nil {
+			logger.Log.Warn().Err(err).Msg("failed to record feed poll")
+		}
+		return nil, errors.Wrapf(err, "failed to read feed %v", fd.Url)
+	}
+
+	guids := make([]string, 0, len(parsed.Entries))
+	for _, entry := range parsed.Entries {
+		guids = append(guids, entry.ID)
+	}
+	handled, err := ies.repo.FindFeedEntries(ctx, fd.ID, guids)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get feed entries")
+	}
+	importedToday, err := ies.repo.CountFeedImportsToday(ctx, fd.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to count feed imports")
+	}
+
+	report := &ie.FeedPollReport{FeedID: fd.ID, Imported: []uint64{}}
+	// the guid may repeat in a feed
+	seen := map[string]bool{}
+	for _, entry := range parsed.Entries {
+		if prev, ok := handled[entry.ID]; seen[entry.ID] || (ok && (prev.Status != ie.FEED_ENTRY_FAILED || prev.Attempts >= MAX_FEED_ENTRY_ATTEMPTS)) {
+			continue
+		}
+		seen[entry.ID] = true
+		report.NewEntries++
+		if fd.Filter.MaxPerDay > 0 && importedToday >= fd.Filter.MaxPerDay {
+			// not recorded, the entry is imported on a later day if the feed still lists it
+			report.Deferred++
+			continue
+		}
+
+		result := ies.importFeedEntry(ctx, fd, &entry)
+		if err := ies.repo.SaveFeedEntry(ctx, result); err != nil {
+			logger.Log.Warn().Err(err).Msgf("failed to record feed entry %v", entry.ID)
+		}
+		switch result.Status {
+		case ie.FEED_ENTRY_IMPORTED:
+			importedToday++
+			report.Imported = append(report.Imported, result.ArticleID)
+		case ie.FEED_ENTRY_SKIPPED:
+			report.Skipped++
+		default:
+			report.Failed++
+		}
+	}
+
+	if err := ies.repo.UpdateFeedPolled(ctx, fd.ID, ""); err != nil {
+		logger.Log.Warn().Err(err).Msg("failed to record feed poll")
+	}
+	return report, nil
+}
+
+// importFeedEntry extracts and saves the article of the entry unless it exists or the filter rejects it
+func (ies *IEservice) importFeedEntry(ctx context.Context, fd *ie.Feed, entry *feed.Entry) *ie.FeedEntry {
+	result := &ie.FeedEntry{FeedID: fd.ID, Guid: entry.ID, Link: entry.Link}
+
+	// the page is not fetched again when its url is known
+	existing, err := ies.repo.FindByCanonicalUrl(ctx, ie.CanonicalUrl(entry.Link))
+	if err != nil {
+		result.Status, result.Reason = ie.FEED_ENTRY_FAILED, err.Error()
+		return result
+	}
+	if existing != nil {
+		result.Status, result.Reason, result.ArticleID = ie.FEED_ENTRY_SKIPPED, "article already exists", existing.ID
+		return result
+	}
+
+	article, err := ies.FetchArticleUrl(ctx, entry.Link)
+	if err != nil {
+		result.Status, result.Reason = ie.FEED_ENTRY_FAILED, err.Error()
+		return result
+	}
+	// the feed knows the entry better than the page when the page lacks metadata
+	article.Origin = entry.Link
+	if article.Title == "" {
+		article.Title = entry.Title
+	}
+	if article.Author == "" {
+		article.Author = entry.Author
+	}
+	if article.PublishDate == "" && !entry.Published.IsZero() {
+		article.PublishDate = entry.Published.Format(time.RFC3339)
+	}
+	if reason := fd.Filter.Reject(article); reason != "" {
+		result.Status, result.Reason = ie.FEED_ENTRY_SKIPPED, reason
+		return result
+	}
+
+	analyzeArticle(article)
+	existing, match, err := ies.findDuplicate(ctx, article)
+	if err != nil {
+		result.Status, result.Reason = ie.FEED_ENTRY_FAILED, err.Error()
+		return result
+	}
+	if existing != nil {
+		result.Status, result.Reason, result.ArticleID = ie.FEED_ENTRY_SKIPPED, "same "+match+" as an existing article", existing.ID
+		return result
+	}
+
+	article.Status = ie.ARTICLE_NEW
+	article, err = ies.repo.Save(ctx, article)
+	if err != nil {
+		result.Status, result.Reason = ie.FEED_ENTRY_FAILED, err.Error()
+		return result
+	}
+	result.Status, result.ArticleID = ie.FEED_ENTRY_IMPORTED, article.ID
+
+	if fd.GenReading {
+		if _, err := ies.GenArticleReading(ctx, article, false); err != nil {
+			logger.Log.Warn().Err(err).Msgf("failed to generate article reading for article %d", article.ID)
+		}
+	}
+	return result
+}
+
+func fetchFeed(ctx context.Context, link string) (*feed.Feed, error) {
+	ctx, cancel := context.WithTimeout(ctx, ARTICLE_FETCH_TIMEOUT)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("User-Agent", ARTICLE_USER_AGENT)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml, text/xml")
+
+	resp, err := pageClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch feed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to fetch feed, status code: %d", resp.StatusCode)
+	}
+	return feed.Parse(io.LimitReader(resp.Body, MAX_FEED_BYTES), resp.Request.URL.String())
+}
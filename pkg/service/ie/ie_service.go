@@ -2,12 +2,14 @@ package ieservice
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/nhuongmh/cfvs.jpx/bootstrap"
 	"github.com/nhuongmh/cfvs.jpx/pkg/database/postgresdb"
 	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
 	"github.com/nhuongmh/cfvs.jpx/pkg/service/ie/ierepo"
 	"github.com/nhuongmh/cfvs.jpx/pkg/service/llm/gemini"
 	"github.com/pkg/errors"
@@ -16,17 +18,24 @@ import (
 type IEservice struct {
 	contextTimeout     time.Duration
 	repo               *ierepo.IErepo
+	practiceRepo       langfi.PracticeRepo
 	env                *bootstrap.Env
 	gemi               *gemini.GoogleAI
 	vocabProposalCache map[uint64]*[]ie.ProposeWord
+	feedPollMu         sync.Mutex
+	feedsPolling       map[uint64]bool
+	bandTables         map[string]ie.BandTable
 }
 
-func NewIEservice(timeout time.Duration, env *bootstrap.Env, db *postgresdb.DB) *IEservice {
+func NewIEservice(timeout time.Duration, env *bootstrap.Env, db *postgresdb.DB, practiceRepo langfi.PracticeRepo) *IEservice {
 	ies := &IEservice{
 		contextTimeout:     timeout,
 		repo:               ierepo.NewIeRepo(db),
+		practiceRepo:       practiceRepo,
 		env:                env,
 		vocabProposalCache: make(map[uint64]*[]ie.ProposeWord),
+		feedsPolling:       make(map[uint64]bool),
+		bandTables:         loadBandTables(env),
 	}
 	gemi, err := gemini.NewGoogleAI(ies.env.GoogleAIKey)
 	if err != nil {
@@ -38,8 +47,38 @@ func NewIEservice(timeout time.Duration, env *bootstrap.Env, db *postgresdb.DB)
 	return ies
 }
 
-func (ies *IEservice) SaveArticle(ctx context.Context, article *ie.Article) (*ie.Article, error) {
-	article, err := ies.repo.Save(ctx, article)
+// SaveArticle saves the article unless it duplicates an existing one, by canonical url or content.
+// Depending on onDuplicate, the duplicate is rejected with the existing article and ErrDuplicateArticle,
+// or merged into the existing article which is returned.
+func (ies *IEservice) SaveArticle(ctx context.Context, article *ie.Article, onDuplicate string) (*ie.Article, error) {
+	if onDuplicate != ie.DUPLICATE_REJECT && onDuplicate != ie.DUPLICATE_MERGE {
+		return nil, errors.Errorf("unknown duplicate handling %v", onDuplicate)
+	}
+	// the status only changes through the lifecycle events
+	article.Status = ie.ARTICLE_NEW
+	article.Tags = langfi.NormalizeTags(article.Tags)
+	analyzeArticle(article)
+	existing, match, err := ies.findDuplicate(ctx, article)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to look for duplicates")
+	}
+	if existing != nil && onDuplicate == ie.DUPLICATE_REJECT {
+		return existing, errors.Wrapf(ie.ErrDuplicateArticle, "same %v as article %d", match, existing.ID)
+	}
+	if existing != nil {
+		ie.MergeArticle(existing, article)
+		existing, err = ies.repo.Update(ctx, existing)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to merge article")
+		}
+		if err = ies.repo.SetArticleTags(ctx, existing.ID, existing.Tags); err != nil {
+			return nil, errors.Wrap(err, "failed to merge article tags")
+		}
+		logger.Log.Info().Msgf("merged duplicate article into article %d", existing.ID)
+		return existing, nil
+	}
+
+	article, err = ies.repo.Save(ctx, article)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to save article")
 	}
@@ -62,13 +101,29 @@ func (ies *IEservice) GetArticle(ctx context.Context, id uint64) (*ie.Article, e
 	return article, nil
 }
 
-func (ies *IEservice) GetAllArticles(ctx context.Context, pageSize, page uint64) ([]*ie.Article, int, error) {
-	articles, total, err := ies.repo.FindAll(ctx, pageSize, page)
+// GetAllArticles returns a page of the articles matching the query, an invalid query is an ErrInvalidArticleQuery
+func (ies *IEservice) GetAllArticles(ctx context.Context, query *ie.ArticleQuery) ([]*ie.Article, *ie.ArticlePageMeta, error) {
+	if err := query.Validate(); err != nil {
+		return nil, nil, err
+	}
+	articles, meta, err := ies.repo.FindAll(ctx, query)
 	if err != nil {
-		return nil, 0, errors.Wrap(err, "failed to get all articles")
+		return nil, nil, errors.Wrap(err, "failed to get all articles")
 	}
 
-	return articles, total, nil
+	return articles, meta, nil
+}
+
+// SetArticleTags replaces the tags of the article and returns them normalized
+func (ies *IEservice) SetArticleTags(ctx context.Context, id uint64, tags []string) ([]string, error) {
+	if _, err := ies.repo.FindByID(ctx, id); err != nil {
+		return nil, errors.Wrap(err, "failed to get article")
+	}
+	tags = langfi.NormalizeTags(tags)
+	if err := ies.repo.SetArticleTags(ctx, id, tags); err != nil {
+		return nil, errors.Wrap(err, "failed to set article tags")
+	}
+	return tags, nil
 }
 
 // find article by title
@@ -0,0 +1,206 @@
+package ierepo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nhuongmh/cfvs.jpx/pkg/database/postgresdb"
+	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
+	"github.com/pkg/errors"
+)
+
+// host of the canonical url, which is always https. `??` is a literal `?` for squirrel.
+const canonicalHostExpr = "substring(canonical_url from '^https://([^/??#:]+)')"
+
+// FindAll returns a page of the articles of the user matching the query, without their content,
+// and the meta of the page. The query must be validated.
+func (ir *IErepo) FindAll(ctx context.Context, q *ie.ArticleQuery) ([]*ie.Article, *ie.ArticlePageMeta, error) {
+	sortExpr := articleSortExpr(q)
+	query := ir.db.QueryBuilder.Select("id", "title", "origin", "author", "cover_image", "publish_date", "status", "canonical_url",
+		"difficulty", "created_at", "updated_at").
+		Column(sq.Alias(sortExpr, "sort_value")).
+		From("ie_articles").
+		Where("user_id = ?", auth.UserIDFromContext(ctx))
+	query = filterArticles(query, &q.Filter)
+
+	direction, comparison := "DESC", "<"
+	if q.Order == ie.SORT_ASC {
+		direction, comparison = "ASC", ">"
+	}
+	pageQuery := query.
+		OrderBy("sort_value "+direction, "id "+direction).
+		// one more row tells whether there is a next page
+		Limit(q.PageSize + 1)
+	if q.After != nil {
+		after, err := articleSortArg(q.Sort, q.After.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+		pageQuery = pageQuery.Where(sq.Expr(fmt.Sprintf("(?, id) %s (?, ?)", comparison), sortExpr, after, q.After.ID))
+	} else {
+		pageQuery = pageQuery.Offset((q.Page - 1) * q.PageSize)
+	}
+
+	sql, args, err := pageQuery.ToSql()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "build query")
+	}
+
+	logger.Log.Debug().Str("sql find all", sql).Msg("query")
+
+	rows, err := ir.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "query")
+	}
+	defer rows.Close()
+
+	articles := []*ie.Article{}
+	sortValues := []interface{}{}
+	for rows.Next() {
+		var article ie.Article
+		var difficultyJSON []byte
+		var sortValue interface{}
+		err = rows.Scan(
+			&article.ID,
+			&article.Title,
+			&article.Origin,
+			&article.Author,
+			&article.Image,
+			&article.PublishDate,
+			&article.Status,
+			&article.CanonicalUrl,
+			&difficultyJSON,
+			&article.CreatedAt,
+			&article.UpdatedAt,
+			&sortValue)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "scan row")
+		}
+		if article.Difficulty, err = parseDifficulty(difficultyJSON); err != nil {
+			return nil, nil, err
+		}
+		articles = append(articles, &article)
+		sortValues = append(sortValues, sortValue)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, errors.Wrap(err, "rows iteration")
+	}
+	rows.Close()
+
+	meta := &ie.ArticlePageMeta{Page: q.Page, PageSize: q.PageSize, Sort: q.Sort, Order: q.Order}
+	if q.After != nil {
+		meta.Page = 0
+	}
+	if uint64(len(articles)) > q.PageSize {
+		articles, sortValues = articles[:q.PageSize], sortValues[:q.PageSize]
+		last := articles[len(articles)-1]
+		cursor := ie.ArticleCursor{Sort: q.Sort, Order: q.Order, Value: formatSortValue(sortValues[len(sortValues)-1]), ID: last.ID}
+		meta.HasMore, meta.NextCursor = true, cursor.Encode()
+	}
+
+	if err = ir.loadArticleTags(ctx, articles); err != nil {
+		return nil, nil, err
+	}
+
+	meta.Total, err = ir.getTotalRowsOfQuery(ctx, query)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("failed to get total rows")
+		meta.Total = len(articles)
+	}
+
+	return articles, meta, nil
+}
+
+func filterArticles(query sq.SelectBuilder, filter *ie.ArticleFilter) sq.SelectBuilder {
+	if len(filter.Statuses) > 0 {
+		query = query.Where(sq.Eq{"status": filter.Statuses})
+	}
+	if filter.Origin != "" {
+		query = query.Where(sq.Or{
+			sq.Expr(canonicalHostExpr+" = ?", filter.Origin),
+			sq.Expr(canonicalHostExpr+" LIKE ?", "%."+filter.Origin),
+		})
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", filter.From.UTC())
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", filter.To.UTC())
+	}
+	if len(filter.Levels) > 0 {
+		query = query.Where(sq.Eq{"cefr_level": filter.Levels})
+	}
+	if filter.MinBand > 0 {
+		query = query.Where("ielts_band >= ?", filter.MinBand)
+	}
+	if filter.MaxBand > 0 {
+		query = query.Where("ielts_band <= ?", filter.MaxBand)
+	}
+	if filter.MinWords > 0 {
+		query = query.Where("word_count >= ?", filter.MinWords)
+	}
+	if filter.MaxWords > 0 {
+		query = query.Where("word_count <= ?", filter.MaxWords)
+	}
+	if len(filter.Tags) > 0 {
+		tagged := sq.Select("article_id").
+			From("ie_article_tags").
+			Where(sq.Eq{"tag": filter.Tags}).
+			GroupBy("article_id").
+			Having("COUNT(*) = ?", len(filter.Tags))
+		query = query.Where(sq.Expr("id IN (?)", tagged))
+	}
+	if len(filter.Terms) > 0 {
+		query = query.Where("search_vector @@ to_tsquery('simple', ?)", postgresdb.PrefixTsQuery(filter.Terms))
+	}
+	return query
+}
+
+func articleSortExpr(q *ie.ArticleQuery) sq.Sqlizer {
+	switch q.Sort {
+	case ie.ARTICLE_SORT_RELEVANCE:
+		return sq.Expr("ts_rank(search_vector, to_tsquery('simple', ?))", postgresdb.PrefixTsQuery(q.Filter.Terms))
+	case ie.ARTICLE_SORT_UPDATED, ie.ARTICLE_SORT_TITLE, ie.ARTICLE_SORT_BAND, ie.ARTICLE_SORT_WORDS:
+		return sq.Expr(q.Sort)
+	default:
+		return sq.Expr(ie.ARTICLE_SORT_CREATED)
+	}
+}
+
+// articleSortArg reads the sort value of a cursor with the type of the sort column
+func articleSortArg(sort, value string) (interface{}, error) {
+	var arg interface{}
+	var err error
+	switch sort {
+	case ie.ARTICLE_SORT_CREATED, ie.ARTICLE_SORT_UPDATED:
+		arg, err = time.Parse(time.RFC3339Nano, value)
+	case ie.ARTICLE_SORT_BAND, ie.ARTICLE_SORT_RELEVANCE:
+		var f float64
+		f, err = strconv.ParseFloat(value, 32)
+		arg = float32(f)
+	case ie.ARTICLE_SORT_WORDS:
+		arg, err = strconv.Atoi(value)
+	default:
+		arg = value
+	}
+	if err != nil {
+		return nil, errors.Wrapf(ie.ErrInvalidArticleQuery, "malformed cursor value %q", value)
+	}
+	return arg, nil
+}
+
+func formatSortValue(value interface{}) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
 	"github.com/pkg/errors"
 )
@@ -13,10 +14,14 @@ func (ir *IErepo) SaveArticleReading(ctx context.Context, articleReading *ie.Art
 	if err != nil {
 		return nil, errors.Wrap(err, "marshal questions to JSON")
 	}
+	paragraphsJSON, err := json.Marshal(articleReading.Paragraphs)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal paragraphs to JSON")
+	}
 
 	query := ir.db.QueryBuilder.Insert("article_reading").
-		Columns("article_id", "questions", "article_status").
-		Values(articleReading.ArticleID, questionsJSON, articleReading.Status).
+		Columns("article_id", "questions", "paragraphs", "article_status").
+		Values(articleReading.ArticleID, questionsJSON, paragraphsJSON, articleReading.Status).
 		Suffix("RETURNING id")
 
 	sql, args, err := query.ToSql()
@@ -33,7 +38,7 @@ func (ir *IErepo) SaveArticleReading(ctx context.Context, articleReading *ie.Art
 }
 
 func (ir *IErepo) FindArticleReadingByID(ctx context.Context, id uint64) (*ie.ArticleReading, error) {
-	query := ir.db.QueryBuilder.Select("id", "article_id", "questions", "article_status", "created_at", "updated_at").
+	query := ir.db.QueryBuilder.Select("id", "article_id", "questions", "paragraphs", "article_status", "created_at", "updated_at").
 		From("article_reading").
 		Where("id = ?", id)
 
@@ -43,14 +48,18 @@ func (ir *IErepo) FindArticleReadingByID(ctx context.Context, id uint64) (*ie.Ar
 	}
 
 	var article ie.ArticleReading
-	var questionsJSON []byte
+	var questionsJSON, paragraphsJSON []byte
 	err = ir.db.QueryRow(ctx, sql, args...).Scan(
 		&article.ID,
 		&article.ArticleID,
 		&questionsJSON,
+		&paragraphsJSON,
 		&article.Status,
 		&article.CreatedAt,
 		&article.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, errors.Wrapf(ie.ErrArticleReadingNotFound, "article reading %d", id)
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, "query row")
 	}
@@ -59,12 +68,18 @@ func (ir *IErepo) FindArticleReadingByID(ctx context.Context, id uint64) (*ie.Ar
 	if err != nil {
 		return nil, errors.Wrap(err, "unmarshal questions JSON")
 	}
+	if paragraphsJSON != nil {
+		err = json.Unmarshal(paragraphsJSON, &article.Paragraphs)
+		if err != nil {
+			return nil, errors.Wrap(err, "unmarshal paragraphs JSON")
+		}
+	}
 
 	return &article, nil
 }
 
 func (ir *IErepo) FindReadingByArticleId(ctx context.Context, articleId uint64) (*ie.ArticleReading, error) {
-	query := ir.db.QueryBuilder.Select("id", "article_id", "questions", "article_status", "created_at", "updated_at").
+	query := ir.db.QueryBuilder.Select("id", "article_id", "questions", "paragraphs", "article_status", "created_at", "updated_at").
 		From("article_reading").
 		Where("article_id = ?", articleId).
 		Limit(1)
@@ -75,11 +90,12 @@ func (ir *IErepo) FindReadingByArticleId(ctx context.Context, articleId uint64)
 	}
 
 	var article ie.ArticleReading
-	var questionsJSON []byte
+	var questionsJSON, paragraphsJSON []byte
 	err = ir.db.QueryRow(ctx, sql, args...).Scan(
 		&article.ID,
 		&article.ArticleID,
 		&questionsJSON,
+		&paragraphsJSON,
 		&article.Status,
 		&article.CreatedAt,
 		&article.UpdatedAt)
@@ -91,6 +107,12 @@ func (ir *IErepo) FindReadingByArticleId(ctx context.Context, articleId uint64)
 	if err != nil {
 		return nil, errors.Wrap(err, "unmarshal questions JSON")
 	}
+	if paragraphsJSON != nil {
+		err = json.Unmarshal(paragraphsJSON, &article.Paragraphs)
+		if err != nil {
+			return nil, errors.Wrap(err, "unmarshal paragraphs JSON")
+		}
+	}
 
 	return &article, nil
 }
@@ -116,8 +138,13 @@ func (ir *IErepo) UpdateArticleReading(ctx context.Context, articleReading *ie.A
 	if err != nil {
 		return nil, errors.Wrap(err, "marshal questions to JSON")
 	}
+	paragraphsJSON, err := json.Marshal(articleReading.Paragraphs)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal paragraphs to JSON")
+	}
 	query := ir.db.QueryBuilder.Update("article_reading").
 		Set("questions", questionsJSON).
+		Set("paragraphs", paragraphsJSON).
 		Set("article_status", articleReading.Status).
 		Where("id = ?", articleReading.ID).
 		Suffix("RETURNING id")
@@ -134,21 +161,3 @@ func (ir *IErepo) UpdateArticleReading(ctx context.Context, articleReading *ie.A
 
 	return articleReading, nil
 }
-
-func (ir *IErepo) UpdateArticleReadingStatus(ctx context.Context, id uint64, status string) error {
-	query := ir.db.QueryBuilder.Update("article_reading").
-		Set("article_status", status).
-		Where("id = ?", id)
-
-	sql, args, err := query.ToSql()
-	if err != nil {
-		return errors.Wrap(err, "build query")
-	}
-
-	_, err = ir.db.Exec(ctx, sql, args...)
-	if err != nil {
-		return errors.Wrap(err, "query row")
-	}
-
-	return nil
-}
@@ -2,16 +2,31 @@ package ierepo
 
 import (
 	"context"
+	"encoding/json"
 
-	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
+	"github.com/nhuongmh/cfvs.jpx/pkg/supporter/readlevel"
 	"github.com/pkg/errors"
 )
 
 func (ir *IErepo) Save(ctx context.Context, article *ie.Article) (*ie.Article, error) {
+	if article.Status == "" {
+		article.Status = ie.ARTICLE_NEW
+	}
+	difficulty, err := difficultyValues(article)
+	if err != nil {
+		return nil, err
+	}
+	values := []interface{}{article.Title, article.Content, article.Origin, article.Author, article.Image, article.PublishDate,
+		article.Status, article.CanonicalUrl, int64(article.Fingerprint)}
+	values = append(values, difficulty...)
 	query := ir.db.QueryBuilder.Insert("ie_articles").
-		Columns("title", "content", "origin", "author", "cover_image", "publish_date").
-		Values(article.Title, article.Content, article.Origin, article.Author, article.Image, article.PublishDate).
+		Columns("title", "content", "origin", "author", "cover_image", "publish_date", "status", "canonical_url", "fingerprint",
+			"word_count", "cefr_level", "ielts_band", "difficulty", "user_id").
+		Values(append(values, auth.UserIDFromContext(ctx))...).
 		Suffix("RETURNING id")
 
 	sql, args, err := query.ToSql()
@@ -24,13 +39,21 @@ func (ir *IErepo) Save(ctx context.Context, article *ie.Article) (*ie.Article, e
 		return nil, errors.Wrap(err, "query row")
 	}
 
+	if len(article.Tags) > 0 {
+		if err = ir.SetArticleTags(ctx, article.ID, article.Tags); err != nil {
+			return nil, err
+		}
+	}
+
 	return article, nil
 }
 
 func (ir *IErepo) FindByID(ctx context.Context, id uint64) (*ie.Article, error) {
-	query := ir.db.QueryBuilder.Select("id", "title", "content", "origin", "author", "cover_image", "publish_date", "created_at", "updated_at").
+	query := ir.db.QueryBuilder.Select("id", "title", "content", "origin", "author", "cover_image", "publish_date", "status",
+		"canonical_url", "COALESCE(fingerprint, 0)", "difficulty", "created_at", "updated_at").
 		From("ie_articles").
-		Where("id = ?", id)
+		Where("id = ?", id).
+		Where("user_id = ?", auth.UserIDFromContext(ctx))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -38,6 +61,8 @@ func (ir *IErepo) FindByID(ctx context.Context, id uint64) (*ie.Article, error)
 	}
 
 	var article ie.Article
+	var fingerprint int64
+	var difficultyJSON []byte
 	err = ir.db.QueryRow(ctx, sql, args...).Scan(
 		&article.ID,
 		&article.Title,
@@ -46,66 +71,30 @@ func (ir *IErepo) FindByID(ctx context.Context, id uint64) (*ie.Article, error)
 		&article.Author,
 		&article.Image,
 		&article.PublishDate,
+		&article.Status,
+		&article.CanonicalUrl,
+		&fingerprint,
+		&difficultyJSON,
 		&article.CreatedAt,
 		&article.UpdatedAt)
 	if err != nil {
 		return nil, errors.Wrap(err, "query row")
 	}
-
-	return &article, nil
-}
-
-// omit content
-func (ir *IErepo) FindAll(ctx context.Context, limit, skip uint64) ([]*ie.Article, int, error) {
-	query := ir.db.QueryBuilder.Select("id", "title", "origin", "author", "cover_image", "publish_date", "created_at", "updated_at").
-		From("ie_articles")
-
-	pageQuery := query.
-		Limit(limit).
-		Offset((skip - 1) * limit)
-
-	sql, args, err := pageQuery.ToSql()
-	if err != nil {
-		return nil, 0, errors.Wrap(err, "build query")
+	article.Fingerprint = uint64(fingerprint)
+	if article.Difficulty, err = parseDifficulty(difficultyJSON); err != nil {
+		return nil, err
 	}
-
-	logger.Log.Debug().Str("sql find all", sql).Msg("query")
-
-	rows, err := ir.db.Query(ctx, sql, args...)
-	if err != nil {
-		return nil, 0, errors.Wrap(err, "query")
+	if err = ir.loadArticleTags(ctx, []*ie.Article{&article}); err != nil {
+		return nil, err
 	}
-	defer rows.Close()
 
-	articles := []*ie.Article{}
-	for rows.Next() {
-		var article ie.Article
-		err = rows.Scan(
-			&article.ID,
-			&article.Title,
-			&article.Origin,
-			&article.Author,
-			&article.Image,
-			&article.PublishDate,
-			&article.CreatedAt,
-			&article.UpdatedAt)
-		if err != nil {
-			return nil, 0, errors.Wrap(err, "scan row")
-		}
-		articles = append(articles, &article)
-	}
-
-	total, err := ir.getTotalRowsOfQuery(ctx, query)
-	if err != nil {
-		logger.Log.Error().Err(err).Msg("failed to get total rows")
-		total = len(articles)
-	}
-
-	return articles, total, nil
+	return &article, nil
 }
 
 func (ir *IErepo) Delete(ctx context.Context, id uint64) error {
-	query := ir.db.QueryBuilder.Delete("ie_articles").Where("id = ?", id)
+	query := ir.db.QueryBuilder.Delete("ie_articles").
+		Where("id = ?", id).
+		Where("user_id = ?", auth.UserIDFromContext(ctx))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -121,6 +110,10 @@ func (ir *IErepo) Delete(ctx context.Context, id uint64) error {
 }
 
 func (ir *IErepo) Update(ctx context.Context, article *ie.Article) (*ie.Article, error) {
+	difficulty, err := difficultyValues(article)
+	if err != nil {
+		return nil, err
+	}
 	query := ir.db.QueryBuilder.Update("ie_articles").
 		Set("title", article.Title).
 		Set("content", article.Content).
@@ -128,7 +121,14 @@ func (ir *IErepo) Update(ctx context.Context, article *ie.Article) (*ie.Article,
 		Set("author", article.Author).
 		Set("cover_image", article.Image).
 		Set("publish_date", article.PublishDate).
+		Set("canonical_url", article.CanonicalUrl).
+		Set("fingerprint", int64(article.Fingerprint)).
+		Set("word_count", difficulty[0]).
+		Set("cefr_level", difficulty[1]).
+		Set("ielts_band", difficulty[2]).
+		Set("difficulty", difficulty[3]).
 		Where("id = ?", article.ID).
+		Where("user_id = ?", auth.UserIDFromContext(ctx)).
 		Suffix("RETURNING id")
 
 	sql, args, err := query.ToSql()
@@ -146,9 +146,11 @@ func (ir *IErepo) Update(ctx context.Context, article *ie.Article) (*ie.Article,
 
 // omit content
 func (ir *IErepo) FindByTitle(ctx context.Context, title string) ([]*ie.Article, error) {
-	query := ir.db.QueryBuilder.Select("id", "title", "origin", "author", "cover_image", "publish_date", "created_at", "updated_at").
+	query := ir.db.QueryBuilder.Select("id", "title", "origin", "author", "cover_image", "publish_date", "status", "canonical_url",
+		"created_at", "updated_at").
 		From("ie_articles").
-		Where("title = ?", title)
+		Where("title = ?", title).
+		Where("user_id = ?", auth.UserIDFromContext(ctx))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -171,6 +173,8 @@ func (ir *IErepo) FindByTitle(ctx context.Context, title string) ([]*ie.Article,
 			&article.Author,
 			&article.Image,
 			&article.PublishDate,
+			&article.Status,
+			&article.CanonicalUrl,
 			&article.CreatedAt,
 			&article.UpdatedAt)
 		if err != nil {
@@ -181,3 +185,161 @@ func (ir *IErepo) FindByTitle(ctx context.Context, title string) ([]*ie.Article,
 
 	return articles, nil
 }
+
+// FindByCanonicalUrl finds the article of the canonical url, nil when there is none
+func (ir *IErepo) FindByCanonicalUrl(ctx context.Context, canonicalUrl string) (*ie.Article, error) {
+	query := ir.db.QueryBuilder.Select("id", "title", "origin", "author", "cover_image", "publish_date", "status", "canonical_url",
+		"created_at", "updated_at").
+		From("ie_articles").
+		Where("canonical_url = ?", canonicalUrl).
+		Where("user_id = ?", auth.UserIDFromContext(ctx)).
+		OrderBy("id").
+		Limit(1)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "build query")
+	}
+
+	var article ie.Article
+	err = ir.db.QueryRow(ctx, sql, args...).Scan(
+		&article.ID,
+		&article.Title,
+		&article.Origin,
+		&article.Author,
+		&article.Image,
+		&article.PublishDate,
+		&article.Status,
+		&article.CanonicalUrl,
+		&article.CreatedAt,
+		&article.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "query row")
+	}
+
+	return &article, nil
+}
+
+// FindFingerprints returns the fingerprints of the articles of the user by article id
+func (ir *IErepo) FindFingerprints(ctx context.Context) (map[uint64]uint64, error) {
+	query := ir.db.QueryBuilder.Select("id", "fingerprint").
+		From("ie_articles").
+		Where("user_id = ?", auth.UserIDFromContext(ctx)).
+		Where("fingerprint IS NOT NULL").
+		Where("fingerprint <> 0")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "build query")
+	}
+
+	rows, err := ir.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query")
+	}
+	defer rows.Close()
+
+	fingerprints := map[uint64]uint64{}
+	for rows.Next() {
+		var id uint64
+		var fingerprint int64
+		if err = rows.Scan(&id, &fingerprint); err != nil {
+			return nil, errors.Wrap(err, "scan row")
+		}
+		fingerprints[id] = uint64(fingerprint)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "rows iteration")
+	}
+
+	return fingerprints, nil
+}
+
+// FindUnanalyzed returns articles of every user saved before fingerprints or difficulty analysis,
+// with their origin, canonical url and content
+func (ir *IErepo) FindUnanalyzed(ctx context.Context, limit uint64) ([]*ie.Article, error) {
+	query := ir.db.QueryBuilder.Select("id", "origin", "canonical_url", "content").
+		From("ie_articles").
+		Where(sq.Or{sq.Eq{"fingerprint": nil}, sq.Eq{"difficulty": nil}}).
+		OrderBy("id").
+		Limit(limit)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "build query")
+	}
+
+	rows, err := ir.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query")
+	}
+	defer rows.Close()
+
+	articles := []*ie.Article{}
+	for rows.Next() {
+		var article ie.Article
+		if err = rows.Scan(&article.ID, &article.Origin, &article.CanonicalUrl, &article.Content); err != nil {
+			return nil, errors.Wrap(err, "scan row")
+		}
+		articles = append(articles, &article)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "rows iteration")
+	}
+
+	return articles, nil
+}
+
+// UpdateAnalysis saves the canonical url, fingerprint and difficulty of the article
+func (ir *IErepo) UpdateAnalysis(ctx context.Context, article *ie.Article) error {
+	difficulty, err := difficultyValues(article)
+	if err != nil {
+		return err
+	}
+	query := ir.db.QueryBuilder.Update("ie_articles").
+		Set("canonical_url", article.CanonicalUrl).
+		Set("fingerprint", int64(article.Fingerprint)).
+		Set("word_count", difficulty[0]).
+		Set("cefr_level", difficulty[1]).
+		Set("ielts_band", difficulty[2]).
+		Set("difficulty", difficulty[3]).
+		Where("id = ?", article.ID)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "build query")
+	}
+
+	_, err = ir.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return errors.Wrap(err, "exec")
+	}
+	return nil
+}
+
+// difficultyValues returns the word_count, cefr_level, ielts_band and difficulty columns of the article,
+// a NULL difficulty when it is not analyzed
+func difficultyValues(article *ie.Article) ([]interface{}, error) {
+	if article.Difficulty == nil {
+		return []interface{}{0, "", 0.0, nil}, nil
+	}
+	difficultyJSON, err := json.Marshal(article.Difficulty)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal difficulty to JSON")
+	}
+	return []interface{}{article.Difficulty.Words, article.Difficulty.CEFR, article.Difficulty.IeltsBand, difficultyJSON}, nil
+}
+
+func parseDifficulty(difficultyJSON []byte) (*readlevel.Stats, error) {
+	if len(difficultyJSON) == 0 {
+		return nil, nil
+	}
+	var difficulty readlevel.Stats
+	if err := json.Unmarshal(difficultyJSON, &difficulty); err != nil {
+		return nil, errors.Wrap(err, "unmarshal difficulty JSON")
+	}
+	return &difficulty, nil
+}
@@ -0,0 +1,82 @@
+package ierepo
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
+	"github.com/pkg/errors"
+)
+
+// SetArticleTags replaces the tags of the article, they must be normalized
+func (ir *IErepo) SetArticleTags(ctx context.Context, articleID uint64, tags []string) error {
+	sql, args, err := ir.db.QueryBuilder.Delete("ie_article_tags").Where("article_id = ?", articleID).ToSql()
+	if err != nil {
+		return errors.Wrap(err, "build query")
+	}
+	_, err = ir.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return errors.Wrap(err, "exec")
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	query := ir.db.QueryBuilder.Insert("ie_article_tags").Columns("article_id", "tag")
+	for _, tag := range tags {
+		query = query.Values(articleID, tag)
+	}
+	sql, args, err = query.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "build query")
+	}
+	_, err = ir.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return errors.Wrap(err, "exec")
+	}
+	return nil
+}
+
+// loadArticleTags fills the tags of the given articles
+func (ir *IErepo) loadArticleTags(ctx context.Context, articles []*ie.Article) error {
+	byID := make(map[uint64]*ie.Article, len(articles))
+	ids := []uint64{}
+	for _, article := range articles {
+		article.Tags = []string{}
+		byID[article.ID] = article
+		ids = append(ids, article.ID)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	sql, args, err := ir.db.QueryBuilder.Select("article_id", "tag").
+		From("ie_article_tags").
+		Where(sq.Eq{"article_id": ids}).
+		OrderBy("tag").
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "build query")
+	}
+
+	rows, err := ir.db.Query(ctx, sql, args...)
+	if err != nil {
+		return errors.Wrap(err, "query")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var articleID uint64
+		var tag string
+		if err = rows.Scan(&articleID, &tag); err != nil {
+			return errors.Wrap(err, "scan row")
+		}
+		if article, ok := byID[articleID]; ok {
+			article.Tags = append(article.Tags, tag)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return errors.Wrap(err, "rows iteration")
+	}
+	return nil
+}
@@ -4,10 +4,46 @@ import (
 	"context"
 	"encoding/json"
 
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
 	"github.com/pkg/errors"
 )
 
+var testResultColumns = []string{"id", "article_reading_id", "questions_result", "score", "COALESCE(test_session_id, 0)",
+	"module", "raw_score", "band", "elapsed_seconds", "created_at", "updated_at"}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTestResult(row rowScanner, testResult *ie.TestResult) error {
+	var questionsJSON []byte
+	err := row.Scan(
+		&testResult.ID,
+		&testResult.ArticleReadingId,
+		&questionsJSON,
+		&testResult.Score,
+		&testResult.TestSessionID,
+		&testResult.Module,
+		&testResult.RawScore,
+		&testResult.Band,
+		&testResult.ElapsedSeconds,
+		&testResult.CreatedAt,
+		&testResult.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(json.Unmarshal(questionsJSON, &testResult.QuestionResults), "unmarshal questions JSON")
+}
+
+// nullableID stores 0 as NULL in a nullable reference
+func nullableID(id uint64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
 func (ir *IErepo) SaveTestSubmission(ctx context.Context, testResult *ie.TestResult) (*ie.TestResult, error) {
 	questionsJSON, err := json.Marshal(testResult.QuestionResults)
 	if err != nil {
@@ -15,8 +51,10 @@ func (ir *IErepo) SaveTestSubmission(ctx context.Context, testResult *ie.TestRes
 	}
 
 	query := ir.db.QueryBuilder.Insert("article_test_result").
-		Columns("article_reading_id", "questions_result", "score").
-		Values(testResult.ArticleReadingId, questionsJSON, testResult.Score).
+		Columns("article_reading_id", "questions_result", "score", "test_session_id", "module", "raw_score", "band",
+			"elapsed_seconds", "user_id").
+		Values(testResult.ArticleReadingId, questionsJSON, testResult.Score, nullableID(testResult.TestSessionID),
+			testResult.Module, testResult.RawScore, testResult.Band, testResult.ElapsedSeconds, auth.UserIDFromContext(ctx)).
 		Suffix("RETURNING id")
 
 	sql, args, err := query.ToSql()
@@ -33,9 +71,10 @@ func (ir *IErepo) SaveTestSubmission(ctx context.Context, testResult *ie.TestRes
 }
 
 func (ir *IErepo) GetTestSubmissionById(ctx context.Context, id uint64) (*ie.TestResult, error) {
-	query := ir.db.QueryBuilder.Select("id", "article_reading_id", "questions_result", "score", "created_at", "updated_at").
+	query := ir.db.QueryBuilder.Select(testResultColumns...).
 		From("article_test_result").
-		Where("id = ?", id)
+		Where("id = ?", id).
+		Where("user_id = ?", auth.UserIDFromContext(ctx))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -43,28 +82,18 @@ func (ir *IErepo) GetTestSubmissionById(ctx context.Context, id uint64) (*ie.Tes
 	}
 
 	var testResult ie.TestResult
-	var questionsJSON []byte
-	err = ir.db.QueryRow(ctx, sql, args...).Scan(
-		&testResult.ID,
-		&testResult.ArticleReadingId,
-		&questionsJSON,
-		&testResult.Score,
-		&testResult.CreatedAt,
-		&testResult.UpdatedAt)
+	err = scanTestResult(ir.db.QueryRow(ctx, sql, args...), &testResult)
 	if err != nil {
 		return nil, errors.Wrap(err, "query row")
 	}
-	err = json.Unmarshal(questionsJSON, &testResult.QuestionResults)
-	if err != nil {
-		return nil, errors.Wrap(err, "unmarshal questions JSON")
-	}
 	return &testResult, nil
 }
 
 func (ir *IErepo) FindSubmissionByReadingId(ctx context.Context, readingId uint64) (*[]ie.TestResult, error) {
-	query := ir.db.QueryBuilder.Select("id", "article_reading_id", "questions_result", "score", "created_at", "updated_at").
+	query := ir.db.QueryBuilder.Select(testResultColumns...).
 		From("article_test_result").
-		Where("article_reading_id = ?", readingId)
+		Where("article_reading_id = ?", readingId).
+		Where("user_id = ?", auth.UserIDFromContext(ctx))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -80,29 +109,20 @@ func (ir *IErepo) FindSubmissionByReadingId(ctx context.Context, readingId uint6
 	var testResults []ie.TestResult
 	for rows.Next() {
 		var testResult ie.TestResult
-		var questionsJSON []byte
-		err = rows.Scan(
-			&testResult.ID,
-			&testResult.ArticleReadingId,
-			&questionsJSON,
-			&testResult.Score,
-			&testResult.CreatedAt,
-			&testResult.UpdatedAt)
+		err = scanTestResult(rows, &testResult)
 		if err != nil {
 			return nil, errors.Wrap(err, "scan row")
 		}
-
-		err = json.Unmarshal(questionsJSON, &testResult.QuestionResults)
-		if err != nil {
-			return nil, errors.Wrap(err, "unmarshal questions JSON")
-		}
+		testResults = append(testResults, testResult)
 	}
 
 	return &testResults, nil
 }
 
 func (ir *IErepo) DeleteSubmission(ctx context.Context, id uint64) error {
-	query := ir.db.QueryBuilder.Delete("article_test_result").Where("id = ?", id)
+	query := ir.db.QueryBuilder.Delete("article_test_result").
+		Where("id = ?", id).
+		Where("user_id = ?", auth.UserIDFromContext(ctx))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -0,0 +1,75 @@
+package ierepo
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
+	"github.com/pkg/errors"
+)
+
+// the status only changes when it is still the one the transition starts from,
+// and the change is recorded in the same statement
+const transitionArticleSql = `WITH updated AS (
+	UPDATE ie_articles SET status = $1 WHERE id = $2 AND user_id = $3 AND status = $4 RETURNING id
+)
+INSERT INTO ie_article_transitions (article_id, event, from_status, to_status)
+SELECT id, $5, $4, $1 FROM updated
+RETURNING id, created_at`
+
+// TransitionArticle moves the article of the user to the status of the transition and records it,
+// false when the article is not in the status the transition starts from anymore
+func (ir *IErepo) TransitionArticle(ctx context.Context, transition *ie.ArticleTransition) (bool, error) {
+	err := ir.db.QueryRow(ctx, transitionArticleSql, transition.ToStatus, transition.ArticleID, auth.UserIDFromContext(ctx),
+		transition.FromStatus, transition.Event).Scan(&transition.ID, &transition.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "query row")
+	}
+	return true, nil
+}
+
+// FindArticleTransitions returns the status changes of the article, oldest first
+func (ir *IErepo) FindArticleTransitions(ctx context.Context, articleID uint64) ([]*ie.ArticleTransition, error) {
+	query := ir.db.QueryBuilder.Select("t.id", "t.article_id", "t.event", "t.from_status", "t.to_status", "t.created_at").
+		From("ie_article_transitions t").
+		Join("ie_articles a ON a.id = t.article_id").
+		Where("t.article_id = ?", articleID).
+		Where("a.user_id = ?", auth.UserIDFromContext(ctx)).
+		OrderBy("t.id")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "build query")
+	}
+
+	rows, err := ir.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query")
+	}
+	defer rows.Close()
+
+	transitions := []*ie.ArticleTransition{}
+	for rows.Next() {
+		var transition ie.ArticleTransition
+		err = rows.Scan(
+			&transition.ID,
+			&transition.ArticleID,
+			&transition.Event,
+			&transition.FromStatus,
+			&transition.ToStatus,
+			&transition.CreatedAt)
+		if err != nil {
+			return nil, errors.Wrap(err, "scan row")
+		}
+		transitions = append(transitions, &transition)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "rows iteration")
+	}
+
+	return transitions, nil
+}
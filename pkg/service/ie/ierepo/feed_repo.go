@@ -0,0 +1,280 @@
+package ierepo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
+	"github.com/pkg/errors"
+)
+
+var feedColumns = []string{"id", "url", "title", "filter", "gen_reading", "enabled", "last_polled_at", "last_error", "user_id",
+	"created_at", "updated_at"}
+
+func (ir *IErepo) SaveFeed(ctx context.Context, feed *ie.Feed) (*ie.Feed, error) {
+	filterJSON, err := json.Marshal(feed.Filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal filter to JSON")
+	}
+	feed.UserID = auth.UserIDFromContext(ctx)
+	query := ir.db.QueryBuilder.Insert("ie_feeds").
+		Columns("url", "title", "filter", "gen_reading", "enabled", "user_id").
+		Values(feed.Url, feed.Title, filterJSON, feed.GenReading, feed.Enabled, feed.UserID).
+		Suffix("RETURNING id, created_at, updated_at")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "build query")
+	}
+
+	err = ir.db.QueryRow(ctx, sql, args...).Scan(&feed.ID, &feed.CreatedAt, &feed.UpdatedAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "query row")
+	}
+
+	return feed, nil
+}
+
+func (ir *IErepo) FindFeedByID(ctx context.Context, id uint64) (*ie.Feed, error) {
+	query := ir.db.QueryBuilder.Select(feedColumns...).
+		From("ie_feeds").
+		Where("id = ?", id).
+		Where("user_id = ?", auth.UserIDFromContext(ctx))
+
+	feeds, err := ir.queryFeeds(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(feeds) == 0 {
+		return nil, errors.Wrap(pgx.ErrNoRows, "query row")
+	}
+	return feeds[0], nil
+}
+
+func (ir *IErepo) FindAllFeeds(ctx context.Context) ([]*ie.Feed, error) {
+	query := ir.db.QueryBuilder.Select(feedColumns...).
+		From("ie_feeds").
+		Where("user_id = ?", auth.UserIDFromContext(ctx)).
+		OrderBy("id")
+
+	return ir.queryFeeds(ctx, query)
+}
+
+// FindFeedsToPoll returns the enabled feeds of every user not polled for the given interval.
+// Times are compared in the database, like the poll times are written.
+func (ir *IErepo) FindFeedsToPoll(ctx context.Context, interval time.Duration) ([]*ie.Feed, error) {
+	query := ir.db.QueryBuilder.Select(feedColumns...).
+		From("ie_feeds").
+		Where("enabled").
+		Where(sq.Or{
+			sq.Eq{"last_polled_at": nil},
+			sq.Expr("last_polled_at <= CURRENT_TIMESTAMP - make_interval(secs => ?)", interval.Seconds()),
+		}).
+		OrderBy("last_polled_at NULLS FIRST", "id")
+
+	return ir.queryFeeds(ctx, query)
+}
+
+func (ir *IErepo) queryFeeds(ctx context.Context, query sq.SelectBuilder) ([]*ie.Feed, error) {
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "build query")
+	}
+
+	rows, err := ir.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query")
+	}
+	defer rows.Close()
+
+	feeds := []*ie.Feed{}
+	for rows.Next() {
+		var feed ie.Feed
+		var filterJSON []byte
+		err = rows.Scan(
+			&feed.ID,
+			&feed.Url,
+			&feed.Title,
+			&filterJSON,
+			&feed.GenReading,
+			&feed.Enabled,
+			&feed.LastPolledAt,
+			&feed.LastError,
+			&feed.UserID,
+			&feed.CreatedAt,
+			&feed.UpdatedAt)
+		if err != nil {
+			return nil, errors.Wrap(err, "scan row")
+		}
+		err = json.Unmarshal(filterJSON, &feed.Filter)
+		if err != nil {
+			return nil, errors.Wrap(err, "unmarshal filter JSON")
+		}
+		feeds = append(feeds, &feed)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "rows iteration")
+	}
+
+	return feeds, nil
+}
+
+func (ir *IErepo) UpdateFeed(ctx context.Context, feed *ie.Feed) (*ie.Feed, error) {
+	filterJSON, err := json.Marshal(feed.Filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal filter to JSON")
+	}
+	query := ir.db.QueryBuilder.Update("ie_feeds").
+		Set("url", feed.Url).
+		Set("title", feed.Title).
+		Set("filter", filterJSON).
+		Set("gen_reading", feed.GenReading).
+		Set("enabled", feed.Enabled).
+		Where("id = ?", feed.ID).
+		Where("user_id = ?", auth.UserIDFromContext(ctx)).
+		Suffix("RETURNING updated_at")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "build query")
+	}
+
+	err = ir.db.QueryRow(ctx, sql, args...).Scan(&feed.UpdatedAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "query row")
+	}
+
+	return feed, nil
+}
+
+// UpdateFeedPolled records the outcome of a poll now, lastError is empty when it succeeded
+func (ir *IErepo) UpdateFeedPolled(ctx context.Context, id uint64, lastError string) error {
+	query := ir.db.QueryBuilder.Update("ie_feeds").
+		Set("last_polled_at", sq.Expr("CURRENT_TIMESTAMP")).
+		Set("last_error", lastError).
+		Where("id = ?", id)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "build query")
+	}
+
+	_, err = ir.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return errors.Wrap(err, "exec")
+	}
+	return nil
+}
+
+// DeleteFeed deletes the feed and its entries, imported articles are kept
+func (ir *IErepo) DeleteFeed(ctx context.Context, id uint64) error {
+	query := ir.db.QueryBuilder.Delete("ie_feeds").
+		Where("id = ?", id).
+		Where("user_id = ?", auth.UserIDFromContext(ctx))
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "build query")
+	}
+
+	_, err = ir.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return errors.Wrap(err, "exec")
+	}
+	return nil
+}
+
+// FindFeedEntries returns the handled entries of the feed among the given guids, by guid
+func (ir *IErepo) FindFeedEntries(ctx context.Context, feedID uint64, guids []string) (map[string]*ie.FeedEntry, error) {
+	entries := map[string]*ie.FeedEntry{}
+	if len(guids) == 0 {
+		return entries, nil
+	}
+	query := ir.db.QueryBuilder.Select("id", "feed_id", "guid", "link", "article_id", "status", "reason", "attempts",
+		"created_at", "updated_at").
+		From("ie_feed_entries").
+		Where("feed_id = ?", feedID).
+		Where(sq.Eq{"guid": guids})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "build query")
+	}
+
+	rows, err := ir.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry ie.FeedEntry
+		err = rows.Scan(
+			&entry.ID,
+			&entry.FeedID,
+			&entry.Guid,
+			&entry.Link,
+			&entry.ArticleID,
+			&entry.Status,
+			&entry.Reason,
+			&entry.Attempts,
+			&entry.CreatedAt,
+			&entry.UpdatedAt)
+		if err != nil {
+			return nil, errors.Wrap(err, "scan row")
+		}
+		entries[entry.Guid] = &entry
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "rows iteration")
+	}
+
+	return entries, nil
+}
+
+// SaveFeedEntry records how the entry was handled, counting the attempts of entries handled again
+func (ir *IErepo) SaveFeedEntry(ctx context.Context, entry *ie.FeedEntry) error {
+	query := ir.db.QueryBuilder.Insert("ie_feed_entries").
+		Columns("feed_id", "guid", "link", "article_id", "status", "reason").
+		Values(entry.FeedID, entry.Guid, entry.Link, entry.ArticleID, entry.Status, entry.Reason).
+		Suffix(`ON CONFLICT (feed_id, guid) DO UPDATE SET link = EXCLUDED.link, article_id = EXCLUDED.article_id,
+			status = EXCLUDED.status, reason = EXCLUDED.reason, attempts = ie_feed_entries.attempts + 1,
+			updated_at = CURRENT_TIMESTAMP`).
+		Suffix("RETURNING id, attempts")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "build query")
+	}
+
+	err = ir.db.QueryRow(ctx, sql, args...).Scan(&entry.ID, &entry.Attempts)
+	if err != nil {
+		return errors.Wrap(err, "query row")
+	}
+	return nil
+}
+
+// CountFeedImportsToday counts the articles imported from the feed since the start of the day
+func (ir *IErepo) CountFeedImportsToday(ctx context.Context, feedID uint64) (int, error) {
+	query := ir.db.QueryBuilder.Select("COUNT(*)").
+		From("ie_feed_entries").
+		Where("feed_id = ?", feedID).
+		Where("status = ?", ie.FEED_ENTRY_IMPORTED).
+		Where("updated_at >= CURRENT_DATE")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return 0, errors.Wrap(err, "build query")
+	}
+
+	var count int
+	err = ir.db.QueryRow(ctx, sql, args...).Scan(&count)
+	if err != nil {
+		return 0, errors.Wrap(err, "query row")
+	}
+	return count, nil
+}
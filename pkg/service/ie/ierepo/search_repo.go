@@ -0,0 +1,128 @@
+package ierepo
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nhuongmh/cfvs.jpx/pkg/database/postgresdb"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/search"
+	"github.com/pkg/errors"
+)
+
+// containsTerms matches the rows whose trigram indexed search_text column contains every term
+func containsTerms(column string, terms []string) sq.And {
+	cond := sq.And{}
+	for _, term := range terms {
+		cond = append(cond, sq.Expr(column+" LIKE ?", postgresdb.ContainsPattern(term)))
+	}
+	return cond
+}
+
+// SearchArticles ranks the articles of the user containing every term, title matches weigh most
+func (ir *IErepo) SearchArticles(ctx context.Context, terms []string, limit int) ([]search.Hit, error) {
+	tsQuery := postgresdb.PrefixTsQuery(terms)
+	query := ir.db.QueryBuilder.Select("id", "title", "content").
+		Column(sq.Expr("ts_rank(search_vector, to_tsquery('simple', ?)) AS score", tsQuery)).
+		From("ie_articles").
+		Where(containsTerms("search_text", terms)).
+		Where("user_id = ?", auth.UserIDFromContext(ctx)).
+		OrderBy("score DESC", "id").
+		Limit(uint64(limit))
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "build query")
+	}
+
+	rows, err := ir.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query")
+	}
+	defer rows.Close()
+
+	hits := []search.Hit{}
+	for rows.Next() {
+		var article ie.Article
+		var score float32
+		err = rows.Scan(&article.ID, &article.Title, &article.Content, &score)
+		if err != nil {
+			return nil, errors.Wrap(err, "scan row")
+		}
+		hits = append(hits, search.Hit{
+			Kind:    search.KIND_ARTICLE,
+			ID:      article.ID,
+			Title:   article.Title,
+			Snippet: search.Snippet(article.Content, terms),
+			Score:   float64(score),
+		})
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "rows iteration")
+	}
+
+	return hits, nil
+}
+
+// SearchVocabs ranks the words of the user's vocab lists containing every term in the word or its definitions
+func (ir *IErepo) SearchVocabs(ctx context.Context, terms []string, limit int) ([]search.Hit, error) {
+	tsQuery := postgresdb.PrefixTsQuery(terms)
+	query := ir.db.QueryBuilder.Select("ie_vocab.id", "ie_vocab.vocab", "ie_vocab.defs").
+		Column(sq.Expr("ts_rank(ie_vocab.search_vector, to_tsquery('simple', ?)) AS score", tsQuery)).
+		From("ie_vocab").
+		Join("ie_vocab_list ON ie_vocab_list.id = ie_vocab.vocab_list_id").
+		Where(containsTerms("ie_vocab.search_text", terms)).
+		Where("ie_vocab_list.user_id = ?", auth.UserIDFromContext(ctx)).
+		OrderBy("score DESC", "ie_vocab.id").
+		Limit(uint64(limit))
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "build query")
+	}
+
+	rows, err := ir.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query")
+	}
+	defer rows.Close()
+
+	hits := []search.Hit{}
+	for rows.Next() {
+		var word ie.IeVocab
+		var defJSON []byte
+		var score float32
+		err = rows.Scan(&word.ID, &word.Word, &defJSON, &score)
+		if err != nil {
+			return nil, errors.Wrap(err, "scan row")
+		}
+		err = json.Unmarshal(defJSON, &word.Definitions)
+		if err != nil {
+			return nil, errors.Wrap(err, "unmarshal definitions JSON")
+		}
+		hits = append(hits, search.Hit{
+			Kind:    search.KIND_VOCAB,
+			ID:      word.ID,
+			Title:   word.Word,
+			Snippet: search.Snippet(vocabSearchText(&word), terms),
+			Score:   float64(score),
+		})
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "rows iteration")
+	}
+
+	return hits, nil
+}
+
+// vocabSearchText is the word followed by its definitions
+func vocabSearchText(word *ie.IeVocab) string {
+	parts := []string{word.Word}
+	for _, def := range word.Definitions {
+		parts = append(parts, def.Text)
+	}
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,176 @@
+package ierepo
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
+	"github.com/pkg/errors"
+)
+
+// the status and the remaining time are derived with the clock of the database, which also set the deadline
+var testSessionColumns = []string{"s.id", "s.article_reading_id", "s.module", "s.duration_seconds", "s.started_at",
+	"s.deadline", "s.submitted_at",
+	fmt.Sprintf("CASE WHEN s.submitted_at IS NOT NULL THEN '%s' WHEN CURRENT_TIMESTAMP > s.deadline THEN '%s' ELSE '%s' END",
+		ie.TEST_SESSION_SUBMITTED, ie.TEST_SESSION_EXPIRED, ie.TEST_SESSION_IN_PROGRESS),
+	"CASE WHEN s.submitted_at IS NULL THEN GREATEST(0, CEIL(EXTRACT(EPOCH FROM s.deadline - CURRENT_TIMESTAMP)))::INTEGER ELSE 0 END",
+	"COALESCE(r.id, 0)", "s.created_at", "s.updated_at"}
+
+// the session is only submitted once, and not after the deadline and its grace
+const submitTestSessionSql = `UPDATE ie_test_sessions SET submitted_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND user_id = $2 AND submitted_at IS NULL AND CURRENT_TIMESTAMP <= deadline + make_interval(secs => $3::INTEGER)
+RETURNING submitted_at, EXTRACT(EPOCH FROM submitted_at - started_at)::INTEGER`
+
+// SaveTestSession starts the timed test of the user now, the deadline is the duration from now
+func (ir *IErepo) SaveTestSession(ctx context.Context, session *ie.TestSession) (*ie.TestSession, error) {
+	query := ir.db.QueryBuilder.Insert("ie_test_sessions").
+		Columns("article_reading_id", "module", "duration_seconds", "deadline", "user_id").
+		Values(session.ArticleReadingID, session.Module, session.DurationSeconds,
+			sq.Expr("CURRENT_TIMESTAMP + make_interval(secs => ?::INTEGER)", session.DurationSeconds), auth.UserIDFromContext(ctx)).
+		Suffix("RETURNING id, started_at, deadline, created_at, updated_at")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "build query")
+	}
+
+	err = ir.db.QueryRow(ctx, sql, args...).Scan(&session.ID, &session.StartedAt, &session.Deadline,
+		&session.CreatedAt, &session.UpdatedAt)
+	if err != nil {
+		return nil, errors.Wrap(err, "query row")
+	}
+	session.Status = ie.TEST_SESSION_IN_PROGRESS
+	session.RemainingSeconds = session.DurationSeconds
+	return session, nil
+}
+
+func (ir *IErepo) FindTestSessionByID(ctx context.Context, id uint64) (*ie.TestSession, error) {
+	query := ir.db.QueryBuilder.Select(testSessionColumns...).
+		From("ie_test_sessions s").
+		LeftJoin("article_test_result r ON r.test_session_id = s.id").
+		Where("s.id = ?", id).
+		Where("s.user_id = ?", auth.UserIDFromContext(ctx))
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "build query")
+	}
+
+	var session ie.TestSession
+	err = ir.db.QueryRow(ctx, sql, args...).Scan(
+		&session.ID,
+		&session.ArticleReadingID,
+		&session.Module,
+		&session.DurationSeconds,
+		&session.StartedAt,
+		&session.Deadline,
+		&session.SubmittedAt,
+		&session.Status,
+		&session.RemainingSeconds,
+		&session.TestResultID,
+		&session.CreatedAt,
+		&session.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, errors.Wrapf(ie.ErrTestSessionNotFound, "test session %d", id)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "query row")
+	}
+	return &session, nil
+}
+
+// SubmitTestSession marks the session of the user submitted and returns the seconds since its start,
+// false when it was submitted already or its deadline passed
+func (ir *IErepo) SubmitTestSession(ctx context.Context, session *ie.TestSession, graceSeconds int) (int, bool, error) {
+	var elapsed int
+	err := ir.db.QueryRow(ctx, submitTestSessionSql, session.ID, auth.UserIDFromContext(ctx), graceSeconds).
+		Scan(&session.SubmittedAt, &elapsed)
+	if err == pgx.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, errors.Wrap(err, "query row")
+	}
+	session.Status = ie.TEST_SESSION_SUBMITTED
+	session.RemainingSeconds = 0
+	return elapsed, true, nil
+}
+
+// ReopenTestSession clears the submission of the session of the user, when its result could not be saved
+func (ir *IErepo) ReopenTestSession(ctx context.Context, session *ie.TestSession) error {
+	query := ir.db.QueryBuilder.Update("ie_test_sessions").
+		Set("submitted_at", nil).
+		Where("id = ?", session.ID).
+		Where("user_id = ?", auth.UserIDFromContext(ctx))
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "build query")
+	}
+
+	_, err = ir.db.Exec(ctx, sql, args...)
+	if err != nil {
+		return errors.Wrap(err, "exec query")
+	}
+	session.SubmittedAt = nil
+	return nil
+}
+
+// FindBandHistory returns the bands of the graded tests of the user, oldest first
+func (ir *IErepo) FindBandHistory(ctx context.Context, q *ie.BandHistoryQuery) ([]ie.BandHistoryEntry, error) {
+	query := ir.db.QueryBuilder.Select("r.id", "COALESCE(r.test_session_id, 0)", "COALESCE(a.id, 0)", "COALESCE(a.title, '')",
+		"r.score", "r.raw_score", "r.band", "r.elapsed_seconds", "r.created_at").
+		From("article_test_result r").
+		LeftJoin("article_reading ar ON ar.id = r.article_reading_id").
+		LeftJoin("ie_articles a ON a.id = ar.article_id").
+		Where("r.user_id = ?", auth.UserIDFromContext(ctx)).
+		Where("r.module = ?", q.Module).
+		OrderBy("r.created_at", "r.id")
+	if q.TimedOnly {
+		query = query.Where("r.test_session_id IS NOT NULL")
+	}
+	if q.From != nil {
+		query = query.Where("r.created_at >= ?", *q.From)
+	}
+	if q.To != nil {
+		query = query.Where("r.created_at < ?", *q.To)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "build query")
+	}
+
+	rows, err := ir.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query")
+	}
+	defer rows.Close()
+
+	entries := []ie.BandHistoryEntry{}
+	for rows.Next() {
+		var entry ie.BandHistoryEntry
+		err = rows.Scan(
+			&entry.TestResultID,
+			&entry.TestSessionID,
+			&entry.ArticleID,
+			&entry.ArticleTitle,
+			&entry.Score,
+			&entry.RawScore,
+			&entry.Band,
+			&entry.ElapsedSeconds,
+			&entry.CreatedAt)
+		if err != nil {
+			return nil, errors.Wrap(err, "scan row")
+		}
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "rows iteration")
+	}
+
+	return entries, nil
+}
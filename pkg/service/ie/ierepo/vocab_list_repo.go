@@ -4,14 +4,15 @@ import (
 	"context"
 
 	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
 	"github.com/pkg/errors"
 )
 
 func (ir *IErepo) SaveVocabList(ctx context.Context, list *ie.IeVocabList) (*ie.IeVocabList, error) {
 	query := ir.db.QueryBuilder.Insert("ie_vocab_list").
-		Columns("name", "article_id").
-		Values(list.Name, list.RefArticleID).
+		Columns("name", "article_id", "user_id").
+		Values(list.Name, list.RefArticleID, auth.UserIDFromContext(ctx)).
 		Suffix("RETURNING id")
 
 	sql, args, err := query.ToSql()
@@ -38,7 +39,8 @@ func (ir *IErepo) SaveVocabList(ctx context.Context, list *ie.IeVocabList) (*ie.
 
 func (ir *IErepo) GetAllVocabList(ctx context.Context, fetchVocabs bool, limit, skip uint64) (*[]ie.IeVocabList, int, error) {
 	query := ir.db.QueryBuilder.Select("id", "name", "article_id", "created_at", "updated_at").
-		From("ie_vocab_list")
+		From("ie_vocab_list").
+		Where("user_id = ?", auth.UserIDFromContext(ctx))
 
 	pageQuery := query.
 		Limit(limit).
@@ -91,7 +93,8 @@ func (ir *IErepo) GetAllVocabList(ctx context.Context, fetchVocabs bool, limit,
 func (ir *IErepo) GetAllVocabListByArticleId(ctx context.Context, articleId uint64) (*ie.IeVocabList, error) {
 	query := ir.db.QueryBuilder.Select("id", "name", "article_id", "created_at", "updated_at").
 		From("ie_vocab_list").
-		Where("article_id = ?", articleId)
+		Where("article_id = ?", articleId).
+		Where("user_id = ?", auth.UserIDFromContext(ctx))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -119,7 +122,8 @@ func (ir *IErepo) GetAllVocabListByArticleId(ctx context.Context, articleId uint
 func (ir *IErepo) FindVocabListByID(ctx context.Context, id uint64, fetchVocab bool) (*ie.IeVocabList, error) {
 	query := ir.db.QueryBuilder.Select("id", "name", "article_id", "created_at", "updated_at").
 		From("ie_vocab_list").
-		Where("id = ?", id)
+		Where("id = ?", id).
+		Where("user_id = ?", auth.UserIDFromContext(ctx))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -147,7 +151,9 @@ func (ir *IErepo) FindVocabListByID(ctx context.Context, id uint64, fetchVocab b
 }
 
 func (ir *IErepo) DeleteVocabList(ctx context.Context, id uint64) error {
-	query := ir.db.QueryBuilder.Delete("ie_vocab_list").Where("id = ?", id)
+	query := ir.db.QueryBuilder.Delete("ie_vocab_list").
+		Where("id = ?", id).
+		Where("user_id = ?", auth.UserIDFromContext(ctx))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
@@ -166,17 +172,21 @@ func (ir *IErepo) UpdateVocabList(ctx context.Context, list *ie.IeVocabList) (*i
 	query := ir.db.QueryBuilder.Update("ie_vocab_list").
 		Set("name", list.Name).
 		Set("article_id", list.RefArticleID).
-		Where("id = ?", list.ID)
+		Where("id = ?", list.ID).
+		Where("user_id = ?", auth.UserIDFromContext(ctx))
 
 	sql, args, err := query.ToSql()
 	if err != nil {
 		return nil, errors.Wrap(err, "build query")
 	}
 
-	_, err = ir.db.Exec(ctx, sql, args...)
+	tag, err := ir.db.Exec(ctx, sql, args...)
 	if err != nil {
 		return nil, errors.Wrap(err, "exec")
 	}
+	if tag.RowsAffected() == 0 {
+		return nil, errors.Errorf("vocab list %v not found", list.ID)
+	}
 
 	// update all vocabs of this list
 	for _, word := range list.Vocabs {
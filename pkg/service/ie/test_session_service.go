@@ -0,0 +1,138 @@
+package ieservice
+
+import (
+	"context"
+
+	"github.com/nhuongmh/cfvs.jpx/bootstrap"
+	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/ie"
+	"github.com/pkg/errors"
+)
+
+// loadBandTables reads the band tables configured by BAND_TABLE_ACADEMIC and BAND_TABLE_GENERAL,
+// a module without a valid table uses the default one
+func loadBandTables(env *bootstrap.Env) map[string]ie.BandTable {
+	tables := map[string]ie.BandTable{}
+	configured := map[string]string{ie.IELTS_ACADEMIC: env.BandTableAcademic, ie.IELTS_GENERAL: env.BandTableGeneral}
+	for _, module := range ie.IELTS_MODULES {
+		tables[module] = ie.DEFAULT_BAND_TABLES[module]
+		if configured[module] == "" {
+			continue
+		}
+		table, err := ie.ParseBandTable(configured[module])
+		if err != nil {
+			logger.Log.Warn().Err(err).Msgf("using the default %v band table", module)
+			continue
+		}
+		tables[module] = table
+	}
+	return tables
+}
+
+// StartTestSession starts a timed test of the reading, lasting READING_TEST_MINUTES unless the dto sets the duration
+func (ies *IEservice) StartTestSession(ctx context.Context, articleReadingId uint64, dto *ie.StartTestDto) (*ie.TestSession, error) {
+	defaultMinutes := ie.DEFAULT_TEST_MINUTES
+	if ies.env.ReadingTestMinutes > 0 {
+		defaultMinutes = ies.env.ReadingTestMinutes
+	}
+	if err := dto.Validate(defaultMinutes); err != nil {
+		return nil, err
+	}
+	articleReading, err := ies.repo.FindArticleReadingByID(ctx, articleReadingId)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get article reading")
+	}
+	article, err := ies.repo.FindByID(ctx, articleReading.ArticleID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get article of reading")
+	}
+	if err = checkArticleEvent(article, ie.EVENT_TEST_SUBMITTED); err != nil {
+		return nil, err
+	}
+
+	session := &ie.TestSession{
+		ArticleReadingID: articleReading.ID,
+		Module:           dto.Module,
+		DurationSeconds:  dto.DurationMinutes * 60,
+	}
+	session, err = ies.repo.SaveTestSession(ctx, session)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start test session")
+	}
+	return session, nil
+}
+
+func (ies *IEservice) GetTestSession(ctx context.Context, id uint64) (*ie.TestSession, error) {
+	session, err := ies.repo.FindTestSessionByID(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get test session")
+	}
+	return session, nil
+}
+
+// SubmitTestSession grades the answers of the timed test with the band of its module.
+// A second submission, or one after the deadline, is ErrTestSessionClosed.
+func (ies *IEservice) SubmitTestSession(ctx context.Context, id uint64, dto *ie.TestSubmissionDto) (*ie.TestResult, error) {
+	session, err := ies.repo.FindTestSessionByID(ctx, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get test session")
+	}
+	if session.SubmittedAt != nil {
+		return nil, errors.Wrapf(ie.ErrTestSessionClosed, "test session %d was submitted", id)
+	}
+	articleReading, err := ies.repo.FindArticleReadingByID(ctx, session.ArticleReadingID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get article reading")
+	}
+	article, err := ies.repo.FindByID(ctx, articleReading.ArticleID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get article of reading")
+	}
+	if err = checkArticleEvent(article, ie.EVENT_TEST_SUBMITTED); err != nil {
+		return nil, err
+	}
+	if err = ie.ValidateAnswers(articleReading.Questions, dto.Answers); err != nil {
+		return nil, err
+	}
+	if err = ie.ValidateQuestionSeconds(articleReading.Questions, dto.QuestionSeconds); err != nil {
+		return nil, err
+	}
+
+	// the submission time is taken before grading, which can wait for the LLM
+	elapsed, submitted, err := ies.repo.SubmitTestSession(ctx, session, ie.TEST_SUBMIT_GRACE_SECONDS)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to submit test session")
+	}
+	if !submitted {
+		return nil, errors.Wrapf(ie.ErrTestSessionClosed, "test session %d was submitted or passed its deadline %v",
+			id, session.Deadline)
+	}
+
+	testResult := ies.gradeReading(ctx, articleReading, dto.Answers, session.Module)
+	testResult.TestSessionID = session.ID
+	testResult.ElapsedSeconds = elapsed
+	testResult.SetQuestionSeconds(dto.QuestionSeconds)
+	savedTestResult, err := ies.repo.SaveTestSubmission(ctx, testResult)
+	if err != nil {
+		// the session is open again so that the answers can be submitted once more,
+		// also when the request was cancelled
+		if err := ies.repo.ReopenTestSession(context.WithoutCancel(ctx), session); err != nil {
+			logger.Log.Error().Err(err).Msgf("failed to reopen test session %d", id)
+		}
+		return nil, errors.Wrap(err, "failed to save test submission")
+	}
+	ies.logArticleEvent(ctx, article.ID, ie.EVENT_TEST_SUBMITTED)
+	return savedTestResult, nil
+}
+
+// GetBandHistory returns the band progression of the graded tests, an invalid query is ErrInvalidBandQuery
+func (ies *IEservice) GetBandHistory(ctx context.Context, q *ie.BandHistoryQuery) (*ie.BandHistory, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	entries, err := ies.repo.FindBandHistory(ctx, q)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get band history")
+	}
+	return ie.NewBandHistory(q.Module, entries), nil
+}
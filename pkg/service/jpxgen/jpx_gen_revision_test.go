@@ -0,0 +1,67 @@
+package jpxgen
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/nhuongmh/cfvs.jpx/pkg/service/jpxpractice/repo"
+	"github.com/open-spaced-repetition/go-fsrs/v3"
+)
+
+func Test_jpxService_RevertCard(t *testing.T) {
+	ctx := context.Background()
+	jps := &jpxService{repo: repo.NewMemoryPracticeRepo()}
+	card := langfi.NewReviewCard("先生", "teacher")
+	card.Status = langfi.CARD_LEARN
+	card.FsrsData.Card = fsrs.Card{State: fsrs.Review, Reps: 3, Stability: 4}
+	if err := jps.repo.AddCard(ctx, &card); err != nil {
+		t.Fatal(err)
+	}
+
+	edit := langfi.NewReviewCard("先生方", "teachers")
+	edit.ID = card.ID
+	edit.SetProp("note", "plural")
+	if _, err := jps.EditCardText(ctx, &edit); err != nil {
+		t.Fatal(err)
+	}
+	// an edit without changes records no revision
+	if _, err := jps.EditCardText(ctx, &edit); err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := jps.GetCardRevisions(ctx, card.ID)
+	if err != nil || len(*revisions) != 1 {
+		t.Fatalf("GetCardRevisions() = %v, %v, want 1 revision", revisions, err)
+	}
+
+	reverted, err := jps.RevertCard(ctx, card.ID, (*revisions)[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reverted.Front != "先生" || reverted.Back != "teacher" || reverted.GetProp("note") != nil {
+		t.Errorf("RevertCard() = %+v", reverted)
+	}
+	stored, _ := jps.repo.GetCard(ctx, card.ID)
+	if stored.Front != "先生" || stored.Status != langfi.CARD_LEARN || stored.FsrsData.Reps != 3 || stored.FsrsData.Stability != 4 {
+		t.Errorf("stored card after revert = %+v", stored)
+	}
+	if revisions, _ = jps.GetCardRevisions(ctx, card.ID); len(*revisions) != 2 || (*revisions)[0].Front != "先生方" {
+		t.Errorf("GetCardRevisions() after revert = %+v", revisions)
+	}
+
+	// an edit of the revision before the revert is rejected
+	edit.Front = "先生たち"
+	edit.Revision = reverted.Revision - 1
+	if _, err = jps.EditCardText(ctx, &edit); !errors.Is(err, langfi.ErrStaleRevision) {
+		t.Errorf("EditCardText() of an older revision error = %v, want %v", err, langfi.ErrStaleRevision)
+	}
+
+	other := langfi.NewReviewCard("学生", "student")
+	if err := jps.repo.AddCard(ctx, &other); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = jps.RevertCard(ctx, other.ID, (*revisions)[0].ID); !errors.Is(err, langfi.ErrInvalidRevision) {
+		t.Errorf("RevertCard() with a revision of another card error = %v, want %v", err, langfi.ErrInvalidRevision)
+	}
+}
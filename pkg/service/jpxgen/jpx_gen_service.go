@@ -11,6 +11,7 @@ import (
 	"github.com/nhuongmh/cfvs.jpx/bootstrap"
 	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model/jp"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
 	"github.com/pkg/errors"
@@ -108,21 +109,12 @@ func (jps *jpxService) BuildCards(ctx context.Context) (*[]langfi.ReviewCard, er
 		logger.Log.Info().Msgf("Successfully built %v cards", len(*proposalList))
 	}
 
-	// check if card exist -> if not, insert to db
-
-	for i := range *proposalList {
-		card := (*proposalList)[i]
-		existed, err := jps.repo.GetCardByFront(ctx, card.Front)
-		if err != nil || len(*existed) == 0 {
-			logger.Log.Info().Msgf("card %v not exist, trying to insert", card.Front)
-			err = jps.repo.AddCard(ctx, &card)
-			if err != nil {
-				logger.Log.Error().Err(err).Msgf("failed to insert card %v", card.Front)
-			}
-		} else {
-			logger.Log.Warn().Msgf("card %v is already existed, skip inserting into database", card.Front)
-		}
+	// cards already in the database are skipped
+	added, err := jps.repo.AddNewCards(ctx, *proposalList)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to insert cards")
 	}
+	logger.Log.Info().Msgf("inserted %v new cards, %v already existed", added, len(*proposalList)-added)
 
 	return proposalList, nil
 }
@@ -217,11 +209,7 @@ func (jps *jpxService) buildSentenceCards(minna string) (*[]langfi.ReviewCard, e
 			if buildSuccess {
 				newCard := langfi.NewReviewCard(sentence, meaning)
 				newCard.Properties = collectiveProps
-				if minna != "" {
-					newCard.Group = minna
-				} else {
-					newCard.Group = "NA"
-				}
+				newCard.Group = minnaDeck(minna)
 				proposalList = append(proposalList, newCard)
 			}
 		}
@@ -242,6 +230,7 @@ func (jps *jpxService) buildWordCards(minna string) (*[]langfi.ReviewCard, error
 			continue
 		}
 		newCard := langfi.NewReviewCard(word.Name, word.GetMeaning())
+		newCard.Group = minnaDeck(minna)
 		for k, v := range word.Properties {
 			newCard.SetProp(k, v)
 		}
@@ -314,6 +303,29 @@ func (jps *jpxService) checkInitialized() bool {
 	return jps.ggService != nil
 }
 
+func minnaDeck(minna string) string {
+	if minna == "" {
+		return langfi.DeckPath(jp.DECK_ROOT, jp.DECK_NO_LESSON)
+	}
+	return langfi.DeckPath(jp.DECK_ROOT, jp.DECK_MINNA, minna)
+}
+
+// GetAvailableLangs lists the root decks, each one holds the cards of a language
+func (jps *jpxService) GetAvailableLangs(ctx context.Context) ([]string, error) {
+	decks, err := jps.repo.GetDecks(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get decks")
+	}
+
+	langs := []string{}
+	for i := range *decks {
+		if (*decks)[i].ParentID == 0 {
+			langs = append(langs, (*decks)[i].Name)
+		}
+	}
+	return langs, nil
+}
+
 func (jps *jpxService) FetchProposal(ctx context.Context, group string) (*langfi.ReviewCard, error) {
 	return jps.repo.FetchUnProcessCard(ctx, group)
 }
@@ -333,18 +345,69 @@ func (jps *jpxService) SubmitProposal(ctx context.Context, cardID uint64, newSta
 	return errors.Errorf("invalid status %v", newStatus)
 }
 
+// EditCardText changes front and back and merges the properties, the earlier text is kept as a revision.
+// The edit is made on the revision of newCard, 0 edits the current revision.
 func (jps *jpxService) EditCardText(ctx context.Context, newCard *langfi.ReviewCard) (*langfi.ReviewCard, error) {
 	card, err := jps.repo.GetCard(ctx, newCard.ID)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get card with id=%v", newCard.ID)
 	}
+	if newCard.Revision != 0 && newCard.Revision != card.Revision {
+		return nil, errors.Wrapf(langfi.ErrStaleRevision, "card id=%v is at revision %v, not %v", card.ID, card.Revision, newCard.Revision)
+	}
 
-	card.Front = newCard.Front
-	card.Back = newCard.Back
+	edited := *card
+	edited.Front = newCard.Front
+	edited.Back = newCard.Back
+	edited.Properties = map[string]interface{}{}
+	for k, v := range card.Properties {
+		edited.SetProp(k, v)
+	}
 	for k, v := range newCard.Properties {
-		card.SetProp(k, v)
+		edited.SetProp(k, v)
 	}
-	return card, jps.repo.UpdateCard(ctx, card)
+	return jps.saveCardText(ctx, card, &edited)
+}
+
+func (jps *jpxService) saveCardText(ctx context.Context, card, edited *langfi.ReviewCard) (*langfi.ReviewCard, error) {
+	revision := langfi.NewCardRevision(card, edited, auth.UserIDFromContext(ctx))
+	if revision == nil {
+		return card, nil
+	}
+	err := jps.repo.UpdateCardText(ctx, edited, revision)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to save text of card id=%v", card.ID)
+	}
+	return edited, nil
+}
+
+func (jps *jpxService) GetCardRevisions(ctx context.Context, cardID uint64) (*[]langfi.CardRevision, error) {
+	_, err := jps.repo.GetCard(ctx, cardID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get card with id=%v", cardID)
+	}
+	return jps.repo.GetCardRevisions(ctx, cardID)
+}
+
+// RevertCard restores the text of the card from one of its revisions, the revert is itself recorded as a revision
+func (jps *jpxService) RevertCard(ctx context.Context, cardID, revisionID uint64) (*langfi.ReviewCard, error) {
+	revision, err := jps.repo.GetCardRevision(ctx, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	if revision.CardID != cardID {
+		return nil, errors.Wrapf(langfi.ErrInvalidRevision, "revision %v is not a revision of card %v", revisionID, cardID)
+	}
+	card, err := jps.repo.GetCard(ctx, cardID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get card with id=%v", cardID)
+	}
+
+	reverted := *card
+	reverted.Front = revision.Front
+	reverted.Back = revision.Back
+	reverted.Properties = revision.Properties
+	return jps.saveCardText(ctx, card, &reverted)
 }
 
 //generate practice sentence
@@ -0,0 +1,156 @@
+package jpxpractice
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/nhuongmh/cfvs.jpx/pkg/supporter/anki"
+	"github.com/open-spaced-repetition/go-fsrs/v3"
+	"github.com/pkg/errors"
+)
+
+// at most this many cards are exported per package
+const MAX_EXPORT_CARDS = 20000
+
+// ExportAnki builds an anki package of the cards matching the query, the caller writes it with Export.
+// The notes are held in memory, which MAX_EXPORT_CARDS bounds.
+func (jps *jpxPracService) ExportAnki(ctx context.Context, export *langfi.AnkiExportDto) (*anki.Package, error) {
+	cardQuery, err := langfi.ParseCardQuery(export.Query)
+	if err != nil {
+		return nil, err
+	}
+	cards, err := jps.repo.SearchCards(ctx, cardQuery, MAX_EXPORT_CARDS)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to search cards")
+	}
+	if len(*cards) == 0 {
+		return nil, errors.Wrap(model.ErrNoMoreDataAvailable, "no card matches the query")
+	}
+
+	noteModel := export.Model
+	if noteModel == nil {
+		noteModel = anki.BasicModel()
+	}
+
+	reviews := map[uint64][]langfi.ReviewLog{}
+	if export.IncludeScheduling {
+		logs, err := jps.repo.GetReviewLogs(ctx, time.Time{})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get review logs")
+		}
+		for _, log := range *logs {
+			reviews[log.CardID] = append(reviews[log.CardID], log)
+		}
+	}
+
+	pkg := anki.NewPackage()
+	addedMedia := map[string]bool{}
+	for i := range *cards {
+		card := &(*cards)[i]
+		note := cardNote(card, noteModel, export.Deck)
+		if export.IncludeScheduling {
+			note.Scheduling, note.Reviews = cardScheduling(card, reviews[card.ID])
+		}
+		err = pkg.AddNote(note)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to export card id = %v", card.ID)
+		}
+		addCardMedia(pkg, note.Fields, addedMedia)
+	}
+	return pkg, nil
+}
+
+func cardNote(card *langfi.ReviewCard, noteModel *anki.NoteModel, deck string) anki.Note {
+	if deck == "" {
+		deck = card.Group
+	}
+	fields := make([]string, len(noteModel.Fields))
+	for i, name := range noteModel.Fields {
+		fields[i] = cardField(card, name)
+	}
+	return anki.Note{
+		GUID:   anki.GUIDFor("cfvs-card-" + strconv.FormatUint(card.ID, 10)),
+		Deck:   deck,
+		Model:  noteModel,
+		Fields: fields,
+		Tags:   card.Tags,
+	}
+}
+
+// cardField fills a note field by name from the front, the back or a card property
+func cardField(card *langfi.ReviewCard, name string) string {
+	switch strings.ToLower(name) {
+	case "front":
+		return card.Front
+	case "back":
+		return card.Back
+	}
+	for key, value := range card.Properties {
+		if strings.EqualFold(key, name) && value != nil {
+			return fmt.Sprint(value)
+		}
+	}
+	return ""
+}
+
+var ankiReviewTypes = map[fsrs.State]int{
+	fsrs.New:        anki.REVIEW_LEARN,
+	fsrs.Learning:   anki.REVIEW_LEARN,
+	fsrs.Review:     anki.REVIEW_REVIEW,
+	fsrs.Relearning: anki.REVIEW_RELEARN,
+}
+
+// cardScheduling converts the fsrs state of the card, logs are sorted by review time and
+// record the state before each review, so the interval after a review is the next log's
+func cardScheduling(card *langfi.ReviewCard, logs []langfi.ReviewLog) (*anki.Scheduling, []anki.Review) {
+	fsrsCard := card.FsrsData.Card
+	scheduling := &anki.Scheduling{
+		Type:       int(fsrsCard.State),
+		Due:        fsrsCard.Due,
+		Interval:   int(fsrsCard.ScheduledDays),
+		Reps:       int(fsrsCard.Reps),
+		Lapses:     int(fsrsCard.Lapses),
+		Stability:  fsrsCard.Stability,
+		Difficulty: fsrsCard.Difficulty,
+		Suspended:  card.Status == langfi.CARD_DISCARD,
+	}
+
+	reviews := make([]anki.Review, len(logs))
+	for i, log := range logs {
+		interval := int(fsrsCard.ScheduledDays)
+		if i+1 < len(logs) {
+			interval = int(logs[i+1].ScheduledDays)
+		}
+		reviews[i] = anki.Review{
+			Time:         log.Review,
+			Rating:       int(log.Rating),
+			Type:         ankiReviewTypes[log.State],
+			Interval:     interval,
+			LastInterval: int(log.ScheduledDays),
+		}
+	}
+	return scheduling, reviews
+}
+
+// addCardMedia attaches the media files referenced by the fields that exist in MEDIA_DIR
+func addCardMedia(pkg *anki.Package, fields []string, added map[string]bool) {
+	for _, field := range fields {
+		for _, name := range anki.MediaRefs(field) {
+			if added[name] || filepath.Base(name) != name {
+				continue
+			}
+			added[name] = true
+			err := pkg.AddMediaFile(name, filepath.Join(langfi.MEDIA_DIR, name))
+			if err != nil {
+				logger.Log.Debug().Err(err).Msgf("skipped media %v", name)
+			}
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package jpxpractice
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/nhuongmh/cfvs.jpx/pkg/supporter/anki"
+	"github.com/open-spaced-repetition/go-fsrs/v3"
+	"github.com/pkg/errors"
+)
+
+// ImportAnki maps every note to one card, scheduled by replaying the review history of
+// its most reviewed anki card through fsrs. All cards are imported in one transaction.
+func (jps *jpxPracService) ImportAnki(ctx context.Context, path string, opts *langfi.AnkiImportDto) (*langfi.AnkiImportReport, error) {
+	rootDeck, err := langfi.NormalizeDeckName(opts.Deck)
+	if err != nil {
+		return nil, err
+	}
+	col, err := anki.OpenPackage(path)
+	if err != nil {
+		return nil, err
+	}
+	defer col.Close()
+
+	report := &langfi.AnkiImportReport{
+		DryRun:     opts.DryRun,
+		Notes:      len(col.Notes),
+		Decks:      map[string]int{},
+		Duplicates: []string{},
+		Skipped:    []string{},
+	}
+	seenFronts := map[string]bool{}
+	media := map[string]bool{}
+	imported := []langfi.ImportedCard{}
+	for i := range col.Notes {
+		note := &col.Notes[i]
+		card, ankiCard, err := importedCard(note, rootDeck, opts)
+		if err != nil {
+			report.Skipped = append(report.Skipped, err.Error())
+			continue
+		}
+
+		duplicate, err := jps.isDuplicate(ctx, card.Front, seenFronts)
+		if err != nil {
+			return report, err
+		}
+		if duplicate {
+			report.Duplicates = append(report.Duplicates, card.Front)
+			continue
+		}
+
+		logs := jps.scheduleImportedCard(ctx, card, ankiCard)
+		report.Imported++
+		report.Reviews += len(logs)
+		report.Decks[card.Group]++
+		for _, field := range note.Fields {
+			for _, name := range anki.MediaRefs(field) {
+				if col.HasMedia(name) && filepath.Base(name) == name {
+					media[name] = true
+				}
+			}
+		}
+		imported = append(imported, langfi.ImportedCard{Card: card, Logs: logs})
+	}
+
+	// media are copied first, files left by an import failing afterwards are not referenced by any card
+	report.Media = len(media)
+	report.MediaConflicts, err = importMedia(col, media, langfi.MEDIA_DIR, opts.DryRun)
+	if err != nil || opts.DryRun {
+		return report, err
+	}
+	err = jps.repo.ImportCards(ctx, imported)
+	if err != nil {
+		return report, errors.Wrap(err, "failed to import cards")
+	}
+	return report, nil
+}
+
+func (jps *jpxPracService) isDuplicate(ctx context.Context, front string, seen map[string]bool) (bool, error) {
+	if seen[front] {
+		return true, nil
+	}
+	seen[front] = true
+	existing, err := jps.repo.GetCardByFront(ctx, front)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to check duplicates of %v", front)
+	}
+	return len(*existing) > 0, nil
+}
+
+// ankiText converts a field to the plain text used for card fronts and backs
+func ankiText(field string) string {
+	for _, ref := range anki.MediaRefs(field) {
+		field = strings.ReplaceAll(field, "[sound:"+ref+"]", "")
+	}
+	field = strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n", "<div>", "\n").Replace(field)
+	return strings.TrimSpace(html.UnescapeString(anki.StripHTML(field)))
+}
+
+// propertyKey lower cases a field name, e.g. `Reading` matches langfi.PROP_READING
+func propertyKey(field string) string {
+	return strings.Join(strings.Fields(strings.ToLower(field)), "_")
+}
+
+// importedCard maps a note to a card without scheduling, and picks the anki card providing the scheduling
+func importedCard(note *anki.ReadNote, rootDeck string, opts *langfi.AnkiImportDto) (*langfi.ReviewCard, *anki.ReadCard, error) {
+	if len(note.Cards) == 0 {
+		return nil, nil, errors.Errorf("note %v has no cards", note.GUID)
+	}
+	frontField, backField := note.Fields[0], ""
+	if len(note.Fields) > 1 {
+		backField = note.Fields[1]
+	}
+	if opts.FrontField != "" {
+		frontField = note.Field(opts.FrontField)
+	}
+	if opts.BackField != "" {
+		backField = note.Field(opts.BackField)
+	}
+
+	card := langfi.NewReviewCard(ankiText(frontField), ankiText(backField))
+	if card.Front == "" {
+		return nil, nil, errors.Errorf("note %v has an empty front", note.GUID)
+	}
+	card.Status = langfi.CARD_LEARN
+	card.Tags = langfi.NormalizeTags(note.Tags)
+	for i, name := range note.FieldNames {
+		if i < len(note.Fields) && note.Fields[i] != "" {
+			card.SetProp(propertyKey(name), note.Fields[i])
+		}
+	}
+	card.SetProp(langfi.PROP_ANKI_GUID, note.GUID)
+	card.SetProp(langfi.PROP_ANKI_MODEL, note.Model)
+
+	ankiCard := &note.Cards[0]
+	for i := range note.Cards {
+		if len(note.Cards[i].Reviews) > len(ankiCard.Reviews) {
+			ankiCard = &note.Cards[i]
+		}
+	}
+	card.Group = rootDeck
+	if ankiCard.Deck != anki.DEFAULT_DECK {
+		group, err := langfi.NormalizeDeckName(langfi.DeckPath(rootDeck, ankiCard.Deck))
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "note %v", note.GUID)
+		}
+		card.Group = group
+	}
+	if ankiCard.Scheduling.Suspended {
+		card.Status = langfi.CARD_DISCARD
+	}
+	return &card, ankiCard, nil
+}
+
+// scheduleImportedCard sets the fsrs state of the card and returns the review logs to record
+func (jps *jpxPracService) scheduleImportedCard(ctx context.Context, card *langfi.ReviewCard, ankiCard *anki.ReadCard) []langfi.ReviewLog {
+	reviews := make([]anki.Review, 0, len(ankiCard.Reviews))
+	for _, review := range ankiCard.Reviews {
+		// manual reschedules have no rating
+		if review.Rating >= int(fsrs.Again) && review.Rating <= int(fsrs.Easy) {
+			reviews = append(reviews, review)
+		}
+	}
+	if len(reviews) == 0 {
+		card.FsrsData.Card = scheduledFsrsCard(&ankiCard.Scheduling)
+		return nil
+	}
+
+	return replayReviews(jps.schedulerFor(ctx, card.Group), card, reviews)
+}
+
+func replayReviews(scheduler *fsrs.FSRS, card *langfi.ReviewCard, reviews []anki.Review) []langfi.ReviewLog {
+	ratings := make([]langfi.OfflineReview, len(reviews))
+	for i, review := range reviews {
+		ratings[i] = langfi.OfflineReview{CardID: card.ID, Rating: uint64(review.Rating), Review: review.Time}
+	}
+	sortReviews(ratings)
+	card.FsrsData.Card = fsrs.NewCard()
+	return applyReviews(scheduler, card, ratings)
+}
+
+// scheduledFsrsCard approximates the fsrs state of a card scheduled without review history
+func scheduledFsrsCard(s *anki.Scheduling) fsrs.Card {
+	card := fsrs.NewCard()
+	if s.Type == anki.CARD_NEW {
+		return card
+	}
+	card.State = fsrs.State(s.Type)
+	card.Due = s.Due
+	card.ScheduledDays = uint64(max(s.Interval, 0))
+	card.Reps = uint64(s.Reps)
+	card.Lapses = uint64(s.Lapses)
+	card.LastReview = s.Due.AddDate(0, 0, -s.Interval)
+	card.Stability = s.Stability
+	if card.Stability <= 0 {
+		card.Stability = float64(max(s.Interval, 1))
+	}
+	card.Difficulty = s.Difficulty
+	if card.Difficulty <= 0 {
+		card.Difficulty = 5
+	}
+	return card
+}
+
+// importMedia copies the media of the package into dir, a file of the same name and content is already
+// there. A file of the same name with another content is kept and returned as a conflict.
+func importMedia(col *anki.Collection, media map[string]bool, dir string, dryRun bool) ([]string, error) {
+	names := make([]string, 0, len(media))
+	for name := range media {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	conflicts := []string{}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		existing, err := fileHash(func() (io.ReadCloser, error) { return os.Open(path) })
+		if errors.Is(err, os.ErrNotExist) {
+			if dryRun {
+				continue
+			}
+			err = copyMedia(col, name, path)
+			if err != nil {
+				return conflicts, err
+			}
+			continue
+		}
+		if err != nil {
+			return conflicts, errors.Wrapf(err, "failed to read media %v", name)
+		}
+
+		imported, err := fileHash(func() (io.ReadCloser, error) { return col.OpenMedia(name) })
+		if err != nil {
+			return conflicts, errors.Wrapf(err, "failed to read media %v of the package", name)
+		}
+		if imported != existing {
+			logger.Log.Warn().Msgf("media %v already exists with another content, not overwritten", name)
+			conflicts = append(conflicts, name)
+		}
+	}
+	return conflicts, nil
+}
+
+func fileHash(open func() (io.ReadCloser, error)) (string, error) {
+	f, err := open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// copyMedia copies a media file of the package to path, which must not exist yet
+func copyMedia(col *anki.Collection, name, path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return errors.Wrap(err, "failed to create media dir")
+	}
+
+	src, err := col.OpenMedia(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create media %v", name)
+	}
+	_, err = io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return errors.Wrapf(err, "failed to copy media %v", name)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package jpxpractice
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/nhuongmh/cfvs.jpx/pkg/supporter/anki"
+	"github.com/open-spaced-repetition/go-fsrs/v3"
+)
+
+func Test_cardNote(t *testing.T) {
+	card := langfi.NewReviewCard("先生", "teacher")
+	card.ID = 7
+	card.Group = "jp::Minna::L01"
+	card.Tags = []string{"lesson1"}
+	card.SetProp(langfi.PROP_READING, "せんせい")
+	noteModel := &anki.NoteModel{Name: "jp", Fields: []string{"Front", langfi.PROP_READING, "Back", "Missing"},
+		Templates: []anki.Template{{Name: "Card 1", Front: "{{Front}}", Back: "{{Back}}"}}}
+
+	note := cardNote(&card, noteModel, "")
+	want := []string{"先生", "せんせい", "teacher", ""}
+	if !reflect.DeepEqual(note.Fields, want) || note.Deck != card.Group || note.GUID != anki.GUIDFor("cfvs-card-7") {
+		t.Errorf("cardNote() = %+v, want fields %v in deck %v", note, want, card.Group)
+	}
+	if note = cardNote(&card, noteModel, "export"); note.Deck != "export" {
+		t.Errorf("cardNote() deck = %v, want the override", note.Deck)
+	}
+}
+
+func Test_cardScheduling(t *testing.T) {
+	now := time.Now()
+	card := langfi.NewReviewCard("先生", "teacher")
+	card.Status = langfi.CARD_DISCARD
+	card.FsrsData.Card = fsrs.Card{Due: now.AddDate(0, 0, 8), State: fsrs.Review, ScheduledDays: 8, Reps: 2, Stability: 8.5, Difficulty: 5}
+	logs := []langfi.ReviewLog{
+		{ReviewLog: fsrs.ReviewLog{Rating: fsrs.Good, State: fsrs.New, ScheduledDays: 0, Review: now.AddDate(0, 0, -3)}},
+		{ReviewLog: fsrs.ReviewLog{Rating: fsrs.Good, State: fsrs.Review, ScheduledDays: 3, Review: now}},
+	}
+
+	scheduling, reviews := cardScheduling(&card, logs)
+	if scheduling.Type != anki.CARD_REVIEW || scheduling.Interval != 8 || !scheduling.Suspended || scheduling.Stability != 8.5 {
+		t.Errorf("cardScheduling() scheduling = %+v", scheduling)
+	}
+	want := []anki.Review{
+		{Time: logs[0].Review, Rating: 3, Type: anki.REVIEW_LEARN, Interval: 3, LastInterval: 0},
+		{Time: logs[1].Review, Rating: 3, Type: anki.REVIEW_REVIEW, Interval: 8, LastInterval: 3},
+	}
+	if !reflect.DeepEqual(reviews, want) {
+		t.Errorf("cardScheduling() reviews = %+v, want %+v", reviews, want)
+	}
+}
+
+func Test_importedCard(t *testing.T) {
+	note := &anki.ReadNote{
+		GUID: "g1", Model: "JP", FieldNames: []string{"Expression", "Meaning", "Reading"},
+		Fields: []string{"<b>犬</b>", "dog<br>[sound:inu.mp3]", "いぬ"}, Tags: []string{"Animal"},
+		Cards: []anki.ReadCard{
+			{Ord: 0, Deck: "Core::L1"},
+			{Ord: 1, Deck: "Core::L1", Reviews: []anki.Review{{Rating: 3}}, Scheduling: anki.Scheduling{Suspended: true}},
+		},
+	}
+
+	card, ankiCard, err := importedCard(note, "jp", &langfi.AnkiImportDto{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.Front != "犬" || card.Back != "dog" || card.Group != "jp::Core::L1" || card.Status != langfi.CARD_DISCARD ||
+		!reflect.DeepEqual(card.Tags, []string{"animal"}) || card.GetProp(langfi.PROP_READING) != "いぬ" ||
+		card.GetProp(langfi.PROP_ANKI_GUID) != "g1" || ankiCard.Ord != 1 {
+		t.Errorf("importedCard() = %+v from anki card %v", card, ankiCard.Ord)
+	}
+
+	card, _, err = importedCard(note, "jp", &langfi.AnkiImportDto{FrontField: "reading", BackField: "expression"})
+	if err != nil || card.Front != "いぬ" || card.Back != "犬" {
+		t.Errorf("importedCard() with fields = %v %v, %v", card.Front, card.Back, err)
+	}
+
+	note.Fields[0] = "<br>"
+	if _, _, err = importedCard(note, "jp", &langfi.AnkiImportDto{}); err == nil {
+		t.Error("importedCard() expected an error for an empty front")
+	}
+}
+
+func Test_replayReviews(t *testing.T) {
+	now := time.Now()
+	card := langfi.NewReviewCard("犬", "dog")
+	reviews := []anki.Review{
+		{Time: now.AddDate(0, 0, -5), Rating: 3},
+		{Time: now.AddDate(0, 0, -10), Rating: 3},
+		{Time: now.AddDate(0, 0, -1), Rating: 1},
+	}
+
+	logs := replayReviews(fsrs.NewFSRS(fsrs.DefaultParam()), &card, reviews)
+	if len(logs) != 3 || logs[0].State != fsrs.New || logs[2].Rating != fsrs.Again {
+		t.Fatalf("replayReviews() logs = %+v", logs)
+	}
+	if card.FsrsData.Reps != 3 || card.FsrsData.Lapses != 1 || card.FsrsData.State != fsrs.Relearning ||
+		!card.FsrsData.LastReview.Equal(reviews[2].Time) {
+		t.Errorf("replayReviews() card = %+v", card.FsrsData.Card)
+	}
+}
+
+func Test_importMedia(t *testing.T) {
+	pkg := anki.NewPackage()
+	_ = pkg.AddNote(anki.Note{Model: anki.BasicModel(), Fields: []string{"先生", "[sound:a.mp3][sound:b.mp3][sound:c.mp3]"}})
+	pkg.AddMedia("a.mp3", []byte("new"))
+	pkg.AddMedia("b.mp3", []byte("same"))
+	pkg.AddMedia("c.mp3", []byte("changed"))
+	path := filepath.Join(t.TempDir(), "media.apkg")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pkg.Export(f); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	col, err := anki.OpenPackage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer col.Close()
+
+	dir := t.TempDir()
+	for name, data := range map[string]string{"b.mp3": "same", "c.mp3": "original"} {
+		if err = os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	media := map[string]bool{"a.mp3": true, "b.mp3": true, "c.mp3": true}
+
+	conflicts, err := importMedia(col, media, dir, true)
+	if err != nil || !reflect.DeepEqual(conflicts, []string{"c.mp3"}) {
+		t.Errorf("importMedia() dry run = %v, %v, want conflict [c.mp3]", conflicts, err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "a.mp3")); !os.IsNotExist(err) {
+		t.Errorf("importMedia() dry run copied a.mp3")
+	}
+
+	conflicts, err = importMedia(col, media, dir, false)
+	if err != nil || !reflect.DeepEqual(conflicts, []string{"c.mp3"}) {
+		t.Errorf("importMedia() = %v, %v, want conflict [c.mp3]", conflicts, err)
+	}
+	for name, want := range map[string]string{"a.mp3": "new", "b.mp3": "same", "c.mp3": "original"} {
+		if data, err := os.ReadFile(filepath.Join(dir, name)); err != nil || string(data) != want {
+			t.Errorf("media %v = %q, %v, want %q", name, data, err, want)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package jpxpractice
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/open-spaced-repetition/go-fsrs/v3"
+	"github.com/pkg/errors"
+)
+
+func (jps *jpxPracService) GetGroups(ctx context.Context, lang string) ([]string, error) {
+	decks, err := jps.repo.GetDecks(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get decks")
+	}
+
+	groups := []string{}
+	for i := range *decks {
+		name := (*decks)[i].Name
+		if lang == "" || strings.EqualFold(langfi.RootDeckName(name), lang) {
+			groups = append(groups, name)
+		}
+	}
+	return groups, nil
+}
+
+func (jps *jpxPracService) GetDeckTree(ctx context.Context) (*[]langfi.DeckTreeDto, error) {
+	decks, err := jps.repo.GetDecks(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get decks")
+	}
+	stats, err := jps.repo.GetGroupStats(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get deck stats")
+	}
+
+	tree := buildDeckTree(*decks, *stats)
+	return &tree, nil
+}
+
+func (jps *jpxPracService) CreateDeck(ctx context.Context, name string) (*langfi.Deck, error) {
+	return jps.repo.EnsureDeck(ctx, name)
+}
+
+func (jps *jpxPracService) UpdateDeckSettings(ctx context.Context, deckID uint64, settings *langfi.DeckSettings) (*langfi.Deck, error) {
+	err := settings.Validate()
+	if err != nil {
+		return nil, err
+	}
+	err = jps.repo.UpdateDeckSettings(ctx, deckID, settings)
+	if err != nil {
+		return nil, err
+	}
+	jps.decks.invalidate(auth.UserIDFromContext(ctx))
+
+	decks, err := jps.repo.GetDecks(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get decks")
+	}
+	for i := range *decks {
+		if (*decks)[i].ID == deckID {
+			return &(*decks)[i], nil
+		}
+	}
+	return nil, errors.Wrapf(langfi.ErrInvalidDeck, "deck id = %v not found", deckID)
+}
+
+func addSummary(total *langfi.GroupSummaryDto, other *langfi.GroupSummaryDto) {
+	total.NumCards += other.NumCards
+	total.Proposal += other.Proposal
+	total.Learning += other.Learning
+	total.Discard += other.Discard
+	total.Save += other.Save
+}
+
+// buildDeckTree nests the decks under their parents and sums the card counts of sub decks
+func buildDeckTree(decks []langfi.Deck, stats []langfi.GroupSummaryDto) []langfi.DeckTreeDto {
+	statsByDeck := map[string]langfi.GroupSummaryDto{}
+	for i := range stats {
+		statsByDeck[stats[i].Group] = stats[i]
+	}
+	children := map[uint64][]langfi.Deck{}
+	for i := range decks {
+		children[decks[i].ParentID] = append(children[decks[i].ParentID], decks[i])
+	}
+
+	var build func(parentID uint64, inherited langfi.DeckSettings) []langfi.DeckTreeDto
+	build = func(parentID uint64, inherited langfi.DeckSettings) []langfi.DeckTreeDto {
+		nodes := []langfi.DeckTreeDto{}
+		for _, deck := range children[parentID] {
+			node := langfi.DeckTreeDto{Deck: deck, Effective: deck.Settings.Inherit(inherited)}
+			node.Own = statsByDeck[deck.Name]
+			node.Own.Group = deck.Name
+			node.Total = node.Own
+			node.Children = build(deck.ID, node.Effective)
+			for i := range node.Children {
+				addSummary(&node.Total, &node.Children[i].Total)
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+	return build(0, langfi.DeckSettings{})
+}
+
+// effectiveSettings resolves the settings of a deck from the deck and its parents
+func effectiveSettings(decks []langfi.Deck, name string) langfi.DeckSettings {
+	byName := map[string]*langfi.Deck{}
+	for i := range decks {
+		byName[decks[i].Name] = &decks[i]
+	}
+
+	settings := langfi.DeckSettings{}
+	for ; name != ""; name = langfi.ParentDeckName(name) {
+		if deck, ok := byName[name]; ok {
+			settings = settings.Inherit(deck.Settings)
+		}
+	}
+	return settings
+}
+
+// deckCache keeps the decks of each user with their settings for scheduling, which looks them up on every
+// review. Settings only change through UpdateDeckSettings, decks created since inherit like unknown decks.
+type deckCache struct {
+	mu    sync.Mutex
+	decks map[uint64][]langfi.Deck
+}
+
+func newDeckCache() *deckCache {
+	return &deckCache{decks: map[uint64][]langfi.Deck{}}
+}
+
+func (dc *deckCache) get(userID uint64) ([]langfi.Deck, bool) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	decks, ok := dc.decks[userID]
+	return decks, ok
+}
+
+func (dc *deckCache) set(userID uint64, decks []langfi.Deck) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.decks[userID] = decks
+}
+
+func (dc *deckCache) invalidate(userID uint64) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	delete(dc.decks, userID)
+}
+
+// settingsDecks returns the decks of the user with their settings, from the cache when possible
+func (jps *jpxPracService) settingsDecks(ctx context.Context) ([]langfi.Deck, error) {
+	userID := auth.UserIDFromContext(ctx)
+	if decks, ok := jps.decks.get(userID); ok {
+		return decks, nil
+	}
+	decks, err := jps.repo.GetDecks(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get decks")
+	}
+	jps.decks.set(userID, *decks)
+	return *decks, nil
+}
+
+func (jps *jpxPracService) deckSettings(ctx context.Context, name string) (langfi.DeckSettings, error) {
+	decks, err := jps.settingsDecks(ctx)
+	if err != nil {
+		return langfi.DeckSettings{}, err
+	}
+	return effectiveSettings(decks, name), nil
+}
+
+// schedulerFor uses the fsrs parameters of the deck, falling back to the defaults
+func (jps *jpxPracService) schedulerFor(ctx context.Context, deck string) *fsrs.FSRS {
+	settings, err := jps.deckSettings(ctx, deck)
+	if err != nil {
+		logger.Log.Warn().Err(err).Msgf("failed to get settings of deck %v, using default parameters", deck)
+		return jps.fsrsService
+	}
+	if settings.RequestRetention == 0 && settings.MaximumInterval == 0 && len(settings.Weights) == 0 {
+		return jps.fsrsService
+	}
+	return fsrs.NewFSRS(settings.FsrsParameters())
+}
+
+// countToday counts first reviews of new cards and other reviews done today in the deck and its sub decks
+func countToday(logs []langfi.ReviewLog, deck string) (newCards, reviews int) {
+	for i := range logs {
+		if !langfi.IsInDeck(logs[i].Group, deck) {
+			continue
+		}
+		if logs[i].State == fsrs.New {
+			newCards++
+		} else {
+			reviews++
+		}
+	}
+	return newCards, reviews
+}
+
+// dailyLeft is what the daily limits of one deck still allow today
+type dailyLeft struct {
+	newCards int
+	reviews  int
+}
+
+// dailyBudget holds what the daily limits still allow today. The limit of a deck counts the reviews of its
+// whole sub tree, so a deck is limited by its own limits and by those of each of its parents, and the
+// limits of a parent are shared by all its sub decks.
+type dailyBudget struct {
+	decks []langfi.Deck
+	logs  []langfi.ReviewLog
+	// the most any limit allows, when the deck has no limit
+	size int
+	left map[string]*dailyLeft
+}
+
+func newDailyBudget(decks []langfi.Deck, logs []langfi.ReviewLog, size int) *dailyBudget {
+	return &dailyBudget{decks: decks, logs: logs, size: size, left: map[string]*dailyLeft{}}
+}
+
+func (b *dailyBudget) deckLeft(name string) *dailyLeft {
+	left, ok := b.left[name]
+	if !ok {
+		settings := effectiveSettings(b.decks, name)
+		newCards, reviews := countToday(b.logs, name)
+		left = &dailyLeft{
+			newCards: dailyCardsLeft(settings.NewPerDay, newCards, b.size),
+			reviews:  dailyCardsLeft(settings.ReviewsPerDay, reviews, b.size),
+		}
+		b.left[name] = left
+	}
+	return left
+}
+
+// Left is how many new cards, or reviewed cards, the deck and its parents still allow
+func (b *dailyBudget) Left(deck string, isNew bool) int {
+	left := b.size
+	for name := deck; name != ""; name = langfi.ParentDeckName(name) {
+		if isNew {
+			left = min(left, b.deckLeft(name).newCards)
+		} else {
+			left = min(left, b.deckLeft(name).reviews)
+		}
+	}
+	return left
+}
+
+// Take uses up one card of the deck and its parents, false when one of their limits is reached
+func (b *dailyBudget) Take(deck string, isNew bool) bool {
+	if b.Left(deck, isNew) == 0 {
+		return false
+	}
+	for name := deck; name != ""; name = langfi.ParentDeckName(name) {
+		if isNew {
+			b.deckLeft(name).newCards--
+		} else {
+			b.deckLeft(name).reviews--
+		}
+	}
+	return true
+}
+
+// limitQuery restricts the query to the kind of cards still allowed by the daily limits of the deck and its parents
+func (jps *jpxPracService) limitQuery(ctx context.Context, deck string, query *langfi.CardQuery) error {
+	decks, err := jps.settingsDecks(ctx)
+	if err != nil {
+		return err
+	}
+	// a parent with a limit gives the deck one by inheritance
+	settings := effectiveSettings(decks, deck)
+	if settings.NewPerDay == 0 && settings.ReviewsPerDay == 0 {
+		return nil
+	}
+
+	logs, err := jps.repo.GetReviewLogs(ctx, startOfDay(time.Now()))
+	if err != nil {
+		return errors.Wrap(err, "failed to get today reviews")
+	}
+	budget := newDailyBudget(decks, *logs, 1)
+	newDone := budget.Left(deck, true) == 0
+	reviewDone := budget.Left(deck, false) == 0
+	newState := langfi.QueryTerm{Field: langfi.QUERY_STATE, Op: langfi.OP_EQ, Value: "new"}
+	switch {
+	case newDone && reviewDone:
+		return model.ErrNoMoreDataAvailable
+	case newDone:
+		newState.Negate = true
+		query.Terms = append(query.Terms, newState)
+	case reviewDone:
+		query.Terms = append(query.Terms, newState)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package jpxpractice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/open-spaced-repetition/go-fsrs/v3"
+)
+
+func newDeck(id, parentID uint64, name string, settings langfi.DeckSettings) langfi.Deck {
+	return langfi.Deck{Base: model.Base{ID: id}, Name: name, ParentID: parentID, Settings: settings}
+}
+
+var testDecks = []langfi.Deck{
+	newDeck(1, 0, "jp", langfi.DeckSettings{NewPerDay: 20, RequestRetention: 0.9}),
+	newDeck(2, 1, "jp::Minna", langfi.DeckSettings{RequestRetention: 0.95}),
+	newDeck(3, 2, "jp::Minna::L05", langfi.DeckSettings{NewPerDay: 5}),
+	newDeck(4, 2, "jp::Minna::L06", langfi.DeckSettings{}),
+	newDeck(5, 0, "en", langfi.DeckSettings{}),
+}
+
+func Test_buildDeckTree(t *testing.T) {
+	stats := []langfi.GroupSummaryDto{
+		{Group: "jp::Minna::L05", NumCards: 3, Learning: 2, Proposal: 1},
+		{Group: "jp::Minna::L06", NumCards: 2, Learning: 2},
+		{Group: "jp", NumCards: 1, Save: 1},
+	}
+
+	tree := buildDeckTree(testDecks, stats)
+	if len(tree) != 2 || tree[0].Name != "jp" || tree[1].Name != "en" {
+		t.Fatalf("buildDeckTree() roots = %+v", tree)
+	}
+	jp := tree[0]
+	if jp.Own.NumCards != 1 || jp.Total.NumCards != 6 || jp.Total.Learning != 4 || jp.Total.Save != 1 {
+		t.Errorf("buildDeckTree() jp own = %+v, total = %+v", jp.Own, jp.Total)
+	}
+	minna := jp.Children[0]
+	if minna.Total.NumCards != 5 || len(minna.Children) != 2 {
+		t.Errorf("buildDeckTree() jp::Minna = %+v", minna)
+	}
+	l05 := minna.Children[0]
+	want := langfi.DeckSettings{NewPerDay: 5, RequestRetention: 0.95}
+	if l05.Effective.NewPerDay != want.NewPerDay || l05.Effective.RequestRetention != want.RequestRetention {
+		t.Errorf("buildDeckTree() jp::Minna::L05 effective = %+v, want %+v", l05.Effective, want)
+	}
+}
+
+func Test_effectiveSettings(t *testing.T) {
+	tests := []struct {
+		deck string
+		want langfi.DeckSettings
+	}{
+		{"jp::Minna::L05", langfi.DeckSettings{NewPerDay: 5, RequestRetention: 0.95}},
+		{"jp::Minna::L06", langfi.DeckSettings{NewPerDay: 20, RequestRetention: 0.95}},
+		// unknown decks still inherit from existing parents
+		{"jp::Other", langfi.DeckSettings{NewPerDay: 20, RequestRetention: 0.9}},
+		{"en", langfi.DeckSettings{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.deck, func(t *testing.T) {
+			got := effectiveSettings(testDecks, tt.deck)
+			if got.NewPerDay != tt.want.NewPerDay || got.RequestRetention != tt.want.RequestRetention {
+				t.Errorf("effectiveSettings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_countToday(t *testing.T) {
+	logs := []langfi.ReviewLog{
+		{Group: "jp::Minna::L05"},
+		{Group: "jp::Minna::L06"},
+		{Group: "jp::Minna"},
+		{Group: "jp::Minnas"},
+		{Group: "en"},
+	}
+	logs[0].State = fsrs.New
+	logs[1].State = fsrs.Review
+	logs[2].State = fsrs.Learning
+	logs[3].State = fsrs.New
+
+	newCards, reviews := countToday(logs, "jp::Minna")
+	if newCards != 1 || reviews != 2 {
+		t.Errorf("countToday() = %v, %v, want 1, 2", newCards, reviews)
+	}
+}
+
+func Test_dailyBudget(t *testing.T) {
+	decks := []langfi.Deck{
+		newDeck(1, 0, "jp", langfi.DeckSettings{NewPerDay: 3, ReviewsPerDay: 10}),
+		newDeck(2, 1, "jp::Minna", langfi.DeckSettings{}),
+		newDeck(3, 2, "jp::Minna::L05", langfi.DeckSettings{NewPerDay: 5}),
+		newDeck(4, 2, "jp::Minna::L06", langfi.DeckSettings{}),
+	}
+	logs := []langfi.ReviewLog{{Group: "jp::Minna::L06"}, {Group: "jp::Minna::L06"}, {Group: "jp::Minna::L06"}}
+	logs[0].State = fsrs.New
+	logs[1].State = fsrs.New
+	logs[2].State = fsrs.Review
+
+	budget := newDailyBudget(decks, logs, 100)
+	// L05 allows 5 new cards of its own, but jp only has 1 left after the 2 of L06
+	if left := budget.Left("jp::Minna::L05", true); left != 1 {
+		t.Errorf("Left(jp::Minna::L05, new) = %v, want 1", left)
+	}
+	if left := budget.Left("jp::Minna::L05", false); left != 9 {
+		t.Errorf("Left(jp::Minna::L05, reviews) = %v, want 9", left)
+	}
+	if !budget.Take("jp::Minna::L05", true) {
+		t.Errorf("Take(jp::Minna::L05, new) = false, want true")
+	}
+	if budget.Take("jp::Minna::L06", true) || budget.Left("jp", true) != 0 {
+		t.Errorf("Take(jp::Minna::L06, new) = true after the limit of jp was reached")
+	}
+	if left := budget.Left("en", true); left != 100 {
+		t.Errorf("Left(en, new) = %v, want the size of 100", left)
+	}
+}
+
+func TestUpdateDeckSettingsPerUser(t *testing.T) {
+	jps, _ := newSyncService(t)
+	alice := auth.WithUser(context.Background(), &auth.User{Base: model.Base{ID: 1}})
+	bob := auth.WithUser(context.Background(), &auth.User{Base: model.Base{ID: 2}})
+	deck, err := jps.CreateDeck(alice, "jp::Minna")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// fills the cache of the scheduler before the settings change
+	if scheduler := jps.schedulerFor(alice, deck.Name); scheduler != jps.fsrsService {
+		t.Fatalf("schedulerFor() without settings is not the default scheduler")
+	}
+
+	if _, err = jps.UpdateDeckSettings(alice, deck.ParentID, &langfi.DeckSettings{RequestRetention: 0.8}); err != nil {
+		t.Fatalf("UpdateDeckSettings() error = %v", err)
+	}
+	if settings, err := jps.deckSettings(alice, deck.Name); err != nil || settings.RequestRetention != 0.8 {
+		t.Errorf("deckSettings() of alice = %+v, %v, want the inherited retention 0.8", settings, err)
+	}
+	if settings, err := jps.deckSettings(bob, deck.Name); err != nil || settings.RequestRetention != 0 {
+		t.Errorf("deckSettings() of bob = %+v, %v, want none", settings, err)
+	}
+}
@@ -0,0 +1,161 @@
+package jpxpractice
+
+import (
+	"context"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/pkg/errors"
+)
+
+const (
+	DEFAULT_QUEUE_SIZE = 50
+	MAX_QUEUE_SIZE     = 500
+)
+
+// deckQueue holds the cards picked for one queued deck, due reviews first
+type deckQueue struct {
+	deck     string
+	priority int
+	cards    []langfi.QueueCardDto
+}
+
+// GetReviewQueue builds one practice session over several decks, every root deck when none is given.
+// Each deck contributes its due reviews and new cards left by its daily limits, and the decks are
+// interleaved by their priority, so a deck of priority 2 gets two cards for every card of a deck of priority 1.
+// Only practice cards are queued: IE vocab joins the queue once its vocab list is turned into cloze cards,
+// vocab without cloze cards never shows up.
+func (jps *jpxPracService) GetReviewQueue(ctx context.Context, decks []string, size int) (*langfi.ReviewQueueDto, error) {
+	if size <= 0 {
+		size = DEFAULT_QUEUE_SIZE
+	}
+	size = min(size, MAX_QUEUE_SIZE)
+
+	allDecks, err := jps.repo.GetDecks(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get decks")
+	}
+	names, err := queuedDeckNames(*allDecks, decks)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := jps.repo.GetReviewLogs(ctx, startOfDay(time.Now()))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get today reviews")
+	}
+
+	budget := newDailyBudget(*allDecks, *logs, size)
+	queues := []deckQueue{}
+	for _, name := range names {
+		settings := effectiveSettings(*allDecks, name)
+		queue := deckQueue{deck: name, priority: max(settings.Priority, 1)}
+		for _, isNew := range []bool{false, true} {
+			cards, err := jps.queueCards(ctx, name, isNew, budget.Left(name, isNew))
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get cards of deck %v", name)
+			}
+			for i := range cards {
+				queue.cards = append(queue.cards, langfi.QueueCardDto{Deck: name, New: isNew, Card: cards[i]})
+			}
+		}
+		queues = append(queues, queue)
+	}
+	return interleaveQueues(queues, size, budget), nil
+}
+
+// queuedDeckNames normalizes the requested decks, defaulting to the root decks
+func queuedDeckNames(decks []langfi.Deck, requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		for i := range decks {
+			if decks[i].ParentID == 0 {
+				requested = append(requested, decks[i].Name)
+			}
+		}
+	}
+
+	names := []string{}
+	seen := map[string]bool{}
+	for _, deck := range requested {
+		name, err := langfi.NormalizeDeckName(deck)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// dailyCardsLeft is what a daily limit still allows today, a limit of 0 only caps at the queue size
+func dailyCardsLeft(limit, done, size int) int {
+	if limit == 0 {
+		return size
+	}
+	return min(max(limit-done, 0), size)
+}
+
+// queueCards returns the due learning cards of the deck that were already reviewed, or its new learning cards
+func (jps *jpxPracService) queueCards(ctx context.Context, deck string, isNew bool, limit int) ([]langfi.ReviewCard, error) {
+	if limit == 0 {
+		return nil, nil
+	}
+	query := &langfi.CardQuery{Terms: []langfi.QueryTerm{
+		{Field: langfi.QUERY_GROUP, Op: langfi.OP_EQ, Value: deck},
+		{Field: langfi.QUERY_STATUS, Op: langfi.OP_EQ, Value: langfi.CARD_LEARN},
+		{Field: langfi.QUERY_STATE, Op: langfi.OP_EQ, Value: "new", Negate: !isNew},
+	}}
+	if !isNew {
+		query.Terms = append(query.Terms, langfi.QueryTerm{Field: langfi.QUERY_DUE, Op: langfi.OP_LTE, Value: "0"})
+	}
+	cards, err := jps.repo.SearchCards(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	return *cards, nil
+}
+
+// interleaveQueues takes cards from the deck queues by smooth weighted round robin on their priority,
+// a card queued for several overlapping decks is only taken once. Queued decks below the same parent
+// share its daily limits, a card is skipped once the budget of its deck is used up.
+func interleaveQueues(queues []deckQueue, size int, budget *dailyBudget) *langfi.ReviewQueueDto {
+	result := &langfi.ReviewQueueDto{Cards: []langfi.QueueCardDto{}, Decks: []langfi.QueueDeckDto{}}
+	for _, queue := range queues {
+		result.Decks = append(result.Decks, langfi.QueueDeckDto{Deck: queue.deck, Priority: queue.priority})
+	}
+
+	seen := map[uint64]bool{}
+	current := make([]int, len(queues))
+	for len(result.Cards) < size {
+		best, total := -1, 0
+		for i := range queues {
+			if len(queues[i].cards) == 0 {
+				continue
+			}
+			current[i] += queues[i].priority
+			total += queues[i].priority
+			if best < 0 || current[i] > current[best] {
+				best = i
+			}
+		}
+		if best < 0 {
+			break
+		}
+		current[best] -= total
+
+		item := queues[best].cards[0]
+		queues[best].cards = queues[best].cards[1:]
+		if seen[item.Card.ID] || !budget.Take(queues[best].deck, item.New) {
+			continue
+		}
+		seen[item.Card.ID] = true
+		result.Cards = append(result.Cards, item)
+		if item.New {
+			result.Decks[best].New++
+		} else {
+			result.Decks[best].Reviews++
+		}
+	}
+	return result
+}
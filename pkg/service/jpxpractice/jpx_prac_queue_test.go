@@ -0,0 +1,138 @@
+package jpxpractice
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/open-spaced-repetition/go-fsrs/v3"
+)
+
+func queuedCards(deck string, ids ...uint64) []langfi.QueueCardDto {
+	cards := []langfi.QueueCardDto{}
+	for _, id := range ids {
+		cards = append(cards, langfi.QueueCardDto{Deck: deck, Card: langfi.ReviewCard{Base: model.Base{ID: id}}})
+	}
+	return cards
+}
+
+func Test_interleaveQueues(t *testing.T) {
+	queues := []deckQueue{
+		{deck: "jp", priority: 2, cards: queuedCards("jp", 1, 2, 3, 4, 5, 6)},
+		{deck: "en", priority: 1, cards: queuedCards("en", 11, 12)},
+		// overlaps jp, a card is only queued for the first deck it comes up in
+		{deck: "jp::Minna", priority: 1, cards: queuedCards("jp::Minna", 2)},
+	}
+	got := interleaveQueues(queues, 7, newDailyBudget(nil, nil, 7))
+
+	ids := []uint64{}
+	for _, card := range got.Cards {
+		ids = append(ids, card.Card.ID)
+	}
+	if want := []uint64{1, 11, 2, 3, 4, 12, 5}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("interleaveQueues() ids = %v, want %v", ids, want)
+	}
+	if got.Decks[0].Reviews != 4 || got.Decks[1].Reviews != 2 || got.Decks[2].Reviews != 1 {
+		t.Errorf("interleaveQueues() decks = %+v", got.Decks)
+	}
+}
+
+func Test_interleaveQueues_sharedLimits(t *testing.T) {
+	decks := []langfi.Deck{
+		newDeck(1, 0, "jp", langfi.DeckSettings{NewPerDay: 3}),
+		newDeck(2, 1, "jp::L05", langfi.DeckSettings{}),
+		newDeck(3, 1, "jp::L06", langfi.DeckSettings{}),
+	}
+	queues := []deckQueue{
+		{deck: "jp::L05", priority: 1, cards: queuedCards("jp::L05", 1, 2, 3)},
+		{deck: "jp::L06", priority: 1, cards: queuedCards("jp::L06", 11, 12, 13)},
+	}
+	for i := range queues {
+		for j := range queues[i].cards {
+			queues[i].cards[j].New = true
+		}
+	}
+	// each sub deck inherits the limit of 3 of jp, which counts the new cards of both
+	got := interleaveQueues(queues, 10, newDailyBudget(decks, nil, 10))
+	if len(got.Cards) != 3 || got.Decks[0].New+got.Decks[1].New != 3 {
+		t.Errorf("interleaveQueues() = %+v, want 3 new cards in all", got)
+	}
+}
+
+func TestGetReviewQueue(t *testing.T) {
+	jps, _ := newSyncService(t)
+	ctx := context.Background()
+	now := time.Now()
+	addCard := func(front, deck string, reviewed bool) uint64 {
+		t.Helper()
+		card := langfi.NewReviewCard(front, "")
+		card.Group = deck
+		card.Status = langfi.CARD_LEARN
+		if reviewed {
+			card.FsrsData.State = fsrs.Review
+			card.FsrsData.Reps = 1
+			card.FsrsData.Due = now.Add(-time.Hour)
+		}
+		if err := jps.repo.AddCard(ctx, &card); err != nil {
+			t.Fatalf("AddCard() error = %v", err)
+		}
+		return card.ID
+	}
+	jp1 := addCard("一", "jp::Minna", true)
+	jp2 := addCard("二", "jp::Minna", true)
+	jpNew := addCard("三", "jp", false)
+	en1 := addCard("[...] rising", "en::IE::news", true)
+	enNew1 := addCard("[...] fall", "en::IE::news", false)
+	addCard("[...] grow", "en::IE::news", false)
+
+	decks, err := jps.repo.GetDecks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, deck := range *decks {
+		settings := map[string]langfi.DeckSettings{
+			"jp": {Priority: 2, NewPerDay: 5},
+			"en": {NewPerDay: 1},
+		}[deck.Name]
+		if err := jps.repo.UpdateDeckSettings(ctx, deck.ID, &settings); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	queue, err := jps.GetReviewQueue(ctx, nil, 0)
+	if err != nil {
+		t.Fatalf("GetReviewQueue() error = %v", err)
+	}
+	ids := []uint64{}
+	for _, card := range queue.Cards {
+		ids = append(ids, card.Card.ID)
+	}
+	// jp gets two cards for every en card, reviews come before new cards in each deck
+	// and en only allows one new card a day
+	if want := []uint64{jp1, en1, jp2, jpNew, enNew1}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("GetReviewQueue() ids = %v, want %v", ids, want)
+	}
+	if len(queue.Decks) != 2 || queue.Decks[0].Priority+queue.Decks[1].Priority != 3 {
+		t.Errorf("GetReviewQueue() decks = %+v", queue.Decks)
+	}
+
+	// a new card reviewed today uses up the new card limit of en
+	err = jps.repo.AddReviewLog(ctx, &langfi.ReviewLog{CardID: enNew1, Group: "en::IE::news", ReviewLog: fsrs.ReviewLog{State: fsrs.New, Review: now}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue, err = jps.GetReviewQueue(ctx, []string{"en"}, 10)
+	if err != nil {
+		t.Fatalf("GetReviewQueue(en) error = %v", err)
+	}
+	if len(queue.Cards) != 1 || queue.Cards[0].Card.ID != en1 || queue.Decks[0].New != 0 {
+		t.Errorf("GetReviewQueue(en) = %+v", queue)
+	}
+
+	if _, err := jps.GetReviewQueue(ctx, []string{"jp::"}, 10); err == nil {
+		t.Errorf("GetReviewQueue(jp::) error = nil, want an invalid deck")
+	}
+}
@@ -0,0 +1,116 @@
+package jpxpractice
+
+import (
+	"context"
+	"slices"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/pkg/errors"
+)
+
+const (
+	DEFAULT_SEARCH_LIMIT = 100
+	MAX_SEARCH_LIMIT     = 1000
+	// bulk edits touch at most this many cards per request
+	MAX_BULK_EDIT = 5000
+)
+
+func (jps *jpxPracService) SearchCards(ctx context.Context, query string, limit int) (*[]langfi.ReviewCard, error) {
+	cardQuery, err := langfi.ParseCardQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DEFAULT_SEARCH_LIMIT
+	}
+	return jps.repo.SearchCards(ctx, cardQuery, min(limit, MAX_SEARCH_LIMIT))
+}
+
+// FetchFilteredCard picks the most due learning card matching the query, so a query like
+// `tag:lesson5 group:kanji` works as a cram session. A status term in the query overrides
+// the learning status.
+func (jps *jpxPracService) FetchFilteredCard(ctx context.Context, query string) (*langfi.ReviewCard, error) {
+	cardQuery, err := langfi.ParseCardQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if !cardQuery.HasField(langfi.QUERY_STATUS) {
+		cardQuery.Terms = append(cardQuery.Terms, langfi.QueryTerm{Field: langfi.QUERY_STATUS, Op: langfi.OP_EQ, Value: langfi.CARD_LEARN})
+	}
+
+	cards, err := jps.repo.SearchCards(ctx, cardQuery, 1)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to search cards")
+	}
+	if len(*cards) == 0 {
+		return nil, model.ErrNoMoreDataAvailable
+	}
+	return &(*cards)[0], nil
+}
+
+func (jps *jpxPracService) BulkEditCards(ctx context.Context, edit *langfi.BulkEditDto) (*langfi.BulkEditResult, error) {
+	cardQuery, err := langfi.ParseCardQuery(edit.Query)
+	if err != nil {
+		return nil, err
+	}
+	if len(cardQuery.Terms) == 0 {
+		return nil, errors.Wrap(langfi.ErrInvalidCardQuery, "bulk edit needs a non empty query")
+	}
+	// the deck is content shared by every user, only their own tags and status are edited in bulk
+	if edit.Group != "" {
+		return nil, errors.Wrap(langfi.ErrInvalidCardQuery, "bulk edit cannot move cards to another deck")
+	}
+	if edit.Status != "" && !slices.Contains(langfi.ALL_CARD_STATUS, edit.Status) {
+		return nil, errors.Wrapf(langfi.ErrInvalidCardQuery, "status must be one of %v", langfi.ALL_CARD_STATUS)
+	}
+
+	cards, err := jps.repo.SearchCards(ctx, cardQuery, MAX_BULK_EDIT)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to search cards")
+	}
+
+	result := &langfi.BulkEditResult{Matched: len(*cards)}
+	for i := range *cards {
+		card := &(*cards)[i]
+		if !applyBulkEdit(card, edit) {
+			continue
+		}
+		err = jps.repo.UpdateCard(ctx, card)
+		if err != nil {
+			return result, errors.Wrapf(err, "failed to update card id = %v", card.ID)
+		}
+		result.Updated++
+	}
+	return result, nil
+}
+
+// applyBulkEdit changes the card in place and reports whether anything changed
+func applyBulkEdit(card *langfi.ReviewCard, edit *langfi.BulkEditDto) bool {
+	changed := false
+	if edit.Status != "" && edit.Status != card.Status {
+		card.Status = edit.Status
+		changed = true
+	}
+
+	removed := map[string]bool{}
+	for _, tag := range langfi.NormalizeTags(edit.RemoveTags) {
+		removed[tag] = true
+	}
+	tags := []string{}
+	for _, tag := range card.Tags {
+		if removed[tag] {
+			changed = true
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	for _, tag := range langfi.NormalizeTags(edit.AddTags) {
+		if !removed[tag] && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+			changed = true
+		}
+	}
+	card.Tags = tags
+	return changed
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/nhuongmh/cfvs.jpx/bootstrap"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
 	"github.com/open-spaced-repetition/go-fsrs/v3"
 	"github.com/pkg/errors"
@@ -14,6 +15,7 @@ type jpxPracService struct {
 	contextTimeout time.Duration
 	repo           langfi.PracticeRepo
 	fsrsService    *fsrs.FSRS
+	decks          *deckCache
 }
 
 func NewJpxPracService(timeout time.Duration, repo langfi.PracticeRepo, env *bootstrap.Env) langfi.PracticeService {
@@ -21,23 +23,32 @@ func NewJpxPracService(timeout time.Duration, repo langfi.PracticeRepo, env *boo
 		contextTimeout: timeout,
 		repo:           repo,
 		fsrsService:    fsrs.NewFSRS(fsrs.DefaultParam()),
+		decks:          newDeckCache(),
 	}
 	return jpa
 }
 
-func (jps *jpxPracService) GetGroups(ctx context.Context) []string {
-	return []string{"jp"}
-}
-
+// FetchCard returns the most due learning card of the deck and its sub decks,
+// within the daily limits of the deck
 func (jps *jpxPracService) FetchCard(ctx context.Context, group string) (*langfi.ReviewCard, error) {
-	// get card from repo
-	card, err := jps.repo.FetchReviewCard(ctx, group)
+	query := &langfi.CardQuery{Terms: []langfi.QueryTerm{
+		{Field: langfi.QUERY_GROUP, Op: langfi.OP_EQ, Value: group},
+		{Field: langfi.QUERY_STATUS, Op: langfi.OP_EQ, Value: langfi.CARD_LEARN},
+	}}
+	err := jps.limitQuery(ctx, group, query)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get card")
 	}
 
-	return card, nil
+	cards, err := jps.repo.SearchCards(ctx, query, 1)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get card")
+	}
+	if len(*cards) == 0 {
+		return nil, errors.Wrap(model.ErrNoMoreDataAvailable, "failed to get card")
+	}
 
+	return &(*cards)[0], nil
 }
 
 func (jps *jpxPracService) GetGroupStats(ctx context.Context) (*[]langfi.GroupSummaryDto, error) {
@@ -54,9 +65,28 @@ func (jps *jpxPracService) SubmitCard(ctx context.Context, cardId uint64, rating
 		return errors.New("rating must be between 1 and 4")
 	}
 
-	schedulingCards := jps.fsrsService.Repeat(card.FsrsData.Card, time.Now())
-	card.FsrsData.Card = schedulingCards[fsrs.Rating(rating)].Card
-	return jps.repo.UpdateCard(ctx, card)
+	return jps.reviewCard(ctx, card, fsrs.Rating(rating), time.Now())
+}
+
+// reviewCard schedules the card with the given rating and records the review, both or neither are stored
+func (jps *jpxPracService) reviewCard(ctx context.Context, card *langfi.ReviewCard, rating fsrs.Rating, now time.Time) error {
+	scheduler := jps.schedulerFor(ctx, card.Group)
+	reviewed, err := jps.repo.ReviewCard(ctx, card.ID, func(locked *langfi.ReviewCard, logs []langfi.ReviewLog) ([]langfi.ReviewLog, error) {
+		schedulingInfo := scheduler.Repeat(locked.FsrsData.Card, now)[rating]
+		locked.FsrsData.Card = schedulingInfo.Card
+		return append(logs, langfi.ReviewLog{
+			ReviewLog:  schedulingInfo.ReviewLog,
+			CardID:     locked.ID,
+			Group:      locked.Group,
+			Stability:  locked.FsrsData.Stability,
+			Difficulty: locked.FsrsData.Difficulty,
+		}), nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to review card")
+	}
+	*card = *reviewed
+	return nil
 }
 
 func (jps *jpxPracService) GetCard(ctx context.Context, cardID uint64) (*langfi.ReviewCard, error) {
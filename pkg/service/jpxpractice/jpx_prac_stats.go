@@ -0,0 +1,281 @@
+package jpxpractice
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/open-spaced-repetition/go-fsrs/v3"
+	"github.com/pkg/errors"
+)
+
+const (
+	DEFAULT_FORECAST_DAYS = 30
+	DEFAULT_HEATMAP_DAYS  = 365
+	// retention is measured over the reviews of the last year unless asked otherwise
+	DEFAULT_RETENTION_WINDOW_DAYS = 365
+	MAX_STATS_DAYS                = 3650
+	// UTC offsets in use range from -12:00 to +14:00
+	MIN_TZ_MINUTES = -12 * 60
+	MAX_TZ_MINUTES = 14 * 60
+
+	statsDateLayout = "2006-01-02"
+)
+
+type statsBucket struct {
+	label string
+	upper float64 // exclusive upper bound
+}
+
+var cardAgeBuckets = []statsBucket{
+	{"< 1 week", 7},
+	{"1 week - 1 month", 30},
+	{"1 - 3 months", 90},
+	{"3 months - 1 year", 365},
+	{"> 1 year", math.Inf(1)},
+}
+
+var stabilityBuckets = []statsBucket{
+	{"< 1 day", 1},
+	{"1 - 7 days", 7},
+	{"7 - 21 days", 21},
+	{"21 - 90 days", 90},
+	{"90 - 365 days", 365},
+	{"> 1 year", math.Inf(1)},
+}
+
+// GetReviewStats aggregates the learning cards, the reviews of the window and the first review of each card,
+// the full review history is never loaded
+func (jps *jpxPracService) GetReviewStats(ctx context.Context, query *langfi.ReviewStatsQuery) (*langfi.ReviewStatsDto, error) {
+	forecastDays := statsDays(query.ForecastDays, DEFAULT_FORECAST_DAYS)
+	heatmapDays := statsDays(query.HeatmapDays, DEFAULT_HEATMAP_DAYS)
+	windowDays := statsDays(query.WindowDays, DEFAULT_RETENTION_WINDOW_DAYS)
+	if query.TzMinutes < MIN_TZ_MINUTES || query.TzMinutes > MAX_TZ_MINUTES {
+		return nil, errors.Wrapf(langfi.ErrInvalidStatsQuery, "tz must be between %d and %d minutes", MIN_TZ_MINUTES, MAX_TZ_MINUTES)
+	}
+	now := time.Now().In(time.FixedZone("", query.TzMinutes*60))
+
+	cards, err := jps.repo.GetCardsByStatus(ctx, langfi.CARD_LEARN)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get learning cards")
+	}
+
+	since := startOfDay(now).AddDate(0, 0, -(max(heatmapDays, windowDays) - 1))
+	logs, err := jps.repo.GetReviewLogs(ctx, since)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get review logs")
+	}
+	windowStart := startOfDay(now).AddDate(0, 0, -(windowDays - 1))
+	window := slices.DeleteFunc(slices.Clone(*logs), func(log langfi.ReviewLog) bool {
+		return log.Review.Before(windowStart)
+	})
+
+	// the age of a card and its time to mastery need its first reviews, which may be older than the window
+	first, err := jps.repo.GetFirstReviewLogs(ctx, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get first reviews")
+	}
+	mastered, err := jps.repo.GetFirstReviewLogs(ctx, langfi.MASTERY_STABILITY_DAYS)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get mastered reviews")
+	}
+
+	return &langfi.ReviewStatsDto{
+		Forecast:         dueForecast(*cards, now, forecastDays),
+		Heatmap:          reviewHeatmap(*logs, now, heatmapDays),
+		RetentionByAge:   retentionByAge(window, reviewTimes(*first)),
+		RetentionByGroup: retentionByGroup(window),
+		Memory:           memoryDistribution(*cards),
+		Mastery:          groupMastery(*first, reviewTimes(*mastered)),
+	}, nil
+}
+
+// statsDays returns the days asked for, the default when unset and at most MAX_STATS_DAYS
+func statsDays(days, defaultDays int) int {
+	if days <= 0 {
+		days = defaultDays
+	}
+	return min(days, MAX_STATS_DAYS)
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// number of calendar days from `from` to `to`, negative if `to` is in the past
+func daysBetween(from, to time.Time) int {
+	return int(math.Floor(startOfDay(to.In(from.Location())).Sub(startOfDay(from)).Hours()/24 + 0.5))
+}
+
+// dueForecast counts cards due on each of the next `days` days, overdue cards are counted on today
+func dueForecast(cards []langfi.ReviewCard, now time.Time, days int) []langfi.DailyCount {
+	today := startOfDay(now)
+	forecast := make([]langfi.DailyCount, days)
+	for i := range forecast {
+		forecast[i].Date = today.AddDate(0, 0, i).Format(statsDateLayout)
+	}
+	for i := range cards {
+		day := max(daysBetween(today, cards[i].FsrsData.Due), 0)
+		if day < days {
+			forecast[day].Count++
+		}
+	}
+	return forecast
+}
+
+// reviewHeatmap counts reviews done on each of the last `days` days, oldest first
+func reviewHeatmap(logs []langfi.ReviewLog, now time.Time, days int) []langfi.DailyCount {
+	first := startOfDay(now).AddDate(0, 0, -(days - 1))
+	heatmap := make([]langfi.DailyCount, days)
+	for i := range heatmap {
+		heatmap[i].Date = first.AddDate(0, 0, i).Format(statsDateLayout)
+	}
+	for i := range logs {
+		day := daysBetween(first, logs[i].Review)
+		if day >= 0 && day < days {
+			heatmap[day].Count++
+		}
+	}
+	return heatmap
+}
+
+// reviewTimes maps each card to the time of its review among logs holding one review per card
+func reviewTimes(logs []langfi.ReviewLog) map[uint64]time.Time {
+	times := make(map[uint64]time.Time, len(logs))
+	for i := range logs {
+		times[logs[i].CardID] = logs[i].Review
+	}
+	return times
+}
+
+// true retention only looks at reviews of cards already graduated to the review state,
+// same-day learning steps would inflate the ratio
+func isRetentionReview(log *langfi.ReviewLog) bool {
+	return log.State == fsrs.Review
+}
+
+func addRetention(dto *langfi.RetentionDto, log *langfi.ReviewLog) {
+	dto.Reviews++
+	if log.Rating > fsrs.Again {
+		dto.Passed++
+	}
+	dto.Retention = float64(dto.Passed) / float64(dto.Reviews)
+}
+
+// retentionByAge buckets the reviews by the age of the card at the review, since its first review
+func retentionByAge(logs []langfi.ReviewLog, first map[uint64]time.Time) []langfi.RetentionDto {
+	result := make([]langfi.RetentionDto, len(cardAgeBuckets))
+	for i := range cardAgeBuckets {
+		result[i].Label = cardAgeBuckets[i].label
+	}
+	for i := range logs {
+		log := &logs[i]
+		if !isRetentionReview(log) {
+			continue
+		}
+		age := log.Review.Sub(first[log.CardID]).Hours() / 24
+		for b := range cardAgeBuckets {
+			if age < cardAgeBuckets[b].upper {
+				addRetention(&result[b], log)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func retentionByGroup(logs []langfi.ReviewLog) []langfi.RetentionDto {
+	groups := map[string]*langfi.RetentionDto{}
+	for i := range logs {
+		log := &logs[i]
+		if !isRetentionReview(log) {
+			continue
+		}
+		dto, ok := groups[log.Group]
+		if !ok {
+			dto = &langfi.RetentionDto{Label: log.Group}
+			groups[log.Group] = dto
+		}
+		addRetention(dto, log)
+	}
+
+	result := make([]langfi.RetentionDto, 0, len(groups))
+	for _, dto := range groups {
+		result = append(result, *dto)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Label < result[j].Label })
+	return result
+}
+
+func memoryDistribution(cards []langfi.ReviewCard) langfi.MemoryDistributionDto {
+	dist := langfi.MemoryDistributionDto{
+		Stability:  make([]langfi.DistributionBucket, len(stabilityBuckets)),
+		Difficulty: make([]langfi.DistributionBucket, 9),
+	}
+	for i := range stabilityBuckets {
+		dist.Stability[i].Label = stabilityBuckets[i].label
+	}
+	for i := range dist.Difficulty {
+		dist.Difficulty[i].Label = fmt.Sprintf("%d - %d", i+1, i+2)
+	}
+
+	var sumStability, sumDifficulty float64
+	for i := range cards {
+		fsrsd := &cards[i].FsrsData
+		if fsrsd.State == fsrs.New {
+			continue
+		}
+		dist.NumCards++
+		sumStability += fsrsd.Stability
+		sumDifficulty += fsrsd.Difficulty
+		for b := range stabilityBuckets {
+			if fsrsd.Stability < stabilityBuckets[b].upper {
+				dist.Stability[b].Count++
+				break
+			}
+		}
+		// fsrs keeps difficulty within [1, 10]
+		d := min(max(int(fsrsd.Difficulty)-1, 0), len(dist.Difficulty)-1)
+		dist.Difficulty[d].Count++
+	}
+	if dist.NumCards > 0 {
+		dist.AvgStability = sumStability / float64(dist.NumCards)
+		dist.AvgDifficulty = sumDifficulty / float64(dist.NumCards)
+	}
+	return dist
+}
+
+// groupMastery measures, per group, how long cards take from their first review
+// until stability reaches MASTERY_STABILITY_DAYS, given the first review and the mastery time of each card
+func groupMastery(first []langfi.ReviewLog, mastered map[uint64]time.Time) []langfi.GroupMasteryDto {
+	groups := map[string]*langfi.GroupMasteryDto{}
+	totalDays := map[string]float64{}
+	for i := range first {
+		log := &first[i]
+		dto, ok := groups[log.Group]
+		if !ok {
+			dto = &langfi.GroupMasteryDto{Group: log.Group}
+			groups[log.Group] = dto
+		}
+		dto.NumCards++
+		if at, ok := mastered[log.CardID]; ok {
+			dto.Mastered++
+			totalDays[log.Group] += at.Sub(log.Review).Hours() / 24
+		}
+	}
+
+	result := make([]langfi.GroupMasteryDto, 0, len(groups))
+	for group, dto := range groups {
+		if dto.Mastered > 0 {
+			dto.AvgDaysToMastery = totalDays[group] / float64(dto.Mastered)
+		}
+		result = append(result, *dto)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Group < result[j].Group })
+	return result
+}
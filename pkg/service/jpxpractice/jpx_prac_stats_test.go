@@ -0,0 +1,123 @@
+package jpxpractice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/open-spaced-repetition/go-fsrs/v3"
+)
+
+func newStatsLog(cardID uint64, group string, review time.Time, state fsrs.State, rating fsrs.Rating, stability float64) langfi.ReviewLog {
+	log := langfi.ReviewLog{CardID: cardID, Group: group, Stability: stability}
+	log.Review = review
+	log.State = state
+	log.Rating = rating
+	return log
+}
+
+func Test_dueForecast(t *testing.T) {
+	now := time.Date(2024, 5, 10, 15, 0, 0, 0, time.UTC)
+	cards := make([]langfi.ReviewCard, 4)
+	cards[0].FsrsData.Due = now.AddDate(0, 0, -3) // overdue counts as today
+	cards[1].FsrsData.Due = now.Add(2 * time.Hour)
+	cards[2].FsrsData.Due = now.AddDate(0, 0, 2)
+	cards[3].FsrsData.Due = now.AddDate(0, 0, 10) // outside forecast window
+
+	got := dueForecast(cards, now, 3)
+	want := []langfi.DailyCount{{Date: "2024-05-10", Count: 2}, {Date: "2024-05-11", Count: 0}, {Date: "2024-05-12", Count: 1}}
+	if len(got) != len(want) {
+		t.Fatalf("dueForecast() returned %v days, want %v", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("dueForecast()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func Test_reviewHeatmap(t *testing.T) {
+	now := time.Date(2024, 5, 10, 15, 0, 0, 0, time.UTC)
+	logs := []langfi.ReviewLog{
+		newStatsLog(1, "L1", now.AddDate(0, 0, -10), fsrs.New, fsrs.Good, 1),
+		newStatsLog(1, "L1", now.AddDate(0, 0, -1), fsrs.Review, fsrs.Good, 5),
+		newStatsLog(2, "L1", now.Add(-time.Hour), fsrs.New, fsrs.Good, 1),
+		newStatsLog(3, "L1", now.Add(-2*time.Hour), fsrs.New, fsrs.Again, 1),
+	}
+
+	got := reviewHeatmap(logs, now, 2)
+	want := []langfi.DailyCount{{Date: "2024-05-09", Count: 1}, {Date: "2024-05-10", Count: 2}}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("reviewHeatmap()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func Test_reviewHeatmapTimezone(t *testing.T) {
+	// 23:30 UTC is already the next day in Tokyo
+	review := time.Date(2024, 5, 9, 23, 30, 0, 0, time.UTC)
+	logs := []langfi.ReviewLog{newStatsLog(1, "L1", review, fsrs.Review, fsrs.Good, 5)}
+
+	now := time.Date(2024, 5, 10, 1, 0, 0, 0, time.UTC)
+	utc := reviewHeatmap(logs, now, 2)
+	if utc[0].Count != 1 || utc[1].Count != 0 {
+		t.Errorf("reviewHeatmap() in UTC = %v, want the review on 2024-05-09", utc)
+	}
+	tokyo := reviewHeatmap(logs, now.In(time.FixedZone("", 9*60*60)), 2)
+	if tokyo[1].Date != "2024-05-10" || tokyo[1].Count != 1 {
+		t.Errorf("reviewHeatmap() at +09:00 = %v, want the review on 2024-05-10", tokyo)
+	}
+}
+
+func Test_retention(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	logs := []langfi.ReviewLog{
+		newStatsLog(1, "L1", start, fsrs.New, fsrs.Good, 2),
+		newStatsLog(1, "L1", start.AddDate(0, 0, 2), fsrs.Review, fsrs.Good, 10),
+		newStatsLog(1, "L1", start.AddDate(0, 0, 12), fsrs.Review, fsrs.Again, 3),
+		newStatsLog(2, "L2", start, fsrs.New, fsrs.Good, 2),
+		newStatsLog(2, "L2", start.AddDate(0, 0, 3), fsrs.Review, fsrs.Easy, 25),
+	}
+
+	first := []langfi.ReviewLog{logs[0], logs[3]}
+	byAge := retentionByAge(logs, reviewTimes(first))
+	if byAge[0].Reviews != 2 || byAge[0].Passed != 2 {
+		t.Errorf("retentionByAge()[0] = %+v, want 2 passed reviews", byAge[0])
+	}
+	if byAge[1].Reviews != 1 || byAge[1].Retention != 0 {
+		t.Errorf("retentionByAge()[1] = %+v, want 1 failed review", byAge[1])
+	}
+
+	byGroup := retentionByGroup(logs)
+	if len(byGroup) != 2 || byGroup[0].Label != "L1" || byGroup[0].Retention != 0.5 || byGroup[1].Retention != 1 {
+		t.Errorf("retentionByGroup() = %+v", byGroup)
+	}
+
+	mastery := groupMastery(first, reviewTimes([]langfi.ReviewLog{logs[4]}))
+	if len(mastery) != 2 || mastery[0].Mastered != 0 || mastery[1].Mastered != 1 || mastery[1].AvgDaysToMastery != 3 {
+		t.Errorf("groupMastery() = %+v", mastery)
+	}
+}
+
+func Test_memoryDistribution(t *testing.T) {
+	cards := make([]langfi.ReviewCard, 3)
+	cards[0].FsrsData.State = fsrs.Review
+	cards[0].FsrsData.Stability = 30
+	cards[0].FsrsData.Difficulty = 4.5
+	cards[1].FsrsData.State = fsrs.Learning
+	cards[1].FsrsData.Stability = 0.5
+	cards[1].FsrsData.Difficulty = 10
+	cards[2].FsrsData.State = fsrs.New
+
+	got := memoryDistribution(cards)
+	if got.NumCards != 2 || got.AvgStability != 15.25 || got.AvgDifficulty != 7.25 {
+		t.Errorf("memoryDistribution() = %+v", got)
+	}
+	if got.Stability[0].Count != 1 || got.Stability[3].Count != 1 {
+		t.Errorf("memoryDistribution().Stability = %+v", got.Stability)
+	}
+	if got.Difficulty[3].Count != 1 || got.Difficulty[8].Count != 1 {
+		t.Errorf("memoryDistribution().Difficulty = %+v", got.Difficulty)
+	}
+}
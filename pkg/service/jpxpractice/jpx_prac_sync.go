@@ -0,0 +1,221 @@
+package jpxpractice
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/open-spaced-repetition/go-fsrs/v3"
+	"github.com/pkg/errors"
+)
+
+const (
+	DEFAULT_SYNC_LIMIT = 500
+	MAX_SYNC_LIMIT     = 2000
+	// reviews per push, clients push bigger queues in several requests
+	MAX_PUSH_REVIEWS = 5000
+	// device clocks may run ahead of the server by this much
+	MAX_CLOCK_SKEW = 5 * time.Minute
+)
+
+// PullChanges returns the current state of the cards changed after the cursor, oldest change first
+func (jps *jpxPracService) PullChanges(ctx context.Context, cursor uint64, limit int) (*langfi.SyncPullDto, error) {
+	if limit <= 0 {
+		limit = DEFAULT_SYNC_LIMIT
+	}
+	limit = min(limit, MAX_SYNC_LIMIT)
+
+	changes, err := jps.repo.GetCardChanges(ctx, cursor, limit+1)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get card changes")
+	}
+	pull := &langfi.SyncPullDto{Cursor: cursor, Cards: []langfi.ReviewCard{}, Deleted: []uint64{}}
+	if len(*changes) > limit {
+		pull.HasMore = true
+		*changes = (*changes)[:limit]
+	}
+
+	changed := []uint64{}
+	for _, change := range *changes {
+		if change.Deleted {
+			pull.Deleted = append(pull.Deleted, change.CardID)
+		} else {
+			changed = append(changed, change.CardID)
+		}
+		pull.Cursor = change.Seq
+	}
+	cards, err := jps.repo.GetCardsByID(ctx, changed)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get changed cards")
+	}
+	pull.Cards = *cards
+	return pull, nil
+}
+
+// PushReviews records the reviews by card. Reviews given after the last recorded review of a card
+// continue its schedule. Older reviews replay all reviews of the card in time order from a new card,
+// so the schedule does not depend on the order devices push in, when its recorded reviews are its whole
+// history; otherwise they are rejected, the card keeps its schedule. A review pushed twice is counted once.
+func (jps *jpxPracService) PushReviews(ctx context.Context, push *langfi.SyncPushDto) (*langfi.SyncPushResult, error) {
+	if len(push.Reviews) > MAX_PUSH_REVIEWS {
+		return nil, errors.Wrapf(langfi.ErrInvalidSync, "at most %v reviews can be pushed at once", MAX_PUSH_REVIEWS)
+	}
+
+	result := &langfi.SyncPushResult{Rejected: []langfi.RejectedReview{}, Cards: []langfi.ReviewCard{}}
+	reject := func(review langfi.OfflineReview, reason string) {
+		result.Rejected = append(result.Rejected, langfi.RejectedReview{OfflineReview: review, Reason: reason})
+	}
+	latest := time.Now().Add(MAX_CLOCK_SKEW)
+	byCard := map[uint64][]langfi.OfflineReview{}
+	for _, review := range push.Reviews {
+		switch {
+		case review.Rating < uint64(fsrs.Again) || review.Rating > uint64(fsrs.Easy):
+			reject(review, "rating must be between 1 and 4")
+		case review.Review.IsZero():
+			reject(review, "review time is required")
+		case review.Review.After(latest):
+			reject(review, "review time is in the future")
+		default:
+			byCard[review.CardID] = append(byCard[review.CardID], review)
+		}
+	}
+
+	cardIDs := make([]uint64, 0, len(byCard))
+	for cardID := range byCard {
+		cardIDs = append(cardIDs, cardID)
+	}
+	sort.Slice(cardIDs, func(i, j int) bool { return cardIDs[i] < cardIDs[j] })
+	for _, cardID := range cardIDs {
+		card, err := jps.repo.GetCard(ctx, cardID)
+		if err == nil {
+			var sync *cardSync
+			sync, err = jps.syncCardReviews(ctx, card, byCard[cardID])
+			if err == nil {
+				result.Accepted += sync.accepted
+				result.Duplicates += sync.duplicates
+				for _, review := range sync.outdated {
+					reject(review, "review is older than the last review of the card and its earlier reviews are not recorded")
+				}
+				if sync.accepted > 0 {
+					result.Cards = append(result.Cards, *sync.card)
+				}
+				continue
+			}
+		}
+		if errors.Is(err, model.ErrNoMoreDataAvailable) {
+			for _, review := range byCard[cardID] {
+				reject(review, "card not found")
+			}
+			continue
+		}
+		return nil, errors.Wrapf(err, "failed to sync reviews of card id = %v", cardID)
+	}
+	return result, nil
+}
+
+// reviewKey identifies a review, times are compared at the microsecond precision of the databases
+type reviewKey struct {
+	micros int64
+	rating uint64
+}
+
+// cardSync is the outcome of the reviews pushed for one card
+type cardSync struct {
+	card       *langfi.ReviewCard
+	accepted   int
+	duplicates int
+	// outdated reviews are older than the last recorded review of a card whose history is incomplete
+	outdated []langfi.OfflineReview
+}
+
+// syncCardReviews merges the reviews into the card while no other review of it runs
+func (jps *jpxPracService) syncCardReviews(ctx context.Context, card *langfi.ReviewCard, reviews []langfi.OfflineReview) (*cardSync, error) {
+	scheduler := jps.schedulerFor(ctx, card.Group)
+	sync := &cardSync{card: card}
+	reviewed, err := jps.repo.ReviewCard(ctx, card.ID, func(locked *langfi.ReviewCard, logs []langfi.ReviewLog) ([]langfi.ReviewLog, error) {
+		*sync = cardSync{card: locked}
+		seen := map[reviewKey]bool{}
+		for _, log := range logs {
+			seen[reviewKey{log.Review.UnixMicro(), uint64(log.Rating)}] = true
+		}
+		pushed := []langfi.OfflineReview{}
+		for _, review := range reviews {
+			key := reviewKey{review.Review.UnixMicro(), review.Rating}
+			if seen[key] {
+				sync.duplicates++
+				continue
+			}
+			seen[key] = true
+			pushed = append(pushed, review)
+		}
+		sortReviews(pushed)
+		last := locked.FsrsData.LastReview
+		if len(logs) > 0 && logs[len(logs)-1].Review.After(last) {
+			last = logs[len(logs)-1].Review
+		}
+		if len(pushed) == 0 || !pushed[0].Review.Before(last) {
+			sync.accepted = len(pushed)
+			return append(logs, applyReviews(scheduler, locked, pushed)...), nil
+		}
+
+		if locked.FsrsData.Reps != uint64(len(logs)) {
+			// the schedule holds reviews that have no log, e.g. imported ones: it cannot be replayed
+			newer := []langfi.OfflineReview{}
+			for _, review := range pushed {
+				if review.Review.Before(last) {
+					sync.outdated = append(sync.outdated, review)
+				} else {
+					newer = append(newer, review)
+				}
+			}
+			sync.accepted = len(newer)
+			return append(logs, applyReviews(scheduler, locked, newer)...), nil
+		}
+
+		history := make([]langfi.OfflineReview, 0, len(logs)+len(pushed))
+		for _, log := range logs {
+			history = append(history, langfi.OfflineReview{CardID: locked.ID, Rating: uint64(log.Rating), Review: log.Review})
+		}
+		history = append(history, pushed...)
+		sortReviews(history)
+		sync.accepted = len(pushed)
+		locked.FsrsData.Card = fsrs.NewCard()
+		return applyReviews(scheduler, locked, history), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sync.card = reviewed
+	return sync, nil
+}
+
+// sortReviews orders reviews by time, reviews at the same time by rating so that replays are deterministic
+func sortReviews(reviews []langfi.OfflineReview) {
+	sort.SliceStable(reviews, func(i, j int) bool {
+		if !reviews[i].Review.Equal(reviews[j].Review) {
+			return reviews[i].Review.Before(reviews[j].Review)
+		}
+		return reviews[i].Rating < reviews[j].Rating
+	})
+}
+
+// applyReviews schedules the card with the ordered reviews and returns their logs
+func applyReviews(scheduler *fsrs.FSRS, card *langfi.ReviewCard, reviews []langfi.OfflineReview) []langfi.ReviewLog {
+	fsrsCard := card.FsrsData.Card
+	logs := make([]langfi.ReviewLog, 0, len(reviews))
+	for _, review := range reviews {
+		info := scheduler.Repeat(fsrsCard, review.Review)[fsrs.Rating(review.Rating)]
+		fsrsCard = info.Card
+		logs = append(logs, langfi.ReviewLog{
+			ReviewLog:  info.ReviewLog,
+			CardID:     card.ID,
+			Group:      card.Group,
+			Stability:  fsrsCard.Stability,
+			Difficulty: fsrsCard.Difficulty,
+		})
+	}
+	card.FsrsData.Card = fsrsCard
+	return logs
+}
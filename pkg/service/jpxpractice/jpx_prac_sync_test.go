@@ -0,0 +1,168 @@
+package jpxpractice
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/nhuongmh/cfvs.jpx/pkg/service/jpxpractice/repo"
+	"github.com/open-spaced-repetition/go-fsrs/v3"
+)
+
+func newSyncService(t *testing.T, fronts ...string) (*jpxPracService, []uint64) {
+	t.Helper()
+	jps := &jpxPracService{repo: repo.NewMemoryPracticeRepo(), fsrsService: fsrs.NewFSRS(fsrs.DefaultParam()), decks: newDeckCache()}
+	ids := []uint64{}
+	for _, front := range fronts {
+		card := langfi.NewReviewCard(front, "")
+		card.Status = langfi.CARD_LEARN
+		if err := jps.repo.AddCard(context.Background(), &card); err != nil {
+			t.Fatalf("AddCard() error = %v", err)
+		}
+		ids = append(ids, card.ID)
+	}
+	return jps, ids
+}
+
+func TestPushReviewsOrderIndependent(t *testing.T) {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	reviews := []langfi.OfflineReview{}
+	for i, rating := range []fsrs.Rating{fsrs.Good, fsrs.Again, fsrs.Good, fsrs.Easy} {
+		reviews = append(reviews, langfi.OfflineReview{Rating: uint64(rating), Review: start.AddDate(0, 0, 3*i)})
+	}
+
+	// one device pushes everything, two others push their halves in reverse order
+	var want langfi.ReviewCard
+	for i, pushes := range [][][]int{{{0, 1, 2, 3}}, {{2, 3}, {0, 1}}, {{3}, {1}, {2, 0}}} {
+		jps, ids := newSyncService(t, "犬")
+		accepted := 0
+		for _, push := range pushes {
+			dto := &langfi.SyncPushDto{}
+			for _, r := range push {
+				review := reviews[r]
+				review.CardID = ids[0]
+				dto.Reviews = append(dto.Reviews, review)
+			}
+			result, err := jps.PushReviews(context.Background(), dto)
+			if err != nil {
+				t.Fatalf("PushReviews() error = %v", err)
+			}
+			accepted += result.Accepted
+		}
+		if accepted != len(reviews) {
+			t.Errorf("pushes %v accepted %v reviews, want %v", pushes, accepted, len(reviews))
+		}
+
+		card, err := jps.repo.GetCard(context.Background(), ids[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		logs, err := jps.repo.GetCardReviewLogs(context.Background(), ids[0])
+		if err != nil || len(*logs) != len(reviews) {
+			t.Fatalf("GetCardReviewLogs() = %v logs, %v", len(*logs), err)
+		}
+		if i == 0 {
+			want = *card
+			if want.FsrsData.Reps != uint64(len(reviews)) || !want.FsrsData.LastReview.Equal(reviews[3].Review) {
+				t.Errorf("pushed card fsrs = %+v", want.FsrsData)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(card.FsrsData.Card, want.FsrsData.Card) {
+			t.Errorf("pushes %v fsrs = %+v, want %+v", pushes, card.FsrsData.Card, want.FsrsData.Card)
+		}
+	}
+}
+
+func TestPushReviewsDuplicatesAndRejects(t *testing.T) {
+	jps, ids := newSyncService(t, "猫")
+	review := langfi.OfflineReview{CardID: ids[0], Rating: uint64(fsrs.Good), Review: time.Now().Add(-time.Hour)}
+	push := &langfi.SyncPushDto{Reviews: []langfi.OfflineReview{
+		review,
+		review,
+		{CardID: ids[0], Rating: 5, Review: review.Review},
+		{CardID: ids[0], Rating: uint64(fsrs.Good)},
+		{CardID: ids[0], Rating: uint64(fsrs.Good), Review: time.Now().Add(time.Hour)},
+		{CardID: 999, Rating: uint64(fsrs.Good), Review: review.Review},
+	}}
+	result, err := jps.PushReviews(context.Background(), push)
+	if err != nil {
+		t.Fatalf("PushReviews() error = %v", err)
+	}
+	if result.Accepted != 1 || result.Duplicates != 1 || len(result.Rejected) != 4 || len(result.Cards) != 1 {
+		t.Errorf("PushReviews() = %+v", result)
+	}
+
+	// a retried push changes nothing
+	result, err = jps.PushReviews(context.Background(), &langfi.SyncPushDto{Reviews: []langfi.OfflineReview{review}})
+	if err != nil || result.Accepted != 0 || result.Duplicates != 1 || len(result.Cards) != 0 {
+		t.Errorf("retried PushReviews() = %+v, %v", result, err)
+	}
+}
+
+func TestPushReviewsKeepsUnrecordedHistory(t *testing.T) {
+	// an imported card has reviews without logs, replaying its logs from a new card would lose them
+	jps, ids := newSyncService(t, "鳥")
+	ctx := context.Background()
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	card, err := jps.repo.GetCard(ctx, ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	card.FsrsData.Card = fsrs.Card{Due: start.AddDate(0, 0, 30), Stability: 20, Difficulty: 5, ScheduledDays: 20,
+		Reps: 6, State: fsrs.Review, LastReview: start.AddDate(0, 0, 10)}
+	if err := jps.repo.UpdateCard(ctx, card); err != nil {
+		t.Fatal(err)
+	}
+
+	older := langfi.OfflineReview{CardID: ids[0], Rating: uint64(fsrs.Again), Review: start.AddDate(0, 0, 5)}
+	newer := langfi.OfflineReview{CardID: ids[0], Rating: uint64(fsrs.Good), Review: start.AddDate(0, 0, 31)}
+	result, err := jps.PushReviews(ctx, &langfi.SyncPushDto{Reviews: []langfi.OfflineReview{older, newer}})
+	if err != nil {
+		t.Fatalf("PushReviews() error = %v", err)
+	}
+	if result.Accepted != 1 || len(result.Rejected) != 1 || result.Rejected[0].OfflineReview != older {
+		t.Errorf("PushReviews() = %+v, want the newer review accepted and the older one rejected", result)
+	}
+	got, err := jps.repo.GetCard(ctx, ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.FsrsData.Reps != 7 || got.FsrsData.State != fsrs.Review || got.FsrsData.Stability <= 20 {
+		t.Errorf("card after the push fsrs = %+v, want its imported schedule continued", got.FsrsData.Card)
+	}
+}
+
+func TestPullChanges(t *testing.T) {
+	jps, ids := newSyncService(t, "一", "二", "三")
+	ctx := context.Background()
+
+	pull, err := jps.PullChanges(ctx, 0, 2)
+	if err != nil {
+		t.Fatalf("PullChanges() error = %v", err)
+	}
+	if !pull.HasMore || len(pull.Cards) != 2 || pull.Cards[0].ID != ids[0] {
+		t.Errorf("first PullChanges() = %+v", pull)
+	}
+	pull, err = jps.PullChanges(ctx, pull.Cursor, 2)
+	if err != nil || pull.HasMore || len(pull.Cards) != 1 || pull.Cards[0].ID != ids[2] {
+		t.Errorf("second PullChanges() = %+v, %v", pull, err)
+	}
+	cursor := pull.Cursor
+
+	_, err = jps.PushReviews(ctx, &langfi.SyncPushDto{Reviews: []langfi.OfflineReview{
+		{CardID: ids[1], Rating: uint64(fsrs.Easy), Review: time.Now().Add(-time.Minute)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pull, err = jps.PullChanges(ctx, cursor, 0)
+	if err != nil || len(pull.Cards) != 1 || pull.Cards[0].ID != ids[1] || pull.Cards[0].FsrsData.Reps != 1 {
+		t.Errorf("PullChanges() after a push = %+v, %v", pull, err)
+	}
+	if pull.Cursor <= cursor {
+		t.Errorf("PullChanges() cursor = %v, want after %v", pull.Cursor, cursor)
+	}
+}
@@ -0,0 +1,106 @@
+package jpxpractice
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/nhuongmh/cfvs.jpx/pkg/supporter/kana"
+	"github.com/open-spaced-repetition/go-fsrs/v3"
+	"github.com/pkg/errors"
+)
+
+const (
+	// answers at least this similar to the expected one are treated as a typo, not a lapse
+	TYPO_SIMILARITY = 0.8
+
+	// time budget for a correct answer: base plus a per character allowance
+	FAST_ANSWER_BASE     = 2 * time.Second
+	FAST_ANSWER_PER_CHAR = 300 * time.Millisecond
+	SLOW_ANSWER_BASE     = 6 * time.Second
+	SLOW_ANSWER_PER_CHAR = 1 * time.Second
+)
+
+// SubmitTypedAnswer grades the answer and suggests a rating, the card is rated with SubmitCard
+func (jps *jpxPracService) SubmitTypedAnswer(ctx context.Context, answer *langfi.TypedAnswerDto) (*langfi.TypedAnswerResult, error) {
+	card, err := jps.repo.GetCard(ctx, answer.CardID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get card")
+	}
+
+	return gradeTypedAnswer(card, answer.Answer, time.Duration(answer.ResponseTimeMs)*time.Millisecond), nil
+}
+
+// expectedAnswers lists what the learner types for the card. Cloze and imported anki cards prompt with
+// their front and are answered with their answer property or their back, other cards prompt with their
+// back and are answered with their front. The reading property is an alternative answer of both.
+func expectedAnswers(card *langfi.ReviewCard) []string {
+	answers := []string{}
+	if answer, ok := card.GetProp(langfi.PROP_ANSWER).(string); ok && strings.TrimSpace(answer) != "" {
+		answers = append(answers, answer)
+	} else if _, ok := card.GetProp(langfi.PROP_ANKI_MODEL).(string); ok {
+		answers = append(answers, card.Back)
+	} else {
+		answers = append(answers, card.Front)
+	}
+	if reading, ok := card.GetProp(langfi.PROP_READING).(string); ok && strings.TrimSpace(reading) != "" {
+		answers = append(answers, reading)
+	}
+	return answers
+}
+
+// gradeTypedAnswer compares the answer with the closest expected answer of the card. Romaji is only read
+// as kana when the expected answer is japanese, latin answers like those of cloze cards are compared as typed.
+func gradeTypedAnswer(card *langfi.ReviewCard, answer string, responseTime time.Duration) *langfi.TypedAnswerResult {
+	var result *langfi.TypedAnswerResult
+	for _, expected := range expectedAnswers(card) {
+		normalize := kana.NormalizeLatin
+		if kana.IsJapanese(expected) {
+			normalize = kana.Normalize
+		}
+		normalizedAnswer, normalizedExpected := normalize(answer), normalize(expected)
+		diff, similarity := kana.Diff(normalizedExpected, normalizedAnswer)
+		if result != nil && similarity <= result.Similarity {
+			continue
+		}
+		result = &langfi.TypedAnswerResult{
+			CardID:             card.ID,
+			Answer:             answer,
+			Expected:           expected,
+			NormalizedAnswer:   normalizedAnswer,
+			NormalizedExpected: normalizedExpected,
+			Exact:              normalizedAnswer != "" && similarity == 1,
+			Similarity:         similarity,
+			Diff:               make([]langfi.AnswerDiffDto, len(diff)),
+		}
+		for i := range diff {
+			result.Diff[i] = langfi.AnswerDiffDto{Op: diff[i].Op, Text: diff[i].Text}
+		}
+	}
+
+	result.SuggestedRating = uint64(suggestRating(result, responseTime))
+	return result
+}
+
+func suggestRating(result *langfi.TypedAnswerResult, responseTime time.Duration) fsrs.Rating {
+	if !result.Exact {
+		if result.NormalizedAnswer != "" && result.Similarity >= TYPO_SIMILARITY {
+			return fsrs.Hard
+		}
+		return fsrs.Again
+	}
+	if responseTime <= 0 {
+		return fsrs.Good
+	}
+
+	length := time.Duration(len([]rune(result.NormalizedExpected)))
+	switch {
+	case responseTime <= FAST_ANSWER_BASE+length*FAST_ANSWER_PER_CHAR:
+		return fsrs.Easy
+	case responseTime <= SLOW_ANSWER_BASE+length*SLOW_ANSWER_PER_CHAR:
+		return fsrs.Good
+	default:
+		return fsrs.Hard
+	}
+}
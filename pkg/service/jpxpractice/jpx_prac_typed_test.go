@@ -0,0 +1,113 @@
+package jpxpractice
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/nhuongmh/cfvs.jpx/pkg/supporter/kana"
+	"github.com/open-spaced-repetition/go-fsrs/v3"
+)
+
+func Test_gradeTypedAnswer(t *testing.T) {
+	card := langfi.NewReviewCard("私は学生です", "I am a student")
+	card.SetProp(langfi.PROP_READING, "わたしはがくせいです")
+
+	tests := []struct {
+		name         string
+		answer       string
+		responseTime time.Duration
+		wantExact    bool
+		wantRating   fsrs.Rating
+	}{
+		{name: "romaji reading, fast", answer: "watashi wa gakusei desu", responseTime: 3 * time.Second, wantExact: true, wantRating: fsrs.Easy},
+		{name: "romaji reading as written", answer: "watashi ha gakusei desu", responseTime: 3 * time.Second, wantExact: true, wantRating: fsrs.Easy},
+		{name: "katakana reading, no timing", answer: "ワタシハガクセイデス。", wantExact: true, wantRating: fsrs.Good},
+		{name: "kanji front, slow", answer: "私は学生です", responseTime: time.Minute, wantExact: true, wantRating: fsrs.Hard},
+		{name: "typo", answer: "わたしはがくせえです", wantExact: false, wantRating: fsrs.Hard},
+		{name: "wrong", answer: "せんせい", wantExact: false, wantRating: fsrs.Again},
+		{name: "empty", answer: "", wantExact: false, wantRating: fsrs.Again},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := gradeTypedAnswer(&card, tt.answer, tt.responseTime)
+			if got.Exact != tt.wantExact || fsrs.Rating(got.SuggestedRating) != tt.wantRating {
+				t.Errorf("gradeTypedAnswer() exact = %v rating = %v, want %v %v (%+v)",
+					got.Exact, got.SuggestedRating, tt.wantExact, tt.wantRating, got)
+			}
+		})
+	}
+}
+
+func Test_gradeTypedAnswer_latin(t *testing.T) {
+	cloze, err := langfi.NewClozeCard("She studied all night.", "study", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported := langfi.NewReviewCard("capital of France", "Paris")
+	imported.SetProp(langfi.PROP_ANKI_MODEL, "Basic")
+
+	tests := []struct {
+		name           string
+		card           langfi.ReviewCard
+		answer         string
+		wantNormalized string
+		wantExact      bool
+		wantRating     fsrs.Rating
+	}{
+		{name: "cloze answer", card: cloze, answer: "Studied", wantNormalized: "studied", wantExact: true, wantRating: fsrs.Good},
+		{name: "cloze typo", card: cloze, answer: "studyed", wantNormalized: "studyed", wantExact: false, wantRating: fsrs.Hard},
+		{name: "cloze base form", card: cloze, answer: "study", wantNormalized: "study", wantExact: false, wantRating: fsrs.Again},
+		{name: "anki answer with spaces", card: imported, answer: "  paris ", wantNormalized: "paris", wantExact: true, wantRating: fsrs.Good},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := gradeTypedAnswer(&tt.card, tt.answer, 0)
+			if got.NormalizedAnswer != tt.wantNormalized || got.Exact != tt.wantExact ||
+				fsrs.Rating(got.SuggestedRating) != tt.wantRating {
+				t.Errorf("gradeTypedAnswer() = %+v, want %q exact = %v rating = %v",
+					got, tt.wantNormalized, tt.wantExact, tt.wantRating)
+			}
+			for _, segment := range got.Diff {
+				if kana.IsJapanese(segment.Text) {
+					t.Errorf("gradeTypedAnswer() diff = %+v, want latin text only", got.Diff)
+				}
+			}
+		})
+	}
+	if got := gradeTypedAnswer(&cloze, "studyed", 0); got.Similarity < TYPO_SIMILARITY {
+		t.Errorf("gradeTypedAnswer(studyed) similarity = %v, want at least %v", got.Similarity, TYPO_SIMILARITY)
+	}
+}
+
+func Test_expectedAnswers(t *testing.T) {
+	cloze, err := langfi.NewClozeCard("She studied all night.", "study", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported := langfi.NewReviewCard("capital of France", "Paris")
+	imported.SetProp(langfi.PROP_ANKI_MODEL, "Basic")
+	generated := langfi.NewReviewCard("私は学生です", "I am a student")
+	generated.SetProp(langfi.PROP_READING, "わたしはがくせいです")
+
+	tests := []struct {
+		name string
+		card langfi.ReviewCard
+		want []string
+	}{
+		{name: "cloze card prompting with its front", card: cloze, want: []string{"studied"}},
+		{name: "anki card prompting with its front", card: imported, want: []string{"Paris"}},
+		{name: "card prompting with its back", card: generated, want: []string{"私は学生です", "わたしはがくせいです"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expectedAnswers(&tt.card); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expectedAnswers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := gradeTypedAnswer(&cloze, cloze.Front, 0); got.Exact {
+		t.Errorf("gradeTypedAnswer() of the cloze prompt = %+v, want not exact", got)
+	}
+}
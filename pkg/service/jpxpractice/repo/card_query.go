@@ -0,0 +1,129 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/pkg/errors"
+)
+
+const likeEscape = `\`
+
+var numberQueryColumns = map[string]string{
+	langfi.QUERY_REPS:       "COALESCE(fsrs.reps, 0)",
+	langfi.QUERY_LAPSES:     "COALESCE(fsrs.lapses, 0)",
+	langfi.QUERY_STABILITY:  "COALESCE(fsrs.stability, 0)",
+	langfi.QUERY_DIFFICULTY: "COALESCE(fsrs.difficulty, 0)",
+}
+
+var textQueryColumns = map[string]string{
+	langfi.QUERY_GROUP: "decks.name",
+	langfi.QUERY_FRONT: "cards.front",
+	langfi.QUERY_BACK:  "cards.back",
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_").Replace(value)
+}
+
+// likePattern turns a query text with `*` wildcards into a LIKE pattern
+func likePattern(value string) string {
+	return strings.ReplaceAll(escapeLike(value), langfi.QUERY_WILDCARD, "%")
+}
+
+// textCondition matches exactly, or with LIKE when the value has wildcards
+func textCondition(column, value string) sq.Sqlizer {
+	if strings.Contains(value, langfi.QUERY_WILDCARD) {
+		return sq.Expr(column+" LIKE ? ESCAPE '"+likeEscape+"'", likePattern(value))
+	}
+	return sq.Eq{column: value}
+}
+
+func compareCondition(column, op string, value interface{}) sq.Sqlizer {
+	return sq.Expr(fmt.Sprintf("%s %s ?", column, op), value)
+}
+
+// compileCardQuery compiles the query into a condition on cards joined with the fsrs row of the user
+func compileCardQuery(query *langfi.CardQuery, userID uint64, now time.Time) (sq.Sqlizer, error) {
+	conditions := sq.And{}
+	for i := range query.Terms {
+		term := &query.Terms[i]
+		var cond sq.Sqlizer
+		switch term.Field {
+		case langfi.QUERY_TEXT:
+			pattern := "%" + likePattern(term.Value) + "%"
+			cond = sq.Or{
+				sq.Expr("cards.front LIKE ? ESCAPE '"+likeEscape+"'", pattern),
+				sq.Expr("cards.back LIKE ? ESCAPE '"+likeEscape+"'", pattern),
+			}
+		case langfi.QUERY_TAG:
+			tagCond, args, err := textCondition("card_tags.tag", term.Value).ToSql()
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to build tag condition")
+			}
+			cond = sq.Expr("EXISTS (SELECT 1 FROM card_tags WHERE card_tags.card_id = cards.id AND card_tags.user_id = ? AND "+tagCond+")",
+				append([]interface{}{userID}, args...)...)
+		case langfi.QUERY_GROUP:
+			// without wildcards a deck also matches its sub decks
+			if strings.Contains(term.Value, langfi.QUERY_WILDCARD) {
+				cond = textCondition(textQueryColumns[term.Field], term.Value)
+			} else {
+				cond = deckCondition(term.Value)
+			}
+		case langfi.QUERY_FRONT, langfi.QUERY_BACK:
+			cond = textCondition(textQueryColumns[term.Field], term.Value)
+		case langfi.QUERY_STATUS:
+			cond = sq.Eq{cardStatusColumn: term.Value}
+		case langfi.QUERY_STATE:
+			// state is stored as text in sqlite, compare it as a number
+			cond = sq.Eq{"CAST(COALESCE(fsrs.state, 0) AS INTEGER)": langfi.QueryStates[term.Value]}
+		case langfi.QUERY_DUE:
+			// due<3d: scheduled within the next 3 days, cards never scheduled have no due date
+			op := term.Op
+			if op == langfi.OP_EQ {
+				op = langfi.OP_LTE
+			}
+			cond = sq.And{sq.NotEq{"fsrs.due": nil}, compareCondition("fsrs.due", op, now.Add(term.DueOffset()))}
+		case langfi.QUERY_REPS, langfi.QUERY_LAPSES, langfi.QUERY_STABILITY, langfi.QUERY_DIFFICULTY:
+			value, err := strconv.ParseFloat(term.Value, 64)
+			if err != nil {
+				return nil, errors.Wrapf(langfi.ErrInvalidCardQuery, "%v must be a number", term.Field)
+			}
+			// postgres would compare with an integer parameter for integer columns
+			cond = compareCondition(numberQueryColumns[term.Field], term.Op, sq.Expr("CAST(? AS DOUBLE PRECISION)", value))
+		default:
+			return nil, errors.Wrapf(langfi.ErrInvalidCardQuery, "unknown field %v", term.Field)
+		}
+
+		if term.Negate {
+			sqlCmd, args, err := cond.ToSql()
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to build negated condition")
+			}
+			cond = sq.Expr("NOT ("+sqlCmd+")", args...)
+		}
+		conditions = append(conditions, cond)
+	}
+	return conditions, nil
+}
+
+func (rp *practiceRepo) SearchCards(ctx context.Context, query *langfi.CardQuery, limit int) (*[]langfi.ReviewCard, error) {
+	cond, err := compileCardQuery(query, auth.UserIDFromContext(ctx), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	selectQuery := rp.selectCards(ctx).
+		Where(cond).
+		OrderBy("fsrs.due IS NULL", "fsrs.due", "cards.id")
+	if limit > 0 {
+		selectQuery = selectQuery.Limit(uint64(limit))
+	}
+	return rp.queryCards(ctx, selectQuery)
+}
@@ -0,0 +1,57 @@
+package repo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+)
+
+func Test_compileCardQuery(t *testing.T) {
+	now := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		query    string
+		wantSql  string
+		wantArgs []interface{}
+	}{
+		{"empty", "", "(1=1)", nil},
+		{"tag with wildcard", "tag:lesson*",
+			`(EXISTS (SELECT 1 FROM card_tags WHERE card_tags.card_id = cards.id AND card_tags.user_id = ? AND card_tags.tag LIKE ? ESCAPE '\'))`,
+			[]interface{}{uint64(7), "lesson%"}},
+		{"state and lapses", "state:review lapses>2",
+			"(CAST(COALESCE(fsrs.state, 0) AS INTEGER) = ? AND COALESCE(fsrs.lapses, 0) > CAST(? AS DOUBLE PRECISION))",
+			[]interface{}{2, 2.0}},
+		{"negated due", "-due<1d",
+			"(NOT ((fsrs.due IS NOT NULL AND fsrs.due < ?)))",
+			[]interface{}{now.Add(24 * time.Hour)}},
+		{"free text escapes like", "50%",
+			`((cards.front LIKE ? ESCAPE '\' OR cards.back LIKE ? ESCAPE '\'))`,
+			[]interface{}{`%50\%%`, `%50\%%`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := langfi.ParseCardQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseCardQuery() error = %v", err)
+			}
+			cond, err := compileCardQuery(query, 7, now)
+			if err != nil {
+				t.Fatalf("compileCardQuery() error = %v", err)
+			}
+			gotSql, gotArgs, err := cond.ToSql()
+			if err != nil {
+				t.Fatalf("ToSql() error = %v", err)
+			}
+			if gotSql != tt.wantSql {
+				t.Errorf("compileCardQuery() sql = %v, want %v", gotSql, tt.wantSql)
+			}
+			if len(gotArgs) > 0 || len(tt.wantArgs) > 0 {
+				if !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+					t.Errorf("compileCardQuery() args = %v, want %v", gotArgs, tt.wantArgs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nhuongmh/cfvs.jpx/pkg/database/postgresdb"
+	"github.com/nhuongmh/cfvs.jpx/pkg/database/sqlite3"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/search"
+	"github.com/pkg/errors"
+)
+
+// cardTextSearch selects id, front, back, properties and score of the cards containing every term,
+// higher scores are better matches
+type cardTextSearch func(qb *sq.StatementBuilderType, terms []string) sq.SelectBuilder
+
+// sqliteCardTextSearch ranks the cards_fts matches by bm25, front matches weigh most.
+// The trigram index matches a term anywhere in the text, also inside Japanese text that has no spaces
+// between words, but it cannot match terms shorter than three characters: those scan the cards instead.
+func sqliteCardTextSearch(qb *sq.StatementBuilderType, terms []string) sq.SelectBuilder {
+	if !sqlite3.FTS5 {
+		return likeCardTextSearch(qb, terms)
+	}
+	phrases := make([]string, len(terms))
+	for i, term := range terms {
+		if utf8.RuneCountInString(term) < sqlite3.MIN_TRIGRAM_TERM {
+			return likeCardTextSearch(qb, terms)
+		}
+		phrases[i] = `"` + term + `"`
+	}
+	// bm25 is lower for better matches
+	return qb.Select("cards.id", "cards.front", "cards.back", "cards.properties", "-bm25(cards_fts, 10.0, 5.0, 1.0) AS score").
+		From("cards_fts").
+		Join("cards ON cards.id = cards_fts.rowid").
+		Where("cards_fts MATCH ?", strings.Join(phrases, " "))
+}
+
+// postgresCardTextSearch matches the terms anywhere in the trigram indexed search_text column,
+// the matches are scored like likeCardTextSearch and ranked by the search_vector column among equal scores
+func postgresCardTextSearch(qb *sq.StatementBuilderType, terms []string) sq.SelectBuilder {
+	score, scoreArgs := likeCardScore(terms)
+	tsQuery := postgresdb.PrefixTsQuery(terms)
+	cond := sq.And{}
+	for _, term := range terms {
+		cond = append(cond, sq.Expr("cards.search_text LIKE ?", postgresdb.ContainsPattern(term)))
+	}
+	return qb.Select("cards.id", "cards.front", "cards.back", "cards.properties").
+		Column(sq.Expr(score+" + ts_rank(cards.search_vector, to_tsquery('simple', ?)) AS score", append(scoreArgs, tsQuery)...)).
+		From("cards").
+		Where(cond)
+}
+
+// likeCardTextSearch scans the cards when sqlite has no fts5, terms match anywhere in a word
+func likeCardTextSearch(qb *sq.StatementBuilderType, terms []string) sq.SelectBuilder {
+	score, scoreArgs := likeCardScore(terms)
+	cond := sq.And{}
+	for _, term := range terms {
+		pattern := "%" + escapeLike(term) + "%"
+		cond = append(cond, sq.Or{
+			sq.Expr("LOWER(cards.front) LIKE ? ESCAPE '"+likeEscape+"'", pattern),
+			sq.Expr("LOWER(cards.back) LIKE ? ESCAPE '"+likeEscape+"'", pattern),
+			sq.Expr("LOWER(cards.properties) LIKE ? ESCAPE '"+likeEscape+"'", pattern),
+		})
+	}
+	return qb.Select("cards.id", "cards.front", "cards.back", "cards.properties").
+		Column(sq.Expr(score+" AS score", scoreArgs...)).
+		From("cards").
+		Where(cond)
+}
+
+// likeCardScore sums 3 for each term in the front, 2 in the back and 1 in the properties
+func likeCardScore(terms []string) (string, []interface{}) {
+	scores := []string{}
+	args := []interface{}{}
+	for _, term := range terms {
+		pattern := "%" + escapeLike(term) + "%"
+		scores = append(scores, "CASE WHEN LOWER(cards.front) LIKE ? ESCAPE '"+likeEscape+"' THEN 3 ELSE 0 END + "+
+			"CASE WHEN LOWER(cards.back) LIKE ? ESCAPE '"+likeEscape+"' THEN 2 ELSE 0 END + "+
+			"CASE WHEN LOWER(cards.properties) LIKE ? ESCAPE '"+likeEscape+"' THEN 1 ELSE 0 END")
+		args = append(args, pattern, pattern, pattern)
+	}
+	return "(" + strings.Join(scores, " + ") + ")", args
+}
+
+func (rp *practiceRepo) SearchCardText(ctx context.Context, terms []string, limit int) ([]search.Hit, error) {
+	query := rp.textSearch(rp.db.QueryBuilder, terms).
+		Where(cardVisible(auth.UserIDFromContext(ctx))).
+		OrderBy("score DESC", "cards.id")
+	if limit > 0 {
+		query = query.Limit(uint64(limit))
+	}
+	sqlCmd, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build sql query")
+	}
+
+	rows, err := rp.db.QueryContext(ctx, sqlCmd, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query SQL")
+	}
+	defer rows.Close()
+	hits := []search.Hit{}
+	for rows.Next() {
+		var card langfi.ReviewCard
+		var properties sql.NullString
+		var score float64
+		if err := rows.Scan(&card.ID, &card.Front, &card.Back, &properties, &score); err != nil {
+			return hits, errors.Wrap(err, "failed to scan SQL")
+		}
+		card.SetPropertiesFromJson(properties.String)
+		hits = append(hits, cardHit(&card, score, terms))
+	}
+	if err = rows.Err(); err != nil {
+		return hits, errors.Wrap(err, "failed to scan SQL")
+	}
+	return hits, nil
+}
+
+func cardHit(card *langfi.ReviewCard, score float64, terms []string) search.Hit {
+	return search.Hit{
+		Kind:    search.KIND_CARD,
+		ID:      card.ID,
+		Title:   card.Front,
+		Snippet: search.Snippet(cardSearchText(card), terms),
+		Score:   score,
+	}
+}
+
+// cardSearchText is the front, back and property values of the card, properties ordered by key
+func cardSearchText(card *langfi.ReviewCard) string {
+	keys := make([]string, 0, len(card.Properties))
+	for k := range card.Properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{card.Front, card.Back}
+	for _, k := range keys {
+		if v := card.Properties[k]; v != nil {
+			parts = append(parts, fmt.Sprint(v))
+		}
+	}
+	return strings.Join(parts, " · ")
+}
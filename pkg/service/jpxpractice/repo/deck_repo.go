@@ -0,0 +1,198 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/pkg/errors"
+)
+
+// deckCondition matches cards of the deck and of all its sub decks
+func deckCondition(name string) sq.Sqlizer {
+	return sq.Or{
+		sq.Eq{"decks.name": name},
+		sq.Expr("decks.name LIKE ? ESCAPE '"+likeEscape+"'", escapeLike(name)+langfi.DECK_SEPARATOR+"%"),
+	}
+}
+
+func scanDeck(row rowScanner, deck *langfi.Deck) error {
+	var parentID sql.NullInt64
+	var settings string
+	err := row.Scan(&deck.ID, &deck.Name, &parentID, &settings, &deck.CreatedAt)
+	if err != nil {
+		return err
+	}
+	deck.ParentID = uint64(parentID.Int64)
+	err = json.Unmarshal([]byte(settings), &deck.Settings)
+	if err != nil {
+		return errors.Wrapf(err, "invalid settings of deck %v", deck.Name)
+	}
+	return nil
+}
+
+// selectDecks selects the decks with the settings of the user, decks the user never changed have none
+func (rp *practiceRepo) selectDecks(ctx context.Context) sq.SelectBuilder {
+	return rp.db.QueryBuilder.Select("decks.id", "decks.name", "decks.parent_id", "COALESCE(deck_settings.settings, '{}')", "decks.created_at").
+		From("decks").
+		LeftJoin("deck_settings ON deck_settings.deck_id = decks.id AND deck_settings.user_id = ?", auth.UserIDFromContext(ctx))
+}
+
+// GetDecks leaves out the decks holding only cards of other owners, their names come from private data
+func (rp *practiceRepo) GetDecks(ctx context.Context) (*[]langfi.Deck, error) {
+	userID := auth.UserIDFromContext(ctx)
+	query := rp.selectDecks(ctx).
+		Where("NOT EXISTS (SELECT 1 FROM cards WHERE cards.deck_id = decks.id AND cards.owner_id != ?) "+
+			"OR EXISTS (SELECT 1 FROM cards WHERE cards.deck_id = decks.id AND (cards.owner_id IS NULL OR cards.owner_id = ?))",
+			userID, userID).
+		OrderBy("decks.name")
+
+	sqlCmd, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build sql query")
+	}
+
+	rows, err := rp.db.QueryContext(ctx, sqlCmd, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query SQL")
+	}
+	defer rows.Close()
+	decks := []langfi.Deck{}
+	for rows.Next() {
+		var deck langfi.Deck
+		if err := scanDeck(rows, &deck); err != nil {
+			return &decks, errors.Wrap(err, "failed to scan SQL")
+		}
+		decks = append(decks, deck)
+	}
+	if err = rows.Err(); err != nil {
+		return &decks, errors.Wrap(err, "failed to scan SQL")
+	}
+
+	return &decks, nil
+}
+
+func (rp *practiceRepo) getDeckByName(ctx context.Context, q queryer, name string) (*langfi.Deck, error) {
+	query := rp.selectDecks(ctx).
+		Where(sq.Eq{"decks.name": name})
+
+	sqlCmd, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build sql query")
+	}
+
+	deck := langfi.Deck{}
+	err = scanDeck(q.QueryRowContext(ctx, sqlCmd, args...), &deck)
+	if err != nil {
+		return nil, err
+	}
+	return &deck, nil
+}
+
+// EnsureDeck returns the deck with the given name, creating it and its missing parents
+func (rp *practiceRepo) EnsureDeck(ctx context.Context, name string) (*langfi.Deck, error) {
+	var deck *langfi.Deck
+	err := rp.db.inTx(ctx, func(q queryer) error {
+		var err error
+		deck, err = rp.ensureDeck(ctx, q, name)
+		return err
+	})
+	return deck, err
+}
+
+func (rp *practiceRepo) ensureDeck(ctx context.Context, q queryer, name string) (*langfi.Deck, error) {
+	name, err := langfi.NormalizeDeckName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	deck, err := rp.getDeckByName(ctx, q, name)
+	if err == nil {
+		return deck, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, errors.Wrapf(err, "failed to get deck %v", name)
+	}
+
+	var parentID interface{}
+	if parentName := langfi.ParentDeckName(name); parentName != "" {
+		parent, err := rp.ensureDeck(ctx, q, parentName)
+		if err != nil {
+			return nil, err
+		}
+		parentID = parent.ID
+	}
+
+	query := rp.db.QueryBuilder.Insert("decks").
+		Columns("name", "parent_id").
+		Values(name, parentID).
+		Suffix("ON CONFLICT(name) DO NOTHING")
+
+	sqlCmd, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build sql query")
+	}
+	_, err = q.ExecContext(ctx, sqlCmd, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to insert deck %v", name)
+	}
+
+	deck, err = rp.getDeckByName(ctx, q, name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get deck %v", name)
+	}
+	return deck, nil
+}
+
+// UpdateDeckSettings replaces the settings of the deck for the user
+func (rp *practiceRepo) UpdateDeckSettings(ctx context.Context, deckID uint64, settings *langfi.DeckSettings) error {
+	settingsJson, err := json.Marshal(settings)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal deck settings")
+	}
+
+	return rp.db.inTx(ctx, func(q queryer) error {
+		sqlCmd, args, err := rp.db.QueryBuilder.Select("id").From("decks").Where("id = ?", deckID).ToSql()
+		if err != nil {
+			return errors.Wrap(err, "failed to build sql query")
+		}
+		var id uint64
+		err = q.QueryRowContext(ctx, sqlCmd, args...).Scan(&id)
+		if err == sql.ErrNoRows {
+			return errors.Wrapf(langfi.ErrInvalidDeck, "deck id = %v not found", deckID)
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to get deck")
+		}
+
+		query := rp.db.QueryBuilder.Insert("deck_settings").
+			Columns("deck_id", "user_id", "settings").
+			Values(deckID, auth.UserIDFromContext(ctx), string(settingsJson)).
+			Suffix("ON CONFLICT(deck_id, user_id) DO UPDATE SET settings = excluded.settings, updated_at = CURRENT_TIMESTAMP")
+
+		sqlCmd, args, err = query.ToSql()
+		if err != nil {
+			return errors.Wrap(err, "failed to build sql query")
+		}
+		_, err = q.ExecContext(ctx, sqlCmd, args...)
+		if err != nil {
+			return errors.Wrap(err, "failed to update deck settings")
+		}
+		return nil
+	})
+}
+
+// deckID resolves the group of a card to its deck, cards without group have no deck
+func (rp *practiceRepo) deckID(ctx context.Context, q queryer, group string) (interface{}, error) {
+	if group == "" {
+		return nil, nil
+	}
+	deck, err := rp.ensureDeck(ctx, q, group)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve deck %v", group)
+	}
+	return deck.ID, nil
+}
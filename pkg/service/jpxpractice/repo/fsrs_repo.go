@@ -3,77 +3,34 @@ package repo
 import (
 	"context"
 
-	sq "github.com/Masterminds/squirrel"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
 	"github.com/pkg/errors"
 )
 
-func (rp *practiceRepo) AddFsrs(ctx context.Context, fsrsd *langfi.FSRSData, cardID uint64) error {
+// saveFsrs stores the status and scheduling data of the card for the current user
+func (rp *practiceRepo) saveFsrs(ctx context.Context, q queryer, card *langfi.ReviewCard) error {
+	fsrsd := &card.FsrsData
 	query := rp.db.QueryBuilder.Insert("fsrs").
-		Columns("card_id", "due", "stability", "difficulty", "elapsed_days", "scheduled_days",
+		Columns("card_id", "user_id", "status", "due", "stability", "difficulty", "elapsed_days", "scheduled_days",
 			"reps", "lapses", "state", "last_review").
-		Values(cardID, fsrsd.Due, fsrsd.Stability, fsrsd.Difficulty, fsrsd.ElapsedDays,
-			fsrsd.ScheduledDays, fsrsd.Reps, fsrsd.Lapses, fsrsd.State, fsrsd.LastReview).
-		Suffix("RETURNING id")
+		Values(card.ID, auth.UserIDFromContext(ctx), card.Status, fsrsd.Due, fsrsd.Stability, fsrsd.Difficulty,
+			fsrsd.ElapsedDays, fsrsd.ScheduledDays, fsrsd.Reps, fsrsd.Lapses, fsrsd.State, fsrsd.LastReview).
+		Suffix(`ON CONFLICT(card_id, user_id) DO UPDATE SET status = excluded.status, due = excluded.due,
+			stability = excluded.stability, difficulty = excluded.difficulty, elapsed_days = excluded.elapsed_days,
+			scheduled_days = excluded.scheduled_days, reps = excluded.reps, lapses = excluded.lapses,
+			state = excluded.state, last_review = excluded.last_review
+			RETURNING id`)
 
 	sql, args, err := query.ToSql()
 	if err != nil {
 		return errors.Wrap(err, "failed to build sql query")
 	}
 
-	err = rp.db.SqlDB.QueryRowContext(ctx, sql, args...).Scan(&fsrsd.ID)
+	err = q.QueryRowContext(ctx, sql, args...).Scan(&fsrsd.ID)
 	if err != nil {
-		return errors.Wrap(err, "failed to insert fsrs")
+		return errors.Wrap(err, "failed to save fsrs")
 	}
 
-	return nil
-}
-
-func (rp *practiceRepo) GetFsrs(ctx context.Context, cardID uint64) (*langfi.FSRSData, error) {
-	query := rp.db.QueryBuilder.Select("id", "due", "stability", "difficulty", "elapsed_days", "scheduled_days",
-		"reps", "lapses", "state", "last_review").
-		From("fsrs").
-		Where(sq.Eq{"card_id": cardID})
-
-	sql, args, err := query.ToSql()
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to build sql query")
-	}
-
-	row := rp.db.SqlDB.QueryRowContext(ctx, sql, args...)
-	fsrsData := langfi.FSRSData{}
-
-	err = row.Scan(&fsrsData.ID, &fsrsData.Due, &fsrsData.Stability, &fsrsData.Difficulty, &fsrsData.ElapsedDays,
-		&fsrsData.ScheduledDays, &fsrsData.Reps, &fsrsData.Lapses, &fsrsData.State, &fsrsData.LastReview)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to scan fsrs")
-	}
-
-	return &fsrsData, nil
-}
-
-func (rp *practiceRepo) UpdateFsrs(ctx context.Context, fsrsd *langfi.FSRSData) error {
-	query := rp.db.QueryBuilder.Update("fsrs").
-		Where("id = ?", fsrsd.ID).
-		Set("due", fsrsd.Due).
-		Set("stability", fsrsd.Stability).
-		Set("difficulty", fsrsd.Difficulty).
-		Set("elapsed_days", fsrsd.ElapsedDays).
-		Set("scheduled_days", fsrsd.ScheduledDays).
-		Set("reps", fsrsd.Reps).
-		Set("lapses", fsrsd.Lapses).
-		Set("state", fsrsd.State).
-		Set("last_review", fsrsd.LastReview)
-
-	sql, args, err := query.ToSql()
-	if err != nil {
-		return errors.Wrap(err, "failed to build sql query")
-	}
-
-	_, err = rp.db.SqlDB.ExecContext(ctx, sql, args...)
-	if err != nil {
-		return errors.Wrap(err, "failed to update fsrs")
-	}
-
-	return nil
+	return rp.recordFsrsChange(ctx, q, card.ID)
 }
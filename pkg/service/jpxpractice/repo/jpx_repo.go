@@ -6,91 +6,112 @@ import (
 	"fmt"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/nhuongmh/cfvs.jpx/pkg/database/postgresdb"
 	"github.com/nhuongmh/cfvs.jpx/pkg/database/sqlite3"
 	"github.com/nhuongmh/cfvs.jpx/pkg/logger"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
 	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
-	"github.com/open-spaced-repetition/go-fsrs/v3"
 	"github.com/pkg/errors"
 )
 
+// card content is shared, status and scheduling live in the fsrs row of each user.
+// A card without a row for the current user has not been processed yet.
+var cardStatusColumn = fmt.Sprintf("COALESCE(fsrs.status, '%s')", langfi.CARD_NEW)
+
+var cardColumns = []string{"cards.id", "cards.front", "cards.back", "cards.properties", "COALESCE(decks.name, '')", cardStatusColumn,
+	"COALESCE(fsrs.id, 0)", "fsrs.due", "COALESCE(fsrs.stability, 0)", "COALESCE(fsrs.difficulty, 0)",
+	"COALESCE(fsrs.elapsed_days, 0)", "COALESCE(fsrs.scheduled_days, 0)", "COALESCE(fsrs.reps, 0)",
+	"COALESCE(fsrs.lapses, 0)", "COALESCE(fsrs.state, 0)", "fsrs.last_review", "cards.revision", "COALESCE(cards.owner_id, 0)"}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
 type practiceRepo struct {
-	db *sqlite3.DB
+	db         *sqlDB
+	textSearch cardTextSearch
+	// rows read to be updated are locked with FOR UPDATE and sync changes are numbered once committed,
+	// sqlite transactions take the write lock when they begin so writes run one at a time
+	lockRows bool
 }
 
 func NewJpxPraticeRepo(db *sqlite3.DB) langfi.PracticeRepo {
 	return &practiceRepo{
-		db: db,
+		db:         newSqlDB(db.SqlDB, db.QueryBuilder),
+		textSearch: sqliteCardTextSearch,
 	}
 }
 
-func (rp *practiceRepo) AddCard(ctx context.Context, card *langfi.ReviewCard) error {
-	query := rp.db.QueryBuilder.Insert("cards").
-		Columns("front", "back", "properties", "status", "card_group").
-		Values(card.Front, card.Back, card.PropertiesToJson(), card.Status, card.Group).
-		Suffix("RETURNING id")
-
-	sqlCmd, args, err := query.ToSql()
-	if err != nil {
-		return errors.Wrap(err, "failed to build sql query")
+// NewJpxPostgresPracticeRepo stores practice data next to the IE data, tables are created by the postgres migrations
+func NewJpxPostgresPracticeRepo(db *postgresdb.DB) langfi.PracticeRepo {
+	return &practiceRepo{
+		db:         newSqlDB(db.SqlDB, db.QueryBuilder),
+		textSearch: postgresCardTextSearch,
+		lockRows:   true,
 	}
+}
 
-	err = rp.db.SqlDB.QueryRowContext(ctx, sqlCmd, args...).Scan(&card.ID)
-	if err != nil {
-		return errors.Wrap(err, "failed to insert card")
-	}
+// cardVisible matches the shared cards and the cards owned by the user
+func cardVisible(userID uint64) sq.Sqlizer {
+	return sq.Or{sq.Eq{"cards.owner_id": nil}, sq.Eq{"cards.owner_id": userID}}
+}
 
-	// also add fsrs data
-	err = rp.AddFsrs(ctx, &card.FsrsData, card.ID)
+func (rp *practiceRepo) selectCards(ctx context.Context) sq.SelectBuilder {
+	userID := auth.UserIDFromContext(ctx)
+	return rp.db.QueryBuilder.Select(cardColumns...).
+		From("cards").
+		LeftJoin("decks ON decks.id = cards.deck_id").
+		LeftJoin("fsrs ON fsrs.card_id = cards.id AND fsrs.user_id = ?", userID).
+		Where(cardVisible(userID))
+}
+
+func scanCard(row rowScanner, card *langfi.ReviewCard) error {
+	var properties sql.NullString
+	var due, lastReview sql.NullTime
+	fsrsd := &card.FsrsData
+	err := row.Scan(&card.ID, &card.Front, &card.Back, &properties, &card.Group, &card.Status,
+		&fsrsd.ID, &due, &fsrsd.Stability, &fsrsd.Difficulty, &fsrsd.ElapsedDays, &fsrsd.ScheduledDays,
+		&fsrsd.Reps, &fsrsd.Lapses, &fsrsd.State, &lastReview, &card.Revision, &card.OwnerID)
 	if err != nil {
-		return errors.Wrapf(err, "failed to insert fsrs data to database of card id = %v", card.ID)
+		return err
 	}
-
+	card.SetPropertiesFromJson(properties.String)
+	fsrsd.Due = due.Time
+	fsrsd.LastReview = lastReview.Time
 	return nil
 }
 
-func (rp *practiceRepo) GetCard(ctx context.Context, cardID uint64) (*langfi.ReviewCard, error) {
-	query := rp.db.QueryBuilder.Select("id", "front", "back", "properties", "status", "card_group").
-		From("cards").
-		Where(sq.Eq{"id": cardID})
-
+func (rp *practiceRepo) queryCard(ctx context.Context, q queryer, query sq.SelectBuilder) (*langfi.ReviewCard, error) {
 	sqlCmd, args, err := query.ToSql()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to build sql query")
 	}
 
-	row := rp.db.SqlDB.QueryRowContext(ctx, sqlCmd, args...)
 	card := langfi.ReviewCard{}
-	var properties string
-	err = row.Scan(&card.ID, &card.Front, &card.Back, &properties, &card.Status, &card.Group)
+	err = scanCard(q.QueryRowContext(ctx, sqlCmd, args...), &card)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, model.ErrNoMoreDataAvailable
+		}
 		return nil, errors.Wrap(err, "failed to scan card")
 	}
-	card.SetPropertiesFromJson(properties)
 
-	// get fsrs data
-	fsrsData, err := rp.GetFsrs(ctx, card.ID)
+	cards := []langfi.ReviewCard{card}
+	err = rp.loadTags(ctx, q, cards)
 	if err != nil {
-		logger.Log.Error().Err(err).Msgf("failed to get fsrs data of card id = %v", card.ID)
-		card.FsrsData = langfi.FSRSData{Card: fsrs.NewCard()}
-	} else {
-		card.FsrsData = *fsrsData
+		return nil, errors.Wrapf(err, "failed to load tags of card id = %v", card.ID)
 	}
-
-	return &card, nil
+	return &cards[0], nil
 }
 
-func (rp *practiceRepo) GetCardByFront(ctx context.Context, front string) (*[]langfi.ReviewCard, error) {
-	query := rp.db.QueryBuilder.Select("id", "front", "back", "properties", "status", "card_group").
-		From("cards").
-		Where(sq.Eq{"front": front})
-
+func (rp *practiceRepo) queryCards(ctx context.Context, query sq.SelectBuilder) (*[]langfi.ReviewCard, error) {
 	sqlCmd, args, err := query.ToSql()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to build sql query")
 	}
 
-	rows, err := rp.db.SqlDB.QueryContext(ctx, sqlCmd, args...)
+	rows, err := rp.db.QueryContext(ctx, sqlCmd, args...)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to query SQL")
 	}
@@ -98,156 +119,305 @@ func (rp *practiceRepo) GetCardByFront(ctx context.Context, front string) (*[]la
 	cards := []langfi.ReviewCard{}
 	for rows.Next() {
 		var card langfi.ReviewCard
-		var properties string
-		if err := rows.Scan(&card.ID, &card.Front, &card.Back, &properties, &card.Status, &card.Group); err != nil {
+		if err := scanCard(rows, &card); err != nil {
 			return &cards, errors.Wrap(err, "failed to scan SQL")
 		}
-		card.SetPropertiesFromJson(properties)
-
-		// get fsrs data
-		fsrsData, err := rp.GetFsrs(ctx, card.ID)
-		if err != nil {
-			logger.Log.Error().Err(err).Msgf("failed to get fsrs data of card id = %v", card.ID)
-			card.FsrsData = langfi.FSRSData{Card: fsrs.NewCard()}
-		} else {
-			card.FsrsData = *fsrsData
-		}
-
 		cards = append(cards, card)
 	}
 	if err = rows.Err(); err != nil {
 		return &cards, errors.Wrap(err, "failed to scan SQL")
 	}
 
+	err = rp.loadTags(ctx, rp.db, cards)
+	if err != nil {
+		return &cards, errors.Wrap(err, "failed to load tags")
+	}
 	return &cards, nil
 }
 
-func (rp *practiceRepo) UpdateCard(ctx context.Context, card *langfi.ReviewCard) error {
-	query := rp.db.QueryBuilder.Update("cards").
-		Where("id = ?", card.ID).
-		Set("front", card.Front).
-		Set("back", card.Back).
-		Set("properties", card.PropertiesToJson()).
-		Set("status", card.Status).
-		Set("card_group", card.Group)
-
-	sqlCmd, args, err := query.ToSql()
+// AddCard inserts the card with its fsrs row and tags in one transaction
+func (rp *practiceRepo) AddCard(ctx context.Context, card *langfi.ReviewCard) error {
+	err := rp.db.inTx(ctx, func(q queryer) error {
+		return rp.addCard(ctx, q, card)
+	})
 	if err != nil {
-		return errors.Wrap(err, "failed to build sql query")
+		card.ID = 0
 	}
+	return err
+}
 
-	_, err = rp.db.SqlDB.ExecContext(ctx, sqlCmd, args...)
+// AddNewCards inserts the cards whose front is not in the database yet, all in one transaction.
+// The front of a card with an owner may be on a card of another owner.
+func (rp *practiceRepo) AddNewCards(ctx context.Context, cards []langfi.ReviewCard) (int, error) {
+	added := 0
+	err := rp.db.inTx(ctx, func(q queryer) error {
+		added = 0
+		for i := range cards {
+			exists, err := rp.frontExists(ctx, q, cards[i].Front, cards[i].OwnerID)
+			if err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+			err = rp.addCard(ctx, q, &cards[i])
+			if err != nil {
+				return err
+			}
+			added++
+		}
+		return nil
+	})
 	if err != nil {
-		return errors.Wrap(err, "failed to update card")
+		return 0, err
 	}
+	return added, nil
+}
 
-	// also add fsrs data
-
-	err = rp.UpdateFsrs(ctx, &card.FsrsData)
+// ImportCards inserts the cards with their review logs, all in one transaction
+func (rp *practiceRepo) ImportCards(ctx context.Context, cards []langfi.ImportedCard) error {
+	err := rp.db.inTx(ctx, func(q queryer) error {
+		for i := range cards {
+			err := rp.addCard(ctx, q, cards[i].Card)
+			if err != nil {
+				return errors.Wrapf(err, "failed to import card %v", cards[i].Card.Front)
+			}
+			for j := range cards[i].Logs {
+				cards[i].Logs[j].CardID = cards[i].Card.ID
+				err = rp.addReviewLog(ctx, q, &cards[i].Logs[j])
+				if err != nil {
+					return errors.Wrapf(err, "failed to import review log of card %v", cards[i].Card.Front)
+				}
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return errors.Wrapf(err, "failed to update fsrs data to database of card id = %v", card.ID)
+		for i := range cards {
+			cards[i].Card.ID = 0
+		}
 	}
-
-	return nil
+	return err
 }
 
-func (rp *practiceRepo) FetchReviewCard(ctx context.Context, group string) (*langfi.ReviewCard, error) {
-	logger.Log.Info().Msgf("FetchReviewCard group = %v", group)
-	query := rp.db.QueryBuilder.Select("cards.id", "cards.front", "cards.back", "cards.properties", "cards.status", "cards.card_group").
+// frontExists tells whether a card seen by the owner has the front
+func (rp *practiceRepo) frontExists(ctx context.Context, q queryer, front string, ownerID uint64) (bool, error) {
+	sqlCmd, args, err := rp.db.QueryBuilder.Select("COUNT(*)").
 		From("cards").
-		Join("fsrs ON cards.id = fsrs.card_id").
-		GroupBy("cards.id", "fsrs.card_id").
-		Where(sq.And{sq.Eq{"cards.status": langfi.CARD_LEARN}, sq.Eq{"cards.card_group": group}}).
-		OrderBy("fsrs.due").
-		Limit(1)
+		Where(sq.Eq{"cards.front": front}).
+		Where(cardVisible(ownerID)).
+		ToSql()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to build sql query")
+	}
+	var count int
+	err = q.QueryRowContext(ctx, sqlCmd, args...).Scan(&count)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to check card %v", front)
+	}
+	return count > 0, nil
+}
+
+func (rp *practiceRepo) addCard(ctx context.Context, q queryer, card *langfi.ReviewCard) error {
+	deckID, err := rp.deckID(ctx, q, card.Group)
+	if err != nil {
+		return err
+	}
+
+	var ownerID interface{}
+	if card.OwnerID != 0 {
+		ownerID = card.OwnerID
+	}
+	query := rp.db.QueryBuilder.Insert("cards").
+		Columns("front", "back", "properties", "deck_id", "owner_id").
+		Values(card.Front, card.Back, card.PropertiesToJson(), deckID, ownerID).
+		Suffix("RETURNING id")
 
 	sqlCmd, args, err := query.ToSql()
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to build sql query")
+		return errors.Wrap(err, "failed to build sql query")
 	}
 
-	row := rp.db.SqlDB.QueryRowContext(ctx, sqlCmd, args...)
-	card := langfi.ReviewCard{}
-	var properties string
-	err = row.Scan(&card.ID, &card.Front, &card.Back, &properties, &card.Status, &card.Group)
+	err = q.QueryRowContext(ctx, sqlCmd, args...).Scan(&card.ID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, model.ErrNoMoreDataAvailable
-		}
-		return nil, errors.Wrap(err, "failed to scan card")
+		return errors.Wrap(err, "failed to insert card")
+	}
+	err = rp.recordCardChange(ctx, q, card.ID, false)
+	if err != nil {
+		return err
 	}
-	card.SetPropertiesFromJson(properties)
 
-	// get fsrs data
-	fsrsData, err := rp.GetFsrs(ctx, card.ID)
+	// also add fsrs data
+	err = rp.saveFsrs(ctx, q, card)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to get fsrs data of card id = %v", card.ID)
+		return errors.Wrapf(err, "failed to insert fsrs data to database of card id = %v", card.ID)
 	}
 
-	card.FsrsData = *fsrsData
+	err = rp.saveTags(ctx, q, card)
+	if err != nil {
+		return errors.Wrapf(err, "failed to insert tags of card id = %v", card.ID)
+	}
 
-	return &card, nil
+	return nil
 }
 
-func (rp *practiceRepo) FetchUnProcessCard(ctx context.Context, group string) (*langfi.ReviewCard, error) {
+func (rp *practiceRepo) GetCard(ctx context.Context, cardID uint64) (*langfi.ReviewCard, error) {
+	card, err := rp.queryCard(ctx, rp.db, rp.selectCards(ctx).Where(sq.Eq{"cards.id": cardID}))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get card id = %v", cardID)
+	}
+	return card, nil
+}
 
-	query := rp.db.QueryBuilder.Select("id", "front", "back", "properties", "status", "card_group").
-		From("cards").
-		Where(sq.And{sq.Eq{"status": langfi.CARD_NEW}, sq.Eq{"card_group": group}}).
-		OrderBy("created_at").
-		Limit(1)
+func (rp *practiceRepo) GetCardByFront(ctx context.Context, front string) (*[]langfi.ReviewCard, error) {
+	return rp.queryCards(ctx, rp.selectCards(ctx).Where(sq.Eq{"cards.front": front}))
+}
+
+// UpdateCard updates the content, fsrs row and tags of the card in one transaction
+func (rp *practiceRepo) UpdateCard(ctx context.Context, card *langfi.ReviewCard) error {
+	return rp.db.inTx(ctx, func(q queryer) error {
+		return rp.updateCard(ctx, q, card)
+	})
+}
+
+func (rp *practiceRepo) updateCard(ctx context.Context, q queryer, card *langfi.ReviewCard) error {
+	deckID, err := rp.deckID(ctx, q, card.Group)
+	if err != nil {
+		return err
+	}
+
+	// reviews leave the content as is, only content changes are synced to every user
+	properties := card.PropertiesToJson()
+	query := rp.db.QueryBuilder.Update("cards").
+		Where("id = ?", card.ID).
+		Where("NOT (front IS NOT DISTINCT FROM ? AND back IS NOT DISTINCT FROM ? AND properties IS NOT DISTINCT FROM ? "+
+			"AND deck_id IS NOT DISTINCT FROM ?)", card.Front, card.Back, properties, deckID).
+		Set("front", card.Front).
+		Set("back", card.Back).
+		Set("properties", properties).
+		Set("deck_id", deckID).
+		Set("revision", sq.Expr("revision + 1"))
 
 	sqlCmd, args, err := query.ToSql()
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to build sql query")
+		return errors.Wrap(err, "failed to build sql query")
 	}
-	logger.Log.Info().Msgf("FetchUnProcessCard group = %v, sql=`%v`, args=%v", group, sqlCmd, args)
 
-	row := rp.db.SqlDB.QueryRowContext(ctx, sqlCmd, args...)
-	var card langfi.ReviewCard
-	var properties string
-	err = row.Scan(&card.ID, &card.Front, &card.Back, &properties, &card.Status, &card.Group)
+	res, err := q.ExecContext(ctx, sqlCmd, args...)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, model.ErrNoMoreDataAvailable
+		return errors.Wrap(err, "failed to update card")
+	}
+	contentChanged, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "failed to update card")
+	}
+
+	// status and fsrs data are kept per user
+	err = rp.saveFsrs(ctx, q, card)
+	if err != nil {
+		return errors.Wrapf(err, "failed to update fsrs data to database of card id = %v", card.ID)
+	}
+
+	tagsChanged, err := rp.cardTagsChanged(ctx, q, card)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read tags of card id = %v", card.ID)
+	}
+	if !tagsChanged {
+		card.Tags = langfi.NormalizeTags(card.Tags)
+	} else {
+		err = rp.saveTags(ctx, q, card)
+		if err != nil {
+			return errors.Wrapf(err, "failed to update tags of card id = %v", card.ID)
 		}
-		return nil, errors.Wrap(err, "failed to scan card")
 	}
-	card.SetPropertiesFromJson(properties)
 
-	return &card, nil
+	// tag changes are synced to the user with the change of its fsrs row
+	if contentChanged > 0 {
+		card.Revision++
+		return rp.recordCardChange(ctx, q, card.ID, false)
+	}
+	return nil
+}
+
+func (rp *practiceRepo) FetchReviewCard(ctx context.Context, group string) (*langfi.ReviewCard, error) {
+	logger.Log.Info().Msgf("FetchReviewCard group = %v", group)
+	query := rp.selectCards(ctx).
+		Where(sq.And{sq.Eq{"fsrs.status": langfi.CARD_LEARN}, deckCondition(group)}).
+		OrderBy("fsrs.due").
+		Limit(1)
+
+	return rp.queryCard(ctx, rp.db, query)
 }
 
+func (rp *practiceRepo) FetchUnProcessCard(ctx context.Context, group string) (*langfi.ReviewCard, error) {
+	logger.Log.Info().Msgf("FetchUnProcessCard group = %v", group)
+	query := rp.selectCards(ctx).
+		Where(sq.And{sq.Eq{cardStatusColumn: langfi.CARD_NEW}, deckCondition(group)}).
+		OrderBy("cards.created_at").
+		Limit(1)
+
+	return rp.queryCard(ctx, rp.db, query)
+}
+
+// DeleteNewCard removes the shared cards and the cards of the current user that no user has processed yet,
+// private cards of other users are left alone
 func (rp *practiceRepo) DeleteNewCard(ctx context.Context) error {
 	logger.Log.Info().Msg("DeleteNewCard")
-	query := rp.db.QueryBuilder.Delete("cards").Where("status = ?", langfi.CARD_NEW)
+	processed := fmt.Sprintf("SELECT 1 FROM fsrs f WHERE f.card_id = cards.id AND f.status != '%s'", langfi.CARD_NEW)
+	unprocessed := sq.And{sq.Expr("NOT EXISTS (" + processed + ")"), cardVisible(auth.UserIDFromContext(ctx))}
+	// nested in sq.Expr, so its placeholders are left for the outer query to number
+	unprocessedIDs := sq.Select("id").From("cards").Where(unprocessed)
+
+	return rp.db.inTx(ctx, func(q queryer) error {
+		tombstones, args, err := rp.db.QueryBuilder.Insert("sync_changes").
+			Columns("card_id", "user_id", "deleted").
+			Select(rp.db.QueryBuilder.Select("id", "CAST(NULL AS INTEGER)", "TRUE").From("cards").Where(unprocessed)).
+			ToSql()
+		if err != nil {
+			return errors.Wrap(err, "failed to build sql query")
+		}
+		_, err = q.ExecContext(ctx, tombstones, args...)
+		if err != nil {
+			return errors.Wrap(err, "failed to record deleted NEW cards")
+		}
 
-	sqlCmd, args, err := query.ToSql()
-	if err != nil {
-		return errors.Wrap(err, "failed to build sql query")
-	}
-	_, err = rp.db.SqlDB.ExecContext(ctx, sqlCmd, args...)
-	if err != nil {
-		return errors.Wrap(err, "failed to delete NEW card")
-	}
-	return nil
+		for _, query := range []sq.DeleteBuilder{
+			rp.db.QueryBuilder.Delete("card_tags").Where(sq.Expr("card_id IN (?)", unprocessedIDs)),
+			rp.db.QueryBuilder.Delete("card_revisions").Where(sq.Expr("card_id IN (?)", unprocessedIDs)),
+			rp.db.QueryBuilder.Delete("fsrs").Where(sq.Expr("card_id IN (?)", unprocessedIDs)),
+			rp.db.QueryBuilder.Delete("cards").Where(unprocessed),
+		} {
+			sqlCmd, args, err := query.ToSql()
+			if err != nil {
+				return errors.Wrap(err, "failed to build sql query")
+			}
+			_, err = q.ExecContext(ctx, sqlCmd, args...)
+			if err != nil {
+				return errors.Wrap(err, "failed to delete NEW card")
+			}
+		}
+		return nil
+	})
 }
 
 func (rp *practiceRepo) GetGroupStats(ctx context.Context) (*[]langfi.GroupSummaryDto, error) {
-	query := rp.db.QueryBuilder.Select("card_group", "count(*) as num_cards",
-		fmt.Sprintf("COUNT(CASE WHEN status = '%s' THEN 1 END) AS card_new", langfi.CARD_NEW),
-		fmt.Sprintf("COUNT(CASE WHEN status = '%s' THEN 1 END) AS card_learn", langfi.CARD_LEARN),
-		fmt.Sprintf("COUNT(CASE WHEN status = '%s' THEN 1 END) AS card_discard", langfi.CARD_DISCARD),
-		fmt.Sprintf("COUNT(CASE WHEN status = '%s' THEN 1 END) AS card_save", langfi.CARD_SAVE)).
+	query := rp.db.QueryBuilder.Select("COALESCE(decks.name, '') AS deck", "count(*) as num_cards",
+		fmt.Sprintf("COUNT(CASE WHEN %s = '%s' THEN 1 END) AS card_new", cardStatusColumn, langfi.CARD_NEW),
+		fmt.Sprintf("COUNT(CASE WHEN %s = '%s' THEN 1 END) AS card_learn", cardStatusColumn, langfi.CARD_LEARN),
+		fmt.Sprintf("COUNT(CASE WHEN %s = '%s' THEN 1 END) AS card_discard", cardStatusColumn, langfi.CARD_DISCARD),
+		fmt.Sprintf("COUNT(CASE WHEN %s = '%s' THEN 1 END) AS card_save", cardStatusColumn, langfi.CARD_SAVE)).
 		From("cards").
-		GroupBy("card_group")
+		LeftJoin("decks ON decks.id = cards.deck_id").
+		LeftJoin("fsrs ON fsrs.card_id = cards.id AND fsrs.user_id = ?", auth.UserIDFromContext(ctx)).
+		Where(cardVisible(auth.UserIDFromContext(ctx))).
+		GroupBy("deck")
 
 	sqlCmd, args, err := query.ToSql()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to build sql query")
 	}
 
-	rows, err := rp.db.SqlDB.QueryContext(ctx, sqlCmd, args...)
+	rows, err := rp.db.QueryContext(ctx, sqlCmd, args...)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to query SQL")
 	}
@@ -266,3 +436,7 @@ func (rp *practiceRepo) GetGroupStats(ctx context.Context) (*[]langfi.GroupSumma
 
 	return &groups, nil
 }
+
+func (rp *practiceRepo) GetCardsByStatus(ctx context.Context, status string) (*[]langfi.ReviewCard, error) {
+	return rp.queryCards(ctx, rp.selectCards(ctx).Where(sq.Eq{cardStatusColumn: status}))
+}
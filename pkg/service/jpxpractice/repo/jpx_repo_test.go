@@ -0,0 +1,118 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/database/postgresdb"
+	"github.com/nhuongmh/cfvs.jpx/pkg/database/sqlite3"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/nhuongmh/cfvs.jpx/pkg/service/jpxpractice/repo/repotest"
+)
+
+// the postgres suite runs against this database when set, its practice tables are emptied
+const POSTGRES_TEST_URL_ENV = "PRACTICE_TEST_POSTGRES_URL"
+
+func TestMemoryPracticeRepo(t *testing.T) {
+	repotest.RunPracticeRepoSuite(t, func(t *testing.T) langfi.PracticeRepo {
+		return NewMemoryPracticeRepo()
+	})
+}
+
+func newSqliteRepo(tb testing.TB) langfi.PracticeRepo {
+	db, err := sqlite3.ConnectDB(context.Background(), filepath.Join(tb.TempDir(), "practice.db"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+	if err := db.Migrate(); err != nil {
+		tb.Fatal(err)
+	}
+	return NewJpxPraticeRepo(db)
+}
+
+func TestSqlitePracticeRepo(t *testing.T) {
+	repotest.RunPracticeRepoSuite(t, func(t *testing.T) langfi.PracticeRepo {
+		return newSqliteRepo(t)
+	})
+}
+
+func TestPostgresPracticeRepo(t *testing.T) {
+	url := os.Getenv(POSTGRES_TEST_URL_ENV)
+	if url == "" {
+		t.Skipf("%v is not set", POSTGRES_TEST_URL_ENV)
+	}
+	repotest.RunPracticeRepoSuite(t, func(t *testing.T) langfi.PracticeRepo {
+		db, err := postgresdb.ConnectDB(context.Background(), url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(db.Close)
+		if err := db.Migrate(); err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.SqlDB.Exec(`TRUNCATE sync_changes, card_revisions, card_tags, review_logs, fsrs, cards RESTART IDENTITY CASCADE`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewJpxPostgresPracticeRepo(db)
+	})
+}
+
+// proposalCards looks like the sentence cards built by BuildCards, spread over minna lessons
+func proposalCards(n int) []langfi.ReviewCard {
+	cards := make([]langfi.ReviewCard, n)
+	for i := range cards {
+		cards[i] = langfi.NewReviewCard(fmt.Sprintf("これは%vです", i), fmt.Sprintf("this is %v", i))
+		cards[i].Group = fmt.Sprintf("jp::Minna::%02d", i%50+1)
+		cards[i].SetProp("formula", "N1 は N2 です")
+		cards[i].Tags = []string{"sentence"}
+	}
+	return cards
+}
+
+// BenchmarkAddNewCards inserts the cards of BuildCards into an empty database in one transaction
+func BenchmarkAddNewCards(b *testing.B) {
+	for _, n := range []int{1000, 5000} {
+		b.Run(fmt.Sprintf("cards=%v", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				repo := newSqliteRepo(b)
+				cards := proposalCards(n)
+				b.StartTimer()
+				added, err := repo.AddNewCards(context.Background(), cards)
+				if err != nil || added != n {
+					b.Fatalf("AddNewCards() = %v, %v", added, err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkAddCardEach inserts the same cards one transaction per card after a duplicate check,
+// as BuildCards did before AddNewCards
+func BenchmarkAddCardEach(b *testing.B) {
+	for _, n := range []int{1000, 5000} {
+		b.Run(fmt.Sprintf("cards=%v", n), func(b *testing.B) {
+			ctx := context.Background()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				repo := newSqliteRepo(b)
+				cards := proposalCards(n)
+				b.StartTimer()
+				for j := range cards {
+					existing, err := repo.GetCardByFront(ctx, cards[j].Front)
+					if err != nil || len(*existing) > 0 {
+						b.Fatalf("GetCardByFront() = %v, %v", existing, err)
+					}
+					if err := repo.AddCard(ctx, &cards[j]); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
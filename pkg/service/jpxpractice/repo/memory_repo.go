@@ -0,0 +1,896 @@
+package repo
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/search"
+	"github.com/pkg/errors"
+)
+
+type memoryCard struct {
+	id         uint64
+	front      string
+	back       string
+	properties map[string]interface{}
+	deckID     uint64
+	revision   uint64
+	// 0 when every user sees the card
+	ownerID uint64
+	// tags of each user
+	tags map[uint64][]string
+}
+
+func (c *memoryCard) visibleTo(userID uint64) bool {
+	return c.ownerID == 0 || c.ownerID == userID
+}
+
+type memoryFsrsKey struct {
+	cardID uint64
+	userID uint64
+}
+
+type memoryDeckKey struct {
+	deckID uint64
+	userID uint64
+}
+
+type memoryFsrs struct {
+	status string
+	data   langfi.FSRSData
+}
+
+type memoryReviewLog struct {
+	userID uint64
+	log    langfi.ReviewLog
+}
+
+// memoryChange is a row of sync_changes, shared for changes of the card content
+type memoryChange struct {
+	seq     uint64
+	cardID  uint64
+	userID  uint64
+	shared  bool
+	deleted bool
+}
+
+// memoryRepo keeps practice data in memory with the same per user semantics as practiceRepo,
+// it is meant for service tests
+type memoryRepo struct {
+	mu        sync.Mutex
+	lastID    uint64
+	cards     map[uint64]*memoryCard
+	fsrs      map[memoryFsrsKey]*memoryFsrs
+	logs      []memoryReviewLog
+	decks     map[string]*langfi.Deck
+	settings  map[memoryDeckKey]langfi.DeckSettings
+	revisions []langfi.CardRevision
+	changes   []memoryChange
+}
+
+func NewMemoryPracticeRepo() langfi.PracticeRepo {
+	return &memoryRepo{
+		cards:    map[uint64]*memoryCard{},
+		fsrs:     map[memoryFsrsKey]*memoryFsrs{},
+		decks:    map[string]*langfi.Deck{},
+		settings: map[memoryDeckKey]langfi.DeckSettings{},
+	}
+}
+
+func (mr *memoryRepo) nextID() uint64 {
+	mr.lastID++
+	return mr.lastID
+}
+
+func (mr *memoryRepo) recordChange(cardID, userID uint64, shared, deleted bool) {
+	mr.changes = append(mr.changes, memoryChange{seq: uint64(len(mr.changes) + 1), cardID: cardID,
+		userID: userID, shared: shared, deleted: deleted})
+}
+
+func copyProperties(props map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(props))
+	for k, v := range props {
+		copied[k] = v
+	}
+	return copied
+}
+
+func (mr *memoryRepo) deckName(deckID uint64) string {
+	for _, deck := range mr.decks {
+		if deck.ID == deckID {
+			return deck.Name
+		}
+	}
+	return ""
+}
+
+// toReviewCard returns a copy of the card as seen by the user, and whether the user has a fsrs row for it
+func (mr *memoryRepo) toReviewCard(c *memoryCard, userID uint64) (langfi.ReviewCard, bool) {
+	card := langfi.ReviewCard{
+		Front:      c.front,
+		Back:       c.back,
+		Properties: copyProperties(c.properties),
+		Status:     langfi.CARD_NEW,
+		Group:      mr.deckName(c.deckID),
+		Tags:       append([]string{}, c.tags[userID]...),
+		Revision:   c.revision,
+		OwnerID:    c.ownerID,
+	}
+	card.ID = c.id
+	row, ok := mr.fsrs[memoryFsrsKey{c.id, userID}]
+	if ok {
+		card.Status = row.status
+		card.FsrsData = row.data
+	}
+	sort.Strings(card.Tags)
+	return card, ok
+}
+
+// filterCards returns the matching cards seen by the user, ordered by id
+func (mr *memoryRepo) filterCards(userID uint64, match func(card *langfi.ReviewCard, scheduled bool) bool) []langfi.ReviewCard {
+	cards := []langfi.ReviewCard{}
+	for _, c := range mr.cards {
+		if !c.visibleTo(userID) {
+			continue
+		}
+		card, scheduled := mr.toReviewCard(c, userID)
+		if match(&card, scheduled) {
+			cards = append(cards, card)
+		}
+	}
+	sort.Slice(cards, func(i, j int) bool { return cards[i].ID < cards[j].ID })
+	return cards
+}
+
+func (mr *memoryRepo) saveCard(ctx context.Context, c *memoryCard, card *langfi.ReviewCard) error {
+	var deckID uint64
+	if card.Group != "" {
+		deck, err := mr.ensureDeck(card.Group)
+		if err != nil {
+			return errors.Wrapf(err, "failed to resolve deck %v", card.Group)
+		}
+		deckID = deck.ID
+	}
+
+	card.Tags = langfi.NormalizeTags(card.Tags)
+	_, stored := mr.cards[c.id]
+	if !stored {
+		c.ownerID = card.OwnerID
+	}
+	contentChanged := !stored || c.front != card.Front || c.back != card.Back || c.deckID != deckID ||
+		!(len(c.properties) == 0 && len(card.Properties) == 0 || reflect.DeepEqual(c.properties, card.Properties))
+	c.front, c.back = card.Front, card.Back
+	c.properties = copyProperties(card.Properties)
+	c.deckID = deckID
+	if c.tags == nil {
+		c.tags = map[uint64][]string{}
+	}
+	c.tags[auth.UserIDFromContext(ctx)] = append([]string{}, card.Tags...)
+	mr.cards[c.id] = c
+	if contentChanged {
+		if stored {
+			c.revision++
+		}
+		card.Revision = c.revision
+		mr.recordChange(c.id, 0, true, false)
+	}
+
+	// tag changes are synced with the change of the fsrs row
+	key := memoryFsrsKey{c.id, auth.UserIDFromContext(ctx)}
+	row, ok := mr.fsrs[key]
+	if !ok {
+		row = &memoryFsrs{}
+		row.data.ID = mr.nextID()
+		mr.fsrs[key] = row
+	}
+	card.FsrsData.ID = row.data.ID
+	row.status = card.Status
+	row.data = card.FsrsData
+	mr.recordChange(c.id, key.userID, false, false)
+	return nil
+}
+
+func (mr *memoryRepo) AddCard(ctx context.Context, card *langfi.ReviewCard) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	c := &memoryCard{id: mr.nextID()}
+	card.ID = c.id
+	return mr.saveCard(ctx, c, card)
+}
+
+func (mr *memoryRepo) AddNewCards(ctx context.Context, cards []langfi.ReviewCard) (int, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	type ownedFront struct {
+		front   string
+		ownerID uint64
+	}
+	fronts := map[ownedFront]bool{}
+	for _, c := range mr.cards {
+		fronts[ownedFront{c.front, c.ownerID}] = true
+	}
+	// validate every deck first so that no card is added on error
+	for i := range cards {
+		if cards[i].Group == "" {
+			continue
+		}
+		if _, err := langfi.NormalizeDeckName(cards[i].Group); err != nil {
+			return 0, errors.Wrapf(err, "failed to resolve deck %v", cards[i].Group)
+		}
+	}
+
+	added := 0
+	for i := range cards {
+		key := ownedFront{cards[i].Front, cards[i].OwnerID}
+		if fronts[ownedFront{cards[i].Front, 0}] || fronts[key] {
+			continue
+		}
+		fronts[key] = true
+		c := &memoryCard{id: mr.nextID()}
+		cards[i].ID = c.id
+		if err := mr.saveCard(ctx, c, &cards[i]); err != nil {
+			return added, err
+		}
+		added++
+	}
+	return added, nil
+}
+
+func (mr *memoryRepo) ImportCards(ctx context.Context, cards []langfi.ImportedCard) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	// validate every deck first so that no card is added on error
+	for i := range cards {
+		if cards[i].Card.Group == "" {
+			continue
+		}
+		if _, err := langfi.NormalizeDeckName(cards[i].Card.Group); err != nil {
+			return errors.Wrapf(err, "failed to import card %v", cards[i].Card.Front)
+		}
+	}
+
+	userID := auth.UserIDFromContext(ctx)
+	for i := range cards {
+		c := &memoryCard{id: mr.nextID()}
+		cards[i].Card.ID = c.id
+		if err := mr.saveCard(ctx, c, cards[i].Card); err != nil {
+			return err
+		}
+		for j := range cards[i].Logs {
+			log := &cards[i].Logs[j]
+			log.ID = mr.nextID()
+			log.CardID = c.id
+			mr.logs = append(mr.logs, memoryReviewLog{userID: userID, log: *log})
+		}
+	}
+	return nil
+}
+
+func (mr *memoryRepo) GetCard(ctx context.Context, cardID uint64) (*langfi.ReviewCard, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	c, ok := mr.cards[cardID]
+	if !ok || !c.visibleTo(auth.UserIDFromContext(ctx)) {
+		return nil, errors.Wrapf(model.ErrNoMoreDataAvailable, "failed to get card id = %v", cardID)
+	}
+	card, _ := mr.toReviewCard(c, auth.UserIDFromContext(ctx))
+	return &card, nil
+}
+
+func (mr *memoryRepo) GetCardByFront(ctx context.Context, front string) (*[]langfi.ReviewCard, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	cards := mr.filterCards(auth.UserIDFromContext(ctx), func(card *langfi.ReviewCard, _ bool) bool {
+		return card.Front == front
+	})
+	return &cards, nil
+}
+
+func (mr *memoryRepo) UpdateCard(ctx context.Context, card *langfi.ReviewCard) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	c, ok := mr.cards[card.ID]
+	if !ok {
+		return errors.Wrapf(model.ErrNoMoreDataAvailable, "failed to update card id = %v", card.ID)
+	}
+	return mr.saveCard(ctx, c, card)
+}
+
+func copyRevision(revision *langfi.CardRevision) langfi.CardRevision {
+	copied := *revision
+	copied.Properties = copyProperties(revision.Properties)
+	copied.Changes = append([]langfi.FieldChange{}, revision.Changes...)
+	return copied
+}
+
+func (mr *memoryRepo) UpdateCardText(ctx context.Context, card *langfi.ReviewCard, revision *langfi.CardRevision) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	c, ok := mr.cards[card.ID]
+	if !ok {
+		return errors.Wrapf(model.ErrNoMoreDataAvailable, "card id = %v not found", card.ID)
+	}
+	if c.revision != card.Revision {
+		return errors.Wrapf(langfi.ErrStaleRevision, "card id = %v is not at revision %v", card.ID, card.Revision)
+	}
+	c.front, c.back = card.Front, card.Back
+	c.properties = copyProperties(card.Properties)
+	c.revision++
+	card.Revision = c.revision
+	mr.recordChange(c.id, 0, true, false)
+
+	revision.ID = mr.nextID()
+	revision.CardID = card.ID
+	revision.CreatedAt = time.Now()
+	mr.revisions = append(mr.revisions, copyRevision(revision))
+	return nil
+}
+
+func (mr *memoryRepo) GetCardRevisions(ctx context.Context, cardID uint64) (*[]langfi.CardRevision, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	revisions := []langfi.CardRevision{}
+	// newest first
+	for i := len(mr.revisions) - 1; i >= 0; i-- {
+		if mr.revisions[i].CardID == cardID {
+			revisions = append(revisions, copyRevision(&mr.revisions[i]))
+		}
+	}
+	return &revisions, nil
+}
+
+func (mr *memoryRepo) GetCardRevision(ctx context.Context, revisionID uint64) (*langfi.CardRevision, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	for i := range mr.revisions {
+		if mr.revisions[i].ID == revisionID {
+			revision := copyRevision(&mr.revisions[i])
+			return &revision, nil
+		}
+	}
+	return nil, errors.Wrapf(model.ErrNoMoreDataAvailable, "revision id = %v not found", revisionID)
+}
+
+// inDeck matches cards of the deck and of all its sub decks, cards without deck never match
+func inDeck(card *langfi.ReviewCard, deck string) bool {
+	return card.Group != "" && langfi.IsInDeck(card.Group, deck)
+}
+
+func (mr *memoryRepo) FetchReviewCard(ctx context.Context, group string) (*langfi.ReviewCard, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	cards := mr.filterCards(auth.UserIDFromContext(ctx), func(card *langfi.ReviewCard, _ bool) bool {
+		return card.Status == langfi.CARD_LEARN && inDeck(card, group)
+	})
+	if len(cards) == 0 {
+		return nil, model.ErrNoMoreDataAvailable
+	}
+	sort.SliceStable(cards, func(i, j int) bool { return cards[i].FsrsData.Due.Before(cards[j].FsrsData.Due) })
+	return &cards[0], nil
+}
+
+func (mr *memoryRepo) FetchUnProcessCard(ctx context.Context, group string) (*langfi.ReviewCard, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	// ids grow with the creation time
+	cards := mr.filterCards(auth.UserIDFromContext(ctx), func(card *langfi.ReviewCard, _ bool) bool {
+		return card.Status == langfi.CARD_NEW && inDeck(card, group)
+	})
+	if len(cards) == 0 {
+		return nil, model.ErrNoMoreDataAvailable
+	}
+	return &cards[0], nil
+}
+
+// DeleteNewCard removes cards that no user has processed yet
+func (mr *memoryRepo) DeleteNewCard(ctx context.Context) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	processed := map[uint64]bool{}
+	for key, row := range mr.fsrs {
+		if row.status != langfi.CARD_NEW {
+			processed[key.cardID] = true
+		}
+	}
+	deleted := []uint64{}
+	for id, c := range mr.cards {
+		if !c.visibleTo(auth.UserIDFromContext(ctx)) {
+			// kept like processed cards
+			processed[id] = true
+			continue
+		}
+		if !processed[id] {
+			deleted = append(deleted, id)
+			delete(mr.cards, id)
+		}
+	}
+	slices.Sort(deleted)
+	for _, id := range deleted {
+		mr.recordChange(id, 0, true, true)
+	}
+	revisions := []langfi.CardRevision{}
+	for _, revision := range mr.revisions {
+		if processed[revision.CardID] {
+			revisions = append(revisions, revision)
+		}
+	}
+	mr.revisions = revisions
+	for key := range mr.fsrs {
+		if !processed[key.cardID] {
+			delete(mr.fsrs, key)
+		}
+	}
+	return nil
+}
+
+func (mr *memoryRepo) GetGroupStats(ctx context.Context) (*[]langfi.GroupSummaryDto, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	byGroup := map[string]*langfi.GroupSummaryDto{}
+	for _, c := range mr.cards {
+		if !c.visibleTo(auth.UserIDFromContext(ctx)) {
+			continue
+		}
+		card, _ := mr.toReviewCard(c, auth.UserIDFromContext(ctx))
+		group, ok := byGroup[card.Group]
+		if !ok {
+			group = &langfi.GroupSummaryDto{Group: card.Group}
+			byGroup[card.Group] = group
+		}
+		group.NumCards++
+		switch card.Status {
+		case langfi.CARD_NEW:
+			group.Proposal++
+		case langfi.CARD_LEARN:
+			group.Learning++
+		case langfi.CARD_DISCARD:
+			group.Discard++
+		case langfi.CARD_SAVE:
+			group.Save++
+		}
+	}
+
+	groups := []langfi.GroupSummaryDto{}
+	for _, group := range byGroup {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Group < groups[j].Group })
+	return &groups, nil
+}
+
+func (mr *memoryRepo) GetCardsByStatus(ctx context.Context, status string) (*[]langfi.ReviewCard, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	cards := mr.filterCards(auth.UserIDFromContext(ctx), func(card *langfi.ReviewCard, _ bool) bool {
+		return card.Status == status
+	})
+	return &cards, nil
+}
+
+func (mr *memoryRepo) AddReviewLog(ctx context.Context, log *langfi.ReviewLog) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	log.ID = mr.nextID()
+	mr.logs = append(mr.logs, memoryReviewLog{userID: auth.UserIDFromContext(ctx), log: *log})
+	return nil
+}
+
+func (mr *memoryRepo) GetReviewLogs(ctx context.Context, since time.Time) (*[]langfi.ReviewLog, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	logs := []langfi.ReviewLog{}
+	for _, entry := range mr.logs {
+		c, ok := mr.cards[entry.log.CardID]
+		if !ok || entry.userID != auth.UserIDFromContext(ctx) || entry.log.Review.Before(since) {
+			continue
+		}
+		log := entry.log
+		log.Group = mr.deckName(c.deckID)
+		logs = append(logs, log)
+	}
+	sort.SliceStable(logs, func(i, j int) bool { return logs[i].Review.Before(logs[j].Review) })
+	return &logs, nil
+}
+
+func (mr *memoryRepo) GetFirstReviewLogs(ctx context.Context, minStability float64) (*[]langfi.ReviewLog, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	first := map[uint64]int{}
+	logs := []langfi.ReviewLog{}
+	for _, entry := range mr.logs {
+		c, ok := mr.cards[entry.log.CardID]
+		if !ok || entry.userID != auth.UserIDFromContext(ctx) || entry.log.Stability < minStability {
+			continue
+		}
+		// the logs are kept in insertion order, so the earlier id wins a tie
+		i, seen := first[entry.log.CardID]
+		if seen && !entry.log.Review.Before(logs[i].Review) {
+			continue
+		}
+		log := entry.log
+		log.Group = mr.deckName(c.deckID)
+		if seen {
+			logs[i] = log
+			continue
+		}
+		first[log.CardID] = len(logs)
+		logs = append(logs, log)
+	}
+	sort.SliceStable(logs, func(i, j int) bool { return logs[i].Review.Before(logs[j].Review) })
+	return &logs, nil
+}
+
+func (mr *memoryRepo) GetCardReviewLogs(ctx context.Context, cardID uint64) (*[]langfi.ReviewLog, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	logs := mr.cardReviewLogs(cardID, auth.UserIDFromContext(ctx))
+	return &logs, nil
+}
+
+func (mr *memoryRepo) cardReviewLogs(cardID, userID uint64) []langfi.ReviewLog {
+	logs := []langfi.ReviewLog{}
+	c, ok := mr.cards[cardID]
+	if !ok {
+		return logs
+	}
+	for _, entry := range mr.logs {
+		if entry.log.CardID == cardID && entry.userID == userID {
+			log := entry.log
+			log.Group = mr.deckName(c.deckID)
+			logs = append(logs, log)
+		}
+	}
+	sort.SliceStable(logs, func(i, j int) bool { return logs[i].Review.Before(logs[j].Review) })
+	return logs
+}
+
+func (mr *memoryRepo) ReviewCard(ctx context.Context, cardID uint64, review langfi.CardReviewFunc) (*langfi.ReviewCard, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	userID := auth.UserIDFromContext(ctx)
+	c, ok := mr.cards[cardID]
+	if !ok || !c.visibleTo(userID) {
+		return nil, errors.Wrapf(model.ErrNoMoreDataAvailable, "failed to review card id = %v", cardID)
+	}
+	card, _ := mr.toReviewCard(c, userID)
+	logs, err := review(&card, mr.cardReviewLogs(cardID, userID))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to review card id = %v", cardID)
+	}
+
+	kept := map[uint64]bool{}
+	for i := range logs {
+		kept[logs[i].ID] = true
+	}
+	entries := []memoryReviewLog{}
+	for _, entry := range mr.logs {
+		if entry.log.CardID != cardID || entry.userID != userID || kept[entry.log.ID] {
+			entries = append(entries, entry)
+		}
+	}
+	for i := range logs {
+		if logs[i].ID != 0 {
+			continue
+		}
+		logs[i].CardID = cardID
+		logs[i].ID = mr.nextID()
+		entries = append(entries, memoryReviewLog{userID: userID, log: logs[i]})
+	}
+	mr.logs = entries
+	err = mr.saveCard(ctx, c, &card)
+	if err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+func (mr *memoryRepo) GetCardChanges(ctx context.Context, cursor uint64, limit int) (*[]langfi.CardChange, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	byCard := map[uint64]*langfi.CardChange{}
+	for _, change := range mr.changes {
+		if change.seq <= cursor || (!change.shared && change.userID != auth.UserIDFromContext(ctx)) {
+			continue
+		}
+		if c, ok := mr.cards[change.cardID]; ok && !c.visibleTo(auth.UserIDFromContext(ctx)) {
+			continue
+		}
+		cardChange, ok := byCard[change.cardID]
+		if !ok {
+			cardChange = &langfi.CardChange{CardID: change.cardID}
+			byCard[change.cardID] = cardChange
+		}
+		cardChange.Seq = change.seq
+		cardChange.Deleted = cardChange.Deleted || change.deleted
+	}
+	changes := []langfi.CardChange{}
+	for _, change := range byCard {
+		changes = append(changes, *change)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Seq < changes[j].Seq })
+	if limit > 0 && len(changes) > limit {
+		changes = changes[:limit]
+	}
+	return &changes, nil
+}
+
+func (mr *memoryRepo) GetCardsByID(ctx context.Context, cardIDs []uint64) (*[]langfi.ReviewCard, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	cards := mr.filterCards(auth.UserIDFromContext(ctx), func(card *langfi.ReviewCard, _ bool) bool {
+		return slices.Contains(cardIDs, card.ID)
+	})
+	return &cards, nil
+}
+
+func (mr *memoryRepo) SearchCards(ctx context.Context, query *langfi.CardQuery, limit int) (*[]langfi.ReviewCard, error) {
+	match, err := compileMemoryCardQuery(query, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	scheduled := map[uint64]bool{}
+	cards := mr.filterCards(auth.UserIDFromContext(ctx), func(card *langfi.ReviewCard, hasFsrs bool) bool {
+		scheduled[card.ID] = hasFsrs
+		return match(card, hasFsrs)
+	})
+	sort.SliceStable(cards, func(i, j int) bool {
+		if scheduled[cards[i].ID] != scheduled[cards[j].ID] {
+			return scheduled[cards[i].ID]
+		}
+		return cards[i].FsrsData.Due.Before(cards[j].FsrsData.Due)
+	})
+	if limit > 0 && len(cards) > limit {
+		cards = cards[:limit]
+	}
+	return &cards, nil
+}
+
+// SearchCardText scores the cards like the sqlite search without fts5
+func (mr *memoryRepo) SearchCardText(ctx context.Context, terms []string, limit int) ([]search.Hit, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	hits := []search.Hit{}
+	for _, card := range mr.filterCards(auth.UserIDFromContext(ctx), func(*langfi.ReviewCard, bool) bool { return true }) {
+		front, back := strings.ToLower(card.Front), strings.ToLower(card.Back)
+		properties := strings.ToLower(card.PropertiesToJson())
+		score := 0
+		for _, term := range terms {
+			termScore := 0
+			if strings.Contains(front, term) {
+				termScore += 3
+			}
+			if strings.Contains(back, term) {
+				termScore += 2
+			}
+			if strings.Contains(properties, term) {
+				termScore++
+			}
+			if termScore == 0 {
+				score = 0
+				break
+			}
+			score += termScore
+		}
+		if score > 0 {
+			hits = append(hits, cardHit(&card, float64(score), terms))
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+func (mr *memoryRepo) GetDecks(ctx context.Context) (*[]langfi.Deck, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	userID := auth.UserIDFromContext(ctx)
+	// decks holding only cards of other owners are left out, like in the sql repo
+	visible, hidden := map[uint64]bool{}, map[uint64]bool{}
+	for _, c := range mr.cards {
+		if c.visibleTo(userID) {
+			visible[c.deckID] = true
+		} else {
+			hidden[c.deckID] = true
+		}
+	}
+	decks := []langfi.Deck{}
+	for _, deck := range mr.decks {
+		if hidden[deck.ID] && !visible[deck.ID] {
+			continue
+		}
+		copied := *deck
+		copied.Settings = mr.settings[memoryDeckKey{deckID: deck.ID, userID: userID}]
+		decks = append(decks, copied)
+	}
+	sort.Slice(decks, func(i, j int) bool { return decks[i].Name < decks[j].Name })
+	return &decks, nil
+}
+
+func (mr *memoryRepo) EnsureDeck(ctx context.Context, name string) (*langfi.Deck, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	deck, err := mr.ensureDeck(name)
+	if err != nil {
+		return nil, err
+	}
+	copied := *deck
+	copied.Settings = mr.settings[memoryDeckKey{deckID: deck.ID, userID: auth.UserIDFromContext(ctx)}]
+	return &copied, nil
+}
+
+func (mr *memoryRepo) ensureDeck(name string) (*langfi.Deck, error) {
+	name, err := langfi.NormalizeDeckName(name)
+	if err != nil {
+		return nil, err
+	}
+	if deck, ok := mr.decks[name]; ok {
+		return deck, nil
+	}
+
+	deck := &langfi.Deck{Name: name}
+	if parentName := langfi.ParentDeckName(name); parentName != "" {
+		parent, err := mr.ensureDeck(parentName)
+		if err != nil {
+			return nil, err
+		}
+		deck.ParentID = parent.ID
+	}
+	deck.ID = mr.nextID()
+	deck.CreatedAt = time.Now()
+	mr.decks[name] = deck
+	return deck, nil
+}
+
+func (mr *memoryRepo) UpdateDeckSettings(ctx context.Context, deckID uint64, settings *langfi.DeckSettings) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	for _, deck := range mr.decks {
+		if deck.ID == deckID {
+			copied := *settings
+			copied.Weights = append([]float64(nil), settings.Weights...)
+			mr.settings[memoryDeckKey{deckID: deckID, userID: auth.UserIDFromContext(ctx)}] = copied
+			return nil
+		}
+	}
+	return errors.Wrapf(langfi.ErrInvalidDeck, "deck id = %v not found", deckID)
+}
+
+type memoryCardMatcher func(card *langfi.ReviewCard, scheduled bool) bool
+
+// wildcardRegexp matches like textCondition, exactly or with `*` wildcards
+func wildcardRegexp(value string, contains bool) *regexp.Regexp {
+	parts := strings.Split(value, langfi.QUERY_WILDCARD)
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	pattern := strings.Join(parts, ".*")
+	if contains {
+		pattern = ".*" + pattern + ".*"
+	}
+	return regexp.MustCompile("(?s)^" + pattern + "$")
+}
+
+func compareFloat(a float64, op string, b float64) bool {
+	switch op {
+	case langfi.OP_LT:
+		return a < b
+	case langfi.OP_LTE:
+		return a <= b
+	case langfi.OP_GT:
+		return a > b
+	case langfi.OP_GTE:
+		return a >= b
+	}
+	return a == b
+}
+
+// compileMemoryCardQuery is compileCardQuery for cards in memory
+func compileMemoryCardQuery(query *langfi.CardQuery, now time.Time) (memoryCardMatcher, error) {
+	matchers := []memoryCardMatcher{}
+	for i := range query.Terms {
+		term := query.Terms[i]
+		var match memoryCardMatcher
+		switch term.Field {
+		case langfi.QUERY_TEXT:
+			re := wildcardRegexp(term.Value, true)
+			match = func(card *langfi.ReviewCard, _ bool) bool {
+				return re.MatchString(card.Front) || re.MatchString(card.Back)
+			}
+		case langfi.QUERY_TAG:
+			re := wildcardRegexp(term.Value, false)
+			match = func(card *langfi.ReviewCard, _ bool) bool {
+				for _, tag := range card.Tags {
+					if re.MatchString(tag) {
+						return true
+					}
+				}
+				return false
+			}
+		case langfi.QUERY_GROUP:
+			re := wildcardRegexp(term.Value, false)
+			wildcard := strings.Contains(term.Value, langfi.QUERY_WILDCARD)
+			match = func(card *langfi.ReviewCard, _ bool) bool {
+				if wildcard {
+					return re.MatchString(card.Group)
+				}
+				return inDeck(card, term.Value)
+			}
+		case langfi.QUERY_FRONT, langfi.QUERY_BACK:
+			re := wildcardRegexp(term.Value, false)
+			match = func(card *langfi.ReviewCard, _ bool) bool {
+				if term.Field == langfi.QUERY_FRONT {
+					return re.MatchString(card.Front)
+				}
+				return re.MatchString(card.Back)
+			}
+		case langfi.QUERY_STATUS:
+			match = func(card *langfi.ReviewCard, _ bool) bool { return card.Status == term.Value }
+		case langfi.QUERY_STATE:
+			match = func(card *langfi.ReviewCard, _ bool) bool {
+				return int(card.FsrsData.State) == langfi.QueryStates[term.Value]
+			}
+		case langfi.QUERY_DUE:
+			op := term.Op
+			if op == langfi.OP_EQ {
+				op = langfi.OP_LTE
+			}
+			limit := now.Add(term.DueOffset())
+			match = func(card *langfi.ReviewCard, scheduled bool) bool {
+				return scheduled && compareFloat(float64(card.FsrsData.Due.UnixNano()), op, float64(limit.UnixNano()))
+			}
+		case langfi.QUERY_REPS, langfi.QUERY_LAPSES, langfi.QUERY_STABILITY, langfi.QUERY_DIFFICULTY:
+			value, err := strconv.ParseFloat(term.Value, 64)
+			if err != nil {
+				return nil, errors.Wrapf(langfi.ErrInvalidCardQuery, "%v must be a number", term.Field)
+			}
+			match = func(card *langfi.ReviewCard, _ bool) bool {
+				fsrsd := &card.FsrsData
+				number := map[string]float64{
+					langfi.QUERY_REPS:       float64(fsrsd.Reps),
+					langfi.QUERY_LAPSES:     float64(fsrsd.Lapses),
+					langfi.QUERY_STABILITY:  fsrsd.Stability,
+					langfi.QUERY_DIFFICULTY: fsrsd.Difficulty,
+				}[term.Field]
+				return compareFloat(number, term.Op, value)
+			}
+		default:
+			return nil, errors.Wrapf(langfi.ErrInvalidCardQuery, "unknown field %v", term.Field)
+		}
+
+		if term.Negate {
+			positive := match
+			match = func(card *langfi.ReviewCard, scheduled bool) bool {
+				// like NOT on a NULL deck name in sql, a card without deck matches no deck term
+				if term.Field == langfi.QUERY_GROUP && card.Group == "" {
+					return false
+				}
+				return !positive(card, scheduled)
+			}
+		}
+		matchers = append(matchers, match)
+	}
+
+	return func(card *langfi.ReviewCard, scheduled bool) bool {
+		for _, match := range matchers {
+			if !match(card, scheduled) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
@@ -0,0 +1,1299 @@
+// Package repotest holds the behaviour shared by every langfi.PracticeRepo implementation
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/search"
+	"github.com/open-spaced-repetition/go-fsrs/v3"
+	"github.com/pkg/errors"
+)
+
+// NewRepo returns a repo without cards and review logs, decks seeded by migrations may exist
+type NewRepo func(t *testing.T) langfi.PracticeRepo
+
+// RunPracticeRepoSuite runs the conformance suite against fresh repos from newRepo
+func RunPracticeRepoSuite(t *testing.T, newRepo NewRepo) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo langfi.PracticeRepo)
+	}{
+		{"CardRoundTrip", testCardRoundTrip},
+		{"AddNewCards", testAddNewCards},
+		{"ImportCards", testImportCards},
+		{"FailedWriteRollsBack", testFailedWriteRollsBack},
+		{"FsrsPersistence", testFsrsPersistence},
+		{"CardRevisions", testCardRevisions},
+		{"FetchReviewCardDueOrder", testFetchReviewCardDueOrder},
+		{"FetchUnProcessCard", testFetchUnProcessCard},
+		{"DeleteNewCard", testDeleteNewCard},
+		{"DeleteNewCardOwners", testDeleteNewCardOwners},
+		{"GroupStats", testGroupStats},
+		{"SearchCards", testSearchCards},
+		{"DueTimeZones", testDueTimeZones},
+		{"SearchCardText", testSearchCardText},
+		{"ReviewLogs", testReviewLogs},
+		{"CardReviews", testCardReviews},
+		{"ReviewCard", testReviewCard},
+		{"ConcurrentReviews", testConcurrentReviews},
+		{"CardOwners", testCardOwners},
+		{"SyncChanges", testSyncChanges},
+		{"OverlappingSyncChanges", testOverlappingSyncChanges},
+		{"Decks", testDecks},
+		{"ConcurrentUpdates", testConcurrentUpdates},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepo(t))
+		})
+	}
+}
+
+// times are compared at second precision, postgres keeps microseconds and sqlite keeps text
+var baseTime = time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+func userContext(userID uint64) context.Context {
+	user := &auth.User{}
+	user.ID = userID
+	return auth.WithUser(context.Background(), user)
+}
+
+func newCard(front, group, status string, due time.Time) *langfi.ReviewCard {
+	card := langfi.NewReviewCard(front, "back of "+front)
+	card.Group = group
+	card.Status = status
+	card.FsrsData.Card = fsrs.Card{Due: due, State: fsrs.Review, Stability: 3.5, Difficulty: 5.25,
+		ElapsedDays: 2, ScheduledDays: 4, Reps: 3, Lapses: 1, LastReview: due.AddDate(0, 0, -4)}
+	return &card
+}
+
+func addCards(t *testing.T, ctx context.Context, repo langfi.PracticeRepo, cards ...*langfi.ReviewCard) {
+	t.Helper()
+	for _, card := range cards {
+		if err := repo.AddCard(ctx, card); err != nil {
+			t.Fatalf("AddCard(%v) error = %v", card.Front, err)
+		}
+		if card.ID == 0 {
+			t.Fatalf("AddCard(%v) did not set the card id", card.Front)
+		}
+	}
+}
+
+func getCard(t *testing.T, ctx context.Context, repo langfi.PracticeRepo, id uint64) *langfi.ReviewCard {
+	t.Helper()
+	card, err := repo.GetCard(ctx, id)
+	if err != nil {
+		t.Fatalf("GetCard(%v) error = %v", id, err)
+	}
+	return card
+}
+
+func fronts(cards []langfi.ReviewCard) []string {
+	names := []string{}
+	for i := range cards {
+		names = append(names, cards[i].Front)
+	}
+	return names
+}
+
+func assertFsrs(t *testing.T, got, want fsrs.Card) {
+	t.Helper()
+	if !got.Due.Equal(want.Due) || !got.LastReview.Equal(want.LastReview) {
+		t.Errorf("fsrs due = %v, last review = %v, want %v, %v", got.Due, got.LastReview, want.Due, want.LastReview)
+	}
+	got.Due, got.LastReview, want.Due, want.LastReview = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+	if got != want {
+		t.Errorf("fsrs = %+v, want %+v", got, want)
+	}
+}
+
+func testCardRoundTrip(t *testing.T, repo langfi.PracticeRepo) {
+	ctx := userContext(1)
+	card := newCard("先生", "suite::Minna::L01", langfi.CARD_LEARN, baseTime)
+	card.SetProp(langfi.PROP_READING, "せんせい")
+	card.Tags = []string{"Noun", "lesson1"}
+	addCards(t, ctx, repo, card)
+
+	got := getCard(t, ctx, repo, card.ID)
+	if got.ID != card.ID || got.Front != card.Front || got.Back != card.Back || got.Group != card.Group ||
+		got.Status != langfi.CARD_LEARN || got.GetProp(langfi.PROP_READING) != "せんせい" ||
+		!reflect.DeepEqual(got.Tags, []string{"lesson1", "noun"}) {
+		t.Errorf("GetCard() = %+v, want %+v", got, card)
+	}
+	assertFsrs(t, got.FsrsData.Card, card.FsrsData.Card)
+
+	got.Front, got.Back, got.Group = "学生", "student", "suite::Minna::L02"
+	got.Tags = []string{"lesson2"}
+	got.SetProp(langfi.PROP_READING, "がくせい")
+	if err := repo.UpdateCard(ctx, got); err != nil {
+		t.Fatalf("UpdateCard() error = %v", err)
+	}
+	updated := getCard(t, ctx, repo, card.ID)
+	if updated.Front != "学生" || updated.Back != "student" || updated.Group != "suite::Minna::L02" ||
+		updated.GetProp(langfi.PROP_READING) != "がくせい" || !reflect.DeepEqual(updated.Tags, []string{"lesson2"}) {
+		t.Errorf("GetCard() after update = %+v", updated)
+	}
+
+	byFront, err := repo.GetCardByFront(ctx, "学生")
+	if err != nil || len(*byFront) != 1 || (*byFront)[0].ID != card.ID {
+		t.Errorf("GetCardByFront() = %v, %v", byFront, err)
+	}
+	if byFront, err = repo.GetCardByFront(ctx, "先生"); err != nil || len(*byFront) != 0 {
+		t.Errorf("GetCardByFront() of the old front = %v, %v", byFront, err)
+	}
+
+	if _, err := repo.GetCard(ctx, card.ID+1000); !errors.Is(err, model.ErrNoMoreDataAvailable) {
+		t.Errorf("GetCard() of a missing card error = %v, want %v", err, model.ErrNoMoreDataAvailable)
+	}
+}
+
+func testAddNewCards(t *testing.T, repo langfi.PracticeRepo) {
+	ctx := userContext(1)
+	existing := newCard("existing", "suite", langfi.CARD_LEARN, baseTime)
+	addCards(t, ctx, repo, existing)
+
+	cards := []langfi.ReviewCard{
+		*newCard("one", "suite::new", langfi.CARD_NEW, baseTime),
+		*newCard("existing", "suite", langfi.CARD_NEW, baseTime),
+		*newCard("two", "suite::new", langfi.CARD_NEW, baseTime),
+		*newCard("one", "suite::new", langfi.CARD_NEW, baseTime),
+	}
+	cards[2].Tags = []string{"batch"}
+	added, err := repo.AddNewCards(ctx, cards)
+	if err != nil || added != 2 {
+		t.Fatalf("AddNewCards() = %v, %v, want 2 added", added, err)
+	}
+	if cards[0].ID == 0 || cards[2].ID == 0 {
+		t.Errorf("AddNewCards() did not set the ids of added cards: %v, %v", cards[0].ID, cards[2].ID)
+	}
+	if got := getCard(t, ctx, repo, cards[2].ID); got.Front != "two" || got.Group != "suite::new" ||
+		!reflect.DeepEqual(got.Tags, []string{"batch"}) || got.Status != langfi.CARD_NEW {
+		t.Errorf("GetCard() of an added card = %+v", got)
+	}
+	for _, front := range []string{"one", "existing"} {
+		if byFront, err := repo.GetCardByFront(ctx, front); err != nil || len(*byFront) != 1 {
+			t.Errorf("GetCardByFront(%v) = %v, %v, want one card", front, byFront, err)
+		}
+	}
+	if got := getCard(t, ctx, repo, existing.ID); got.Status != langfi.CARD_LEARN {
+		t.Errorf("AddNewCards() changed the existing card status to %v", got.Status)
+	}
+}
+
+func newLog(rating fsrs.Rating, review time.Time) langfi.ReviewLog {
+	return langfi.ReviewLog{ReviewLog: fsrs.ReviewLog{Rating: rating, State: fsrs.Review, Review: review}}
+}
+
+func testImportCards(t *testing.T, repo langfi.PracticeRepo) {
+	ctx := userContext(1)
+	reviewed := newCard("reviewed", "suite::import", langfi.CARD_LEARN, baseTime)
+	fresh := newCard("fresh", "suite::import", langfi.CARD_LEARN, baseTime)
+	logs := []langfi.ReviewLog{newLog(fsrs.Good, baseTime.AddDate(0, 0, -2)), newLog(fsrs.Easy, baseTime.AddDate(0, 0, -1))}
+	err := repo.ImportCards(ctx, []langfi.ImportedCard{{Card: reviewed, Logs: logs}, {Card: fresh}})
+	if err != nil {
+		t.Fatalf("ImportCards() error = %v", err)
+	}
+	if reviewed.ID == 0 || fresh.ID == 0 {
+		t.Fatalf("ImportCards() did not set the ids of the cards: %v, %v", reviewed.ID, fresh.ID)
+	}
+	got, err := repo.GetCardReviewLogs(ctx, reviewed.ID)
+	if err != nil || len(*got) != 2 || (*got)[0].Rating != fsrs.Good || (*got)[1].CardID != reviewed.ID {
+		t.Errorf("GetCardReviewLogs() of an imported card = %+v, %v, want its 2 logs", got, err)
+	}
+
+	// a card failing half way leaves none of the import behind
+	first := newCard("first of failed import", "suite::import", langfi.CARD_LEARN, baseTime)
+	invalid := newCard("invalid deck", "suite::::broken", langfi.CARD_LEARN, baseTime)
+	err = repo.ImportCards(ctx, []langfi.ImportedCard{{Card: first, Logs: []langfi.ReviewLog{newLog(fsrs.Good, baseTime)}}, {Card: invalid}})
+	if err == nil {
+		t.Fatal("ImportCards() of a card with an invalid deck expected an error")
+	}
+	if byFront, err := repo.GetCardByFront(ctx, first.Front); err != nil || len(*byFront) != 0 {
+		t.Errorf("GetCardByFront() after a failed import = %v, %v, want no card", byFront, err)
+	}
+}
+
+// a write failing half way leaves no card behind
+func testFailedWriteRollsBack(t *testing.T, repo langfi.PracticeRepo) {
+	ctx := userContext(1)
+	invalid := newCard("invalid deck", "suite::::broken", langfi.CARD_NEW, baseTime)
+	if err := repo.AddCard(ctx, invalid); err == nil {
+		t.Fatal("AddCard() of a card with an invalid deck expected an error")
+	}
+
+	cards := []langfi.ReviewCard{*newCard("valid", "suite", langfi.CARD_NEW, baseTime), *invalid}
+	if _, err := repo.AddNewCards(ctx, cards); err == nil {
+		t.Fatal("AddNewCards() with an invalid deck expected an error")
+	}
+	for _, front := range []string{"valid", "invalid deck"} {
+		if byFront, err := repo.GetCardByFront(ctx, front); err != nil || len(*byFront) != 0 {
+			t.Errorf("GetCardByFront(%v) after a failed write = %v, %v", front, fronts(*byFront), err)
+		}
+	}
+
+	card := newCard("kept", "suite", langfi.CARD_LEARN, baseTime)
+	addCards(t, ctx, repo, card)
+	update := *card
+	update.Front, update.Group = "renamed", "suite::::broken"
+	if err := repo.UpdateCard(ctx, &update); err == nil {
+		t.Fatal("UpdateCard() to an invalid deck expected an error")
+	}
+	if got := getCard(t, ctx, repo, card.ID); got.Front != "kept" || got.Group != "suite" {
+		t.Errorf("GetCard() after a failed update = %v in %v", got.Front, got.Group)
+	}
+}
+
+func testFsrsPersistence(t *testing.T, repo langfi.PracticeRepo) {
+	alice, bob := userContext(1), userContext(2)
+	card := newCard("犬", "suite", langfi.CARD_LEARN, baseTime)
+	addCards(t, alice, repo, card)
+
+	card.FsrsData.Card = fsrs.Card{Due: baseTime.AddDate(0, 0, 9), State: fsrs.Relearning, Stability: 1.25,
+		Difficulty: 7.5, ElapsedDays: 5, ScheduledDays: 9, Reps: 4, Lapses: 2, LastReview: baseTime}
+	card.Status = langfi.CARD_SAVE
+	if err := repo.UpdateCard(alice, card); err != nil {
+		t.Fatalf("UpdateCard() error = %v", err)
+	}
+	got := getCard(t, alice, repo, card.ID)
+	if got.Status != langfi.CARD_SAVE || got.FsrsData.ID != card.FsrsData.ID {
+		t.Errorf("GetCard() status = %v, fsrs id = %v, want %v, %v", got.Status, got.FsrsData.ID, langfi.CARD_SAVE, card.FsrsData.ID)
+	}
+	assertFsrs(t, got.FsrsData.Card, card.FsrsData.Card)
+
+	// scheduling is kept per user, a user who never practiced the card sees it unprocessed
+	other := getCard(t, bob, repo, card.ID)
+	if other.Status != langfi.CARD_NEW || other.FsrsData.ID != 0 || other.FsrsData.Reps != 0 || other.Front != card.Front {
+		t.Errorf("GetCard() of another user = %+v", other)
+	}
+	other.Status = langfi.CARD_LEARN
+	other.FsrsData.Card = fsrs.Card{Due: baseTime, State: fsrs.Learning, Reps: 1, LastReview: baseTime}
+	if err := repo.UpdateCard(bob, other); err != nil {
+		t.Fatalf("UpdateCard() of another user error = %v", err)
+	}
+	if got = getCard(t, alice, repo, card.ID); got.Status != langfi.CARD_SAVE || got.FsrsData.Reps != 4 {
+		t.Errorf("GetCard() after an update of another user = %v reps %v", got.Status, got.FsrsData.Reps)
+	}
+	if got = getCard(t, bob, repo, card.ID); got.Status != langfi.CARD_LEARN || got.FsrsData.Reps != 1 {
+		t.Errorf("GetCard() of another user after update = %v reps %v", got.Status, got.FsrsData.Reps)
+	}
+}
+
+func testCardRevisions(t *testing.T, repo langfi.PracticeRepo) {
+	alice, bob := userContext(1), userContext(2)
+	card := newCard("先生", "suite", langfi.CARD_LEARN, baseTime)
+	card.SetProp(langfi.PROP_READING, "せんせい")
+	addCards(t, alice, repo, card)
+
+	// edits of another user keep the scheduling of everyone as is
+	edited := *getCard(t, bob, repo, card.ID)
+	edited.Properties = map[string]interface{}{langfi.PROP_READING: "せんせい", "note": "teacher"}
+	edited.Front = "先生方"
+	first := langfi.NewCardRevision(card, &edited, 2)
+	if err := repo.UpdateCardText(bob, &edited, first); err != nil {
+		t.Fatalf("UpdateCardText() error = %v", err)
+	}
+	if first.ID == 0 || first.CardID != card.ID {
+		t.Errorf("UpdateCardText() revision = %+v", first)
+	}
+	got := getCard(t, alice, repo, card.ID)
+	if got.Front != "先生方" || got.GetProp("note") != "teacher" || got.Status != langfi.CARD_LEARN {
+		t.Errorf("GetCard() after UpdateCardText() = %+v", got)
+	}
+	assertFsrs(t, got.FsrsData.Card, card.FsrsData.Card)
+	if other := getCard(t, bob, repo, card.ID); other.FsrsData.ID != 0 || other.Status != langfi.CARD_NEW {
+		t.Errorf("UpdateCardText() created a fsrs row for the editor: %+v", other.FsrsData)
+	}
+
+	edited = *got
+	edited.Back = "teachers"
+	second := langfi.NewCardRevision(got, &edited, 1)
+	if err := repo.UpdateCardText(alice, &edited, second); err != nil {
+		t.Fatalf("UpdateCardText() error = %v", err)
+	}
+	if edited.Revision != got.Revision+1 || getCard(t, bob, repo, card.ID).Revision != edited.Revision {
+		t.Errorf("UpdateCardText() revision = %v, want %v", edited.Revision, got.Revision+1)
+	}
+
+	// an edit made on the text before the last edit is rejected and leaves no revision
+	stale := *got
+	stale.Front = "生徒"
+	if err := repo.UpdateCardText(bob, &stale, langfi.NewCardRevision(got, &stale, 2)); !errors.Is(err, langfi.ErrStaleRevision) {
+		t.Errorf("UpdateCardText() of an older revision error = %v, want %v", err, langfi.ErrStaleRevision)
+	}
+	if current := getCard(t, alice, repo, card.ID); current.Front != "先生方" || current.Back != "teachers" {
+		t.Errorf("GetCard() after a stale edit = %+v", current)
+	}
+
+	revisions, err := repo.GetCardRevisions(alice, card.ID)
+	if err != nil || len(*revisions) != 2 {
+		t.Fatalf("GetCardRevisions() = %v, %v, want 2 revisions", revisions, err)
+	}
+	newest, oldest := (*revisions)[0], (*revisions)[1]
+	if newest.ID != second.ID || oldest.ID != first.ID || newest.UserID != 1 || oldest.UserID != 2 {
+		t.Errorf("GetCardRevisions() = %+v", *revisions)
+	}
+	if oldest.Front != "先生" || !reflect.DeepEqual(oldest.Properties, map[string]interface{}{langfi.PROP_READING: "せんせい"}) {
+		t.Errorf("oldest revision text = %v %v", oldest.Front, oldest.Properties)
+	}
+	wantChanges := []langfi.FieldChange{
+		{Field: langfi.REVISION_FIELD_FRONT, Old: "先生", New: "先生方"},
+		{Field: langfi.REVISION_FIELD_PROPERTIES + "note", Old: nil, New: "teacher"},
+	}
+	if !reflect.DeepEqual(oldest.Changes, wantChanges) || oldest.CreatedAt.IsZero() {
+		t.Errorf("oldest revision changes = %+v at %v, want %+v", oldest.Changes, oldest.CreatedAt, wantChanges)
+	}
+
+	byID, err := repo.GetCardRevision(alice, first.ID)
+	if err != nil || byID.Front != "先生" || byID.CardID != card.ID {
+		t.Errorf("GetCardRevision() = %+v, %v", byID, err)
+	}
+	if _, err = repo.GetCardRevision(alice, second.ID+1000); !errors.Is(err, model.ErrNoMoreDataAvailable) {
+		t.Errorf("GetCardRevision() of a missing revision error = %v, want %v", err, model.ErrNoMoreDataAvailable)
+	}
+	missing := *got
+	missing.ID = card.ID + 1000
+	if err = repo.UpdateCardText(alice, &missing, second); !errors.Is(err, model.ErrNoMoreDataAvailable) {
+		t.Errorf("UpdateCardText() of a missing card error = %v, want %v", err, model.ErrNoMoreDataAvailable)
+	}
+	if revisions, err = repo.GetCardRevisions(alice, card.ID+1000); err != nil || len(*revisions) != 0 {
+		t.Errorf("GetCardRevisions() of a missing card = %v, %v", revisions, err)
+	}
+}
+
+func testFetchReviewCardDueOrder(t *testing.T, repo langfi.PracticeRepo) {
+	ctx := userContext(1)
+	late := newCard("late", "suite", langfi.CARD_LEARN, baseTime.AddDate(0, 0, 3))
+	early := newCard("early", "suite::sub", langfi.CARD_LEARN, baseTime.AddDate(0, 0, -2))
+	middle := newCard("middle", "suite", langfi.CARD_LEARN, baseTime)
+	otherDeck := newCard("other deck", "suite2", langfi.CARD_LEARN, baseTime.AddDate(0, 0, -10))
+	discarded := newCard("discarded", "suite", langfi.CARD_DISCARD, baseTime.AddDate(0, 0, -10))
+	unprocessed := newCard("unprocessed", "suite", langfi.CARD_NEW, baseTime.AddDate(0, 0, -10))
+	addCards(t, ctx, repo, late, early, middle, otherDeck, discarded, unprocessed)
+
+	for _, want := range []*langfi.ReviewCard{early, middle, late} {
+		got, err := repo.FetchReviewCard(ctx, "suite")
+		if err != nil {
+			t.Fatalf("FetchReviewCard() error = %v", err)
+		}
+		if got.ID != want.ID {
+			t.Fatalf("FetchReviewCard() = %v, want %v", got.Front, want.Front)
+		}
+		// reviewing a card schedules it after the others
+		got.FsrsData.Due = baseTime.AddDate(0, 1, int(got.ID))
+		if err := repo.UpdateCard(ctx, got); err != nil {
+			t.Fatalf("UpdateCard() error = %v", err)
+		}
+	}
+
+	if got, err := repo.FetchReviewCard(ctx, "suite::sub"); err != nil || got.ID != early.ID {
+		t.Errorf("FetchReviewCard() of the sub deck = %v, %v", got, err)
+	}
+	if _, err := repo.FetchReviewCard(userContext(2), "suite"); !errors.Is(err, model.ErrNoMoreDataAvailable) {
+		t.Errorf("FetchReviewCard() of another user error = %v, want %v", err, model.ErrNoMoreDataAvailable)
+	}
+	if _, err := repo.FetchReviewCard(ctx, "suite::missing"); !errors.Is(err, model.ErrNoMoreDataAvailable) {
+		t.Errorf("FetchReviewCard() of an empty deck error = %v, want %v", err, model.ErrNoMoreDataAvailable)
+	}
+}
+
+func testFetchUnProcessCard(t *testing.T, repo langfi.PracticeRepo) {
+	ctx := userContext(1)
+	learning := newCard("learning", "suite", langfi.CARD_LEARN, baseTime)
+	first := newCard("first", "suite::sub", langfi.CARD_NEW, baseTime)
+	second := newCard("second", "suite", langfi.CARD_NEW, baseTime)
+	addCards(t, ctx, repo, learning, first, second)
+
+	got, err := repo.FetchUnProcessCard(ctx, "suite")
+	if err != nil || got.ID != first.ID {
+		t.Fatalf("FetchUnProcessCard() = %v, %v, want %v", got, err, first.Front)
+	}
+	got.Status = langfi.CARD_LEARN
+	if err := repo.UpdateCard(ctx, got); err != nil {
+		t.Fatalf("UpdateCard() error = %v", err)
+	}
+	if got, err = repo.FetchUnProcessCard(ctx, "suite"); err != nil || got.ID != second.ID {
+		t.Errorf("FetchUnProcessCard() = %v, %v, want %v", got, err, second.Front)
+	}
+	if _, err = repo.FetchUnProcessCard(ctx, "suite::sub"); !errors.Is(err, model.ErrNoMoreDataAvailable) {
+		t.Errorf("FetchUnProcessCard() of a processed deck error = %v, want %v", err, model.ErrNoMoreDataAvailable)
+	}
+}
+
+func testDeleteNewCard(t *testing.T, repo langfi.PracticeRepo) {
+	alice, bob := userContext(1), userContext(2)
+	fresh := newCard("fresh", "suite", langfi.CARD_NEW, baseTime)
+	learning := newCard("learning", "suite", langfi.CARD_LEARN, baseTime)
+	saved := newCard("saved", "suite", langfi.CARD_SAVE, baseTime)
+	shared := newCard("shared", "suite", langfi.CARD_NEW, baseTime)
+	shared.Tags = []string{"kept"}
+	fresh.Tags = []string{"dropped"}
+	addCards(t, alice, repo, fresh, learning, saved, shared)
+	// a card is kept when any user has processed it
+	shared.Status = langfi.CARD_LEARN
+	if err := repo.UpdateCard(bob, shared); err != nil {
+		t.Fatalf("UpdateCard() error = %v", err)
+	}
+
+	if err := repo.DeleteNewCard(alice); err != nil {
+		t.Fatalf("DeleteNewCard() error = %v", err)
+	}
+	if _, err := repo.GetCard(alice, fresh.ID); !errors.Is(err, model.ErrNoMoreDataAvailable) {
+		t.Errorf("GetCard() of a deleted card error = %v, want %v", err, model.ErrNoMoreDataAvailable)
+	}
+	for _, card := range []*langfi.ReviewCard{learning, saved, shared} {
+		getCard(t, alice, repo, card.ID)
+	}
+	if got := getCard(t, alice, repo, shared.ID); got.Status != langfi.CARD_NEW || !reflect.DeepEqual(got.Tags, []string{"kept"}) {
+		t.Errorf("GetCard() of the shared card = %v tags %v", got.Status, got.Tags)
+	}
+
+	cards, err := repo.SearchCards(alice, &langfi.CardQuery{Terms: []langfi.QueryTerm{{Field: langfi.QUERY_TAG, Op: langfi.OP_EQ, Value: "dropped"}}}, 0)
+	if err != nil || len(*cards) != 0 {
+		t.Errorf("SearchCards() of the tags of deleted cards = %v, %v", cards, err)
+	}
+}
+
+func testDeleteNewCardOwners(t *testing.T, repo langfi.PracticeRepo) {
+	alice, bob := userContext(1), userContext(2)
+	shared := newCard("shared new", "suite::owners", langfi.CARD_NEW, baseTime)
+	mine := newCard("alice new", "suite::owners", langfi.CARD_NEW, baseTime)
+	mine.OwnerID = 1
+	theirs := newCard("bob new", "suite::owners", langfi.CARD_NEW, baseTime)
+	theirs.OwnerID = 2
+	theirs.Tags = []string{"bob"}
+	addCards(t, alice, repo, shared, mine)
+	addCards(t, bob, repo, theirs)
+
+	if err := repo.DeleteNewCard(alice); err != nil {
+		t.Fatalf("DeleteNewCard() error = %v", err)
+	}
+	for _, card := range []*langfi.ReviewCard{shared, mine} {
+		if _, err := repo.GetCard(alice, card.ID); !errors.Is(err, model.ErrNoMoreDataAvailable) {
+			t.Errorf("GetCard(%v) after delete error = %v, want %v", card.Front, err, model.ErrNoMoreDataAvailable)
+		}
+	}
+	// the private cards of other users are not touched
+	if got := getCard(t, bob, repo, theirs.ID); got.Status != langfi.CARD_NEW || !reflect.DeepEqual(got.Tags, []string{"bob"}) {
+		t.Errorf("GetCard() of the card of bob = %v tags %v", got.Status, got.Tags)
+	}
+}
+
+func testGroupStats(t *testing.T, repo langfi.PracticeRepo) {
+	alice, bob := userContext(1), userContext(2)
+	addCards(t, alice, repo,
+		newCard("a", "suite::one", langfi.CARD_NEW, baseTime),
+		newCard("b", "suite::one", langfi.CARD_LEARN, baseTime),
+		newCard("c", "suite::one", langfi.CARD_LEARN, baseTime),
+		newCard("d", "suite::one", langfi.CARD_DISCARD, baseTime),
+		newCard("e", "suite::two", langfi.CARD_SAVE, baseTime),
+		newCard("f", "", langfi.CARD_LEARN, baseTime),
+	)
+
+	statsOf := func(ctx context.Context) map[string]langfi.GroupSummaryDto {
+		stats, err := repo.GetGroupStats(ctx)
+		if err != nil {
+			t.Fatalf("GetGroupStats() error = %v", err)
+		}
+		byGroup := map[string]langfi.GroupSummaryDto{}
+		for _, group := range *stats {
+			byGroup[group.Group] = group
+		}
+		return byGroup
+	}
+
+	want := map[string]langfi.GroupSummaryDto{
+		"suite::one": {Group: "suite::one", NumCards: 4, Proposal: 1, Learning: 2, Discard: 1},
+		"suite::two": {Group: "suite::two", NumCards: 1, Save: 1},
+		"":           {Group: "", NumCards: 1, Learning: 1},
+	}
+	if got := statsOf(alice); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetGroupStats() = %+v, want %+v", got, want)
+	}
+
+	// another user has processed none of the cards
+	want = map[string]langfi.GroupSummaryDto{
+		"suite::one": {Group: "suite::one", NumCards: 4, Proposal: 4},
+		"suite::two": {Group: "suite::two", NumCards: 1, Proposal: 1},
+		"":           {Group: "", NumCards: 1, Proposal: 1},
+	}
+	if got := statsOf(bob); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetGroupStats() of another user = %+v, want %+v", got, want)
+	}
+}
+
+func testSearchCards(t *testing.T, repo langfi.PracticeRepo) {
+	ctx := userContext(1)
+	now := time.Now().UTC().Truncate(time.Second)
+	soon := newCard("本屋", "suite::Minna::L01", langfi.CARD_LEARN, now.Add(time.Hour))
+	soon.Tags = []string{"lesson1", "place"}
+	later := newCard("本", "suite::Minna::L02", langfi.CARD_LEARN, now.AddDate(0, 0, 10))
+	later.Tags = []string{"lesson2"}
+	later.FsrsData.Lapses = 4
+	overdue := newCard("水", "suite::Other", langfi.CARD_SAVE, now.AddDate(0, 0, -1))
+	overdue.FsrsData.State = fsrs.Learning
+	addCards(t, ctx, repo, later, soon, overdue)
+	unscheduled := newCard("山", "suite::Minna::L01", langfi.CARD_NEW, now)
+	addCards(t, userContext(2), repo, unscheduled)
+
+	tests := []struct {
+		query string
+		limit int
+		want  []string
+	}{
+		{"", 0, []string{"水", "本屋", "本", "山"}},
+		{"", 2, []string{"水", "本屋"}},
+		{"deck:suite::Minna", 0, []string{"本屋", "本", "山"}},
+		{"-deck:suite::Minna", 0, []string{"水"}},
+		{"group:suite::*::L01", 0, []string{"本屋", "山"}},
+		{"tag:lesson*", 0, []string{"本屋", "本"}},
+		{"-tag:place", 0, []string{"水", "本", "山"}},
+		{"status:learn", 0, []string{"本屋", "本"}},
+		{"status:new", 0, []string{"山"}},
+		{"state:learning", 0, []string{"水"}},
+		{"due<1d", 0, []string{"水", "本屋"}},
+		{"-due<1d", 0, []string{"本", "山"}},
+		{"lapses>=4", 0, []string{"本"}},
+		{"reps>2.5 stability<4", 0, []string{"水", "本屋", "本"}},
+		{"front:本*", 0, []string{"本屋", "本"}},
+		{"front:本", 0, []string{"本"}},
+		{"本", 0, []string{"本屋", "本"}},
+		{`back:"back of 水"`, 0, []string{"水"}},
+	}
+	for _, tt := range tests {
+		query, err := langfi.ParseCardQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseCardQuery(%q) error = %v", tt.query, err)
+		}
+		cards, err := repo.SearchCards(ctx, query, tt.limit)
+		if err != nil {
+			t.Errorf("SearchCards(%q) error = %v", tt.query, err)
+			continue
+		}
+		if got := fronts(*cards); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SearchCards(%q, %v) = %v, want %v", tt.query, tt.limit, got, tt.want)
+		}
+	}
+
+	// tags are kept per user
+	tagged := &langfi.CardQuery{Terms: []langfi.QueryTerm{{Field: langfi.QUERY_TAG, Op: langfi.OP_EQ, Value: "lesson*"}}}
+	if cards, err := repo.SearchCards(userContext(2), tagged, 0); err != nil || len(*cards) != 0 {
+		t.Errorf("SearchCards(tag:lesson*) of another user = %v, %v, want none", fronts(*cards), err)
+	}
+
+	invalid := &langfi.CardQuery{Terms: []langfi.QueryTerm{{Field: langfi.QUERY_REPS, Op: langfi.OP_GT, Value: "many"}}}
+	if _, err := repo.SearchCards(ctx, invalid, 0); !errors.Is(err, langfi.ErrInvalidCardQuery) {
+		t.Errorf("SearchCards() of an invalid query error = %v, want %v", err, langfi.ErrInvalidCardQuery)
+	}
+}
+
+// testDueTimeZones checks that due dates written in other zones compare in time order
+func testDueTimeZones(t *testing.T, repo langfi.PracticeRepo) {
+	ctx := userContext(1)
+	now := time.Now().UTC().Truncate(time.Second)
+	// ahead of UTC, the clock reads later than now although the card is due before
+	soon := newCard("東", "suite::zones", langfi.CARD_LEARN, now.Add(2*time.Hour).In(time.FixedZone("JST", 9*3600)))
+	// behind UTC, the clock reads earlier than now although the card is due in two days
+	later := newCard("西", "suite::zones", langfi.CARD_LEARN, now.AddDate(0, 0, 2).In(time.FixedZone("HST", -10*3600)))
+	addCards(t, ctx, repo, later, soon)
+
+	query, err := langfi.ParseCardQuery("due<1d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cards, err := repo.SearchCards(ctx, query, 0)
+	if err != nil {
+		t.Fatalf("SearchCards(due<1d) error = %v", err)
+	}
+	if got := fronts(*cards); !reflect.DeepEqual(got, []string{"東"}) {
+		t.Errorf("SearchCards(due<1d) = %v, want [東]", got)
+	}
+	all, err := repo.SearchCards(ctx, &langfi.CardQuery{}, 0)
+	if err != nil {
+		t.Fatalf("SearchCards() error = %v", err)
+	}
+	if got := fronts(*all); !reflect.DeepEqual(got, []string{"東", "西"}) {
+		t.Errorf("SearchCards() = %v, want soonest due first [東 西]", got)
+	}
+}
+
+func testSearchCardText(t *testing.T, repo langfi.PracticeRepo) {
+	ctx := userContext(1)
+	pie := newCard("apple pie", "suite::Food", langfi.CARD_LEARN, baseTime)
+	bread := newCard("banana bread", "suite::Food", langfi.CARD_LEARN, baseTime)
+	bread.Back = "made with apple sauce"
+	cherry := newCard("cherry", "suite::Food", langfi.CARD_LEARN, baseTime)
+	cherry.SetProp("season", "summer")
+	teacher := newCard("わたしは先生です", "suite::Minna::L01", langfi.CARD_LEARN, baseTime)
+	addCards(t, ctx, repo, pie, bread, cherry, teacher)
+
+	searchFronts := func(text string, limit int) []string {
+		t.Helper()
+		terms, err := search.ParseTerms(text)
+		if err != nil {
+			t.Fatalf("ParseTerms(%q) error = %v", text, err)
+		}
+		hits, err := repo.SearchCardText(ctx, terms, limit)
+		if err != nil {
+			t.Fatalf("SearchCardText(%q) error = %v", text, err)
+		}
+		got := []string{}
+		for _, hit := range hits {
+			got = append(got, hit.Title)
+		}
+		return got
+	}
+
+	tests := []struct {
+		text  string
+		limit int
+		want  []string
+	}{
+		{"apple", 0, []string{"apple pie", "banana bread"}},
+		{"apple", 1, []string{"apple pie"}},
+		{"Apple PIE", 0, []string{"apple pie"}},
+		{"app", 0, []string{"apple pie", "banana bread"}},
+		{"summer", 0, []string{"cherry"}},
+		{"わたし", 0, []string{"わたしは先生です"}},
+		// Japanese words are not separated by spaces, terms match inside the text
+		{"先生", 0, []string{"わたしは先生です"}},
+		{"は先生で", 0, []string{"わたしは先生です"}},
+		{"ppl", 0, []string{"apple pie", "banana bread"}},
+		{"zzz", 0, []string{}},
+	}
+	for _, tt := range tests {
+		if got := searchFronts(tt.text, tt.limit); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SearchCardText(%q, %v) = %v, want %v", tt.text, tt.limit, got, tt.want)
+		}
+	}
+
+	hits, err := repo.SearchCardText(ctx, []string{"pie"}, 0)
+	if err != nil || len(hits) != 1 {
+		t.Fatalf("SearchCardText(pie) = %v, %v", hits, err)
+	}
+	want := search.Hit{Kind: search.KIND_CARD, ID: pie.ID, Title: "apple pie",
+		Snippet: "apple <mark>pie</mark> · back of apple <mark>pie</mark>", Score: hits[0].Score}
+	if hits[0] != want || hits[0].Score <= 0 {
+		t.Errorf("SearchCardText(pie) = %+v, want %+v with a positive score", hits[0], want)
+	}
+
+	// edited text is searchable at once
+	edited := *cherry
+	edited.Back = "apple crumble"
+	if err := repo.UpdateCardText(ctx, &edited, langfi.NewCardRevision(cherry, &edited, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchFronts("crumble", 0); !reflect.DeepEqual(got, []string{"cherry"}) {
+		t.Errorf("SearchCardText(crumble) after edit = %v, want [cherry]", got)
+	}
+	if got := searchFronts("back of cherry", 0); len(got) != 0 {
+		t.Errorf("SearchCardText(back of cherry) after edit = %v, want none", got)
+	}
+}
+
+func testReviewLogs(t *testing.T, repo langfi.PracticeRepo) {
+	alice, bob := userContext(1), userContext(2)
+	card := newCard("猫", "suite::logs", langfi.CARD_LEARN, baseTime)
+	addCards(t, alice, repo, card)
+
+	for i, ctx := range []context.Context{alice, alice, bob, alice} {
+		log := &langfi.ReviewLog{CardID: card.ID, Stability: 2.5, Difficulty: 6,
+			ReviewLog: fsrs.ReviewLog{Rating: fsrs.Good, State: fsrs.Review, ScheduledDays: uint64(i + 1),
+				ElapsedDays: 1, Review: baseTime.AddDate(0, 0, 3-i)}}
+		if err := repo.AddReviewLog(ctx, log); err != nil {
+			t.Fatalf("AddReviewLog() error = %v", err)
+		}
+		if log.ID == 0 {
+			t.Fatal("AddReviewLog() did not set the log id")
+		}
+	}
+
+	logs, err := repo.GetReviewLogs(alice, baseTime.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("GetReviewLogs() error = %v", err)
+	}
+	scheduled := []uint64{}
+	for _, log := range *logs {
+		scheduled = append(scheduled, log.ScheduledDays)
+		if log.CardID != card.ID || log.Group != card.Group || log.Rating != fsrs.Good || log.Stability != 2.5 {
+			t.Errorf("GetReviewLogs() log = %+v", log)
+		}
+	}
+	// oldest first, logs of other users and before since are left out
+	if want := []uint64{2, 1}; !reflect.DeepEqual(scheduled, want) {
+		t.Errorf("GetReviewLogs() scheduled days = %v, want %v", scheduled, want)
+	}
+
+	// the earliest log of each card, of the user
+	for _, tt := range []struct {
+		ctx          context.Context
+		minStability float64
+		want         []uint64
+	}{{alice, 0, []uint64{4}}, {bob, 0, []uint64{3}}, {alice, 2.5, []uint64{4}}, {alice, 3, []uint64{}}} {
+		first, err := repo.GetFirstReviewLogs(tt.ctx, tt.minStability)
+		if err != nil {
+			t.Fatalf("GetFirstReviewLogs() error = %v", err)
+		}
+		scheduled := []uint64{}
+		for _, log := range *first {
+			scheduled = append(scheduled, log.ScheduledDays)
+			if log.Group != card.Group {
+				t.Errorf("GetFirstReviewLogs() log = %+v", log)
+			}
+		}
+		if !reflect.DeepEqual(scheduled, tt.want) {
+			t.Errorf("GetFirstReviewLogs(%v) scheduled days = %v, want %v", tt.minStability, scheduled, tt.want)
+		}
+	}
+}
+
+func testCardReviews(t *testing.T, repo langfi.PracticeRepo) {
+	alice, bob := userContext(1), userContext(2)
+	card := newCard("鳥", "suite::logs", langfi.CARD_LEARN, baseTime)
+	other := newCard("魚", "suite::logs", langfi.CARD_LEARN, baseTime)
+	addCards(t, alice, repo, card, other)
+	for _, add := range []struct {
+		ctx  context.Context
+		card *langfi.ReviewCard
+	}{{alice, card}, {alice, other}, {bob, card}} {
+		log := &langfi.ReviewLog{CardID: add.card.ID, ReviewLog: fsrs.ReviewLog{Rating: fsrs.Hard, Review: baseTime}}
+		if err := repo.AddReviewLog(add.ctx, log); err != nil {
+			t.Fatalf("AddReviewLog() error = %v", err)
+		}
+	}
+
+	replayed := []langfi.ReviewLog{
+		{ReviewLog: fsrs.ReviewLog{Rating: fsrs.Good, State: fsrs.New, Review: baseTime.AddDate(0, 0, 2)}, Stability: 3},
+		{ReviewLog: fsrs.ReviewLog{Rating: fsrs.Again, State: fsrs.Review, Review: baseTime.AddDate(0, 0, 1)}, Stability: 1},
+	}
+	// a replay returns new logs only, the stored ones are deleted
+	_, err := repo.ReviewCard(alice, card.ID, func(locked *langfi.ReviewCard, logs []langfi.ReviewLog) ([]langfi.ReviewLog, error) {
+		locked.FsrsData.Reps = 2
+		locked.FsrsData.Stability = 3
+		return replayed, nil
+	})
+	if err != nil {
+		t.Fatalf("ReviewCard() replay error = %v", err)
+	}
+	if replayed[0].ID == 0 || replayed[0].CardID != card.ID {
+		t.Errorf("ReviewCard() replay did not set the log ids, logs = %+v", replayed)
+	}
+	if got := getCard(t, alice, repo, card.ID); got.FsrsData.Reps != 2 || got.FsrsData.Stability != 3 {
+		t.Errorf("GetCard() after ReviewCard() replay fsrs = %+v", got.FsrsData)
+	}
+
+	logs, err := repo.GetCardReviewLogs(alice, card.ID)
+	if err != nil {
+		t.Fatalf("GetCardReviewLogs() error = %v", err)
+	}
+	ratings := []fsrs.Rating{}
+	for _, log := range *logs {
+		ratings = append(ratings, log.Rating)
+		if log.CardID != card.ID || log.Group != card.Group {
+			t.Errorf("GetCardReviewLogs() log = %+v", log)
+		}
+	}
+	// the logs of alice are replaced oldest first, the logs of bob and of other cards are kept
+	if want := []fsrs.Rating{fsrs.Again, fsrs.Good}; !reflect.DeepEqual(ratings, want) {
+		t.Errorf("GetCardReviewLogs() ratings = %v, want %v", ratings, want)
+	}
+	for _, kept := range []struct {
+		ctx    context.Context
+		cardID uint64
+	}{{bob, card.ID}, {alice, other.ID}} {
+		logs, err := repo.GetCardReviewLogs(kept.ctx, kept.cardID)
+		if err != nil || len(*logs) != 1 || (*logs)[0].Rating != fsrs.Hard {
+			t.Errorf("GetCardReviewLogs(%v) = %+v, %v", kept.cardID, logs, err)
+		}
+	}
+}
+
+func testReviewCard(t *testing.T, repo langfi.PracticeRepo) {
+	alice, bob := userContext(1), userContext(2)
+	card := newCard("蛙", "suite::logs", langfi.CARD_LEARN, baseTime)
+	addCards(t, alice, repo, card)
+	stored := []*langfi.ReviewLog{}
+	for _, ctx := range []context.Context{alice, alice, bob} {
+		log := &langfi.ReviewLog{CardID: card.ID, ReviewLog: fsrs.ReviewLog{Rating: fsrs.Hard, Review: baseTime}}
+		if err := repo.AddReviewLog(ctx, log); err != nil {
+			t.Fatalf("AddReviewLog() error = %v", err)
+		}
+		stored = append(stored, log)
+	}
+
+	failed := errors.New("review failed")
+	_, err := repo.ReviewCard(alice, card.ID, func(locked *langfi.ReviewCard, logs []langfi.ReviewLog) ([]langfi.ReviewLog, error) {
+		locked.FsrsData.Reps = 9
+		return nil, failed
+	})
+	if !errors.Is(err, failed) {
+		t.Errorf("ReviewCard() error = %v, want %v", err, failed)
+	}
+	if got := getCard(t, alice, repo, card.ID); got.FsrsData.Reps != card.FsrsData.Reps {
+		t.Errorf("GetCard() after failed ReviewCard() reps = %v, want %v", got.FsrsData.Reps, card.FsrsData.Reps)
+	}
+
+	reviewed, err := repo.ReviewCard(alice, card.ID, func(locked *langfi.ReviewCard, logs []langfi.ReviewLog) ([]langfi.ReviewLog, error) {
+		if len(logs) != 2 || locked.FsrsData.Reps != card.FsrsData.Reps {
+			t.Errorf("ReviewCard() review got reps %v and %v logs, want reps %v and 2 logs", locked.FsrsData.Reps, len(logs), card.FsrsData.Reps)
+		}
+		locked.FsrsData.Reps++
+		// the first log is kept, the second replaced
+		return []langfi.ReviewLog{logs[0], {ReviewLog: fsrs.ReviewLog{Rating: fsrs.Easy, Review: baseTime.AddDate(0, 0, 1)}}}, nil
+	})
+	if err != nil {
+		t.Fatalf("ReviewCard() error = %v", err)
+	}
+	if reviewed.FsrsData.Reps != card.FsrsData.Reps+1 || reviewed.Group != card.Group {
+		t.Errorf("ReviewCard() card = %+v", reviewed)
+	}
+	if got := getCard(t, alice, repo, card.ID); got.FsrsData.Reps != card.FsrsData.Reps+1 {
+		t.Errorf("GetCard() after ReviewCard() reps = %v, want %v", got.FsrsData.Reps, card.FsrsData.Reps+1)
+	}
+	logs, err := repo.GetCardReviewLogs(alice, card.ID)
+	if err != nil {
+		t.Fatalf("GetCardReviewLogs() error = %v", err)
+	}
+	if len(*logs) != 2 || (*logs)[0].ID != stored[0].ID || (*logs)[1].Rating != fsrs.Easy || (*logs)[1].ID == 0 {
+		t.Errorf("GetCardReviewLogs() after ReviewCard() = %+v", *logs)
+	}
+	if logs, err := repo.GetCardReviewLogs(bob, card.ID); err != nil || len(*logs) != 1 || (*logs)[0].ID != stored[2].ID {
+		t.Errorf("GetCardReviewLogs() of bob after ReviewCard() = %+v, %v", logs, err)
+	}
+
+	_, err = repo.ReviewCard(alice, card.ID+1000, func(locked *langfi.ReviewCard, logs []langfi.ReviewLog) ([]langfi.ReviewLog, error) {
+		t.Error("ReviewCard() reviewed a missing card")
+		return logs, nil
+	})
+	if !errors.Is(err, model.ErrNoMoreDataAvailable) {
+		t.Errorf("ReviewCard() of a missing card error = %v, want %v", err, model.ErrNoMoreDataAvailable)
+	}
+}
+
+// testConcurrentReviews checks that reviews of a card run one after the other, none of them is lost
+func testConcurrentReviews(t *testing.T, repo langfi.PracticeRepo) {
+	const workers = 8
+	ctx := userContext(1)
+	card := newCard("concurrent review", "suite::concurrent", langfi.CARD_LEARN, baseTime)
+	card.FsrsData.Reps = 0
+	addCards(t, ctx, repo, card)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.ReviewCard(ctx, card.ID, func(locked *langfi.ReviewCard, logs []langfi.ReviewLog) ([]langfi.ReviewLog, error) {
+				locked.FsrsData.Reps++
+				return append(logs, langfi.ReviewLog{ReviewLog: fsrs.ReviewLog{Rating: fsrs.Good, Review: baseTime}}), nil
+			})
+			if err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent review error = %v", err)
+	}
+
+	logs, err := repo.GetCardReviewLogs(ctx, card.ID)
+	if err != nil {
+		t.Fatalf("GetCardReviewLogs() error = %v", err)
+	}
+	if got := getCard(t, ctx, repo, card.ID); got.FsrsData.Reps != workers || len(*logs) != workers {
+		t.Errorf("after %v concurrent reviews reps = %v, logs = %v", workers, got.FsrsData.Reps, len(*logs))
+	}
+}
+
+func testCardOwners(t *testing.T, repo langfi.PracticeRepo) {
+	alice, bob := userContext(1), userContext(2)
+	shared := newCard("shared sentence", "suite::owners", langfi.CARD_LEARN, baseTime)
+	addCards(t, alice, repo, shared)
+	private := newCard("private sentence", "suite::owners::Alice list", langfi.CARD_LEARN, baseTime)
+	private.OwnerID = 1
+	if added, err := repo.AddNewCards(alice, []langfi.ReviewCard{*private}); err != nil || added != 1 {
+		t.Fatalf("AddNewCards() of alice = %v, %v", added, err)
+	}
+	mine, err := repo.GetCardByFront(alice, private.Front)
+	if err != nil || len(*mine) != 1 || (*mine)[0].OwnerID != 1 {
+		t.Fatalf("GetCardByFront() of the owner = %+v, %v", mine, err)
+	}
+	private = &(*mine)[0]
+
+	if _, err := repo.GetCard(bob, private.ID); !errors.Is(err, model.ErrNoMoreDataAvailable) {
+		t.Errorf("GetCard() of another user error = %v, want %v", err, model.ErrNoMoreDataAvailable)
+	}
+	cards, err := repo.SearchCards(bob, &langfi.CardQuery{}, 0)
+	if err != nil || !reflect.DeepEqual(fronts(*cards), []string{shared.Front}) {
+		t.Errorf("SearchCards() of another user = %v, %v", fronts(*cards), err)
+	}
+	if hits, err := repo.SearchCardText(bob, []string{"sentence"}, 0); err != nil || len(hits) != 1 || hits[0].ID != shared.ID {
+		t.Errorf("SearchCardText() of another user = %+v, %v", hits, err)
+	}
+	changes, err := repo.GetCardChanges(bob, 0, 0)
+	if err != nil {
+		t.Fatalf("GetCardChanges() error = %v", err)
+	}
+	for _, change := range *changes {
+		if change.CardID == private.ID {
+			t.Errorf("GetCardChanges() of another user has the private card, changes = %+v", *changes)
+		}
+	}
+	decks, err := repo.GetDecks(bob)
+	if err != nil {
+		t.Fatalf("GetDecks() error = %v", err)
+	}
+	for _, deck := range *decks {
+		if deck.Name == private.Group {
+			t.Errorf("GetDecks() of another user has the deck of the private card %v", deck.Name)
+		}
+	}
+	stats, err := repo.GetGroupStats(bob)
+	if err != nil {
+		t.Fatalf("GetGroupStats() error = %v", err)
+	}
+	for _, group := range *stats {
+		if group.Group == private.Group {
+			t.Errorf("GetGroupStats() of another user = %+v", *stats)
+		}
+	}
+
+	// the owner sees the card and its deck, another user may own a card with the same front
+	if hits, err := repo.SearchCardText(alice, []string{"private"}, 0); err != nil || len(hits) != 1 || hits[0].ID != private.ID {
+		t.Errorf("SearchCardText() of the owner = %+v, %v", hits, err)
+	}
+	decks, err = repo.GetDecks(alice)
+	if err != nil {
+		t.Fatalf("GetDecks() error = %v", err)
+	}
+	found := false
+	for _, deck := range *decks {
+		found = found || deck.Name == private.Group
+	}
+	if !found {
+		t.Errorf("GetDecks() of the owner has no deck %v", private.Group)
+	}
+	copied := *newCard(private.Front, "suite::owners::Bob list", langfi.CARD_LEARN, baseTime)
+	copied.OwnerID = 2
+	if added, err := repo.AddNewCards(bob, []langfi.ReviewCard{copied}); err != nil || added != 1 {
+		t.Errorf("AddNewCards() of a front owned by another user = %v, %v, want 1 added", added, err)
+	}
+	again := *newCard(private.Front, "suite::owners::Alice list", langfi.CARD_LEARN, baseTime)
+	again.OwnerID = 1
+	if added, err := repo.AddNewCards(alice, []langfi.ReviewCard{again}); err != nil || added != 0 {
+		t.Errorf("AddNewCards() of a front the owner has = %v, %v, want none added", added, err)
+	}
+}
+
+func testSyncChanges(t *testing.T, repo langfi.PracticeRepo) {
+	alice, bob := userContext(1), userContext(2)
+	changedCards := func(ctx context.Context, cursor uint64, limit int) ([]uint64, []uint64, uint64) {
+		t.Helper()
+		changes, err := repo.GetCardChanges(ctx, cursor, limit)
+		if err != nil {
+			t.Fatalf("GetCardChanges() error = %v", err)
+		}
+		changed, deleted := []uint64{}, []uint64{}
+		for _, change := range *changes {
+			if change.Seq <= cursor {
+				t.Errorf("GetCardChanges(%v) change %+v is not after the cursor", cursor, change)
+			}
+			if change.Deleted {
+				deleted = append(deleted, change.CardID)
+			} else {
+				changed = append(changed, change.CardID)
+			}
+			cursor = change.Seq
+		}
+		return changed, deleted, cursor
+	}
+
+	first := newCard("一", "suite::sync", langfi.CARD_LEARN, baseTime)
+	second := newCard("二", "suite::sync", langfi.CARD_LEARN, baseTime)
+	addCards(t, alice, repo, first)
+	addCards(t, bob, repo, second)
+
+	changed, _, aliceCursor := changedCards(alice, 0, 0)
+	if want := []uint64{first.ID, second.ID}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("first pull of alice = %v, want %v", changed, want)
+	}
+	if changed, _, _ := changedCards(alice, 0, 1); !reflect.DeepEqual(changed, []uint64{first.ID}) {
+		t.Errorf("first pull of alice with limit 1 = %v, want [%v]", changed, first.ID)
+	}
+	_, _, bobCursor := changedCards(bob, 0, 0)
+
+	// a review changes the fsrs row of alice only
+	first.FsrsData.Reps++
+	if err := repo.UpdateCard(alice, first); err != nil {
+		t.Fatal(err)
+	}
+	changed, _, aliceCursor = changedCards(alice, aliceCursor, 0)
+	if !reflect.DeepEqual(changed, []uint64{first.ID}) {
+		t.Errorf("pull of alice after her review = %v, want [%v]", changed, first.ID)
+	}
+	if changed, _, _ := changedCards(bob, bobCursor, 0); len(changed) != 0 {
+		t.Errorf("pull of bob after the review of alice = %v, want none", changed)
+	}
+
+	// content changes are seen by everyone, tags are kept per user
+	edited := *second
+	edited.Back = "two"
+	if err := repo.UpdateCardText(bob, &edited, langfi.NewCardRevision(second, &edited, 2)); err != nil {
+		t.Fatal(err)
+	}
+	first.Tags = []string{"numbers"}
+	if err := repo.UpdateCard(alice, first); err != nil {
+		t.Fatal(err)
+	}
+	changed, _, aliceCursor = changedCards(alice, aliceCursor, 0)
+	if want := []uint64{second.ID, first.ID}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("pull of alice after the edits = %v, want %v", changed, want)
+	}
+	changed, _, bobCursor = changedCards(bob, bobCursor, 0)
+	if want := []uint64{second.ID}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("pull of bob after the edits = %v, want %v", changed, want)
+	}
+	if got := getCard(t, bob, repo, first.ID); len(got.Tags) != 0 {
+		t.Errorf("GetCard() of bob after alice tagged the card tags = %v, want none", got.Tags)
+	}
+
+	fresh := newCard("三", "suite::sync", langfi.CARD_NEW, baseTime)
+	addCards(t, alice, repo, fresh)
+	if err := repo.DeleteNewCard(alice); err != nil {
+		t.Fatal(err)
+	}
+	changed, deleted, _ := changedCards(bob, bobCursor, 0)
+	if len(changed) != 0 || !reflect.DeepEqual(deleted, []uint64{fresh.ID}) {
+		t.Errorf("pull of bob after DeleteNewCard() = changed %v deleted %v, want deleted [%v]", changed, deleted, fresh.ID)
+	}
+
+	cards, err := repo.GetCardsByID(alice, []uint64{second.ID, first.ID, fresh.ID})
+	if err != nil {
+		t.Fatalf("GetCardsByID() error = %v", err)
+	}
+	if got := fronts(*cards); !reflect.DeepEqual(got, []string{"一", "二"}) {
+		t.Errorf("GetCardsByID() = %v, want [一 二]", got)
+	}
+	if got := (*cards)[1]; got.Back != "two" || got.Status != langfi.CARD_NEW || !reflect.DeepEqual((*cards)[0].Tags, []string{"numbers"}) {
+		t.Errorf("GetCardsByID() = %+v", *cards)
+	}
+}
+
+// testOverlappingSyncChanges pulls while a change written first is still uncommitted and a change written
+// after it has committed, the first change must not fall behind the cursor
+func testOverlappingSyncChanges(t *testing.T, repo langfi.PracticeRepo) {
+	ctx := userContext(1)
+	slow := newCard("遅", "suite::sync", langfi.CARD_LEARN, baseTime)
+	fast := newCard("速", "suite::sync", langfi.CARD_LEARN, baseTime)
+	addCards(t, ctx, repo, slow, fast)
+	changes, err := repo.GetCardChanges(ctx, 0, 0)
+	if err != nil {
+		t.Fatalf("GetCardChanges() error = %v", err)
+	}
+	cursor := (*changes)[len(*changes)-1].Seq
+
+	started, release := make(chan struct{}), make(chan struct{})
+	reviewed := make(chan error, 1)
+	go func() {
+		_, err := repo.ReviewCard(ctx, slow.ID, func(locked *langfi.ReviewCard, logs []langfi.ReviewLog) ([]langfi.ReviewLog, error) {
+			close(started)
+			<-release
+			locked.FsrsData.Reps++
+			return logs, nil
+		})
+		reviewed <- err
+	}()
+	<-started
+
+	// sqlite and the memory repo run one write at a time, the update and the pull then wait for the review
+	updated := make(chan error, 1)
+	go func() {
+		edited := *fast
+		edited.FsrsData.Reps++
+		updated <- repo.UpdateCard(ctx, &edited)
+	}()
+	select {
+	case err := <-updated:
+		if err != nil {
+			t.Fatalf("UpdateCard() error = %v", err)
+		}
+		updated <- nil
+	case <-time.After(200 * time.Millisecond):
+	}
+	type pull struct {
+		changes *[]langfi.CardChange
+		err     error
+	}
+	pulled := make(chan pull, 1)
+	go func() {
+		changes, err := repo.GetCardChanges(ctx, cursor, 0)
+		pulled <- pull{changes, err}
+	}()
+	seen := map[uint64]bool{}
+	collect := func(result pull) {
+		t.Helper()
+		if result.err != nil {
+			t.Fatalf("GetCardChanges() error = %v", result.err)
+		}
+		for _, change := range *result.changes {
+			seen[change.CardID] = true
+			cursor = change.Seq
+		}
+	}
+	select {
+	case result := <-pulled:
+		collect(result)
+		close(release)
+	case <-time.After(200 * time.Millisecond):
+		close(release)
+		collect(<-pulled)
+	}
+	if err := <-reviewed; err != nil {
+		t.Fatalf("ReviewCard() error = %v", err)
+	}
+	if err := <-updated; err != nil {
+		t.Fatalf("UpdateCard() error = %v", err)
+	}
+
+	changes, err = repo.GetCardChanges(ctx, cursor, 0)
+	collect(pull{changes, err})
+	if !seen[slow.ID] || !seen[fast.ID] {
+		t.Errorf("pulls around overlapping writes saw %v, want cards %v and %v", seen, slow.ID, fast.ID)
+	}
+}
+
+func testDecks(t *testing.T, repo langfi.PracticeRepo) {
+	ctx := context.Background()
+	deck, err := repo.EnsureDeck(ctx, " suite :: Minna ::L05 ")
+	if err != nil {
+		t.Fatalf("EnsureDeck() error = %v", err)
+	}
+	if deck.Name != "suite::Minna::L05" || deck.ID == 0 || deck.ParentID == 0 {
+		t.Errorf("EnsureDeck() = %+v", deck)
+	}
+	again, err := repo.EnsureDeck(ctx, "suite::Minna::L05")
+	if err != nil || again.ID != deck.ID {
+		t.Errorf("EnsureDeck() of an existing deck = %+v, %v, want id %v", again, err, deck.ID)
+	}
+	if _, err = repo.EnsureDeck(ctx, "suite::::x"); !errors.Is(err, langfi.ErrInvalidDeck) {
+		t.Errorf("EnsureDeck() of an invalid name error = %v, want %v", err, langfi.ErrInvalidDeck)
+	}
+
+	settings := &langfi.DeckSettings{NewPerDay: 5, RequestRetention: 0.85}
+	if err = repo.UpdateDeckSettings(ctx, deck.ParentID, settings); err != nil {
+		t.Fatalf("UpdateDeckSettings() error = %v", err)
+	}
+	if err = repo.UpdateDeckSettings(ctx, deck.ID+1000, settings); !errors.Is(err, langfi.ErrInvalidDeck) {
+		t.Errorf("UpdateDeckSettings() of a missing deck error = %v, want %v", err, langfi.ErrInvalidDeck)
+	}
+
+	decks, err := repo.GetDecks(ctx)
+	if err != nil {
+		t.Fatalf("GetDecks() error = %v", err)
+	}
+	byName := map[string]langfi.Deck{}
+	names := []string{}
+	for _, d := range *decks {
+		byName[d.Name] = d
+		names = append(names, d.Name)
+	}
+	root, parent := byName["suite"], byName["suite::Minna"]
+	if root.ID == 0 || root.ParentID != 0 || parent.ParentID != root.ID || parent.ID != deck.ParentID ||
+		!reflect.DeepEqual(parent.Settings, *settings) || !reflect.DeepEqual(byName[deck.Name].Settings, langfi.DeckSettings{}) {
+		t.Errorf("GetDecks() = %+v", *decks)
+	}
+	for i := 1; i < len(names); i++ {
+		if names[i-1] >= names[i] {
+			t.Errorf("GetDecks() not ordered by name: %v", names)
+			break
+		}
+	}
+
+	// settings are kept per user, updating them again replaces them
+	other := userContext(2)
+	otherSettings := &langfi.DeckSettings{ReviewsPerDay: 50}
+	if err = repo.UpdateDeckSettings(other, deck.ParentID, settings); err != nil {
+		t.Fatalf("UpdateDeckSettings() of another user error = %v", err)
+	}
+	if err = repo.UpdateDeckSettings(other, deck.ParentID, otherSettings); err != nil {
+		t.Fatalf("UpdateDeckSettings() of another user error = %v", err)
+	}
+	for _, tt := range []struct {
+		ctx  context.Context
+		want langfi.DeckSettings
+	}{{ctx, *settings}, {other, *otherSettings}, {userContext(3), langfi.DeckSettings{}}} {
+		got, err := repo.EnsureDeck(tt.ctx, "suite::Minna")
+		if err != nil || !reflect.DeepEqual(got.Settings, tt.want) {
+			t.Errorf("EnsureDeck() of user %v = %+v, %v, want settings %+v", auth.UserIDFromContext(tt.ctx), got, err, tt.want)
+		}
+	}
+}
+
+func testConcurrentUpdates(t *testing.T, repo langfi.PracticeRepo) {
+	const workers, rounds = 8, 10
+	ctx := userContext(1)
+	shared := newCard("shared", "suite::concurrent", langfi.CARD_LEARN, baseTime)
+	addCards(t, ctx, repo, shared)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*rounds*2)
+	owned := make([]*langfi.ReviewCard, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			// every worker owns a card and also rewrites the shared card
+			card := newCard(fmt.Sprintf("card %v", w), fmt.Sprintf("suite::concurrent::%v", w), langfi.CARD_LEARN, baseTime)
+			if err := repo.AddCard(ctx, card); err != nil {
+				errs <- err
+				return
+			}
+			owned[w] = card
+			for r := 1; r <= rounds; r++ {
+				card.FsrsData.Reps = uint64(r)
+				card.FsrsData.Due = baseTime.AddDate(0, 0, r)
+				if err := repo.UpdateCard(ctx, card); err != nil {
+					errs <- err
+				}
+				sharedCopy := *shared
+				sharedCopy.FsrsData.Reps = uint64(w*rounds + r)
+				if err := repo.UpdateCard(ctx, &sharedCopy); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent update error = %v", err)
+	}
+
+	for _, card := range owned {
+		if card == nil {
+			continue
+		}
+		got := getCard(t, ctx, repo, card.ID)
+		if got.FsrsData.Reps != rounds || !got.FsrsData.Due.Equal(baseTime.AddDate(0, 0, rounds)) || got.Group != card.Group {
+			t.Errorf("GetCard(%v) after concurrent updates = reps %v due %v", card.Front, got.FsrsData.Reps, got.FsrsData.Due)
+		}
+	}
+	got := getCard(t, ctx, repo, shared.ID)
+	if got.FsrsData.Reps == 0 || got.FsrsData.Reps > workers*rounds {
+		t.Errorf("shared card reps = %v, want one of the written values", got.FsrsData.Reps)
+	}
+	byFront, err := repo.GetCardByFront(ctx, "shared")
+	if err != nil || len(*byFront) != 1 {
+		t.Errorf("GetCardByFront() of the shared card = %v, %v", byFront, err)
+	}
+}
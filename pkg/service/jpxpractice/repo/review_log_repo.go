@@ -0,0 +1,201 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/pkg/errors"
+)
+
+func (rp *practiceRepo) AddReviewLog(ctx context.Context, log *langfi.ReviewLog) error {
+	return rp.addReviewLog(ctx, rp.db, log)
+}
+
+func (rp *practiceRepo) addReviewLog(ctx context.Context, q queryer, log *langfi.ReviewLog) error {
+	query := rp.db.QueryBuilder.Insert("review_logs").
+		Columns("card_id", "user_id", "rating", "state", "elapsed_days", "scheduled_days",
+			"stability", "difficulty", "review").
+		Values(log.CardID, auth.UserIDFromContext(ctx), log.Rating, log.State, log.ElapsedDays, log.ScheduledDays,
+			log.Stability, log.Difficulty, log.Review).
+		Suffix("RETURNING id")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "failed to build sql query")
+	}
+
+	err = q.QueryRowContext(ctx, sql, args...).Scan(&log.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert review log")
+	}
+
+	return nil
+}
+
+func (rp *practiceRepo) selectReviewLogs(ctx context.Context) sq.SelectBuilder {
+	return rp.db.QueryBuilder.Select("review_logs.id", "review_logs.card_id", "COALESCE(decks.name, '')",
+		"review_logs.rating", "review_logs.state", "review_logs.elapsed_days", "review_logs.scheduled_days",
+		"review_logs.stability", "review_logs.difficulty", "review_logs.review").
+		From("review_logs").
+		Join("cards ON cards.id = review_logs.card_id").
+		LeftJoin("decks ON decks.id = cards.deck_id").
+		Where(sq.Eq{"review_logs.user_id": auth.UserIDFromContext(ctx)})
+}
+
+func (rp *practiceRepo) GetReviewLogs(ctx context.Context, since time.Time) (*[]langfi.ReviewLog, error) {
+	return rp.queryReviewLogs(ctx, rp.db, rp.selectReviewLogs(ctx).
+		Where(sq.GtOrEq{"review_logs.review": since}).
+		OrderBy("review_logs.review"))
+}
+
+func (rp *practiceRepo) GetFirstReviewLogs(ctx context.Context, minStability float64) (*[]langfi.ReviewLog, error) {
+	// one row per card, so the cost follows the number of cards rather than the length of the history
+	return rp.queryReviewLogs(ctx, rp.db, rp.selectReviewLogs(ctx).
+		Where(sq.GtOrEq{"review_logs.stability": minStability}).
+		Where(sq.Expr(`NOT EXISTS (SELECT 1 FROM review_logs earlier
+			WHERE earlier.card_id = review_logs.card_id AND earlier.user_id = review_logs.user_id
+			AND earlier.stability >= ?
+			AND (earlier.review < review_logs.review OR (earlier.review = review_logs.review AND earlier.id < review_logs.id)))`,
+			minStability)).
+		OrderBy("review_logs.review", "review_logs.id"))
+}
+
+func (rp *practiceRepo) GetCardReviewLogs(ctx context.Context, cardID uint64) (*[]langfi.ReviewLog, error) {
+	return rp.queryReviewLogs(ctx, rp.db, rp.selectReviewLogs(ctx).
+		Where(sq.Eq{"review_logs.card_id": cardID}).
+		OrderBy("review_logs.review", "review_logs.id"))
+}
+
+func (rp *practiceRepo) queryReviewLogs(ctx context.Context, q queryer, query sq.SelectBuilder) (*[]langfi.ReviewLog, error) {
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build sql query")
+	}
+
+	rows, err := q.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query SQL")
+	}
+	defer rows.Close()
+	logs := []langfi.ReviewLog{}
+	for rows.Next() {
+		var log langfi.ReviewLog
+		if err := rows.Scan(&log.ID, &log.CardID, &log.Group, &log.Rating, &log.State, &log.ElapsedDays,
+			&log.ScheduledDays, &log.Stability, &log.Difficulty, &log.Review); err != nil {
+			return &logs, errors.Wrap(err, "failed to scan SQL")
+		}
+		logs = append(logs, log)
+	}
+	if err = rows.Err(); err != nil {
+		return &logs, errors.Wrap(err, "failed to scan SQL")
+	}
+
+	return &logs, nil
+}
+
+// ReviewCard locks the fsrs row of the card for the current user, so concurrent reviews of the card are
+// scheduled one after the other, then stores the fsrs data and the logs returned by review
+func (rp *practiceRepo) ReviewCard(ctx context.Context, cardID uint64, review langfi.CardReviewFunc) (*langfi.ReviewCard, error) {
+	var card *langfi.ReviewCard
+	err := rp.db.inTx(ctx, func(q queryer) error {
+		err := rp.lockFsrs(ctx, q, cardID)
+		if err != nil {
+			return err
+		}
+		card, err = rp.queryCard(ctx, q, rp.selectCards(ctx).Where(sq.Eq{"cards.id": cardID}))
+		if err != nil {
+			return err
+		}
+		stored, err := rp.queryReviewLogs(ctx, q, rp.selectReviewLogs(ctx).
+			Where(sq.Eq{"review_logs.card_id": cardID}).
+			OrderBy("review_logs.review", "review_logs.id"))
+		if err != nil {
+			return err
+		}
+
+		logs, err := review(card, *stored)
+		if err != nil {
+			return err
+		}
+		kept := map[uint64]bool{}
+		for i := range logs {
+			kept[logs[i].ID] = true
+		}
+		for _, log := range *stored {
+			if kept[log.ID] {
+				continue
+			}
+			sqlCmd, args, err := rp.db.QueryBuilder.Delete("review_logs").Where(sq.Eq{"id": log.ID}).ToSql()
+			if err != nil {
+				return errors.Wrap(err, "failed to build sql query")
+			}
+			_, err = q.ExecContext(ctx, sqlCmd, args...)
+			if err != nil {
+				return errors.Wrapf(err, "failed to delete review log id = %v", log.ID)
+			}
+		}
+		for i := range logs {
+			if logs[i].ID != 0 {
+				continue
+			}
+			logs[i].CardID = cardID
+			err = rp.addReviewLog(ctx, q, &logs[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		err = rp.saveFsrs(ctx, q, card)
+		if err != nil {
+			return errors.Wrapf(err, "failed to save fsrs data of card id = %v", cardID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to review card id = %v", cardID)
+	}
+	return card, nil
+}
+
+// lockFsrs locks the fsrs row of the card for the current user until the transaction ends,
+// creating the row of a card the user has not processed yet
+func (rp *practiceRepo) lockFsrs(ctx context.Context, q queryer, cardID uint64) error {
+	if !rp.lockRows {
+		return nil
+	}
+	userID := auth.UserIDFromContext(ctx)
+	ensure, args, err := rp.db.QueryBuilder.Insert("fsrs").
+		Columns("card_id", "user_id", "status").
+		Select(rp.db.QueryBuilder.Select("id").
+			Column("CAST(? AS INTEGER)", userID).
+			Column("CAST(? AS VARCHAR)", langfi.CARD_NEW).
+			From("cards").
+			Where(sq.Eq{"id": cardID})).
+		Suffix("ON CONFLICT(card_id, user_id) DO NOTHING").
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "failed to build sql query")
+	}
+	_, err = q.ExecContext(ctx, ensure, args...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create fsrs row of card id = %v", cardID)
+	}
+
+	lock, args, err := rp.db.QueryBuilder.Select("id").From("fsrs").
+		Where(sq.Eq{"card_id": cardID, "user_id": userID}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "failed to build sql query")
+	}
+	var fsrsID uint64
+	err = q.QueryRowContext(ctx, lock, args...).Scan(&fsrsID)
+	if err != nil && err != sql.ErrNoRows {
+		return errors.Wrapf(err, "failed to lock fsrs row of card id = %v", cardID)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/pkg/errors"
+)
+
+var revisionColumns = []string{"id", "card_id", "user_id", "front", "back", "properties", "changes", "created_at"}
+
+func scanRevision(row rowScanner, revision *langfi.CardRevision) error {
+	var properties, changes sql.NullString
+	err := row.Scan(&revision.ID, &revision.CardID, &revision.UserID, &revision.Front, &revision.Back,
+		&properties, &changes, &revision.CreatedAt)
+	if err != nil {
+		return err
+	}
+	revision.Properties = map[string]interface{}{}
+	if properties.String != "" {
+		err = json.Unmarshal([]byte(properties.String), &revision.Properties)
+		if err != nil {
+			return errors.Wrapf(err, "invalid properties of revision %v", revision.ID)
+		}
+	}
+	revision.Changes = []langfi.FieldChange{}
+	if changes.String != "" {
+		err = json.Unmarshal([]byte(changes.String), &revision.Changes)
+		if err != nil {
+			return errors.Wrapf(err, "invalid changes of revision %v", revision.ID)
+		}
+	}
+	return nil
+}
+
+// UpdateCardText stores the text of the card and its revision in one transaction, when the card is still
+// at the revision it was edited from
+func (rp *practiceRepo) UpdateCardText(ctx context.Context, card *langfi.ReviewCard, revision *langfi.CardRevision) error {
+	changes, err := json.Marshal(revision.Changes)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal revision changes")
+	}
+	properties, err := json.Marshal(revision.Properties)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal revision properties")
+	}
+	revision.CreatedAt = time.Now()
+
+	return rp.db.inTx(ctx, func(q queryer) error {
+		update := rp.db.QueryBuilder.Update("cards").
+			Where("id = ?", card.ID).
+			Where("revision = ?", card.Revision).
+			Set("front", card.Front).
+			Set("back", card.Back).
+			Set("properties", card.PropertiesToJson()).
+			Set("revision", sq.Expr("revision + 1"))
+
+		sqlCmd, args, err := update.ToSql()
+		if err != nil {
+			return errors.Wrap(err, "failed to build sql query")
+		}
+		res, err := q.ExecContext(ctx, sqlCmd, args...)
+		if err != nil {
+			return errors.Wrap(err, "failed to update card")
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			_, err = rp.queryCard(ctx, q, rp.selectCards(ctx).Where("cards.id = ?", card.ID))
+			if err == nil {
+				return errors.Wrapf(langfi.ErrStaleRevision, "card id = %v is not at revision %v", card.ID, card.Revision)
+			}
+			return errors.Wrapf(err, "card id = %v not found", card.ID)
+		}
+		card.Revision++
+		err = rp.recordCardChange(ctx, q, card.ID, false)
+		if err != nil {
+			return err
+		}
+
+		insert := rp.db.QueryBuilder.Insert("card_revisions").
+			Columns("card_id", "user_id", "front", "back", "properties", "changes", "created_at").
+			Values(card.ID, revision.UserID, revision.Front, revision.Back, string(properties), string(changes), revision.CreatedAt).
+			Suffix("RETURNING id")
+
+		sqlCmd, args, err = insert.ToSql()
+		if err != nil {
+			return errors.Wrap(err, "failed to build sql query")
+		}
+		err = q.QueryRowContext(ctx, sqlCmd, args...).Scan(&revision.ID)
+		if err != nil {
+			return errors.Wrap(err, "failed to insert card revision")
+		}
+		revision.CardID = card.ID
+		return nil
+	})
+}
+
+func (rp *practiceRepo) GetCardRevisions(ctx context.Context, cardID uint64) (*[]langfi.CardRevision, error) {
+	query := rp.db.QueryBuilder.Select(revisionColumns...).
+		From("card_revisions").
+		Where(sq.Eq{"card_id": cardID}).
+		OrderBy("created_at DESC", "id DESC")
+
+	sqlCmd, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build sql query")
+	}
+
+	rows, err := rp.db.QueryContext(ctx, sqlCmd, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query SQL")
+	}
+	defer rows.Close()
+	revisions := []langfi.CardRevision{}
+	for rows.Next() {
+		var revision langfi.CardRevision
+		if err := scanRevision(rows, &revision); err != nil {
+			return &revisions, errors.Wrap(err, "failed to scan SQL")
+		}
+		revisions = append(revisions, revision)
+	}
+	if err = rows.Err(); err != nil {
+		return &revisions, errors.Wrap(err, "failed to scan SQL")
+	}
+
+	return &revisions, nil
+}
+
+func (rp *practiceRepo) GetCardRevision(ctx context.Context, revisionID uint64) (*langfi.CardRevision, error) {
+	sqlCmd, args, err := rp.db.QueryBuilder.Select(revisionColumns...).
+		From("card_revisions").
+		Where(sq.Eq{"id": revisionID}).
+		ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build sql query")
+	}
+
+	revision := langfi.CardRevision{}
+	err = scanRevision(rp.db.QueryRowContext(ctx, sqlCmd, args...), &revision)
+	if err == sql.ErrNoRows {
+		return nil, errors.Wrapf(model.ErrNoMoreDataAvailable, "revision id = %v not found", revisionID)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get revision id = %v", revisionID)
+	}
+	return &revision, nil
+}
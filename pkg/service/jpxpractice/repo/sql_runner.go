@@ -0,0 +1,144 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+)
+
+// queries with variable shapes, e.g. IN lists, are run unprepared once the cache is full
+const maxPreparedStatements = 256
+
+// queryer runs queries on the database or inside a transaction
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// sqlDB is what the repo uses of sqlite3.DB and postgresdb.DB,
+// queries are written to run on both and prepared once per query text
+type sqlDB struct {
+	SqlDB        *sql.DB
+	QueryBuilder *sq.StatementBuilderType
+
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+func newSqlDB(db *sql.DB, builder *sq.StatementBuilderType) *sqlDB {
+	return &sqlDB{SqlDB: db, QueryBuilder: builder, stmts: map[string]*sql.Stmt{}}
+}
+
+// stmt returns the prepared statement of the query, nil when it is not cached
+func (db *sqlDB) stmt(ctx context.Context, query string) *sql.Stmt {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if stmt, ok := db.stmts[query]; ok {
+		return stmt
+	}
+	if len(db.stmts) >= maxPreparedStatements {
+		return nil
+	}
+	// prepared on the database, not on the connection of ctx, so it outlives the request
+	stmt, err := db.SqlDB.PrepareContext(context.WithoutCancel(ctx), query)
+	if err != nil {
+		// the unprepared query reports the error
+		return nil
+	}
+	db.stmts[query] = stmt
+	return stmt
+}
+
+// utcArgs converts the times of the query to UTC. Sqlite keeps times as text with the offset they
+// were written with, which only compare in time order when every time is in the same zone.
+func utcArgs(args []any) []any {
+	for i, arg := range args {
+		if t, ok := arg.(time.Time); ok {
+			args[i] = t.UTC()
+		}
+	}
+	return args
+}
+
+func (db *sqlDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	args = utcArgs(args)
+	if stmt := db.stmt(ctx, query); stmt != nil {
+		return stmt.ExecContext(ctx, args...)
+	}
+	return db.SqlDB.ExecContext(ctx, query, args...)
+}
+
+func (db *sqlDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	args = utcArgs(args)
+	if stmt := db.stmt(ctx, query); stmt != nil {
+		return stmt.QueryContext(ctx, args...)
+	}
+	return db.SqlDB.QueryContext(ctx, query, args...)
+}
+
+func (db *sqlDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	args = utcArgs(args)
+	if stmt := db.stmt(ctx, query); stmt != nil {
+		return stmt.QueryRowContext(ctx, args...)
+	}
+	return db.SqlDB.QueryRowContext(ctx, query, args...)
+}
+
+// inTx runs fn in a transaction, committed when fn succeeds and rolled back otherwise
+func (db *sqlDB) inTx(ctx context.Context, fn func(q queryer) error) error {
+	tx, err := db.SqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	err = fn(&sqlTx{db: db, tx: tx})
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(tx.Commit(), "failed to commit transaction")
+}
+
+// sqlTx runs the prepared statements of the database in a transaction
+type sqlTx struct {
+	db *sqlDB
+	tx *sql.Tx
+}
+
+func (t *sqlTx) stmt(ctx context.Context, query string) *sql.Stmt {
+	stmt := t.db.stmt(ctx, query)
+	if stmt == nil {
+		return nil
+	}
+	// closed with the transaction
+	return t.tx.StmtContext(ctx, stmt)
+}
+
+func (t *sqlTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	args = utcArgs(args)
+	if stmt := t.stmt(ctx, query); stmt != nil {
+		return stmt.ExecContext(ctx, args...)
+	}
+	return t.tx.ExecContext(ctx, query, args...)
+}
+
+func (t *sqlTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	args = utcArgs(args)
+	if stmt := t.stmt(ctx, query); stmt != nil {
+		return stmt.QueryContext(ctx, args...)
+	}
+	return t.tx.QueryContext(ctx, query, args...)
+}
+
+func (t *sqlTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	args = utcArgs(args)
+	if stmt := t.stmt(ctx, query); stmt != nil {
+		return stmt.QueryRowContext(ctx, args...)
+	}
+	return t.tx.QueryRowContext(ctx, query, args...)
+}
@@ -0,0 +1,153 @@
+package repo
+
+import (
+	"context"
+	"slices"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/pkg/errors"
+)
+
+// recordCardChange marks the content of the card as changed for every user
+func (rp *practiceRepo) recordCardChange(ctx context.Context, q queryer, cardID uint64, deleted bool) error {
+	return rp.recordChange(ctx, q, cardID, nil, deleted)
+}
+
+// recordFsrsChange marks the fsrs row of the card as changed for the current user
+func (rp *practiceRepo) recordFsrsChange(ctx context.Context, q queryer, cardID uint64) error {
+	return rp.recordChange(ctx, q, cardID, auth.UserIDFromContext(ctx), false)
+}
+
+func (rp *practiceRepo) recordChange(ctx context.Context, q queryer, cardID uint64, userID interface{}, deleted bool) error {
+	sqlCmd, args, err := rp.db.QueryBuilder.Insert("sync_changes").
+		Columns("card_id", "user_id", "deleted").
+		Values(cardID, userID, deleted).
+		ToSql()
+	if err != nil {
+		return errors.Wrap(err, "failed to build sql query")
+	}
+	_, err = q.ExecContext(ctx, sqlCmd, args...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to record change of card id = %v", cardID)
+	}
+	return nil
+}
+
+// numberChanges numbers the changes of finished postgres transactions. Transaction ids below the xmin of
+// the snapshot belong to finished transactions and running or later transactions have greater ones,
+// so a change is numbered after every change committed before it. Pulls number the changes one at a time.
+func (rp *practiceRepo) numberChanges(ctx context.Context) error {
+	return rp.db.inTx(ctx, func(q queryer) error {
+		_, err := q.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('sync_changes'))")
+		if err != nil {
+			return errors.Wrap(err, "failed to lock the change numbers")
+		}
+		_, err = q.ExecContext(ctx, "UPDATE sync_changes SET seq = nextval('sync_changes_seq') "+
+			"WHERE seq IS NULL AND tx < pg_snapshot_xmin(pg_current_snapshot())")
+		if err != nil {
+			return errors.Wrap(err, "failed to number changes")
+		}
+		return nil
+	})
+}
+
+// GetCardChanges groups the changes after the cursor by card, leaving out the cards of other owners.
+// Card ids are never reused, so a card with a deleted change stays deleted.
+// Sqlite runs one write at a time, the ids of the changes follow their commits. Postgres takes ids when
+// a change is written, so the changes are numbered once their transaction has finished and the cursor
+// is that number.
+func (rp *practiceRepo) GetCardChanges(ctx context.Context, cursor uint64, limit int) (*[]langfi.CardChange, error) {
+	seq := "id"
+	if rp.lockRows {
+		seq = "seq"
+		if err := rp.numberChanges(ctx); err != nil {
+			return nil, err
+		}
+	}
+	query := rp.db.QueryBuilder.Select("card_id", "MAX("+seq+") AS seq", "MAX(CASE WHEN deleted THEN 1 ELSE 0 END)").
+		From("sync_changes").
+		Where(sq.Gt{seq: cursor}).
+		Where(sq.Or{sq.Eq{"user_id": nil}, sq.Eq{"user_id": auth.UserIDFromContext(ctx)}}).
+		Where("NOT EXISTS (SELECT 1 FROM cards WHERE cards.id = sync_changes.card_id AND cards.owner_id != ?)",
+			auth.UserIDFromContext(ctx)).
+		GroupBy("card_id").
+		OrderBy("seq")
+	if limit > 0 {
+		query = query.Limit(uint64(limit))
+	}
+
+	sqlCmd, args, err := query.ToSql()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build sql query")
+	}
+
+	rows, err := rp.db.QueryContext(ctx, sqlCmd, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query SQL")
+	}
+	defer rows.Close()
+	changes := []langfi.CardChange{}
+	for rows.Next() {
+		var change langfi.CardChange
+		var deleted int
+		if err := rows.Scan(&change.CardID, &change.Seq, &deleted); err != nil {
+			return &changes, errors.Wrap(err, "failed to scan SQL")
+		}
+		change.Deleted = deleted > 0
+		changes = append(changes, change)
+	}
+	if err = rows.Err(); err != nil {
+		return &changes, errors.Wrap(err, "failed to scan SQL")
+	}
+
+	return &changes, nil
+}
+
+func (rp *practiceRepo) GetCardsByID(ctx context.Context, cardIDs []uint64) (*[]langfi.ReviewCard, error) {
+	cards := []langfi.ReviewCard{}
+	for start := 0; start < len(cardIDs); start += tagLoadBatch {
+		batch, err := rp.queryCards(ctx, rp.selectCards(ctx).
+			Where(sq.Eq{"cards.id": cardIDs[start:min(start+tagLoadBatch, len(cardIDs))]}).
+			OrderBy("cards.id"))
+		if err != nil {
+			return &cards, err
+		}
+		cards = append(cards, *batch...)
+	}
+	return &cards, nil
+}
+
+// cardTagsChanged tells whether the stored tags of the card for the current user differ from its tags
+func (rp *practiceRepo) cardTagsChanged(ctx context.Context, q queryer, card *langfi.ReviewCard) (bool, error) {
+	sqlCmd, args, err := rp.db.QueryBuilder.Select("tag").
+		From("card_tags").
+		Where(sq.Eq{"card_id": card.ID, "user_id": auth.UserIDFromContext(ctx)}).
+		ToSql()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to build sql query")
+	}
+	rows, err := q.QueryContext(ctx, sqlCmd, args...)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to query SQL")
+	}
+	defer rows.Close()
+	stored := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return false, errors.Wrap(err, "failed to scan SQL")
+		}
+		stored = append(stored, tag)
+	}
+	if err = rows.Err(); err != nil {
+		return false, errors.Wrap(err, "failed to scan SQL")
+	}
+
+	// sorted here as the postgres collation may order differently
+	slices.Sort(stored)
+	tags := langfi.NormalizeTags(card.Tags)
+	slices.Sort(tags)
+	return !slices.Equal(stored, tags), nil
+}
@@ -0,0 +1,90 @@
+package repo
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/auth"
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/langfi"
+	"github.com/pkg/errors"
+)
+
+// keep IN lists well below the sqlite variable limit
+const tagLoadBatch = 500
+
+// saveTags replaces the tags of the card for the current user, tags are kept per user like the fsrs row
+func (rp *practiceRepo) saveTags(ctx context.Context, q queryer, card *langfi.ReviewCard) error {
+	card.Tags = langfi.NormalizeTags(card.Tags)
+	userID := auth.UserIDFromContext(ctx)
+	sqlCmd, args, err := rp.db.QueryBuilder.Delete("card_tags").Where(sq.Eq{"card_id": card.ID, "user_id": userID}).ToSql()
+	if err != nil {
+		return errors.Wrap(err, "failed to build sql query")
+	}
+	_, err = q.ExecContext(ctx, sqlCmd, args...)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete tags")
+	}
+	if len(card.Tags) == 0 {
+		return nil
+	}
+
+	query := rp.db.QueryBuilder.Insert("card_tags").Columns("card_id", "user_id", "tag")
+	for _, tag := range card.Tags {
+		query = query.Values(card.ID, userID, tag)
+	}
+	sqlCmd, args, err = query.ToSql()
+	if err != nil {
+		return errors.Wrap(err, "failed to build sql query")
+	}
+	_, err = q.ExecContext(ctx, sqlCmd, args...)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert tags")
+	}
+	return nil
+}
+
+// loadTags fills the tags of the given cards for the current user
+func (rp *practiceRepo) loadTags(ctx context.Context, q queryer, cards []langfi.ReviewCard) error {
+	byID := make(map[uint64]*langfi.ReviewCard, len(cards))
+	for i := range cards {
+		cards[i].Tags = []string{}
+		byID[cards[i].ID] = &cards[i]
+	}
+
+	for start := 0; start < len(cards); start += tagLoadBatch {
+		ids := []uint64{}
+		for i := start; i < min(start+tagLoadBatch, len(cards)); i++ {
+			ids = append(ids, cards[i].ID)
+		}
+		query := rp.db.QueryBuilder.Select("card_id", "tag").
+			From("card_tags").
+			Where(sq.Eq{"card_id": ids, "user_id": auth.UserIDFromContext(ctx)}).
+			OrderBy("tag")
+
+		sqlCmd, args, err := query.ToSql()
+		if err != nil {
+			return errors.Wrap(err, "failed to build sql query")
+		}
+		rows, err := q.QueryContext(ctx, sqlCmd, args...)
+		if err != nil {
+			return errors.Wrap(err, "failed to query SQL")
+		}
+		for rows.Next() {
+			var cardID uint64
+			var tag string
+			if err := rows.Scan(&cardID, &tag); err != nil {
+				rows.Close()
+				return errors.Wrap(err, "failed to scan SQL")
+			}
+			if card, ok := byID[cardID]; ok {
+				card.Tags = append(card.Tags, tag)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return errors.Wrap(err, "failed to scan SQL")
+		}
+	}
+	return nil
+}
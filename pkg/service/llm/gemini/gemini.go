@@ -32,8 +32,10 @@ func NewGoogleAI(apiKey string) (*GoogleAI, error) {
 }
 
 func (g *GoogleAI) GenerateContent(ctx context.Context, expectedType *genai.Schema, prompt string) (*genai.GenerateContentResponse, error) {
-	g.model.ResponseSchema = expectedType
-	resp, err := g.model.GenerateContent(ctx, genai.Text(prompt))
+	// the schema is set on a copy, the shared model keeps answering without a schema
+	model := *g.model
+	model.ResponseSchema = expectedType
+	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed generate content")
 	}
@@ -0,0 +1,95 @@
+package searchservice
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/search"
+	"github.com/pkg/errors"
+)
+
+const (
+	DEFAULT_SEARCH_LIMIT = 20
+	MAX_SEARCH_LIMIT     = 100
+)
+
+type searchService struct {
+	contextTimeout time.Duration
+	sources        map[string]search.Source
+}
+
+// NewSearchService searches the sources by kind, kinds without a source are invalid in a search
+func NewSearchService(timeout time.Duration, sources map[string]search.Source) search.SearchService {
+	return &searchService{
+		contextTimeout: timeout,
+		sources:        sources,
+	}
+}
+
+// Search queries each kind for the best hits and merges them. The scores of a source are scaled
+// so that its best hit scores 1, as sqlite bm25 and postgres ts_rank are on different scales.
+func (ss *searchService) Search(ctx context.Context, text string, kinds []string, limit int) (*[]search.Hit, error) {
+	terms, err := search.ParseTerms(text)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DEFAULT_SEARCH_LIMIT
+	}
+	limit = min(limit, MAX_SEARCH_LIMIT)
+
+	if len(kinds) == 0 {
+		for kind := range ss.sources {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+	}
+	for _, kind := range kinds {
+		if _, ok := ss.sources[kind]; !ok {
+			return nil, errors.Wrapf(search.ErrInvalidSearch, "unknown kind %q", kind)
+		}
+	}
+
+	if ss.contextTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ss.contextTimeout)
+		defer cancel()
+	}
+
+	hits := []search.Hit{}
+	searched := map[string]bool{}
+	for _, kind := range kinds {
+		if searched[kind] {
+			continue
+		}
+		searched[kind] = true
+		kindHits, err := ss.sources[kind](ctx, terms, limit)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to search %v", kind)
+		}
+		hits = append(hits, scaleScores(kindHits)...)
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return &hits, nil
+}
+
+// scaleScores divides the scores by the best one, hits without a positive score keep their order below it
+func scaleScores(hits []search.Hit) []search.Hit {
+	best := 0.0
+	for _, hit := range hits {
+		best = max(best, hit.Score)
+	}
+	for i := range hits {
+		if best > 0 && hits[i].Score > 0 {
+			hits[i].Score /= best
+		} else {
+			hits[i].Score = 0
+		}
+	}
+	return hits
+}
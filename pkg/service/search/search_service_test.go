@@ -0,0 +1,81 @@
+package searchservice
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/nhuongmh/cfvs.jpx/pkg/model/search"
+)
+
+func fixedSource(kind string, scores ...float64) search.Source {
+	return func(ctx context.Context, terms []string, limit int) ([]search.Hit, error) {
+		hits := []search.Hit{}
+		for i, score := range scores {
+			hits = append(hits, search.Hit{Kind: kind, ID: uint64(i + 1), Score: score})
+		}
+		return hits[:min(limit, len(hits))], nil
+	}
+}
+
+func hitKeys(hits []search.Hit) []string {
+	keys := []string{}
+	for _, hit := range hits {
+		keys = append(keys, hit.Kind+string(rune('0'+hit.ID)))
+	}
+	return keys
+}
+
+func TestSearchMergesScaledScores(t *testing.T) {
+	// bm25 like scores of cards and ts_rank like scores of articles
+	ss := NewSearchService(0, map[string]search.Source{
+		search.KIND_CARD:    fixedSource(search.KIND_CARD, 12, 9, 3),
+		search.KIND_ARTICLE: fixedSource(search.KIND_ARTICLE, 0.1, 0.08, 0.01),
+	})
+
+	tests := []struct {
+		kinds []string
+		limit int
+		want  []string
+	}{
+		// ties keep the kinds in alphabetical order
+		{nil, 0, []string{"article1", "card1", "article2", "card2", "card3", "article3"}},
+		{nil, 3, []string{"article1", "card1", "article2"}},
+		{[]string{search.KIND_CARD}, 0, []string{"card1", "card2", "card3"}},
+		{[]string{search.KIND_ARTICLE, search.KIND_ARTICLE}, 2, []string{"article1", "article2"}},
+	}
+	for _, tt := range tests {
+		hits, err := ss.Search(context.Background(), "apple", tt.kinds, tt.limit)
+		if err != nil {
+			t.Fatalf("Search(%v, %v) error = %v", tt.kinds, tt.limit, err)
+		}
+		if got := hitKeys(*hits); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%v, %v) = %v, want %v", tt.kinds, tt.limit, got, tt.want)
+		}
+		if (*hits)[0].Score != 1 {
+			t.Errorf("Search(%v, %v) best score = %v, want 1", tt.kinds, tt.limit, (*hits)[0].Score)
+		}
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	failing := func(ctx context.Context, terms []string, limit int) ([]search.Hit, error) {
+		return nil, errors.New("database is down")
+	}
+	ss := NewSearchService(0, map[string]search.Source{
+		search.KIND_CARD:  fixedSource(search.KIND_CARD, 1),
+		search.KIND_VOCAB: failing,
+	})
+
+	if _, err := ss.Search(context.Background(), "?!", nil, 0); !errors.Is(err, search.ErrInvalidSearch) {
+		t.Errorf("Search() without words error = %v, want ErrInvalidSearch", err)
+	}
+	if _, err := ss.Search(context.Background(), "apple", []string{"deck"}, 0); !errors.Is(err, search.ErrInvalidSearch) {
+		t.Errorf("Search() of an unknown kind error = %v, want ErrInvalidSearch", err)
+	}
+	_, err := ss.Search(context.Background(), "apple", nil, 0)
+	if err == nil || errors.Is(err, search.ErrInvalidSearch) {
+		t.Errorf("Search() with a failing source error = %v, want the source error", err)
+	}
+}
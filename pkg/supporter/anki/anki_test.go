@@ -0,0 +1,259 @@
+package anki
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func unpack(t *testing.T, data []byte) (*sql.DB, map[string]string, map[string][]byte) {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("package is not a zip: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+
+	colPath := filepath.Join(t.TempDir(), COLLECTION_FILE)
+	if err := os.WriteFile(colPath, files[COLLECTION_FILE], 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", colPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	media := map[string]string{}
+	if err := json.Unmarshal(files[MEDIA_FILE], &media); err != nil {
+		t.Fatalf("invalid media map: %v", err)
+	}
+	return db, media, files
+}
+
+func TestExport(t *testing.T) {
+	now := time.Now()
+	pkg := NewPackage()
+	basic := BasicModel()
+	err := pkg.AddNote(Note{GUID: GUIDFor("1"), Deck: "jp::Minna::L01", Model: basic,
+		Fields: []string{"<b>先生</b>", "teacher [sound:sensei.mp3]"}, Tags: []string{"lesson1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = pkg.AddNote(Note{Deck: "jp::Minna::L01", Model: basic, Fields: []string{"学生", "student"},
+		Scheduling: &Scheduling{Type: CARD_REVIEW, Due: now.AddDate(0, 0, 3), Interval: 5, Reps: 3,
+			Stability: 5.2, Difficulty: 4.1},
+		Reviews: []Review{
+			{Time: now.AddDate(0, 0, -2), Rating: 3, Type: REVIEW_REVIEW, Interval: 5, LastInterval: 2},
+			{Time: now.AddDate(0, 0, -4), Rating: 3, Type: REVIEW_LEARN, Interval: 2},
+		}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pkg.AddNote(Note{Model: basic, Fields: []string{"only front"}}); err == nil {
+		t.Error("expected an error for a note with missing fields")
+	}
+	pkg.AddMedia("sensei.mp3", []byte("mp3"))
+
+	var buf bytes.Buffer
+	if err := pkg.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+	db, media, files := unpack(t, buf.Bytes())
+
+	if !reflect.DeepEqual(media, map[string]string{"0": "sensei.mp3"}) || string(files["0"]) != "mp3" {
+		t.Errorf("media = %v, file 0 = %q", media, files["0"])
+	}
+
+	var sfld, tags string
+	var csum int64
+	err = db.QueryRow(`SELECT sfld, tags, csum FROM notes WHERE guid = ?`, GUIDFor("1")).Scan(&sfld, &tags, &csum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sfld != "先生" || tags != " lesson1 " || csum != FieldChecksum("先生") {
+		t.Errorf("note sfld = %q, tags = %q, csum = %v", sfld, tags, csum)
+	}
+
+	var cardType, queue, ivl int
+	var due int64
+	var data string
+	err = db.QueryRow(`SELECT c.type, c.queue, c.due, c.ivl, c.data FROM cards c JOIN notes n ON n.id = c.nid
+		WHERE n.sfld = '学生'`).Scan(&cardType, &queue, &due, &ivl, &data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var crt int64
+	if err := db.QueryRow(`SELECT crt FROM col`).Scan(&crt); err != nil {
+		t.Fatal(err)
+	}
+	wantDue := int64(startOfDay(now.AddDate(0, 0, 3)).Sub(time.Unix(crt, 0)).Hours()/24 + 0.5)
+	if cardType != CARD_REVIEW || queue != QUEUE_REVIEW || due != wantDue || ivl != 5 || data != `{"s":5.2000,"d":4.1000}` {
+		t.Errorf("review card = type %v queue %v due %v (want %v) ivl %v data %v", cardType, queue, due, wantDue, ivl, data)
+	}
+
+	var reviews int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM revlog`).Scan(&reviews); err != nil || reviews != 2 {
+		t.Errorf("revlog has %v entries, err %v", reviews, err)
+	}
+
+	var decksJson string
+	if err := db.QueryRow(`SELECT decks FROM col`).Scan(&decksJson); err != nil {
+		t.Fatal(err)
+	}
+	decks := map[string]struct{ Name string }{}
+	if err := json.Unmarshal([]byte(decksJson), &decks); err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, deck := range decks {
+		names[deck.Name] = true
+	}
+	for _, name := range []string{DEFAULT_DECK, "jp", "jp::Minna", "jp::Minna::L01"} {
+		if !names[name] {
+			t.Errorf("deck %v missing from %v", name, names)
+		}
+	}
+}
+
+func TestMediaRefs(t *testing.T) {
+	got := MediaRefs(`a [sound:a.mp3] <img src="b.png"> <IMG class=x src=c.jpg>`)
+	want := []string{"a.mp3", "b.png", "c.jpg"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MediaRefs = %v, want %v", got, want)
+	}
+}
+
+func TestOpenPackage(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	pkg := NewPackage()
+	basic := BasicModel()
+	_ = pkg.AddNote(Note{GUID: "guid1", Deck: "Minna::L01", Model: basic, Fields: []string{"先生", "teacher [sound:sensei.mp3]"},
+		Tags: []string{"lesson1", "noun"}})
+	_ = pkg.AddNote(Note{GUID: "guid2", Model: basic, Fields: []string{"学生", "student"},
+		Scheduling: &Scheduling{Type: CARD_REVIEW, Due: now.AddDate(0, 0, 3), Interval: 5, Reps: 2, Suspended: true, Stability: 5.5, Difficulty: 4},
+		Reviews: []Review{
+			{Time: now.AddDate(0, 0, -7), Rating: 3, Type: REVIEW_LEARN, Interval: 2, DurationMs: 3000},
+			{Time: now.AddDate(0, 0, -2), Rating: 4, Type: REVIEW_REVIEW, Interval: 5, LastInterval: 2, DurationMs: 1500},
+		}})
+	pkg.AddMedia("sensei.mp3", []byte("mp3"))
+
+	path := filepath.Join(t.TempDir(), "test.apkg")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pkg.Export(f); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	col, err := OpenPackage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer col.Close()
+	if len(col.Notes) != 2 {
+		t.Fatalf("read %v notes, want 2", len(col.Notes))
+	}
+
+	first := col.Notes[0]
+	if first.GUID != "guid1" || first.Model != basic.Name || first.Field("back") != "teacher [sound:sensei.mp3]" ||
+		!reflect.DeepEqual(first.Tags, []string{"lesson1", "noun"}) || len(first.Cards) != 1 ||
+		first.Cards[0].Deck != "Minna::L01" || first.Cards[0].Scheduling.Type != CARD_NEW {
+		t.Errorf("first note = %+v", first)
+	}
+
+	second := col.Notes[1].Cards[0]
+	s := second.Scheduling
+	if second.Deck != DEFAULT_DECK || s.Type != CARD_REVIEW || !s.Suspended || s.Interval != 5 || s.Stability != 5.5 ||
+		!startOfDay(s.Due).Equal(startOfDay(now.AddDate(0, 0, 3))) {
+		t.Errorf("second card = %+v", second)
+	}
+	wantReviews := []Review{
+		{Time: now.AddDate(0, 0, -7), Rating: 3, Type: REVIEW_LEARN, Interval: 2, DurationMs: 3000},
+		{Time: now.AddDate(0, 0, -2), Rating: 4, Type: REVIEW_REVIEW, Interval: 5, LastInterval: 2, DurationMs: 1500},
+	}
+	if !reflect.DeepEqual(second.Reviews, wantReviews) {
+		t.Errorf("reviews = %+v, want %+v", second.Reviews, wantReviews)
+	}
+
+	rc, err := col.OpenMedia("sensei.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "mp3" || col.HasMedia("missing.png") {
+		t.Errorf("media = %q", data)
+	}
+}
+
+func TestOpenPackageTooLarge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bomb.apkg")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	// the header claims more than the collection may inflate to, nothing is extracted
+	w, err := zw.CreateRaw(&zip.FileHeader{Name: COLLECTION_FILE, Method: zip.Deflate,
+		UncompressedSize64: MAX_COLLECTION_BYTES + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte{0x03, 0x00}); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if _, err := OpenPackage(path); !errors.Is(err, ErrPackageTooLarge) {
+		t.Errorf("OpenPackage() error = %v, want %v", err, ErrPackageTooLarge)
+	}
+}
+
+func TestOpenLimited(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(bytes.Repeat([]byte("a"), 100))
+	zw.Close()
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := openLimited(zr.File[0], 10); !errors.Is(err, ErrPackageTooLarge) {
+		t.Errorf("openLimited() error = %v, want %v", err, ErrPackageTooLarge)
+	}
+	rc, err := openLimited(zr.File[0], 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if data, err := io.ReadAll(rc); err != nil || len(data) != 100 {
+		t.Errorf("openLimited() read %d bytes, error = %v", len(data), err)
+	}
+}
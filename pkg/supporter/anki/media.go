@@ -0,0 +1,18 @@
+package anki
+
+import "regexp"
+
+var soundRefRegex = regexp.MustCompile(`\[sound:([^\]]+)\]`)
+var imgRefRegex = regexp.MustCompile(`(?i)<img[^>]*\ssrc=["']?([^"'\s>]+)`)
+
+// MediaRefs returns the media file names referenced by a field, `[sound:x.mp3]` or `<img src="x.png">`
+func MediaRefs(field string) []string {
+	refs := []string{}
+	for _, match := range soundRefRegex.FindAllStringSubmatch(field, -1) {
+		refs = append(refs, match[1])
+	}
+	for _, match := range imgRefRegex.FindAllStringSubmatch(field, -1) {
+		refs = append(refs, match[1])
+	}
+	return refs
+}
@@ -0,0 +1,125 @@
+package anki
+
+import (
+	"hash/fnv"
+	"strings"
+)
+
+// ids of anki objects are millisecond timestamps, keep generated ones in the same range
+// and below 2^53 so that they survive the json of the collection
+const idMask = 1<<52 - 1
+
+const DEFAULT_CSS = `.card {
+ font-family: arial;
+ font-size: 20px;
+ text-align: center;
+ color: black;
+ background-color: white;
+}`
+
+// NoteModel is an anki note type: named fields and the card templates generated from them
+type NoteModel struct {
+	// 0 derives a stable id from the name and fields
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Fields    []string   `json:"fields"`
+	Templates []Template `json:"templates"`
+	CSS       string     `json:"css"`
+	// index of the field used for sorting and duplicate checks
+	SortField int `json:"sort_field"`
+}
+
+// Template renders one card of a note, Front and Back use the anki syntax, e.g. {{Front}}
+type Template struct {
+	Name  string `json:"name"`
+	Front string `json:"front"`
+	Back  string `json:"back"`
+}
+
+func BasicModel() *NoteModel {
+	return &NoteModel{
+		Name:   "cfvs Basic",
+		Fields: []string{"Front", "Back"},
+		Templates: []Template{{
+			Name:  "Card 1",
+			Front: "{{Front}}",
+			Back:  "{{FrontSide}}<hr id=answer>{{Back}}",
+		}},
+		CSS: DEFAULT_CSS,
+	}
+}
+
+func stableID(parts ...string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(parts, "\x1f")))
+	id := int64(h.Sum64() & idMask)
+	if id == 0 {
+		id = 1
+	}
+	return id
+}
+
+func (m *NoteModel) id() int64 {
+	if m.ID != 0 {
+		return m.ID
+	}
+	return stableID(append([]string{"model", m.Name}, m.Fields...)...)
+}
+
+func (m *NoteModel) FieldIndex(name string) int {
+	for i := range m.Fields {
+		if strings.EqualFold(m.Fields[i], name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func deckID(name string) int64 {
+	if name == DEFAULT_DECK {
+		return 1
+	}
+	return stableID("deck", name)
+}
+
+// anki collection json for the model
+func (m *NoteModel) toJson(mod int64, did int64) map[string]interface{} {
+	fields := make([]map[string]interface{}, len(m.Fields))
+	for i, name := range m.Fields {
+		fields[i] = map[string]interface{}{
+			"name": name, "ord": i, "sticky": false, "rtl": false,
+			"font": "Arial", "size": 20, "media": []string{},
+		}
+	}
+	templates := make([]map[string]interface{}, len(m.Templates))
+	req := make([]interface{}, len(m.Templates))
+	for i, tmpl := range m.Templates {
+		templates[i] = map[string]interface{}{
+			"name": tmpl.Name, "ord": i, "qfmt": tmpl.Front, "afmt": tmpl.Back,
+			"bqfmt": "", "bafmt": "", "did": nil, "bfont": "", "bsize": 0,
+		}
+		// a card is generated when any of the fields used on its front is not empty
+		used := []int{}
+		for f, name := range m.Fields {
+			if strings.Contains(tmpl.Front, "{{"+name+"}}") {
+				used = append(used, f)
+			}
+		}
+		if len(used) == 0 {
+			used = []int{0}
+		}
+		req[i] = []interface{}{i, "any", used}
+	}
+
+	css := m.CSS
+	if css == "" {
+		css = DEFAULT_CSS
+	}
+	return map[string]interface{}{
+		"id": m.id(), "name": m.Name, "type": 0, "mod": mod, "usn": -1,
+		"sortf": m.SortField, "did": did, "tmpls": templates, "flds": fields,
+		"css": css, "req": req, "tags": []string{}, "vers": []interface{}{},
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}", "latexsvg": false,
+	}
+}
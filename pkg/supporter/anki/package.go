@@ -0,0 +1,449 @@
+package anki
+
+import (
+	"archive/zip"
+	"crypto/rand"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+const (
+	DEFAULT_DECK = "Default"
+	// separates the fields of a note in the collection
+	FIELD_SEPARATOR = "\x1f"
+	COLLECTION_FILE = "collection.anki2"
+	MEDIA_FILE      = "media"
+	DEFAULT_FACTOR  = 2500
+)
+
+// card types, also the state of a card in fsrs
+const (
+	CARD_NEW        = 0
+	CARD_LEARNING   = 1
+	CARD_REVIEW     = 2
+	CARD_RELEARNING = 3
+)
+
+// card queues
+const (
+	QUEUE_SUSPENDED = -1
+	QUEUE_NEW       = 0
+	QUEUE_LEARNING  = 1
+	QUEUE_REVIEW    = 2
+	QUEUE_DAY_LEARN = 3
+)
+
+// revlog types
+const (
+	REVIEW_LEARN    = 0
+	REVIEW_REVIEW   = 1
+	REVIEW_RELEARN  = 2
+	REVIEW_FILTERED = 3
+	REVIEW_MANUAL   = 4
+)
+
+var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// Scheduling is the state of the cards of a note
+type Scheduling struct {
+	Type     int
+	Due      time.Time
+	Interval int // days
+	Reps     int
+	Lapses   int
+	// fsrs memory state, 0 when unknown
+	Stability  float64
+	Difficulty float64
+	Suspended  bool
+}
+
+// Review is one entry of the review history of the cards of a note
+type Review struct {
+	Time         time.Time
+	Rating       int // 1-4
+	Type         int
+	Interval     int // days, after the review
+	LastInterval int
+	DurationMs   int
+}
+
+type Note struct {
+	// notes with the same guid update each other on import, empty generates a random one
+	GUID   string
+	Deck   string
+	Model  *NoteModel
+	Fields []string
+	Tags   []string
+	// nil exports the cards as new
+	Scheduling *Scheduling
+	Reviews    []Review
+}
+
+type mediaFile struct {
+	name string
+	path string
+	data []byte
+}
+
+// Package collects notes and media in memory and writes them as an .apkg file,
+// media added with AddMediaFile stay on disk until the export
+type Package struct {
+	notes []Note
+	media []mediaFile
+	now   time.Time
+}
+
+func NewPackage() *Package {
+	return &Package{now: time.Now()}
+}
+
+func (p *Package) NumNotes() int {
+	return len(p.notes)
+}
+
+func (p *Package) AddNote(note Note) error {
+	if note.Model == nil || len(note.Model.Fields) == 0 || len(note.Model.Templates) == 0 {
+		return errors.New("note model needs fields and templates")
+	}
+	if len(note.Fields) != len(note.Model.Fields) {
+		return errors.Errorf("note has %v fields, model %v expects %v", len(note.Fields), note.Model.Name, len(note.Model.Fields))
+	}
+	if note.Deck == "" {
+		note.Deck = DEFAULT_DECK
+	}
+	p.notes = append(p.notes, note)
+	return nil
+}
+
+func (p *Package) AddMedia(name string, data []byte) {
+	p.media = append(p.media, mediaFile{name: name, data: data})
+}
+
+// AddMediaFile adds a file from disk, it is only read while exporting
+func (p *Package) AddMediaFile(name, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return errors.Wrapf(err, "media file %v not found", path)
+	}
+	p.media = append(p.media, mediaFile{name: name, path: path})
+	return nil
+}
+
+// Export writes the .apkg to w. The collection is a SQLite database, which is built complete in a
+// temporary file first, so nothing is written to w when building it fails. The zip of the collection
+// and the media is then written to w as it is compressed, without holding the whole package.
+func (p *Package) Export(w io.Writer) error {
+	tmpDir, err := os.MkdirTemp("", "anki-export")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temp dir")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	colPath := filepath.Join(tmpDir, COLLECTION_FILE)
+	err = p.writeCollection(colPath)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	err = addZipFile(zw, COLLECTION_FILE, colPath, nil)
+	if err != nil {
+		return err
+	}
+	mediaMap := map[string]string{}
+	for i, media := range p.media {
+		key := strconv.Itoa(i)
+		mediaMap[key] = media.name
+		err = addZipFile(zw, key, media.path, media.data)
+		if err != nil {
+			return err
+		}
+	}
+	mediaJson, err := json.Marshal(mediaMap)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal media map")
+	}
+	err = addZipFile(zw, MEDIA_FILE, "", mediaJson)
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func addZipFile(zw *zip.Writer, name, path string, data []byte) error {
+	entry, err := zw.Create(name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to add %v to package", name)
+	}
+	if path == "" {
+		_, err = entry.Write(data)
+		return errors.Wrapf(err, "failed to write %v", name)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %v", path)
+	}
+	defer f.Close()
+	_, err = io.Copy(entry, f)
+	return errors.Wrapf(err, "failed to write %v", name)
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// collection creation day, review due dates are stored as days since then
+func (p *Package) creationTime() time.Time {
+	crt := startOfDay(p.now)
+	for i := range p.notes {
+		s := p.notes[i].Scheduling
+		if s != nil && s.Type == CARD_REVIEW && s.Due.Before(crt) {
+			crt = startOfDay(s.Due)
+		}
+	}
+	return crt
+}
+
+func (p *Package) writeCollection(path string) error {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return errors.Wrap(err, "failed to create collection")
+	}
+	defer db.Close()
+
+	_, err = db.Exec(collectionSchema)
+	if err != nil {
+		return errors.Wrap(err, "failed to create collection schema")
+	}
+
+	crt := p.creationTime()
+	err = p.insertCol(db, crt)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	ids := newIDGenerator(p.now)
+	for i := range p.notes {
+		err = insertNote(tx, &p.notes[i], i+1, crt, p.now, ids)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (p *Package) insertCol(db *sql.DB, crt time.Time) error {
+	mod := p.now.UnixMilli()
+	models := map[string]interface{}{}
+	decks := map[string]interface{}{"1": deckJson(1, DEFAULT_DECK, mod)}
+	var curModel int64
+	for i := range p.notes {
+		note := &p.notes[i]
+		mid := note.Model.id()
+		if _, ok := models[strconv.FormatInt(mid, 10)]; !ok {
+			models[strconv.FormatInt(mid, 10)] = note.Model.toJson(mod, deckID(note.Deck))
+			curModel = mid
+		}
+		// parents are created by anki too, but listing them keeps the tree explicit
+		for name := note.Deck; name != ""; name = parentDeck(name) {
+			decks[strconv.FormatInt(deckID(name), 10)] = deckJson(deckID(name), name, mod)
+		}
+	}
+
+	conf := map[string]interface{}{}
+	for k, v := range defaultColConf {
+		conf[k] = v
+	}
+	conf["curModel"] = strconv.FormatInt(curModel, 10)
+
+	values := []interface{}{}
+	for _, v := range []interface{}{conf, models, decks, defaultDeckConf, map[string]interface{}{}} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal collection config")
+		}
+		values = append(values, string(b))
+	}
+
+	_, err := db.Exec(`INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, ?)`,
+		append([]interface{}{crt.Unix(), mod, mod}, values...)...)
+	return errors.Wrap(err, "failed to insert collection")
+}
+
+func parentDeck(name string) string {
+	idx := strings.LastIndex(name, "::")
+	if idx < 0 {
+		return ""
+	}
+	return name[:idx]
+}
+
+func deckJson(id int64, name string, mod int64) map[string]interface{} {
+	return map[string]interface{}{
+		"id": id, "name": name, "mod": mod / 1000, "usn": -1, "lrnToday": []int{0, 0},
+		"revToday": []int{0, 0}, "newToday": []int{0, 0}, "timeToday": []int{0, 0},
+		"collapsed": false, "browserCollapsed": false, "desc": "", "dyn": 0, "conf": 1,
+		"extendNew": 0, "extendRev": 0,
+	}
+}
+
+// idGenerator hands out unique millisecond ids
+type idGenerator struct {
+	next  int64
+	taken map[int64]bool
+}
+
+func newIDGenerator(now time.Time) *idGenerator {
+	return &idGenerator{next: now.UnixMilli(), taken: map[int64]bool{}}
+}
+
+func (g *idGenerator) newID() int64 {
+	for g.taken[g.next] {
+		g.next++
+	}
+	id := g.next
+	g.taken[id] = true
+	g.next++
+	return id
+}
+
+// idAt returns an unused id as close as possible to t, used for the review log
+func (g *idGenerator) idAt(t time.Time) int64 {
+	id := t.UnixMilli()
+	for g.taken[id] {
+		id++
+	}
+	g.taken[id] = true
+	return id
+}
+
+func randomGUID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return base64.RawStdEncoding.EncodeToString(b[:])
+}
+
+// GUIDFor derives a stable guid, so exporting the same source again updates the notes in anki
+func GUIDFor(key string) string {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(stableID("guid", key)))
+	return base64.RawStdEncoding.EncodeToString(b[:])
+}
+
+// StripHTML removes tags, as anki does for the sort field and checksum
+func StripHTML(s string) string {
+	return strings.TrimSpace(htmlTagRegex.ReplaceAllString(s, ""))
+}
+
+// FieldChecksum is the first 8 hex digits of the sha1 of the stripped field
+func FieldChecksum(field string) int64 {
+	sum := sha1.Sum([]byte(StripHTML(field)))
+	return int64(binary.BigEndian.Uint32(sum[:4]))
+}
+
+func insertNote(tx *sql.Tx, note *Note, position int, crt, now time.Time, ids *idGenerator) error {
+	guid := note.GUID
+	if guid == "" {
+		guid = randomGUID()
+	}
+	tags := ""
+	if len(note.Tags) > 0 {
+		tags = " " + strings.Join(note.Tags, " ") + " "
+	}
+	sortField := StripHTML(note.Fields[min(note.Model.SortField, len(note.Fields)-1)])
+	nid := ids.newID()
+	_, err := tx.Exec(`INSERT INTO notes VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')`,
+		nid, guid, note.Model.id(), now.Unix(), tags, strings.Join(note.Fields, FIELD_SEPARATOR),
+		sortField, FieldChecksum(note.Fields[0]))
+	if err != nil {
+		return errors.Wrapf(err, "failed to insert note %v", sortField)
+	}
+
+	for ord := range note.Model.Templates {
+		cid := ids.newID()
+		c := cardColumns(note.Scheduling, position, crt, now)
+		_, err = tx.Exec(`INSERT INTO cards VALUES (?, ?, ?, ?, ?, -1, ?, ?, ?, ?, ?, ?, ?, ?, 0, 0, 0, ?)`,
+			cid, nid, deckID(note.Deck), ord, now.Unix(), c.cardType, c.queue, c.due, c.ivl,
+			c.factor, c.reps, c.lapses, c.left, c.data)
+		if err != nil {
+			return errors.Wrapf(err, "failed to insert card of note %v", sortField)
+		}
+
+		// the history belongs to the first card, other templates were never reviewed
+		if ord > 0 {
+			continue
+		}
+		reviews := append([]Review{}, note.Reviews...)
+		sort.Slice(reviews, func(i, j int) bool { return reviews[i].Time.Before(reviews[j].Time) })
+		for _, r := range reviews {
+			_, err = tx.Exec(`INSERT INTO revlog VALUES (?, ?, -1, ?, ?, ?, ?, ?, ?)`,
+				ids.idAt(r.Time), cid, r.Rating, r.Interval, r.LastInterval, DEFAULT_FACTOR, r.DurationMs, r.Type)
+			if err != nil {
+				return errors.Wrapf(err, "failed to insert review log of note %v", sortField)
+			}
+		}
+	}
+	return nil
+}
+
+type cardRow struct {
+	cardType, queue           int
+	due                       int64
+	ivl, factor, reps, lapses int
+	left                      int
+	data                      string
+}
+
+func cardColumns(s *Scheduling, position int, crt, now time.Time) cardRow {
+	if s == nil || s.Type == CARD_NEW {
+		return cardRow{cardType: CARD_NEW, queue: QUEUE_NEW, due: int64(position)}
+	}
+
+	row := cardRow{cardType: s.Type, ivl: s.Interval, factor: DEFAULT_FACTOR, reps: s.Reps, lapses: s.Lapses}
+	dueDay := int64(startOfDay(s.Due).Sub(crt).Hours()/24 + 0.5)
+	switch s.Type {
+	case CARD_REVIEW:
+		row.queue = QUEUE_REVIEW
+		row.due = dueDay
+		row.ivl = max(s.Interval, 1)
+	default:
+		// learning steps due after today are kept by day, like anki does
+		row.left = 1001
+		if s.Due.After(startOfDay(now).AddDate(0, 0, 1)) {
+			row.queue = QUEUE_DAY_LEARN
+			row.due = dueDay
+		} else {
+			row.queue = QUEUE_LEARNING
+			row.due = s.Due.Unix()
+		}
+	}
+	if s.Suspended {
+		row.queue = QUEUE_SUSPENDED
+	}
+	if s.Stability > 0 {
+		row.data = fmt.Sprintf(`{"s":%.4f,"d":%.4f}`, s.Stability, s.Difficulty)
+	}
+	return row
+}
@@ -0,0 +1,343 @@
+package anki
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	COLLECTION_21_FILE = "collection.anki21"
+	// zstd compressed collection of anki >= 2.1.50, not supported
+	COLLECTION_21B_FILE = "collection.anki21b"
+
+	// a small package may inflate to much more, the collection and each media are not extracted past these
+	MAX_COLLECTION_BYTES = 1 << 30
+	MAX_MEDIA_BYTES      = 100 << 20
+)
+
+var (
+	ErrUnsupportedPackage = errors.New("unsupported anki package, export it with `Support older Anki versions` checked")
+	ErrPackageTooLarge    = errors.New("anki package is too large once uncompressed")
+)
+
+// ReadCard is a card of an imported note, with its scheduling and review history
+type ReadCard struct {
+	Ord        int
+	Deck       string
+	Scheduling Scheduling
+	Reviews    []Review
+}
+
+type ReadNote struct {
+	GUID       string
+	Model      string
+	FieldNames []string
+	Fields     []string
+	Tags       []string
+	Cards      []ReadCard
+}
+
+// Collection is an opened .apkg or .colpkg, media are read from the package on demand
+type Collection struct {
+	Notes []ReadNote
+	media map[string]*zip.File
+	zr    *zip.ReadCloser
+}
+
+// Field returns the named field, "" when the model of the note has no such field
+func (n *ReadNote) Field(name string) string {
+	for i := range n.FieldNames {
+		if strings.EqualFold(n.FieldNames[i], name) && i < len(n.Fields) {
+			return n.Fields[i]
+		}
+	}
+	return ""
+}
+
+// OpenPackage reads the notes of a package, the collection must be closed to release the file
+func OpenPackage(path string) (*Collection, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open anki package")
+	}
+	col := &Collection{media: map[string]*zip.File{}, zr: zr}
+	err = col.read()
+	if err != nil {
+		zr.Close()
+		return nil, err
+	}
+	return col, nil
+}
+
+func (c *Collection) Close() error {
+	return c.zr.Close()
+}
+
+func (c *Collection) MediaNames() []string {
+	names := make([]string, 0, len(c.media))
+	for name := range c.media {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *Collection) HasMedia(name string) bool {
+	return c.media[name] != nil
+}
+
+func (c *Collection) OpenMedia(name string) (io.ReadCloser, error) {
+	f := c.media[name]
+	if f == nil {
+		return nil, errors.Errorf("media %v not in package", name)
+	}
+	return openLimited(f, MAX_MEDIA_BYTES)
+}
+
+func (c *Collection) read() error {
+	files := map[string]*zip.File{}
+	for _, f := range c.zr.File {
+		files[f.Name] = f
+	}
+
+	// newer packages keep a stub collection.anki2 asking to update anki next to the real one
+	colFile := files[COLLECTION_21_FILE]
+	if colFile == nil && files[COLLECTION_21B_FILE] == nil {
+		colFile = files[COLLECTION_FILE]
+	}
+	if colFile == nil {
+		return ErrUnsupportedPackage
+	}
+
+	if mediaFile := files[MEDIA_FILE]; mediaFile != nil {
+		mediaMap := map[string]string{}
+		err := readZipJson(mediaFile, &mediaMap)
+		if err != nil {
+			return errors.Wrap(ErrUnsupportedPackage, "media map is not json")
+		}
+		for key, name := range mediaMap {
+			if files[key] != nil {
+				c.media[name] = files[key]
+			}
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "anki-import")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temp dir")
+	}
+	defer os.RemoveAll(tmpDir)
+	colPath := filepath.Join(tmpDir, COLLECTION_FILE)
+	err = extractZipFile(colFile, colPath)
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+colPath+"?mode=ro")
+	if err != nil {
+		return errors.Wrap(err, "failed to open collection")
+	}
+	defer db.Close()
+	return c.readCollection(db)
+}
+
+func readZipJson(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return json.NewDecoder(rc).Decode(v)
+}
+
+func extractZipFile(f *zip.File, path string) error {
+	rc, err := openLimited(f, MAX_COLLECTION_BYTES)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	out, err := os.Create(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create %v", path)
+	}
+	defer out.Close()
+	_, err = io.Copy(out, rc)
+	return errors.Wrapf(err, "failed to extract %v", f.Name)
+}
+
+// openLimited opens the file of the package unless it is larger than max bytes uncompressed.
+// The size in the header is not trusted, reading past max fails too.
+func openLimited(f *zip.File, max int64) (io.ReadCloser, error) {
+	if f.UncompressedSize64 > uint64(max) {
+		return nil, errors.Wrapf(ErrPackageTooLarge, "%v is %d bytes, at most %d are read", f.Name, f.UncompressedSize64, max)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %v", f.Name)
+	}
+	return &limitedReader{ReadCloser: rc, r: io.LimitReader(rc, max+1), name: f.Name, max: max}, nil
+}
+
+type limitedReader struct {
+	io.ReadCloser
+	r    io.Reader
+	name string
+	max  int64
+	read int64
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	n, err := lr.r.Read(p)
+	lr.read += int64(n)
+	if lr.read > lr.max {
+		return n, errors.Wrapf(ErrPackageTooLarge, "%v is over %d bytes", lr.name, lr.max)
+	}
+	return n, err
+}
+
+type modelJson struct {
+	Name   string `json:"name"`
+	Fields []struct {
+		Name string `json:"name"`
+		Ord  int    `json:"ord"`
+	} `json:"flds"`
+}
+
+type deckJsonName struct {
+	Name string `json:"name"`
+}
+
+func (c *Collection) readCollection(db *sql.DB) error {
+	var crtUnix int64
+	var modelsText, decksText string
+	err := db.QueryRow(`SELECT crt, models, decks FROM col`).Scan(&crtUnix, &modelsText, &decksText)
+	if err != nil {
+		return errors.Wrap(err, "failed to read collection")
+	}
+	models := map[string]modelJson{}
+	decks := map[string]deckJsonName{}
+	if json.Unmarshal([]byte(modelsText), &models) != nil || json.Unmarshal([]byte(decksText), &decks) != nil {
+		return errors.Wrap(ErrUnsupportedPackage, "collection has no legacy models and decks")
+	}
+	for id, m := range models {
+		sort.Slice(m.Fields, func(i, j int) bool { return m.Fields[i].Ord < m.Fields[j].Ord })
+		models[id] = m
+	}
+
+	reviews, err := readReviews(db)
+	if err != nil {
+		return err
+	}
+	cards, err := readCards(db, time.Unix(crtUnix, 0), decks, reviews)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.Query(`SELECT id, guid, mid, tags, flds FROM notes ORDER BY id`)
+	if err != nil {
+		return errors.Wrap(err, "failed to read notes")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var mid, tags, fields string
+		note := ReadNote{}
+		if err := rows.Scan(&id, &note.GUID, &mid, &tags, &fields); err != nil {
+			return errors.Wrap(err, "failed to read note")
+		}
+		m := models[mid]
+		note.Model = m.Name
+		for _, f := range m.Fields {
+			note.FieldNames = append(note.FieldNames, f.Name)
+		}
+		note.Fields = strings.Split(fields, FIELD_SEPARATOR)
+		note.Tags = strings.Fields(tags)
+		note.Cards = cards[id]
+		c.Notes = append(c.Notes, note)
+	}
+	return errors.Wrap(rows.Err(), "failed to read notes")
+}
+
+func readReviews(db *sql.DB) (map[int64][]Review, error) {
+	rows, err := db.Query(`SELECT id, cid, ease, ivl, lastIvl, time, type FROM revlog ORDER BY id`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read review log")
+	}
+	defer rows.Close()
+	reviews := map[int64][]Review{}
+	for rows.Next() {
+		var id, cid int64
+		var r Review
+		if err := rows.Scan(&id, &cid, &r.Rating, &r.Interval, &r.LastInterval, &r.DurationMs, &r.Type); err != nil {
+			return nil, errors.Wrap(err, "failed to read review")
+		}
+		r.Time = time.UnixMilli(id)
+		// negative intervals are learning steps in seconds
+		r.Interval = max(r.Interval, 0)
+		r.LastInterval = max(r.LastInterval, 0)
+		reviews[cid] = append(reviews[cid], r)
+	}
+	return reviews, errors.Wrap(rows.Err(), "failed to read review log")
+}
+
+type cardData struct {
+	Stability  float64 `json:"s"`
+	Difficulty float64 `json:"d"`
+}
+
+// unix seconds are used for learning cards, day numbers since the collection creation otherwise
+const minUnixDue = 1_000_000_000
+
+func readCards(db *sql.DB, crt time.Time, decks map[string]deckJsonName, reviews map[int64][]Review) (map[int64][]ReadCard, error) {
+	rows, err := db.Query(`SELECT id, nid, did, odid, ord, type, queue, due, odue, ivl, reps, lapses, data
+		FROM cards ORDER BY nid, ord`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read cards")
+	}
+	defer rows.Close()
+	cards := map[int64][]ReadCard{}
+	for rows.Next() {
+		var id, nid, did, odid, due, odue int64
+		var queue int
+		var data string
+		card := ReadCard{}
+		s := &card.Scheduling
+		if err := rows.Scan(&id, &nid, &did, &odid, &card.Ord, &s.Type, &queue, &due, &odue,
+			&s.Interval, &s.Reps, &s.Lapses, &data); err != nil {
+			return nil, errors.Wrap(err, "failed to read card")
+		}
+		// cards in a filtered deck keep their home deck and due in odid and odue
+		if odid != 0 {
+			did, due = odid, odue
+		}
+		card.Deck = decks[strconv.FormatInt(did, 10)].Name
+		if card.Deck == "" {
+			card.Deck = DEFAULT_DECK
+		}
+		s.Suspended = queue == QUEUE_SUSPENDED
+		if s.Type != CARD_NEW {
+			if due >= minUnixDue {
+				s.Due = time.Unix(due, 0)
+			} else {
+				s.Due = crt.AddDate(0, 0, int(due))
+			}
+		}
+		var memory cardData
+		if data != "" && json.Unmarshal([]byte(data), &memory) == nil {
+			s.Stability, s.Difficulty = memory.Stability, memory.Difficulty
+		}
+		card.Reviews = reviews[id]
+		cards[nid] = append(cards[nid], card)
+	}
+	return cards, errors.Wrap(rows.Err(), "failed to read cards")
+}
@@ -0,0 +1,251 @@
+// Package feed parses RSS 2.0, RSS 1.0 (RDF) and Atom feeds into a common list of entries.
+package feed
+
+import (
+	"encoding/xml"
+	"html"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html/charset"
+)
+
+var ErrNotAFeed = errors.New("document is not an RSS or Atom feed")
+
+type Feed struct {
+	Title   string  `json:"title"`
+	Link    string  `json:"link"`
+	Entries []Entry `json:"entries"`
+}
+
+type Entry struct {
+	// guid or id of the entry, the link when the feed has none
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Link    string `json:"link"`
+	Summary string `json:"summary"`
+	Author  string `json:"author"`
+	// zero when the feed has no date or it could not be parsed
+	Published time.Time `json:"published"`
+}
+
+// publish dates of feeds, RSS uses RFC 822 with many variations, Atom RFC 3339
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 02 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+type rssDoc struct {
+	Channel struct {
+		Title string `xml:"title"`
+		// atom:link elements of the channel match too, they have no text
+		Links []string  `xml:"link"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	// RSS 1.0 has its items next to the channel
+	Items []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	GUID        string `xml:"guid"`
+	About       string `xml:"about,attr"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Encoded     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Author      string `xml:"author"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+}
+
+type atomDoc struct {
+	Title   string      `xml:"title"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	Summary   string     `xml:"summary"`
+	Content   string     `xml:"content"`
+	Author    string     `xml:"author>name"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+}
+
+// Parse reads the feed, its charset is taken from the XML declaration.
+// Relative links of the entries resolve against feedURL.
+func Parse(r io.Reader, feedURL string) (*Feed, error) {
+	base, err := url.Parse(feedURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid feed url %v", feedURL)
+	}
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReaderLabel
+	// feeds in the wild often use HTML entities, no auto closing though as <link> holds the url in RSS
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	var root xml.StartElement
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, ErrNotAFeed
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read feed")
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			root = start
+			break
+		}
+	}
+
+	var feed *Feed
+	switch strings.ToLower(root.Name.Local) {
+	case "rss", "rdf":
+		var doc rssDoc
+		if err := decoder.DecodeElement(&doc, &root); err != nil {
+			return nil, errors.Wrap(err, "failed to parse rss feed")
+		}
+		feed = doc.feed(base)
+	case "feed":
+		var doc atomDoc
+		if err := decoder.DecodeElement(&doc, &root); err != nil {
+			return nil, errors.Wrap(err, "failed to parse atom feed")
+		}
+		feed = doc.feed(base)
+	default:
+		return nil, ErrNotAFeed
+	}
+	return feed, nil
+}
+
+func (doc *rssDoc) feed(base *url.URL) *Feed {
+	feed := &Feed{
+		Title:   clean(doc.Channel.Title),
+		Link:    resolveURL(base, firstOf(doc.Channel.Links...)),
+		Entries: []Entry{},
+	}
+	for _, item := range append(doc.Channel.Items, doc.Items...) {
+		entry := Entry{
+			ID:        firstOf(item.GUID, item.About),
+			Title:     clean(item.Title),
+			Link:      resolveURL(base, item.Link),
+			Summary:   clean(firstOf(item.Description, item.Encoded)),
+			Author:    clean(firstOf(item.Creator, item.Author)),
+			Published: parseDate(firstOf(item.PubDate, item.Date)),
+		}
+		feed.Entries = appendEntry(feed.Entries, entry)
+	}
+	return feed
+}
+
+func (doc *atomDoc) feed(base *url.URL) *Feed {
+	feed := &Feed{
+		Title:   clean(doc.Title),
+		Link:    resolveURL(base, alternateLink(doc.Links)),
+		Entries: []Entry{},
+	}
+	for _, item := range doc.Entries {
+		entry := Entry{
+			ID:        strings.TrimSpace(item.ID),
+			Title:     clean(item.Title),
+			Link:      resolveURL(base, alternateLink(item.Links)),
+			Summary:   clean(firstOf(item.Summary, item.Content)),
+			Author:    clean(item.Author),
+			Published: parseDate(firstOf(item.Published, item.Updated)),
+		}
+		feed.Entries = appendEntry(feed.Entries, entry)
+	}
+	return feed
+}
+
+// appendEntry drops entries without a link, they cannot be imported
+func appendEntry(entries []Entry, entry Entry) []Entry {
+	if entry.Link == "" {
+		return entries
+	}
+	if entry.ID == "" {
+		entry.ID = entry.Link
+	}
+	return append(entries, entry)
+}
+
+// alternateLink is the link to the web page, the rel attribute defaults to alternate
+func alternateLink(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+func firstOf(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// clean collapses white space, summaries also lose their HTML tags and entities
+func clean(text string) string {
+	var b strings.Builder
+	inTag := false
+	for _, r := range text {
+		switch {
+		case r == '<':
+			inTag = true
+			b.WriteRune(' ')
+		case r == '>' && inTag:
+			inTag = false
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(b.String())), " ")
+}
+
+func parseDate(date string) time.Time {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func resolveURL(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	return u.String()
+}
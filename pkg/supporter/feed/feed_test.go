@@ -0,0 +1,155 @@
+package feed
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseFile(t *testing.T, name, feedURL string) *Feed {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	feed, err := Parse(f, feedURL)
+	if err != nil {
+		t.Fatalf("Parse(%v) error = %v", name, err)
+	}
+	return feed
+}
+
+func date(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		file    string
+		feedURL string
+		want    *Feed
+	}{
+		{
+			file:    "rss2.xml",
+			feedURL: "https://science.example.com/feed.xml",
+			want: &Feed{
+				Title: "Science Daily & More",
+				Link:  "https://science.example.com/",
+				Entries: []Entry{
+					{
+						ID:        "science-1042",
+						Title:     "Coral reefs recover faster than expected",
+						Link:      "https://science.example.com/2024/03/coral-reefs",
+						Summary:   "Researchers found that coral reefs bounce back quickly.",
+						Author:    "Maya Lind",
+						Published: date("2024-03-18T06:30:00+01:00"),
+					},
+					{
+						ID:        "https://science.example.com/2024/03/relative",
+						Title:     "Relative links resolve",
+						Link:      "https://science.example.com/2024/03/relative",
+						Summary:   "Only encoded content",
+						Published: date("2024-03-17T10:00:00Z"),
+					},
+				},
+			},
+		},
+		{
+			file:    "atom.xml",
+			feedURL: "https://blog.example.org/atom.xml",
+			want: &Feed{
+				Title: "Engineering Blog",
+				Link:  "https://blog.example.org/",
+				Entries: []Entry{
+					{
+						ID:        "tag:blog.example.org,2024:post-7",
+						Title:     "Scaling queues",
+						Link:      "https://blog.example.org/posts/scaling-queues",
+						Summary:   "How we scaled the job queues.",
+						Author:    "Sam Okafor",
+						Published: date("2024-02-10T08:00:00+02:00"),
+					},
+					{
+						ID:        "tag:blog.example.org,2024:post-6",
+						Title:     "Only updated",
+						Link:      "https://blog.example.org/posts/only-updated",
+						Summary:   "Content instead of a summary",
+						Published: date("2024-01-05T12:00:00Z"),
+					},
+				},
+			},
+		},
+		{
+			file:    "rdf.xml",
+			feedURL: "https://news.example.jp/rss",
+			want: &Feed{
+				Title: "Example News",
+				Link:  "https://news.example.jp/",
+				Entries: []Entry{
+					{
+						ID:        "https://news.example.jp/articles/1",
+						Title:     "Tokyo opens a new library",
+						Link:      "https://news.example.jp/articles/1",
+						Author:    "Aiko Sato",
+						Published: date("2024-04-01T09:00:00+09:00"),
+					},
+				},
+			},
+		},
+		{
+			file:    "rss_windows1252.xml",
+			feedURL: "https://cafe.example.com/rss",
+			want: &Feed{
+				Title: "Café News",
+				Link:  "https://cafe.example.com/",
+				Entries: []Entry{
+					{
+						ID:        "https://cafe.example.com/creme",
+						Title:     "Crème brûlée – a history",
+						Link:      "https://cafe.example.com/creme",
+						Published: date("2024-04-02T15:04:05Z"),
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got := parseFile(t, tt.file, tt.feedURL)
+			if got.Title != tt.want.Title || got.Link != tt.want.Link {
+				t.Errorf("Parse() feed = %q %q, want %q %q", got.Title, got.Link, tt.want.Title, tt.want.Link)
+			}
+			if len(got.Entries) != len(tt.want.Entries) {
+				t.Fatalf("Parse() entries = %+v, want %+v", got.Entries, tt.want.Entries)
+			}
+			for i := range got.Entries {
+				gotEntry, wantEntry := got.Entries[i], tt.want.Entries[i]
+				if !gotEntry.Published.Equal(wantEntry.Published) {
+					t.Errorf("entry %d published = %v, want %v", i, gotEntry.Published, wantEntry.Published)
+				}
+				gotEntry.Published, wantEntry.Published = time.Time{}, time.Time{}
+				if !reflect.DeepEqual(gotEntry, wantEntry) {
+					t.Errorf("entry %d = %+v, want %+v", i, gotEntry, wantEntry)
+				}
+			}
+		})
+	}
+}
+
+func TestParseNotAFeed(t *testing.T) {
+	for _, doc := range []string{"", "<html><body><p>a page</p></body></html>"} {
+		_, err := Parse(strings.NewReader(doc), "https://example.com/")
+		if !errors.Is(err, ErrNotAFeed) {
+			t.Errorf("Parse(%q) error = %v, want ErrNotAFeed", doc, err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package kana
+
+const (
+	DIFF_EQUAL  = "equal"
+	DIFF_INSERT = "insert" // only in the typed answer
+	DIFF_DELETE = "delete" // missing from the typed answer
+)
+
+// lcs table is quadratic, longer inputs fall back to a full replace
+const maxDiffCells = 1 << 20
+
+// the particles は, へ and を are pronounced, and typed in romaji, as わ, え and お
+var particleSounds = map[rune]rune{'は': 'わ', 'へ': 'え', 'を': 'お'}
+
+type DiffSegment struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Diff returns a character level diff turning expected into actual and the
+// similarity ratio of both strings in [0, 1]. A は, へ or を expected may be
+// typed as it is pronounced, equal segments hold the expected text.
+func Diff(expected, actual string) ([]DiffSegment, float64) {
+	a, b := []rune(expected), []rune(actual)
+	if len(a) == 0 && len(b) == 0 {
+		return []DiffSegment{}, 1
+	}
+	if len(a)*len(b) > maxDiffCells {
+		segments := []DiffSegment{}
+		segments = appendSegment(segments, DIFF_DELETE, a...)
+		segments = appendSegment(segments, DIFF_INSERT, b...)
+		return segments, 0
+	}
+
+	// lcs[i][j] is the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if sameSound(a[i], b[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	segments := []DiffSegment{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case sameSound(a[i], b[j]):
+			segments = appendSegment(segments, DIFF_EQUAL, a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			segments = appendSegment(segments, DIFF_DELETE, a[i])
+			i++
+		default:
+			segments = appendSegment(segments, DIFF_INSERT, b[j])
+			j++
+		}
+	}
+	segments = appendSegment(segments, DIFF_DELETE, a[i:]...)
+	segments = appendSegment(segments, DIFF_INSERT, b[j:]...)
+
+	return segments, 2 * float64(lcs[0][0]) / float64(len(a)+len(b))
+}
+
+// sameSound reports whether the typed rune stands for the expected one
+func sameSound(expected, typed rune) bool {
+	return expected == typed || particleSounds[expected] == typed
+}
+
+func appendSegment(segments []DiffSegment, op string, runes ...rune) []DiffSegment {
+	if len(runes) == 0 {
+		return segments
+	}
+	if n := len(segments); n > 0 && segments[n-1].Op == op {
+		segments[n-1].Text += string(runes)
+		return segments
+	}
+	return append(segments, DiffSegment{Op: op, Text: string(runes)})
+}
@@ -0,0 +1,81 @@
+package kana
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "hiragana", in: "わたしは がくせいです。", want: "わたしはがくせいです"},
+		{name: "katakana", in: "コーヒー", want: "こーひー"},
+		{name: "half width katakana", in: "ｺｰﾋｰ ｶﾞｲﾄﾞ", want: "こーひーがいど"},
+		{name: "full width romaji", in: "ＫＯＮＮＩＣＨＩＷＡ", want: "こんにちわ"},
+		{name: "romaji", in: "Watashi wa gakusei desu.", want: "わたしわがくせいです"},
+		{name: "small tsu and moraic n", in: "kitte, shinbun, matcha, kin'en", want: "きってしんぶんまっちゃきんえん"},
+		{name: "yoon", in: "kyou ryokou", want: "きょうりょこう"},
+		{name: "kanji kept", in: "「先生」です！", want: "先生です"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.in); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeLatin(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "romaji is kept", in: "Studied", want: "studied"},
+		{name: "spaces folded", in: "  ice   cream ", want: "ice cream"},
+		{name: "full width latin", in: "ＰＡＲＩＳ.", want: "paris"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeLatin(tt.in); got != tt.want {
+				t.Errorf("NormalizeLatin(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+	if IsJapanese("Paris") || !IsJapanese("パリ") || !IsJapanese("先生") {
+		t.Error("IsJapanese() only holds for kana and kanji")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	got, similarity := Diff("せんせい", "せいせい")
+	want := []DiffSegment{
+		{Op: DIFF_EQUAL, Text: "せ"},
+		{Op: DIFF_DELETE, Text: "ん"},
+		{Op: DIFF_INSERT, Text: "い"},
+		{Op: DIFF_EQUAL, Text: "せい"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+	if similarity != 0.75 {
+		t.Errorf("Diff() similarity = %v, want 0.75", similarity)
+	}
+
+	if _, similarity := Diff("", ""); similarity != 1 {
+		t.Errorf("Diff() of empty strings similarity = %v, want 1", similarity)
+	}
+
+	// particles may be typed as pronounced, the diff shows them as written
+	got, similarity = Diff("わたしはがっこうへいく", Normalize("watashi wa gakkou e iku"))
+	if want := []DiffSegment{{Op: DIFF_EQUAL, Text: "わたしはがっこうへいく"}}; !reflect.DeepEqual(got, want) || similarity != 1 {
+		t.Errorf("Diff() of particles typed as pronounced = %v %v, want %v 1", got, similarity, want)
+	}
+	if _, similarity := Diff("わに", "はに"); similarity == 1 {
+		t.Error("Diff() matched は typed for an expected わ")
+	}
+}
@@ -0,0 +1,66 @@
+package kana
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	katakanaStart = 'ァ'
+	katakanaEnd   = 'ヶ'
+	kanaOffset    = 'ァ' - 'ぁ'
+)
+
+// KatakanaToHiragana folds full width katakana to hiragana, the long vowel mark is kept
+func KatakanaToHiragana(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= katakanaStart && r <= katakanaEnd {
+			return r - kanaOffset
+		}
+		return r
+	}, s)
+}
+
+// IsJapanese reports whether s holds kana or kanji, romaji typed for it is converted to kana
+func IsJapanese(s string) bool {
+	for _, r := range s {
+		if unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Han) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeLatin prepares a typed answer in a latin script for comparison, like Normalize without
+// converting romaji: NFKC, lower case and spaces folded to one, punctuation and symbols dropped
+func NormalizeLatin(s string) string {
+	s = norm.NFKC.String(s)
+	s = strings.ToLower(s)
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Normalize prepares a typed answer for comparison:
+//   - NFKC folds full width latin and half width katakana
+//   - romaji is converted to hiragana
+//   - katakana is folded to hiragana
+//   - punctuation, symbols and spaces are dropped
+func Normalize(s string) string {
+	s = norm.NFKC.String(s)
+	s = strings.ToLower(s)
+	s = RomajiToHiragana(s)
+	s = KatakanaToHiragana(s)
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
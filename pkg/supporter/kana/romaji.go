@@ -0,0 +1,120 @@
+package kana
+
+import "strings"
+
+// hepburn, kunrei and common IME spellings to hiragana
+var romajiTable = map[string]string{
+	"a": "あ", "i": "い", "u": "う", "e": "え", "o": "お",
+	"ka": "か", "ki": "き", "ku": "く", "ke": "け", "ko": "こ",
+	"ga": "が", "gi": "ぎ", "gu": "ぐ", "ge": "げ", "go": "ご",
+	"sa": "さ", "shi": "し", "si": "し", "su": "す", "se": "せ", "so": "そ",
+	"za": "ざ", "ji": "じ", "zi": "じ", "zu": "ず", "ze": "ぜ", "zo": "ぞ",
+	"ta": "た", "chi": "ち", "ti": "ち", "tsu": "つ", "tu": "つ", "te": "て", "to": "と",
+	"da": "だ", "di": "ぢ", "du": "づ", "de": "で", "do": "ど",
+	"na": "な", "ni": "に", "nu": "ぬ", "ne": "ね", "no": "の",
+	"ha": "は", "hi": "ひ", "fu": "ふ", "hu": "ふ", "he": "へ", "ho": "ほ",
+	"ba": "ば", "bi": "び", "bu": "ぶ", "be": "べ", "bo": "ぼ",
+	"pa": "ぱ", "pi": "ぴ", "pu": "ぷ", "pe": "ぺ", "po": "ぽ",
+	"ma": "ま", "mi": "み", "mu": "む", "me": "め", "mo": "も",
+	"ya": "や", "yu": "ゆ", "yo": "よ",
+	"ra": "ら", "ri": "り", "ru": "る", "re": "れ", "ro": "ろ",
+	"wa": "わ", "wo": "を", "vu": "ゔ",
+
+	"kya": "きゃ", "kyu": "きゅ", "kyo": "きょ",
+	"gya": "ぎゃ", "gyu": "ぎゅ", "gyo": "ぎょ",
+	"sha": "しゃ", "shu": "しゅ", "she": "しぇ", "sho": "しょ",
+	"sya": "しゃ", "syu": "しゅ", "syo": "しょ",
+	"ja": "じゃ", "ju": "じゅ", "je": "じぇ", "jo": "じょ",
+	"jya": "じゃ", "jyu": "じゅ", "jyo": "じょ",
+	"zya": "じゃ", "zyu": "じゅ", "zyo": "じょ",
+	"cha": "ちゃ", "chu": "ちゅ", "che": "ちぇ", "cho": "ちょ",
+	"tya": "ちゃ", "tyu": "ちゅ", "tyo": "ちょ",
+	"dya": "ぢゃ", "dyu": "ぢゅ", "dyo": "ぢょ",
+	"nya": "にゃ", "nyu": "にゅ", "nyo": "にょ",
+	"hya": "ひゃ", "hyu": "ひゅ", "hyo": "ひょ",
+	"bya": "びゃ", "byu": "びゅ", "byo": "びょ",
+	"pya": "ぴゃ", "pyu": "ぴゅ", "pyo": "ぴょ",
+	"mya": "みゃ", "myu": "みゅ", "myo": "みょ",
+	"rya": "りゃ", "ryu": "りゅ", "ryo": "りょ",
+	"fa": "ふぁ", "fi": "ふぃ", "fe": "ふぇ", "fo": "ふぉ",
+	"thi": "てぃ", "dhi": "でぃ",
+
+	"xa": "ぁ", "xi": "ぃ", "xu": "ぅ", "xe": "ぇ", "xo": "ぉ",
+	"la": "ぁ", "li": "ぃ", "lu": "ぅ", "le": "ぇ", "lo": "ぉ",
+	"xya": "ゃ", "xyu": "ゅ", "xyo": "ょ",
+	"lya": "ゃ", "lyu": "ゅ", "lyo": "ょ",
+	"xtu": "っ", "ltu": "っ", "xtsu": "っ", "ltsu": "っ",
+	"n'": "ん", "-": "ー",
+}
+
+const maxRomajiLen = 4
+
+func isVowel(c byte) bool {
+	return strings.IndexByte("aiueo", c) >= 0
+}
+
+func isRomajiLetter(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
+
+// RomajiToHiragana converts lower case romaji runs in s to hiragana, leaving any
+// other character untouched. Letters that do not form a syllable are kept as is.
+func RomajiToHiragana(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		if !isRomajiLetter(c) && c != '-' {
+			sb.WriteByte(c)
+			i++
+			continue
+		}
+
+		// doubled consonant is a small tsu: "kk" -> "っk"
+		if i+1 < len(s) && c == s[i+1] && isRomajiLetter(c) && !isVowel(c) && c != 'n' {
+			sb.WriteString("っ")
+			i++
+			continue
+		}
+
+		// "tch" is also a small tsu in hepburn: "matcha" -> "まっちゃ"
+		if strings.HasPrefix(s[i:], "tch") {
+			sb.WriteString("っ")
+			i++
+			continue
+		}
+
+		// "nn" is the moraic n, but before a vowel the second n starts the next syllable
+		// so that "konnichiwa" reads "こんにちわ"
+		if c == 'n' && i+1 < len(s) && s[i+1] == 'n' {
+			sb.WriteString("ん")
+			if i+2 < len(s) && (isVowel(s[i+2]) || s[i+2] == 'y') {
+				i++
+			} else {
+				i += 2
+			}
+			continue
+		}
+
+		// a single "n" not followed by a vowel or "y" is the moraic n
+		if c == 'n' && (i+1 == len(s) || (!isVowel(s[i+1]) && s[i+1] != 'y' && s[i+1] != '\'')) {
+			sb.WriteString("ん")
+			i++
+			continue
+		}
+
+		matched := false
+		for l := min(maxRomajiLen, len(s)-i); l > 0; l-- {
+			if kana, ok := romajiTable[s[i:i+l]]; ok {
+				sb.WriteString(kana)
+				i += l
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String()
+}
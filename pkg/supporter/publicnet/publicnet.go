@@ -0,0 +1,94 @@
+// Package publicnet makes http clients that only connect to public internet addresses, for urls given by users.
+// The address is checked when the connection is made, after name resolution and on every redirect,
+// so a name resolving to a private address is rejected too.
+package publicnet
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrNonPublicAddress = errors.New("address is not public")
+
+// ranges that are not reachable on the public internet, besides the private, loopback, link local,
+// multicast and unspecified addresses of the net/netip helpers
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // this network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, may reach any IPv4 address
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local NAT64
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4, may reach any IPv4 address
+}
+
+// IsPublic tells whether the address is reachable on the public internet
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckHost resolves the host and fails when one of its addresses is not public, for urls handed to
+// programs that fetch them without this package. The name may resolve differently when they fetch it.
+func CheckHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return errors.Wrapf(err, "failed to resolve %v", host)
+	}
+	for _, addr := range addrs {
+		if !IsPublic(addr) {
+			return errors.Wrapf(ErrNonPublicAddress, "%v resolves to %v", host, addr)
+		}
+	}
+	return nil
+}
+
+// control rejects the connection before it is made when the resolved address is not public
+func control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return errors.Wrapf(ErrNonPublicAddress, "cannot check address %v of %v: %v", address, network, err)
+	}
+	if !IsPublic(addrPort.Addr()) {
+		return errors.Wrapf(ErrNonPublicAddress, "%v", addrPort.Addr())
+	}
+	return nil
+}
+
+// NewClient returns an http client connecting to public addresses only, proxies of the environment are
+// not used as the client could not check the addresses they connect to
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second, Control: control}
+	transport := &http.Transport{
+		Proxy: nil,
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, address)
+		},
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          20,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
@@ -0,0 +1,58 @@
+package publicnet
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.215.14":         true,
+		"2606:2800:21f:cb07::1": true,
+		"127.0.0.1":             false,
+		"10.1.2.3":              false,
+		"172.16.0.1":            false,
+		"192.168.1.1":           false,
+		"169.254.169.254":       false,
+		"100.64.0.1":            false,
+		"0.0.0.0":               false,
+		"255.255.255.255":       false,
+		"::1":                   false,
+		"fd00::1":               false,
+		"fe80::1":               false,
+		"::ffff:127.0.0.1":      false,
+		"64:ff9b::a00:1":        false,
+	}
+	for address, want := range tests {
+		if got := IsPublic(netip.MustParseAddr(address)); got != want {
+			t.Errorf("IsPublic(%v) = %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	if err := CheckHost(context.Background(), "127.0.0.1"); !errors.Is(err, ErrNonPublicAddress) {
+		t.Errorf("CheckHost(127.0.0.1) error = %v, want %v", err, ErrNonPublicAddress)
+	}
+	if err := CheckHost(context.Background(), "93.184.215.14"); err != nil {
+		t.Errorf("CheckHost(93.184.215.14) error = %v", err)
+	}
+}
+
+func TestClientRejectsLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	_, err := NewClient(5 * time.Second).Get(server.URL)
+	if !errors.Is(err, ErrNonPublicAddress) {
+		t.Errorf("Get(%v) error = %v, want %v", server.URL, err, ErrNonPublicAddress)
+	}
+}
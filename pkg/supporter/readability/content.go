@@ -0,0 +1,301 @@
+package readability
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// class and id patterns of page chrome, and of the elements likely holding the article
+var (
+	unlikelyCandidate = regexp.MustCompile(`(?i)(^|\s)(ad|ads|advert|advertisement)(\s|-|$)|ad-break|agegate|banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|legends|menu|modal|newsletter|pager|pagination|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|supplemental`)
+	maybeCandidate    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow|story`)
+	positiveClass     = regexp.MustCompile(`(?i)article|body|content|entry|hentry|main|page|post|text|blog|story`)
+	negativeClass     = regexp.MustCompile(`(?i)-ad-|hidden|banner|combx|comment|contact|foot|footnote|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+// elements never part of the article text
+var removedTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "iframe": true, "object": true, "embed": true,
+	"svg": true, "canvas": true, "form": true, "button": true, "input": true, "select": true, "textarea": true,
+	"nav": true, "aside": true, "header": true, "footer": true, "dialog": true,
+}
+
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "dd": true, "div": true, "dl": true, "dt": true,
+	"figcaption": true, "figure": true, "footer": true, "form": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "header": true, "hr": true, "li": true, "main": true, "nav": true, "ol": true, "p": true,
+	"pre": true, "section": true, "table": true, "td": true, "th": true, "tr": true, "ul": true,
+}
+
+// elements written as one paragraph of the content
+var paragraphTags = map[string]bool{
+	"p": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "li": true, "pre": true, "dt": true, "dd": true,
+}
+
+const (
+	// shorter paragraphs do not score their ancestors
+	MIN_PARAGRAPH_LENGTH = 25
+	// paragraphs whose text is mostly links are dropped from the content
+	MAX_LINK_DENSITY = 0.5
+)
+
+func isBlock(n *html.Node) bool {
+	return n.Type == html.ElementNode && blockTags[n.Data]
+}
+
+// extractContent scores the ancestors of the paragraphs of the page, keeps the best one
+// with its related siblings and writes their paragraphs separated by an empty line
+func extractContent(doc *html.Node) string {
+	bodies := findAll(doc, isElement("body"))
+	if len(bodies) == 0 {
+		return ""
+	}
+	body := bodies[0]
+	prune(body)
+
+	scores := scoreCandidates(body)
+	// candidates are compared in document order so that ties are stable
+	var best *html.Node
+	for _, n := range findAll(body, func(n *html.Node) bool { _, ok := scores[n]; return ok }) {
+		if best == nil || scores[n] > scores[best] {
+			best = n
+		}
+	}
+	if best == nil {
+		best = body
+	}
+
+	nodes := []*html.Node{best}
+	if best.Parent != nil && best != body {
+		nodes = relatedSiblings(best, scores)
+	}
+	paragraphs := []string{}
+	for _, n := range nodes {
+		paragraphs = appendParagraphs(paragraphs, n)
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// prune removes the elements that cannot be content
+func prune(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || (c.Type == html.ElementNode && isUnlikely(c)) {
+			n.RemoveChild(c)
+		} else {
+			prune(c)
+		}
+		c = next
+	}
+}
+
+func isUnlikely(n *html.Node) bool {
+	if removedTags[n.Data] || attr(n, "hidden") != "" || strings.EqualFold(attr(n, "aria-hidden"), "true") {
+		return true
+	}
+	style := strings.ReplaceAll(strings.ToLower(attr(n, "style")), " ", "")
+	if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+		return true
+	}
+	if n.Data == "body" || n.Data == "article" || n.Data == "main" {
+		return false
+	}
+	match := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyCandidate.MatchString(match) && !maybeCandidate.MatchString(match)
+}
+
+// scoreCandidates gives each paragraph a score by its length and commas, shared with its parent,
+// half of it with its grandparent and a third with its great-grandparent.
+// Scores are then lowered by the share of link text of the candidate.
+func scoreCandidates(body *html.Node) map[*html.Node]float64 {
+	scores := map[*html.Node]float64{}
+	paragraphs := findAll(body, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		switch n.Data {
+		case "p", "pre", "td":
+			return true
+		case "div":
+			// a div of text without paragraphs is scored as a paragraph
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if isBlock(c) {
+					return false
+				}
+			}
+			return true
+		}
+		return false
+	})
+
+	for _, p := range paragraphs {
+		text := textOf(p)
+		length := utf8.RuneCountInString(text)
+		if length < MIN_PARAGRAPH_LENGTH {
+			continue
+		}
+		score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "、")) + min(float64(length/100), 3)
+		ancestor := p.Parent
+		for level := 1; level <= 3 && ancestor != nil && ancestor.Type == html.ElementNode; level++ {
+			if _, ok := scores[ancestor]; !ok {
+				scores[ancestor] = initialScore(ancestor)
+			}
+			scores[ancestor] += score / float64(level)
+			ancestor = ancestor.Parent
+		}
+	}
+
+	for n := range scores {
+		scores[n] *= 1 - linkDensity(n)
+	}
+	return scores
+}
+
+func initialScore(n *html.Node) float64 {
+	score := 0.0
+	switch n.Data {
+	case "div", "article", "main", "section":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+	return score + classWeight(n)
+}
+
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, value := range []string{attr(n, "class"), attr(n, "id")} {
+		if value == "" {
+			continue
+		}
+		if negativeClass.MatchString(value) {
+			weight -= 25
+		}
+		if positiveClass.MatchString(value) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the share of the text of the node inside links
+func linkDensity(n *html.Node) float64 {
+	length := utf8.RuneCountInString(textOf(n))
+	if length == 0 {
+		return 0
+	}
+	linkLength := 0
+	for _, a := range findAll(n, isElement("a")) {
+		linkLength += utf8.RuneCountInString(textOf(a))
+	}
+	return float64(linkLength) / float64(length)
+}
+
+// relatedSiblings keeps the siblings of the best candidate that also look like content,
+// e.g. paragraphs split in several divs by ads
+func relatedSiblings(best *html.Node, scores map[*html.Node]float64) []*html.Node {
+	threshold := max(10, scores[best]*0.2)
+	nodes := []*html.Node{}
+	for s := best.Parent.FirstChild; s != nil; s = s.NextSibling {
+		if s.Type != html.ElementNode {
+			continue
+		}
+		keep := s == best
+		if score, ok := scores[s]; ok && score+classWeight(s)*0.2 >= threshold {
+			keep = true
+		}
+		if s.Data == "p" {
+			text := textOf(s)
+			length := utf8.RuneCountInString(text)
+			density := linkDensity(s)
+			keep = keep || (length > 80 && density < 0.25) ||
+				(length > 0 && density == 0 && strings.HasSuffix(text, "."))
+		}
+		if keep {
+			nodes = append(nodes, s)
+		}
+	}
+	return nodes
+}
+
+// appendParagraphs writes the text of the paragraph elements below the node, text directly
+// in other elements forms its own paragraph
+func appendParagraphs(paragraphs []string, n *html.Node) []string {
+	add := func(text string) {
+		if text != "" && (len(paragraphs) == 0 || paragraphs[len(paragraphs)-1] != text) {
+			paragraphs = append(paragraphs, text)
+		}
+	}
+	if n.Type == html.ElementNode && (paragraphTags[n.Data] || (n.Data == "blockquote" && !hasParagraphs(n))) {
+		if linkDensity(n) <= MAX_LINK_DENSITY {
+			add(textOf(n))
+		}
+		return paragraphs
+	}
+	if n.Type == html.ElementNode && n.Data == "h1" {
+		// the title is read from the metadata
+		return paragraphs
+	}
+
+	var inline []*html.Node
+	flush := func() {
+		text := strings.Join(strings.Fields(inlineText(inline)), " ")
+		if text != "" && inlineLinkDensity(inline, text) <= MAX_LINK_DENSITY {
+			add(text)
+		}
+		inline = nil
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if isBlock(c) {
+			flush()
+			paragraphs = appendParagraphs(paragraphs, c)
+			continue
+		}
+		inline = append(inline, c)
+	}
+	flush()
+	return paragraphs
+}
+
+func hasParagraphs(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if isBlock(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func inlineText(nodes []*html.Node) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		} else if n.Type == html.ElementNode {
+			b.WriteString(" " + textOf(n) + " ")
+		}
+	}
+	return b.String()
+}
+
+// inlineLinkDensity is the share of the text of the inline nodes inside links
+func inlineLinkDensity(nodes []*html.Node, text string) float64 {
+	length := utf8.RuneCountInString(text)
+	if length == 0 {
+		return 0
+	}
+	linkLength := 0
+	for _, n := range nodes {
+		for _, a := range findAll(n, isElement("a")) {
+			linkLength += utf8.RuneCountInString(textOf(a))
+		}
+	}
+	return float64(linkLength) / float64(length)
+}
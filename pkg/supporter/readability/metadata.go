@@ -0,0 +1,300 @@
+package readability
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// meta tags by lower cased property, name or itemprop, in order of preference
+var (
+	titleMetas  = []string{"og:title", "twitter:title"}
+	authorMetas = []string{"author", "article:author", "parsely-author", "dc.creator", "sailthru.author", "byl"}
+	dateMetas   = []string{"article:published_time", "og:published_time", "datepublished", "parsely-pub-date",
+		"pubdate", "publishdate", "dc.date.issued", "dc.date", "date"}
+	imageMetas = []string{"og:image", "og:image:url", "og:image:secure_url", "twitter:image", "twitter:image:src"}
+	urlMetas   = []string{"og:url", "twitter:url"}
+)
+
+// separators between the article title and the site name in page titles
+var titleSeparators = []string{" | ", " - ", " – ", " — ", " :: ", " · "}
+
+// publish dates are tried with these layouts, layouts without a zone are read as UTC
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2006/01/02",
+}
+
+var bylinePrefix = regexp.MustCompile(`(?i)^(written\s+)?by\s+`)
+
+type metadata struct {
+	metaTitle   string
+	headline    string
+	siteName    string
+	author      string
+	publishDate string
+	image       string
+	canonical   string
+}
+
+// linked data of an article, see https://schema.org/Article
+type linkedArticle struct {
+	Type          interface{}       `json:"@type"`
+	Graph         []json.RawMessage `json:"@graph"`
+	Headline      string            `json:"headline"`
+	Author        interface{}       `json:"author"`
+	DatePublished string            `json:"datePublished"`
+	Image         interface{}       `json:"image"`
+}
+
+func readMetadata(doc *html.Node, base *url.URL) *metadata {
+	metas := map[string]string{}
+	for _, n := range findAll(doc, isElement("meta")) {
+		content := attr(n, "content")
+		if content == "" {
+			continue
+		}
+		for _, key := range []string{"property", "name", "itemprop"} {
+			name := strings.ToLower(attr(n, key))
+			if _, ok := metas[name]; name != "" && !ok {
+				metas[name] = content
+			}
+		}
+	}
+	first := func(names []string, valid func(string) bool) string {
+		for _, name := range names {
+			if value := strings.TrimSpace(metas[name]); value != "" && valid(value) {
+				return value
+			}
+		}
+		return ""
+	}
+	anyValue := func(string) bool { return true }
+	notURL := func(value string) bool { return !strings.HasPrefix(value, "http") }
+
+	meta := &metadata{
+		metaTitle: first(titleMetas, anyValue),
+		siteName:  metas["og:site_name"],
+		author:    cleanAuthor(first(authorMetas, notURL)),
+		image:     resolveURL(base, first(imageMetas, anyValue)),
+		canonical: canonicalURL(doc, base),
+	}
+	if meta.canonical == "" {
+		meta.canonical = resolveURL(base, first(urlMetas, anyValue))
+	}
+	date := first(dateMetas, anyValue)
+
+	for _, article := range linkedArticles(doc) {
+		if meta.headline == "" {
+			meta.headline = strings.TrimSpace(article.Headline)
+		}
+		if meta.author == "" {
+			meta.author = strings.Join(linkedNames(article.Author), ", ")
+		}
+		if date == "" {
+			date = article.DatePublished
+		}
+		if meta.image == "" {
+			if images := linkedNames(article.Image); len(images) > 0 {
+				meta.image = resolveURL(base, images[0])
+			}
+		}
+	}
+
+	if meta.author == "" {
+		meta.author = pageAuthor(doc)
+	}
+	if date == "" {
+		date = pageDate(doc)
+	}
+	meta.publishDate = normalizeDate(date)
+	if meta.image == "" {
+		for _, article := range findAll(doc, isElement("article")) {
+			if imgs := findAll(article, isElement("img")); len(imgs) > 0 {
+				meta.image = resolveURL(base, attr(imgs[0], "src"))
+				break
+			}
+		}
+	}
+	return meta
+}
+
+// title prefers the meta tags and linked data, then the page title without the site name
+func (meta *metadata) title(doc *html.Node) string {
+	if meta.metaTitle != "" {
+		return meta.metaTitle
+	}
+	if meta.headline != "" {
+		return meta.headline
+	}
+
+	title := ""
+	if titles := findAll(doc, isElement("title")); len(titles) > 0 {
+		title = textOf(titles[0])
+	}
+	headings := findAll(doc, isElement("h1"))
+	for _, h1 := range headings {
+		if text := textOf(h1); text != "" && strings.Contains(title, text) {
+			return text
+		}
+	}
+	for _, sep := range titleSeparators {
+		if meta.siteName != "" && strings.HasSuffix(title, sep+meta.siteName) {
+			return strings.TrimSpace(strings.TrimSuffix(title, sep+meta.siteName))
+		}
+	}
+	for _, sep := range titleSeparators {
+		if idx := strings.LastIndex(title, sep); idx > 0 && len(strings.Fields(title[:idx])) >= 3 {
+			return strings.TrimSpace(title[:idx])
+		}
+	}
+	if title == "" && len(headings) > 0 {
+		return textOf(headings[0])
+	}
+	return title
+}
+
+// linkedArticles reads the articles of the JSON-LD scripts, including those of a @graph
+func linkedArticles(doc *html.Node) []linkedArticle {
+	articles := []linkedArticle{}
+	var read func(raw []byte)
+	read = func(raw []byte) {
+		var list []json.RawMessage
+		if json.Unmarshal(raw, &list) == nil {
+			for _, item := range list {
+				read(item)
+			}
+			return
+		}
+		var article linkedArticle
+		if json.Unmarshal(raw, &article) != nil {
+			return
+		}
+		for _, item := range article.Graph {
+			read(item)
+		}
+		for _, typ := range linkedNames(article.Type) {
+			if strings.HasSuffix(typ, "Article") || typ == "BlogPosting" {
+				articles = append(articles, article)
+				break
+			}
+		}
+	}
+	for _, script := range findAll(doc, isElement("script")) {
+		if strings.EqualFold(attr(script, "type"), "application/ld+json") && script.FirstChild != nil {
+			read([]byte(script.FirstChild.Data))
+		}
+	}
+	return articles
+}
+
+// linkedNames reads a JSON-LD value that is a string, an object with a name or url, or a list of those
+func linkedNames(value interface{}) []string {
+	names := []string{}
+	switch v := value.(type) {
+	case string:
+		if v = strings.TrimSpace(v); v != "" {
+			names = append(names, v)
+		}
+	case map[string]interface{}:
+		for _, key := range []string{"name", "url"} {
+			if name, ok := v[key].(string); ok && strings.TrimSpace(name) != "" {
+				names = append(names, strings.TrimSpace(name))
+				break
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			names = append(names, linkedNames(item)...)
+		}
+	}
+	return names
+}
+
+// pageAuthor looks for an author in the microdata, then the author links and then the bylines of the page
+func pageAuthor(doc *html.Node) string {
+	matchers := []func(n *html.Node) bool{
+		func(n *html.Node) bool { return strings.EqualFold(attr(n, "itemprop"), "author") },
+		func(n *html.Node) bool { return strings.EqualFold(attr(n, "rel"), "author") },
+		func(n *html.Node) bool {
+			class := strings.ToLower(attr(n, "class") + " " + attr(n, "id"))
+			return n.Type == html.ElementNode && (strings.Contains(class, "byline") || strings.Contains(class, "author"))
+		},
+	}
+	for _, match := range matchers {
+		for _, n := range findAll(doc, match) {
+			name := ""
+			if names := findAll(n, func(c *html.Node) bool { return strings.EqualFold(attr(c, "itemprop"), "name") }); len(names) > 0 {
+				name = attr(names[0], "content")
+				if name == "" {
+					name = textOf(names[0])
+				}
+			}
+			if name == "" {
+				name = textOf(n)
+			}
+			// long texts are author bios rather than names
+			if name = cleanAuthor(name); name != "" && len(strings.Fields(name)) <= 6 {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// canonicalURL reads the url the page declares as its canonical one
+func canonicalURL(doc *html.Node, base *url.URL) string {
+	for _, n := range findAll(doc, isElement("link")) {
+		if strings.EqualFold(attr(n, "rel"), "canonical") && attr(n, "href") != "" {
+			return resolveURL(base, attr(n, "href"))
+		}
+	}
+	return ""
+}
+
+// pageDate reads the publish date of the microdata or of the first time element
+func pageDate(doc *html.Node) string {
+	for _, n := range findAll(doc, func(n *html.Node) bool { return strings.EqualFold(attr(n, "itemprop"), "datePublished") }) {
+		for _, key := range []string{"datetime", "content"} {
+			if date := attr(n, key); date != "" {
+				return date
+			}
+		}
+		return textOf(n)
+	}
+	for _, n := range findAll(doc, isElement("time")) {
+		if date := attr(n, "datetime"); date != "" {
+			return date
+		}
+	}
+	return ""
+}
+
+func cleanAuthor(author string) string {
+	author = strings.Join(strings.Fields(author), " ")
+	return strings.TrimSpace(bylinePrefix.ReplaceAllString(author, ""))
+}
+
+func normalizeDate(date string) string {
+	date = strings.TrimSpace(date)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t.Format(time.RFC3339)
+		}
+	}
+	return date
+}
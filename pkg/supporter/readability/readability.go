@@ -0,0 +1,127 @@
+// Package readability extracts the main article of a web page: its metadata and the text of its
+// paragraphs without navigation, comments, ads and other page chrome.
+package readability
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+var ErrNoContent = errors.New("no article content found")
+
+type Article struct {
+	Title  string `json:"title"`
+	Author string `json:"author"`
+	// RFC 3339 when the date could be parsed, as written in the page otherwise
+	PublishDate string `json:"publish_date"`
+	// absolute url of the cover image
+	Image string `json:"image"`
+	// absolute url the page declares as canonical, empty when it has none
+	Canonical string `json:"canonical"`
+	// paragraphs separated by an empty line
+	Content string `json:"content"`
+}
+
+// Extract parses the page, decoding it from the charset of the content type or of the page itself.
+// pageURL resolves relative links, e.g. of the cover image.
+func Extract(r io.Reader, contentType, pageURL string) (*Article, error) {
+	decoded, err := charset.NewReader(r, contentType)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to detect page charset")
+	}
+	doc, err := html.Parse(decoded)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse page")
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid page url %v", pageURL)
+	}
+
+	meta := readMetadata(doc, base)
+	content := extractContent(doc)
+	if content == "" {
+		return nil, ErrNoContent
+	}
+	return &Article{
+		Title:       meta.title(doc),
+		Author:      meta.author,
+		PublishDate: meta.publishDate,
+		Image:       meta.image,
+		Canonical:   meta.canonical,
+		Content:     content,
+	}, nil
+}
+
+// textOf joins the text below the node with collapsed white space
+func textOf(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == html.ElementNode && (n.Data == "br" || isBlock(n)):
+			b.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
+
+func findAll(n *html.Node, match func(*html.Node) bool) []*html.Node {
+	found := []*html.Node{}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if match(n) {
+			found = append(found, n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return found
+}
+
+func isElement(tags ...string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		for _, tag := range tags {
+			if n.Data == tag {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func resolveURL(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	return u.String()
+}
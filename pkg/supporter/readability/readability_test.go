@@ -0,0 +1,88 @@
+package readability
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata")
+
+// pageUrls are the urls the real pages of testdata were saved from, see testdata/SOURCES.md.
+// The synthetic_ pages are written by hand for metadata the real pages lack.
+var pageUrls = map[string]string{
+	"golang_go1_release_notes": "http://golang.org/doc/go1.html",
+	"npm_scripts":              "https://docs.npmjs.com/cli/v10/using-npm/scripts",
+	"rustdoc_what_is_rustdoc":  "https://doc.rust-lang.org/rustdoc/what-is-rustdoc.html",
+}
+
+// every testdata/<page>.html is extracted and compared with testdata/<page>.golden.json
+func TestExtractGolden(t *testing.T) {
+	pages, err := filepath.Glob(filepath.Join("testdata", "*.html"))
+	if err != nil || len(pages) == 0 {
+		t.Fatalf("no test pages: %v", err)
+	}
+	for _, page := range pages {
+		name := strings.TrimSuffix(filepath.Base(page), ".html")
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open(page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			pageUrl, ok := pageUrls[name]
+			if !ok {
+				pageUrl = "https://news.example.com/section/" + name
+			}
+			article, err := Extract(f, "text/html", pageUrl)
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			got, err := json.MarshalIndent(article, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", name+".golden.json")
+			if *update {
+				if err := os.WriteFile(golden, append(got, '\n'), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("missing golden file, run the test with -update: %v", err)
+			}
+			if string(got)+"\n" != string(want) {
+				t.Errorf("Extract() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestExtractNoContent(t *testing.T) {
+	page := `<html><head><title>Empty</title></head><body><nav><a href="/">Home</a></nav></body></html>`
+	_, err := Extract(strings.NewReader(page), "text/html; charset=utf-8", "https://example.com/")
+	if !errors.Is(err, ErrNoContent) {
+		t.Errorf("Extract() error = %v, want ErrNoContent", err)
+	}
+}
+
+func Test_normalizeDate(t *testing.T) {
+	tests := map[string]string{
+		"2024-03-18T06:30:00+01:00": "2024-03-18T06:30:00+01:00",
+		"2024-03-18T06:30:00+0100":  "2024-03-18T06:30:00+01:00",
+		"2024-03-18":                "2024-03-18T00:00:00Z",
+		"March 18, 2024":            "2024-03-18T00:00:00Z",
+		"yesterday":                 "yesterday",
+	}
+	for date, want := range tests {
+		if got := normalizeDate(date); got != want {
+			t.Errorf("normalizeDate(%q) = %q, want %q", date, got, want)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// Package readlevel measures how hard an English text is to read: the length of its sentences, the share
+// of uncommon words, and the CEFR level and IELTS band they suggest.
+package readlevel
+
+import (
+	_ "embed"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var paragraphBreak = regexp.MustCompile(`\n[ \t\r]*\n`)
+
+// CEFR levels from the easiest
+var CEFR_LEVELS = []string{"A1", "A2", "B1", "B2", "C1", "C2"}
+
+// typical mean sentence length, share of uncommon words and IELTS band of the texts of each CEFR level,
+// levels in between are interpolated
+var (
+	sentenceLengthAnchors = []float64{8, 11, 14, 18, 22, 27}
+	rareShareAnchors      = []float64{0.03, 0.06, 0.09, 0.13, 0.17, 0.22}
+	bandAnchors           = []float64{2.5, 3.5, 4.5, 6.0, 7.5, 8.5}
+)
+
+const (
+	// the vocabulary weighs more than the sentence length in the level
+	RARE_SHARE_WEIGHT = 0.6
+	// upper bounds in words of the buckets of the sentence length histogram, the last bucket has no bound
+	SENTENCE_BUCKET_SIZE = 10
+	SENTENCE_BUCKETS     = 5
+)
+
+// words ending with a period that do not end a sentence
+var abbreviations = map[string]bool{
+	"mr.": true, "mrs.": true, "ms.": true, "dr.": true, "prof.": true, "st.": true, "jr.": true, "sr.": true,
+	"e.g.": true, "i.e.": true, "etc.": true, "vs.": true, "approx.": true, "no.": true, "fig.": true, "u.s.": true,
+	"u.k.": true, "inc.": true, "ltd.": true, "co.": true, "jan.": true, "feb.": true, "aug.": true, "sept.": true,
+	"oct.": true, "nov.": true, "dec.": true,
+}
+
+//go:embed common_en.txt
+var commonList string
+
+var commonWords = func() map[string]bool {
+	words := map[string]bool{}
+	for _, line := range strings.Split(commonList, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, word := range strings.Fields(line) {
+			words[word] = true
+		}
+	}
+	return words
+}()
+
+type SentenceLengths struct {
+	Mean   float64 `json:"mean"`
+	Median int     `json:"median"`
+	P90    int     `json:"p90"`
+	Max    int     `json:"max"`
+	// number of sentences of 1-10, 11-20, 21-30, 31-40 and more than 40 words
+	Histogram []int `json:"histogram"`
+}
+
+type Stats struct {
+	Words           int             `json:"words"`
+	Sentences       int             `json:"sentences"`
+	SentenceLengths SentenceLengths `json:"sentence_lengths"`
+	// share of the words outside the common words, names and numbers are not counted
+	RareWordShare float64 `json:"rare_word_share"`
+	// empty for a text without words
+	CEFR      string  `json:"cefr"`
+	IeltsBand float64 `json:"ielts_band"`
+}
+
+// Analyze measures the text, paragraphs are separated by empty lines like in the extracted articles,
+// single line breaks are hard wraps
+func Analyze(text string) *Stats {
+	stats := &Stats{SentenceLengths: SentenceLengths{Histogram: make([]int, SENTENCE_BUCKETS)}}
+	lengths := []int{}
+	counted, rare := 0, 0
+
+	for _, paragraph := range paragraphBreak.Split(text, -1) {
+		sentenceLength := 0
+		for _, field := range strings.Fields(paragraph) {
+			word := trimPunctuation(field)
+			if word == "" {
+				continue
+			}
+			sentenceStart := sentenceLength == 0
+			sentenceLength++
+			stats.Words++
+
+			if countsForVocabulary(word, sentenceStart) {
+				counted++
+				if !IsCommon(word) {
+					rare++
+				}
+			}
+			if endsSentence(field) {
+				lengths = append(lengths, sentenceLength)
+				sentenceLength = 0
+			}
+		}
+		// a paragraph without final punctuation, like a heading, is a sentence of its own
+		if sentenceLength > 0 {
+			lengths = append(lengths, sentenceLength)
+		}
+	}
+	if stats.Words == 0 {
+		return stats
+	}
+
+	stats.Sentences = len(lengths)
+	stats.SentenceLengths = sentenceLengths(lengths)
+	if counted > 0 {
+		stats.RareWordShare = round(float64(rare)/float64(counted), 1000)
+	}
+
+	level := RARE_SHARE_WEIGHT*levelOf(stats.RareWordShare, rareShareAnchors) +
+		(1-RARE_SHARE_WEIGHT)*levelOf(stats.SentenceLengths.Mean, sentenceLengthAnchors)
+	stats.CEFR = CEFR_LEVELS[int(math.Round(level))]
+	stats.IeltsBand = round(interpolate(level, bandAnchors), 2)
+	return stats
+}
+
+// IsCommon tells whether the word, or its base form, is among the common words
+func IsCommon(word string) bool {
+	word = strings.ToLower(strings.ReplaceAll(word, "’", "'"))
+	for _, part := range strings.Split(word, "-") {
+		if part != "" && !isCommonPart(part) {
+			return false
+		}
+	}
+	return true
+}
+
+func isCommonPart(word string) bool {
+	if commonWords[word] {
+		return true
+	}
+	for _, lemma := range lemmas(word) {
+		if commonWords[lemma] {
+			return true
+		}
+	}
+	return false
+}
+
+// lemmas guesses the base forms of an inflected word: plurals, past forms, participles, comparatives and adverbs
+func lemmas(word string) []string {
+	word = strings.TrimSuffix(strings.TrimSuffix(word, "'s"), "'")
+	candidates := []string{word}
+	replace := func(suffix string, bases ...string) {
+		stem, ok := strings.CutSuffix(word, suffix)
+		if !ok || len(stem) < 2 {
+			return
+		}
+		for _, base := range bases {
+			candidates = append(candidates, stem+base)
+		}
+		// stopped, bigger
+		if n := len(stem); n > 2 && stem[n-1] == stem[n-2] && !strings.ContainsRune("aeiou", rune(stem[n-1])) {
+			candidates = append(candidates, stem[:n-1])
+		}
+	}
+	replace("ies", "y")
+	replace("ied", "y")
+	replace("ier", "y")
+	replace("iest", "y")
+	replace("ily", "y")
+	replace("es", "", "e")
+	replace("s", "")
+	replace("ed", "", "e")
+	replace("ing", "", "e")
+	replace("er", "", "e")
+	replace("est", "", "e")
+	replace("ly", "", "le")
+	return candidates
+}
+
+// countsForVocabulary leaves out numbers, acronyms and names, capitalized words inside a sentence
+func countsForVocabulary(word string, sentenceStart bool) bool {
+	letters, upper := 0, 0
+	for _, r := range word {
+		if unicode.IsDigit(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters == 0 || (upper > 1 && upper == letters) {
+		return false
+	}
+	first := []rune(word)[0]
+	return sentenceStart || !unicode.IsUpper(first)
+}
+
+func endsSentence(field string) bool {
+	field = strings.TrimRight(field, `"'”’)]`)
+	if field == "" || !strings.ContainsAny(field[len(field)-1:], ".!?") {
+		return false
+	}
+	if strings.HasSuffix(field, "!") || strings.HasSuffix(field, "?") {
+		return true
+	}
+	lower := strings.ToLower(strings.TrimLeft(field, `"'“‘([`))
+	// initials like "J." do not end a sentence either
+	return !abbreviations[lower] && !(len([]rune(lower)) == 2 && unicode.IsLetter([]rune(lower)[0]))
+}
+
+// trimPunctuation keeps the letters, digits, apostrophes and hyphens inside the word
+func trimPunctuation(field string) string {
+	return strings.TrimFunc(field, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func sentenceLengths(lengths []int) SentenceLengths {
+	result := SentenceLengths{Histogram: make([]int, SENTENCE_BUCKETS)}
+	if len(lengths) == 0 {
+		return result
+	}
+	total := 0
+	for _, length := range lengths {
+		total += length
+		bucket := min((length-1)/SENTENCE_BUCKET_SIZE, SENTENCE_BUCKETS-1)
+		result.Histogram[bucket]++
+	}
+	sorted := append([]int{}, lengths...)
+	sort.Ints(sorted)
+	result.Mean = round(float64(total)/float64(len(lengths)), 10)
+	result.Median = sorted[len(sorted)/2]
+	result.P90 = sorted[min(len(sorted)*9/10, len(sorted)-1)]
+	result.Max = sorted[len(sorted)-1]
+	return result
+}
+
+// levelOf places the value between the anchors of the levels, from 0 for A1 to 5 for C2
+func levelOf(value float64, anchors []float64) float64 {
+	if value <= anchors[0] {
+		return 0
+	}
+	for i := 1; i < len(anchors); i++ {
+		if value <= anchors[i] {
+			return float64(i-1) + (value-anchors[i-1])/(anchors[i]-anchors[i-1])
+		}
+	}
+	return float64(len(anchors) - 1)
+}
+
+// interpolate reads the value of a level between the anchors
+func interpolate(level float64, anchors []float64) float64 {
+	i := min(int(level), len(anchors)-2)
+	return anchors[i] + (level-float64(i))*(anchors[i+1]-anchors[i])
+}
+
+// round to the nearest fraction, e.g. 2 rounds to halves
+func round(value, fraction float64) float64 {
+	return math.Round(value*fraction) / fraction
+}
@@ -0,0 +1,96 @@
+package readlevel
+
+import (
+	"reflect"
+	"testing"
+)
+
+const simpleText = `My name is Tom. I live in a small house with my family. We have a dog and a cat.
+Every morning I walk to school with my sister. After school we play in the park.
+On Sunday we visit my grandmother. She makes a big lunch for us. I like her cakes very much.`
+
+const academicText = `Notwithstanding the considerable heterogeneity of the epidemiological evidence, researchers
+increasingly contend that chronic exposure to particulate pollutants exacerbates cardiovascular morbidity,
+particularly among socioeconomically disadvantaged cohorts whose residential proximity to arterial thoroughfares
+is disproportionately pronounced. Longitudinal investigations, however, remain methodologically constrained
+by confounding variables, attrition and the inherent difficulty of quantifying cumulative individual exposure.`
+
+func TestAnalyze(t *testing.T) {
+	text := "Dr. Smith arrived late. The meeting, which had started at nine, was almost over!\n\nA heading\n\nShort\none."
+	got := Analyze(text)
+	want := SentenceLengths{Mean: 4.5, Median: 4, P90: 10, Max: 10, Histogram: []int{4, 0, 0, 0, 0}}
+	if got.Words != 18 || got.Sentences != 4 {
+		t.Errorf("Analyze() words, sentences = %d, %d, want 18, 4", got.Words, got.Sentences)
+	}
+	if !reflect.DeepEqual(got.SentenceLengths, want) {
+		t.Errorf("Analyze() sentence lengths = %+v, want %+v", got.SentenceLengths, want)
+	}
+}
+
+func TestAnalyzeLevels(t *testing.T) {
+	simple, academic := Analyze(simpleText), Analyze(academicText)
+	if simple.CEFR != "A1" && simple.CEFR != "A2" {
+		t.Errorf("simple text level = %v (%+v), want A1 or A2", simple.CEFR, simple)
+	}
+	if academic.CEFR != "C1" && academic.CEFR != "C2" {
+		t.Errorf("academic text level = %v (%+v), want C1 or C2", academic.CEFR, academic)
+	}
+	if simple.IeltsBand >= academic.IeltsBand || simple.RareWordShare >= academic.RareWordShare {
+		t.Errorf("simple text %+v is not easier than academic text %+v", simple, academic)
+	}
+	if band := academic.IeltsBand; band < 2.5 || band > 9 || band*2 != float64(int(band*2)) {
+		t.Errorf("band %v is not a half band between 2.5 and 9", band)
+	}
+}
+
+func TestAnalyzeEmpty(t *testing.T) {
+	got := Analyze(" \n -- \n")
+	if got.Words != 0 || got.CEFR != "" || got.IeltsBand != 0 {
+		t.Errorf("Analyze() of an empty text = %+v", got)
+	}
+}
+
+func TestIsCommon(t *testing.T) {
+	tests := map[string]bool{
+		"house":        true,
+		"Houses":       true,
+		"stopped":      true,
+		"studies":      true,
+		"carried":      true,
+		"making":       true,
+		"bigger":       true,
+		"simply":       true,
+		"children":     true,
+		"don’t":        true,
+		"well-known":   true,
+		"family's":     true,
+		"exacerbates":  false,
+		"cohort":       false,
+		"self-evident": false,
+	}
+	for word, want := range tests {
+		if got := IsCommon(word); got != want {
+			t.Errorf("IsCommon(%q) = %v, want %v", word, got, want)
+		}
+	}
+}
+
+func Test_countsForVocabulary(t *testing.T) {
+	tests := []struct {
+		word          string
+		sentenceStart bool
+		want          bool
+	}{
+		{"house", false, true},
+		{"The", true, true},
+		{"London", false, false},
+		{"NASA", true, false},
+		{"2024", false, false},
+		{"I", false, false},
+	}
+	for _, tt := range tests {
+		if got := countsForVocabulary(tt.word, tt.sentenceStart); got != tt.want {
+			t.Errorf("countsForVocabulary(%q, %v) = %v, want %v", tt.word, tt.sentenceStart, got, tt.want)
+		}
+	}
+}
@@ -25,3 +25,18 @@ func ExecuteHostCmd(cmd string) (string, error) {
 
 	return stdout.String(), nil
 }
+
+// ExecuteHostProgram runs the program with its arguments as they are, without a shell
+func ExecuteHostProgram(name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	command := exec.Command(name, args...)
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+
+	err := command.Run()
+	if err != nil {
+		return stderr.String(), err
+	}
+
+	return stdout.String(), nil
+}